@@ -0,0 +1,24 @@
+package main
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+// inFlight counts the requests being served, so shutdown can say how many
+// it waited for.
+type inFlight struct {
+	n atomic.Int64
+}
+
+func (f *inFlight) wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.n.Add(1)
+		defer f.n.Add(-1)
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (f *inFlight) count() int64 {
+	return f.n.Load()
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestInFlightCounts(t *testing.T) {
+	var requests inFlight
+	var during int64
+	handler := requests.wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		during = requests.count()
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if during != 1 {
+		t.Fatalf("count during request = %d, want 1", during)
+	}
+	if n := requests.count(); n != 0 {
+		t.Fatalf("count after request = %d, want 0", n)
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+
+	"secure.share/config"
+)
+
+// unixSocketMode lets a proxy in the server's group connect while keeping
+// everyone else out.
+const unixSocketMode = 0o660
+
+// listen opens the configured Unix socket, or the TCP address otherwise.
+// Closing the returned listener removes the socket file.
+func listen(cfg *config.Config) (net.Listener, error) {
+	path := cfg.Server.UnixSocket
+	if path == "" {
+		return net.Listen("tcp", cfg.Addr())
+	}
+
+	// A socket left behind by a crash would make Listen fail; anything that
+	// isn't a socket is left alone.
+	if fi, err := os.Lstat(path); err == nil {
+		if fi.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("removing stale socket: %w", err)
+		}
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, unixSocketMode); err != nil {
+		ln.Close()
+		return nil, fmt.Errorf("setting socket permissions: %w", err)
+	}
+	return ln, nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"secure.share/config"
+	"secure.share/internal/api"
+	"secure.share/internal/store"
+)
+
+func TestListenUnixSocket(t *testing.T) {
+	cfg := config.Default()
+	cfg.Server.UnixSocket = filepath.Join(t.TempDir(), "http.sock")
+
+	// A stale socket from a previous run must not block startup.
+	stale, err := net.Listen("unix", cfg.Server.UnixSocket)
+	if err != nil {
+		t.Fatalf("failed to create stale socket: %v", err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	ln, err := listen(cfg)
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	fi, err := os.Stat(cfg.Server.UnixSocket)
+	if err != nil {
+		t.Fatalf("socket file missing: %v", err)
+	}
+	if perm := fi.Mode().Perm(); perm != unixSocketMode {
+		t.Fatalf("socket mode mismatch: got %o, want %o", perm, unixSocketMode)
+	}
+
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	server := &http.Server{Handler: api.SetupRouter(st, cfg)}
+	go server.Serve(ln)
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", cfg.Server.UnixSocket)
+		},
+	}}
+	resp, err := client.Get("http://unix/health")
+	if err != nil {
+		t.Fatalf("health request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("health status mismatch: got %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+
+	if err := server.Shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown failed: %v", err)
+	}
+	if _, err := os.Stat(cfg.Server.UnixSocket); !os.IsNotExist(err) {
+		t.Fatalf("socket file left after shutdown: %v", err)
+	}
+}
+
+func TestListenRefusesNonSocket(t *testing.T) {
+	cfg := config.Default()
+	cfg.Server.UnixSocket = filepath.Join(t.TempDir(), "not-a-socket")
+	if err := os.WriteFile(cfg.Server.UnixSocket, []byte("data"), 0o600); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if _, err := listen(cfg); err == nil {
+		t.Fatal("listen replaced a regular file")
+	}
+}
@@ -1,13 +1,20 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
-	"log"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"secure.share/config"
 	"secure.share/internal/api"
+	"secure.share/internal/crypto"
+	"secure.share/internal/logging"
 	"secure.share/internal/store"
 
 	"github.com/redis/go-redis/v9"
@@ -15,50 +22,205 @@ import (
 
 func main() {
 	configPath := flag.String("config", "", "path to config file")
+	dropPassphrases := flag.Bool("drop-passphrases", false, "blank the passphrase stored with existing secrets, then exit")
 	flag.Parse()
 
 	cfg, err := config.Load(*configPath)
 	if err != nil {
-		log.Fatal("config error:", err)
+		fatal("config error", err)
 	}
 
+	logger, err := logging.New(os.Stderr, cfg.Logging.Format, cfg.Logging.Level)
+	if err != nil {
+		fatal("config error", err)
+	}
+	slog.SetDefault(logger)
+
+	if err := crypto.SetKDFParams(crypto.KDFParams{
+		Memory:      cfg.Crypto.KDF.Memory,
+		Iterations:  cfg.Crypto.KDF.Iterations,
+		Parallelism: cfg.Crypto.KDF.Parallelism,
+	}); err != nil {
+		fatal("config error", err)
+	}
+	crypto.SetCompression(cfg.Crypto.Compress)
+
 	st := initStore(cfg)
 	defer st.Close()
 
-	router := api.SetupRouter(st, cfg)
+	if *dropPassphrases {
+		if cfg.Store.Type == "memory" {
+			fatal("config error", errors.New("-drop-passphrases needs a persistent store; a new memory store holds no secrets"))
+		}
+		n, err := store.DropPassphrases(context.Background(), st)
+		if err != nil {
+			fatal("dropping passphrases failed", err, "dropped", n)
+		}
+		slog.Info("dropped stored passphrases", "secrets", n)
+		return
+	}
+
+	router, metricsRouter := api.SetupRouters(st, cfg)
+
+	ln, err := listen(cfg)
+	if err != nil {
+		fatal("listen failed", err)
+	}
 
-	log.Printf("Server starting on %s", cfg.Addr())
-	log.Printf("Base URL: %s", cfg.Server.BaseURL)
-	log.Printf("Store: %s", cfg.Store.Type)
+	addr := cfg.Addr()
+	if cfg.Server.UnixSocket != "" {
+		addr = "unix:" + cfg.Server.UnixSocket
+	}
+	slog.Info("server starting", "addr", addr, "base_url", cfg.Server.BaseURL, "store", cfg.Store.Type)
 
+	var requests inFlight
 	server := &http.Server{
-		Addr:         cfg.Addr(),
-		Handler:      router,
+		Handler:      requests.wrap(router),
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
+		ErrorLog:     slog.NewLogLogger(logger.Handler(), slog.LevelWarn),
+	}
+	if cfg.TLS.ClientCAFile != "" {
+		server.TLSConfig, err = api.ClientCATLSConfig(cfg.TLS.ClientCAFile)
+		if err != nil {
+			fatal("client ca", err)
+		}
 	}
 
+	var metricsServer *http.Server
+	if metricsRouter != nil {
+		metricsServer = &http.Server{
+			Addr:         cfg.Metrics.Addr,
+			Handler:      metricsRouter,
+			ReadTimeout:  15 * time.Second,
+			WriteTimeout: 15 * time.Second,
+		}
+		go func() {
+			slog.Info("metrics server starting", "addr", cfg.Metrics.Addr)
+			if err := metricsServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+				slog.Error("metrics server failed", "error", err)
+			}
+		}()
+	}
+
+	var redirectServer *http.Server
+	if cfg.TLS.RedirectAddr != "" {
+		redirectServer = &http.Server{
+			Addr:         cfg.TLS.RedirectAddr,
+			Handler:      redirectToHTTPS(cfg.Server.BaseURL),
+			ReadTimeout:  15 * time.Second,
+			WriteTimeout: 15 * time.Second,
+		}
+		go func() {
+			slog.Info("redirecting HTTP to HTTPS", "addr", cfg.TLS.RedirectAddr, "base_url", cfg.Server.BaseURL)
+			if err := redirectServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+				slog.Error("redirect server failed", "error", err)
+			}
+		}()
+	}
+
+	// Shutdown closes the listener, which also removes a Unix socket file,
+	// and waits for in-flight requests; the store is closed once it returns.
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+		<-sig
+
+		pending := requests.count()
+		slog.Info("shutting down", "in_flight", pending)
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+		defer cancel()
+		if metricsServer != nil {
+			metricsServer.Close()
+		}
+		if redirectServer != nil {
+			redirectServer.Close()
+		}
+		err := server.Shutdown(ctx)
+		switch {
+		case errors.Is(err, context.DeadlineExceeded):
+			slog.Warn("shutdown timed out", "timeout", cfg.Server.ShutdownTimeout, "in_flight", requests.count())
+		case err != nil:
+			slog.Error("shutdown failed", "error", err)
+		default:
+			slog.Info("drained in-flight requests", "drained", pending)
+		}
+	}()
+
 	if cfg.TLS.CertFile != "" && cfg.TLS.KeyFile != "" {
-		log.Fatal(server.ListenAndServeTLS(cfg.TLS.CertFile, cfg.TLS.KeyFile))
+		err = server.ServeTLS(ln, cfg.TLS.CertFile, cfg.TLS.KeyFile)
 	} else {
-		log.Fatal(server.ListenAndServe())
+		err = server.Serve(ln)
 	}
+	if !errors.Is(err, http.ErrServerClosed) {
+		st.Close()
+		fatal("server failed", err)
+	}
+	<-stopped
+}
+
+// fatal logs err and exits, for failures the server can't start or run
+// past.
+func fatal(msg string, err error, args ...any) {
+	slog.Error(msg, append([]any{"error", err}, args...)...)
+	os.Exit(1)
 }
 
 func initStore(cfg *config.Config) store.Store {
+	compression, err := store.ParseCompression(cfg.Store.Compression)
+	if err != nil {
+		fatal("config error", err)
+	}
+
 	switch cfg.Store.Type {
 	case "redis":
-		st, err := store.NewRedisStore(&redis.Options{
-			Addr:     cfg.Store.Redis.Addr,
-			Password: cfg.Store.Redis.Password,
-			DB:       cfg.Store.Redis.DB,
+		addrs := cfg.Store.Redis.Addrs
+		if cfg.Store.Redis.Mode == store.RedisSingle {
+			addrs = []string{cfg.Store.Redis.Addr}
+		}
+		st, err := store.NewRedisStoreForMode(cfg.Store.Redis.Mode, &redis.UniversalOptions{
+			Addrs:      addrs,
+			MasterName: cfg.Store.Redis.MasterName,
+			Password:   cfg.Store.Redis.Password,
+			DB:         cfg.Store.Redis.DB,
+		}, store.RedisStoreOptions{
+			Failover: store.FailoverPolicy{
+				Retries: cfg.Store.Redis.FailoverRetries,
+				Backoff: cfg.Store.Redis.FailoverBackoff,
+			},
+			Compression: compression,
+			MaxInflated: cfg.Store.MaxInflatedBytes,
+		})
+		if err != nil {
+			fatal("redis connection failed", err)
+		}
+		return st
+	case "dynamodb":
+		st, err := store.NewDynamoStore(store.DynamoOptions{
+			Region:      cfg.Store.DynamoDB.Region,
+			Table:       cfg.Store.DynamoDB.Table,
+			Endpoint:    cfg.Store.DynamoDB.Endpoint,
+			Compression: compression,
+			MaxInflated: cfg.Store.MaxInflatedBytes,
+		})
+		if err != nil {
+			fatal("dynamodb connection failed", err)
+		}
+		return st
+	case "sqlite":
+		st, err := store.NewSQLiteStoreWithOptions(store.SQLiteOptions{
+			Path:        cfg.Store.SQLite.Path,
+			Compression: compression,
+			MaxInflated: cfg.Store.MaxInflatedBytes,
 		})
 		if err != nil {
-			log.Fatal("redis connection failed:", err)
+			fatal("sqlite open failed", err)
 		}
 		return st
 	default:
-		return store.NewMemoryStore(30 * time.Second)
+		return store.NewMemoryStoreWithGrace(30*time.Second, cfg.Store.Memory.GracePeriod)
 	}
 }
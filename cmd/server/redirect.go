@@ -0,0 +1,16 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+)
+
+// redirectToHTTPS sends every request to the same path and query under
+// baseURL, the server's https address. 308 keeps the method and body, so
+// an API client posting over plain HTTP is redirected rather than broken.
+func redirectToHTTPS(baseURL string) http.Handler {
+	base := strings.TrimSuffix(baseURL, "/")
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, base+r.URL.RequestURI(), http.StatusPermanentRedirect)
+	})
+}
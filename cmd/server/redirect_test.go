@@ -0,0 +1,20 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRedirectToHTTPS(t *testing.T) {
+	handler := redirectToHTTPS("https://share.example.com/")
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "http://share.example.com/api/secrets?x=1", nil))
+	if rec.Code != http.StatusPermanentRedirect {
+		t.Fatalf("status = %d, want 308", rec.Code)
+	}
+	if got := rec.Header().Get("Location"); got != "https://share.example.com/api/secrets?x=1" {
+		t.Fatalf("Location = %q", got)
+	}
+}
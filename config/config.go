@@ -2,11 +2,20 @@
 package config
 
 import (
+	"encoding/json"
 	"fmt"
+	"mime"
+	"net"
+	"net/url"
 	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/BurntSushi/toml"
 	"gopkg.in/yaml.v3"
 )
 
@@ -16,23 +25,132 @@ type Config struct {
 	Secrets   SecretsConfig   `yaml:"secrets"`
 	RateLimit RateLimitConfig `yaml:"rate_limit"`
 	TLS       TLSConfig       `yaml:"tls"`
+	Crypto    CryptoConfig    `yaml:"crypto"`
+	Honeypot  HoneypotConfig  `yaml:"honeypot"`
+	Admin     AdminConfig     `yaml:"admin"`
+	Hooks     HooksConfig     `yaml:"hooks"`
+	Geo       GeoConfig       `yaml:"geo"`
+	APIKeys   APIKeysConfig   `yaml:"api_keys"`
+	Offload   OffloadConfig   `yaml:"offload"`
+	Stats     StatsConfig     `yaml:"stats"`
+	Metrics   MetricsConfig   `yaml:"metrics"`
+	Logging   LoggingConfig   `yaml:"logging"`
+	// SignedLinks lets a trusted system mint reveal links that work
+	// without the passphrase.
+	SignedLinks SignedLinksConfig `yaml:"signed_links"`
 }
 
 type ServerConfig struct {
-	Host    string `yaml:"host"`
-	Port    int    `yaml:"port"`
-	BaseURL string `yaml:"base_url"`
+	Host            string `yaml:"host"`
+	Port            int    `yaml:"port"`
+	BaseURL         string `yaml:"base_url"`
+	RequestIDHeader string `yaml:"request_id_header"`
+	// UnixSocket, when set, is listened on instead of Host:Port, e.g. for a
+	// sidecar proxy. The file is created with mode 0660.
+	UnixSocket string `yaml:"unix_socket"`
+	// JSONMaxDepth and JSONMaxFields bound request bodies before they are
+	// decoded: nesting depth, and object keys plus array elements overall.
+	JSONMaxDepth  int `yaml:"json_max_depth"`
+	JSONMaxFields int `yaml:"json_max_fields"`
+	// MaxBodyBytes caps JSON request bodies; larger ones get a 413. File
+	// uploads have their own limit, max_file_bytes.
+	MaxBodyBytes int64 `yaml:"max_body_bytes"`
+	// SameOrigin refuses creates and reveals from browser pages not served
+	// from BaseURL's host. Callers sending no Origin or Referer are exempt.
+	SameOrigin bool `yaml:"same_origin"`
+	// LogSampleRate logs one in every LogSampleRate successful requests;
+	// errors and rate limited requests are always logged.
+	LogSampleRate int `yaml:"log_sample_rate"`
+	// JSONContentTypes are media types accepted for request bodies besides
+	// application/json, e.g. "application/merge-patch+json".
+	JSONContentTypes []string `yaml:"json_content_types"`
+	// ShutdownTimeout is how long in-flight requests get to finish after
+	// SIGINT or SIGTERM before the server stops anyway.
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	CORS            CORSConfig    `yaml:"cors"`
+}
+
+// CORSConfig lists what cross-origin browser pages may call the API with.
+// AllowedOrigins are full origins such as "https://example.com", or "*"
+// for any; none allows no cross-origin calls.
+type CORSConfig struct {
+	AllowedOrigins []string `yaml:"allowed_origins"`
+	AllowedMethods []string `yaml:"allowed_methods"`
+	AllowedHeaders []string `yaml:"allowed_headers"`
 }
 
 type StoreConfig struct {
-	Type  string      `yaml:"type"`
-	Redis RedisConfig `yaml:"redis"`
+	Type     string         `yaml:"type"`
+	Memory   MemoryConfig   `yaml:"memory"`
+	Redis    RedisConfig    `yaml:"redis"`
+	DynamoDB DynamoDBConfig `yaml:"dynamodb"`
+	SQLite   SQLiteConfig   `yaml:"sqlite"`
+	Health   HealthConfig   `yaml:"health"`
+	// NegativeCache answers repeated lookups of missing IDs in process,
+	// without asking the store.
+	NegativeCache NegativeCacheConfig `yaml:"negative_cache"`
+	// Compression is applied by the redis, dynamodb and sqlite stores to
+	// encoded secrets before writing: "none", "flate" or "gzip". Blobs
+	// written with any setting stay readable after changing it.
+	Compression string `yaml:"compression"`
+	// MaxInflatedBytes caps how large a compressed blob may inflate when
+	// read back, so a crafted blob can't exhaust memory on reveal. Reads
+	// of larger blobs fail.
+	MaxInflatedBytes int64 `yaml:"max_inflated_bytes"`
+}
+
+type NegativeCacheConfig struct {
+	// TTL is how long a missing ID stays cached; keep it short, as an ID
+	// created through another instance stays hidden here until then. Zero
+	// disables the cache, as does a memory store grace period.
+	TTL  time.Duration `yaml:"ttl"`
+	Size int           `yaml:"size"`
+}
+
+// HealthConfig marks the store degraded on /readyz once, over Window, at
+// least MinSamples operations ran and either more than MaxErrorRate of them
+// failed or their mean latency passed MaxLatency (zero ignores latency).
+type HealthConfig struct {
+	Window       time.Duration `yaml:"window"`
+	MaxErrorRate float64       `yaml:"max_error_rate"`
+	MaxLatency   time.Duration `yaml:"max_latency"`
+	MinSamples   int           `yaml:"min_samples"`
+}
+
+type MemoryConfig struct {
+	// GracePeriod keeps a secret briefly after its last view so the same
+	// request (by request id) can retry a reveal whose response was lost.
+	GracePeriod time.Duration `yaml:"grace_period"`
 }
 
 type RedisConfig struct {
-	Addr     string `yaml:"addr"`
-	Password string `yaml:"password"`
-	DB       int    `yaml:"db"`
+	// Mode is "single" for one server at Addr, "sentinel" for the master
+	// named MasterName found through the Sentinels at Addrs, or "cluster"
+	// for a Redis Cluster with Addrs as seed nodes.
+	Mode       string   `yaml:"mode"`
+	Addr       string   `yaml:"addr"`
+	Addrs      []string `yaml:"addrs"`
+	MasterName string   `yaml:"master_name"`
+	Password   string   `yaml:"password"`
+	DB         int      `yaml:"db"`
+	// FailoverRetries and FailoverBackoff control retries of operations
+	// rejected during a failover (MOVED, ASK, connection refused, ...);
+	// the backoff doubles per retry. Past them the API answers 503.
+	FailoverRetries int           `yaml:"failover_retries"`
+	FailoverBackoff time.Duration `yaml:"failover_backoff"`
+}
+
+// DynamoDBConfig points at a table with a string partition key "id" and TTL
+// enabled on the "expires_at" attribute.
+type DynamoDBConfig struct {
+	Region   string `yaml:"region"`
+	Table    string `yaml:"table"`
+	Endpoint string `yaml:"endpoint"`
+}
+
+type SQLiteConfig struct {
+	// Path is the database file, created with its schema if missing.
+	Path string `yaml:"path"`
 }
 
 type SecretsConfig struct {
@@ -40,49 +158,393 @@ type SecretsConfig struct {
 	MaxTTL       time.Duration `yaml:"max_ttl"`
 	DefaultViews int           `yaml:"default_views"`
 	MaxViews     int           `yaml:"max_views"`
+	// BlockedPatterns are regular expressions matched against plaintext at
+	// create; any match rejects the secret.
+	BlockedPatterns []string `yaml:"blocked_patterns"`
+	// OwnerTokens issues an owner token at create that can delete or extend
+	// the secret without knowing its passphrase.
+	OwnerTokens bool `yaml:"owner_tokens"`
+	// TombstoneTTL keeps a content-free marker after a secret's last view so
+	// status can report "already revealed" rather than "not found". Zero
+	// disables tombstones.
+	TombstoneTTL time.Duration `yaml:"tombstone_ttl"`
+	// AllowPIN lets creators add a short numeric PIN on top of the
+	// passphrase. MaxPINAttempts is how many wrong guesses a secret takes
+	// before it burns; wrong PINs, reveal codes and chosen passphrases all
+	// count towards the same limit.
+	AllowPIN       bool `yaml:"allow_pin"`
+	MaxPINAttempts int  `yaml:"max_pin_attempts"`
+	// IntegrityMAC lets creators ask for a detached MAC over their content,
+	// verifiable later with the key only they receive.
+	IntegrityMAC bool `yaml:"integrity_mac"`
+	// RevealCodeTTL is how long a code from /request-code stays valid, at
+	// least; it is accepted for up to twice that.
+	RevealCodeTTL time.Duration `yaml:"reveal_code_ttl"`
+	// AckTTL is how long a require_ack acknowledgment token stays valid.
+	AckTTL time.Duration `yaml:"ack_ttl"`
+	// MaxArchiveBytes caps the total content of a multi-file secret, which
+	// is revealed as a zip download. Zero disables archives.
+	MaxArchiveBytes int `yaml:"max_archive_bytes"`
+	// MaxFileBytes caps a file uploaded to POST /api/secrets/file. Zero
+	// disables file uploads.
+	MaxFileBytes int `yaml:"max_file_bytes"`
+	// DownloadRate caps the speed of archive and file secret downloads in
+	// bytes per second, either for each download or, with
+	// DownloadRateScope "global", for all of them together. Other reveals
+	// aren't paced. Zero is unlimited. A throttled download may take as
+	// long as it needs: each chunk gets its own 15s write deadline, and the
+	// request timeout doesn't apply.
+	DownloadRate      int    `yaml:"download_rate"`
+	DownloadRateScope string `yaml:"download_rate_scope"`
+	// RevalidateTTL lets the recipient of an archive download re-fetch it
+	// for this long with its ETag and reservation token, getting a 304
+	// without using another view. Zero disables it.
+	RevalidateTTL time.Duration `yaml:"revalidate_ttl"`
+	// RevealNonces adds a one-time nonce to reveal URLs that changes with
+	// every view, so a cached or replayed URL can't be used to reveal.
+	RevealNonces bool `yaml:"reveal_nonces"`
+	// StreamReveal enables GET /api/secrets/{id}/raw, which sends the
+	// content as application/octet-stream in flushed chunks rather than
+	// inside a JSON response.
+	StreamReveal bool `yaml:"stream_reveal"`
+	// MaxTotal caps how many live secrets the store holds at once; creates
+	// beyond it fail until some expire or are revealed. Zero is unlimited.
+	MaxTotal int `yaml:"max_total"`
+	// MaxLinks caps how many share links a key-split secret may have. It
+	// can't exceed 255, the most shares a split supports.
+	MaxLinks int `yaml:"max_links"`
+	// DraftTTL is how long a draft from POST /api/secrets/draft waits for
+	// its commit. Drafts are held in process memory only, so they are off
+	// by default; zero disables them.
+	DraftTTL time.Duration `yaml:"draft_ttl"`
+	// TTLJitter lengthens each secret's TTL by a random amount up to this,
+	// never past MaxTTL, to spread out the expiry of secrets created in a
+	// burst. Zero disables it.
+	TTLJitter time.Duration `yaml:"ttl_jitter"`
+	// Renderers are the content types GET /api/secrets/{id}/render
+	// formats: "application/json" (indented) and "text/markdown" (as
+	// HTML). Everything else is returned as plain text.
+	Renderers []string `yaml:"renderers"`
+	// DuplicateWindow refuses a create with 429 when the same client
+	// shared the same content within it. Zero allows duplicates.
+	DuplicateWindow time.Duration `yaml:"duplicate_window"`
+	// StrictText refuses content declared as plain text, or not declared,
+	// that contains NULs or isn't valid UTF-8, which is usually a binary
+	// file pasted by mistake.
+	StrictText bool `yaml:"strict_text"`
+	// ClientEncryption accepts secrets the browser encrypted itself. The
+	// server only stores their ciphertext, so blocked_patterns can't apply
+	// to them.
+	ClientEncryption bool `yaml:"client_encryption"`
+	// AllowUserPassphrase lets creators choose their own passphrase
+	// instead of a generated one, held to crypto.passphrase_policy. A
+	// wrong guess at one counts towards MaxPINAttempts.
+	AllowUserPassphrase bool `yaml:"allow_user_passphrase"`
+	// AccessLogEntries is how many reveals of each secret are recorded for
+	// its owner, most recent kept. Zero disables the access log.
+	AccessLogEntries int `yaml:"access_log_entries"`
 }
 
 type RateLimitConfig struct {
 	Enabled        bool `yaml:"enabled"`
 	RequestsPerMin int  `yaml:"requests_per_min"`
 	RevealPerMin   int  `yaml:"reveal_per_min"`
+	// PerSecretRate caps reveals per second of any single secret, across
+	// all clients and instances, independently of Enabled. Zero disables.
+	PerSecretRate  float64 `yaml:"per_secret_rate"`
+	PerSecretBurst int     `yaml:"per_secret_burst"`
 }
 
+// TLSConfig serves HTTPS directly when CertFile and KeyFile are set.
 type TLSConfig struct {
 	CertFile string `yaml:"cert_file"`
 	KeyFile  string `yaml:"key_file"`
+	// RedirectAddr, when set, is a second, plain HTTP listener that
+	// redirects every request to the same path under server.base_url.
+	RedirectAddr string `yaml:"redirect_addr"`
+	// ClientCAFile verifies client certificates against these CAs when
+	// clients offer one. Certificates aren't required; they only count
+	// for admin.client_subjects.
+	ClientCAFile string `yaml:"client_ca_file"`
+}
+
+type APIKeysConfig struct {
+	// Enabled accepts API keys in the X-API-Key header. Keys are issued and
+	// revoked under /api/admin/keys and stored hashed. A key's scopes
+	// (create, reveal, admin) limit what it may do; it is rate limited on
+	// its own rather than by IP, and same_origin doesn't apply to it.
+	Enabled bool `yaml:"enabled"`
+	// Required refuses creates and reveals made without a key.
+	Required bool `yaml:"required"`
+}
+
+type AdminConfig struct {
+	// Token enables the /api/admin endpoints; they are not routed when empty.
+	Token string `yaml:"token"`
+	// ClientSubjects admits requests with a client certificate verified by
+	// tls.client_ca_file whose subject common name or full distinguished
+	// name is listed, with or without a token. They enable the admin
+	// endpoints too.
+	ClientSubjects []string `yaml:"client_subjects"`
+}
+
+// SignedLinksConfig is for deployments behind a system that authenticates
+// users itself. Secrets created with signed_links keep their passphrase
+// wrapped under a key derived from Key; the owner can then mint links,
+// HMAC-signed over the ID, expiry and scope, that reveal without the
+// passphrase for up to TTL. Anyone holding such a link can reveal, so
+// links must only be handed out after that system's own checks.
+type SignedLinksConfig struct {
+	// Key enables signed links; at least 32 bytes. Changing it invalidates
+	// outstanding links and the wrapped passphrases of existing secrets.
+	Key string        `yaml:"key"`
+	TTL time.Duration `yaml:"ttl"`
+}
+
+type GeoConfig struct {
+	// DatabasePath is a MaxMind DB file (e.g. GeoLite2-Country.mmdb) used
+	// to enforce the country restrictions senders may put on a secret.
+	// Without it, creates asking for restrictions are refused.
+	DatabasePath string `yaml:"database_path"`
+}
+
+// OffloadConfig sends large archive downloads through object storage: the
+// zip is written to Bucket and the recipient gets a pre-signed URL to it,
+// valid for TTL, instead of the zip itself. This process deletes the object
+// once TTL passes; a bucket lifecycle rule should catch any a restart
+// leaves behind.
+type OffloadConfig struct {
+	Bucket string `yaml:"bucket"`
+	Region string `yaml:"region"`
+	// Endpoint is an S3-compatible service such as MinIO; empty is AWS.
+	Endpoint string        `yaml:"endpoint"`
+	TTL      time.Duration `yaml:"ttl"`
+	// MinBytes is the smallest archive content offloaded; smaller ones are
+	// sent directly.
+	MinBytes int `yaml:"min_bytes"`
+}
+
+// StatsConfig shapes the public GET /api/stats, meant for a status or
+// transparency page. Its counts come from a scan of the store, so a result
+// is served from cache for CacheTTL and each client may ask RequestsPerMin
+// times a minute (zero is unlimited). It is off unless Enabled.
+type StatsConfig struct {
+	Enabled        bool          `yaml:"enabled"`
+	CacheTTL       time.Duration `yaml:"cache_ttl"`
+	RequestsPerMin int           `yaml:"requests_per_min"`
+}
+
+// MetricsConfig exposes Prometheus metrics on /metrics, on the main
+// listener or, with Addr set, on a separate one that needn't be public.
+type MetricsConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Addr    string `yaml:"addr"`
+}
+
+// LoggingConfig picks how log lines are written: Format "text" for
+// key=value lines or "json" for one JSON object per line, and the lowest
+// Level logged, one of "debug", "info", "warn" or "error".
+type LoggingConfig struct {
+	Format string `yaml:"format"`
+	Level  string `yaml:"level"`
+}
+
+type HoneypotConfig struct {
+	// DecoyIDs and IDs matching DecoyPatterns always appear to exist. A
+	// reveal logs a warning, fires the "decoy" hook event and returns fake
+	// content; the store is never consulted.
+	DecoyIDs      []string `yaml:"decoy_ids"`
+	DecoyPatterns []string `yaml:"decoy_patterns"`
+}
+
+type HooksConfig struct {
+	// ExecCommand is run on create, reveal, observed expiry and decoy
+	// reveals with event details (never content) in SECRET_* environment
+	// variables. Empty disables the hook.
+	ExecCommand       string        `yaml:"exec_command"`
+	ExecTimeout       time.Duration `yaml:"exec_timeout"`
+	ExecMaxConcurrent int           `yaml:"exec_max_concurrent"`
+	// ExecQueueDepth events may wait for a free command slot. When that is
+	// full too, ExecOverflow "drop" discards the event and "block" holds
+	// up the request that raised it.
+	ExecQueueDepth int    `yaml:"exec_queue_depth"`
+	ExecOverflow   string `yaml:"exec_overflow"`
+	// CodeCommand sends one-time reveal codes, with the contact and code in
+	// REVEAL_CODE_CONTACT and REVEAL_CODE, under ExecTimeout. Empty
+	// disables reveal codes.
+	CodeCommand string `yaml:"code_command"`
+}
+
+type CryptoConfig struct {
+	IDEncoding string `yaml:"id_encoding"`
+	// LegacyIDFormats are tried in order when an ID isn't found as given,
+	// so links from an older ID scheme keep resolving.
+	LegacyIDFormats []string `yaml:"legacy_id_formats"`
+	// TrimPassphrase strips surrounding whitespace from passphrases before
+	// key derivation. It must be applied the same way on encrypt and decrypt,
+	// so it stays off unless every passphrase source agrees on it.
+	TrimPassphrase bool `yaml:"trim_passphrase"`
+	// MaxConcurrentOps caps simultaneous encrypt/decrypt calls (0 = no cap);
+	// requests wait up to QueueTimeout for a slot before getting a 503.
+	MaxConcurrentOps int                    `yaml:"max_concurrent_ops"`
+	QueueTimeout     time.Duration          `yaml:"queue_timeout"`
+	PassphrasePolicy PassphrasePolicyConfig `yaml:"passphrase_policy"`
+	// Profiles are the cipher and KDF cost choices a client can make per
+	// secret with the X-Crypto-Profile header. Without the header
+	// AES-256-GCM is used with a key from Argon2id at the KDF costs.
+	Profiles map[string]CryptoProfileConfig `yaml:"profiles"`
+	// PadLength hides content length by padding plaintext before
+	// encryption to the next power of two up to PadLength bytes, and past
+	// that to a multiple of it. Zero disables padding.
+	PadLength int `yaml:"pad_length"`
+	// Compress gzips plaintexts before encryption when that makes them
+	// smaller. The stored size then hints at how repetitive the content
+	// is, so it is off by default, and padded secrets are never compressed.
+	Compress bool `yaml:"compress"`
+	// KDF is the Argon2id cost of new secrets. Each secret records its
+	// own, so raising it leaves existing secrets readable.
+	KDF KDFConfig `yaml:"kdf"`
+}
+
+type KDFConfig struct {
+	Memory      uint32 `yaml:"memory"` // KiB
+	Iterations  uint32 `yaml:"iterations"`
+	Parallelism uint8  `yaml:"parallelism"`
+}
+
+type CryptoProfileConfig struct {
+	Cipher        string `yaml:"cipher"` // aes-128-gcm or aes-256-gcm
+	KDFIterations int    `yaml:"kdf_iterations"`
+}
+
+type PassphrasePolicyConfig struct {
+	MinLength  int `yaml:"min_length"`
+	MaxLength  int `yaml:"max_length"`
+	MinClasses int `yaml:"min_classes"`
+	// MinEntropyBits is the estimated entropy a creator's chosen
+	// passphrase needs; zero leaves only the repetition and sequence
+	// checks.
+	MinEntropyBits float64 `yaml:"min_entropy_bits"`
 }
 
 func Default() *Config {
 	return &Config{
 		Server: ServerConfig{
-			Host:    "0.0.0.0",
-			Port:    8080,
-			BaseURL: "http://localhost:8080",
+			Host:            "0.0.0.0",
+			Port:            8080,
+			BaseURL:         "http://localhost:8080",
+			RequestIDHeader: "X-Request-ID",
+			JSONMaxDepth:    32,
+			JSONMaxFields:   1024,
+			MaxBodyBytes:    2 << 20,
+			LogSampleRate:   1,
+			ShutdownTimeout: 10 * time.Second,
+			CORS: CORSConfig{
+				AllowedMethods: []string{"GET", "POST", "DELETE", "OPTIONS"},
+				AllowedHeaders: []string{"Content-Type", "X-Request-ID", "X-Owner-Token", "X-Crypto-Profile", "X-Reservation-Token", "If-None-Match"},
+			},
 		},
 		Store: StoreConfig{
 			Type: "memory",
 			Redis: RedisConfig{
-				Addr:     "localhost:6379",
-				Password: "",
-				DB:       0,
+				Mode:            "single",
+				Addr:            "localhost:6379",
+				Password:        "",
+				DB:              0,
+				FailoverRetries: 3,
+				FailoverBackoff: 200 * time.Millisecond,
+			},
+			DynamoDB: DynamoDBConfig{
+				Region: "us-east-1",
+				Table:  "secrets",
+			},
+			SQLite: SQLiteConfig{
+				Path: "secrets.db",
+			},
+			Health: HealthConfig{
+				Window:       time.Minute,
+				MaxErrorRate: 0.25,
+				MaxLatency:   250 * time.Millisecond,
+				MinSamples:   20,
+			},
+			NegativeCache: NegativeCacheConfig{
+				TTL:  5 * time.Second,
+				Size: 10000,
 			},
+			Compression:      "none",
+			MaxInflatedBytes: 64 << 20,
 		},
 		Secrets: SecretsConfig{
-			DefaultTTL:   1 * time.Hour,
-			MaxTTL:       24 * time.Hour,
-			DefaultViews: 1,
-			MaxViews:     10,
+			DefaultTTL:        1 * time.Hour,
+			MaxTTL:            24 * time.Hour,
+			DefaultViews:      1,
+			MaxViews:          10,
+			OwnerTokens:       true,
+			AllowPIN:          true,
+			IntegrityMAC:      true,
+			MaxPINAttempts:    5,
+			AckTTL:            5 * time.Minute,
+			RevealCodeTTL:     10 * time.Minute,
+			MaxArchiveBytes:   1 << 20,
+			MaxFileBytes:      1 << 20,
+			DownloadRateScope: "download",
+			MaxLinks:          16,
+			Renderers:         []string{"application/json", "text/markdown"},
+			ClientEncryption:  true,
+
+			AllowUserPassphrase: true,
 		},
 		RateLimit: RateLimitConfig{
 			Enabled:        true,
 			RequestsPerMin: 100,
 			RevealPerMin:   20,
+			PerSecretBurst: 5,
+		},
+		Hooks: HooksConfig{
+			ExecTimeout:       5 * time.Second,
+			ExecMaxConcurrent: 4,
+			ExecOverflow:      "drop",
 		},
 		TLS: TLSConfig{
 			CertFile: "",
 			KeyFile:  "",
 		},
+		Offload: OffloadConfig{
+			TTL:      5 * time.Minute,
+			MinBytes: 256 * 1024,
+		},
+		SignedLinks: SignedLinksConfig{
+			TTL: 15 * time.Minute,
+		},
+		Logging: LoggingConfig{
+			Format: "text",
+			Level:  "info",
+		},
+		Stats: StatsConfig{
+			CacheTTL:       time.Minute,
+			RequestsPerMin: 30,
+		},
+		Crypto: CryptoConfig{
+			IDEncoding:       "base64url",
+			LegacyIDFormats:  []string{"padded", "base64std"},
+			MaxConcurrentOps: 32,
+			QueueTimeout:     5 * time.Second,
+			PassphrasePolicy: PassphrasePolicyConfig{
+				MinLength:      12,
+				MaxLength:      1024,
+				MinClasses:     0,
+				MinEntropyBits: 40,
+			},
+			Profiles: map[string]CryptoProfileConfig{
+				"fast":   {Cipher: "aes-128-gcm", KDFIterations: 1000},
+				"strong": {Cipher: "aes-256-gcm", KDFIterations: 600000},
+			},
+			KDF: KDFConfig{
+				Memory:      64 * 1024,
+				Iterations:  1,
+				Parallelism: 4,
+			},
+		},
 	}
 }
 
@@ -113,13 +575,44 @@ func (c *Config) loadFromFile(path string) error {
 		return fmt.Errorf("reading config file: %w", err)
 	}
 
-	if err := yaml.Unmarshal(data, c); err != nil {
+	if err := c.decode(path, data); err != nil {
 		return fmt.Errorf("parsing config file: %w", err)
 	}
 
 	return nil
 }
 
+// decode unmarshals data in the format named by path's extension: .json,
+// .toml, or YAML for anything else. JSON and TOML are decoded generically
+// and passed through YAML, so the yaml tags, durations and defaults work the
+// same in every format.
+func (c *Config) decode(path string, data []byte) error {
+	var doc interface{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return err
+		}
+	case ".toml":
+		var table map[string]interface{}
+		if err := toml.Unmarshal(data, &table); err != nil {
+			return err
+		}
+		doc = table
+	default:
+		return yaml.Unmarshal(data, c)
+	}
+
+	if doc == nil {
+		return nil
+	}
+	out, err := yaml.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return yaml.Unmarshal(out, c)
+}
+
 func (c *Config) loadFromEnv() {
 	// Server
 	if v := os.Getenv("HOST"); v != "" {
@@ -133,13 +626,73 @@ func (c *Config) loadFromEnv() {
 	if v := os.Getenv("BASE_URL"); v != "" {
 		c.Server.BaseURL = v
 	}
+	if v := os.Getenv("SAME_ORIGIN"); v != "" {
+		c.Server.SameOrigin = v == "true" || v == "1"
+	}
+	if v := os.Getenv("REQUEST_ID_HEADER"); v != "" {
+		c.Server.RequestIDHeader = v
+	}
+	if v := os.Getenv("LOG_SAMPLE_RATE"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			c.Server.LogSampleRate = n
+		}
+	}
+	if v := os.Getenv("UNIX_SOCKET"); v != "" {
+		c.Server.UnixSocket = v
+	}
+	if v := os.Getenv("SHUTDOWN_TIMEOUT"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			c.Server.ShutdownTimeout = d
+		}
+	}
+	if v := os.Getenv("JSON_MAX_DEPTH"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			c.Server.JSONMaxDepth = n
+		}
+	}
+	if v := os.Getenv("JSON_MAX_FIELDS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			c.Server.JSONMaxFields = n
+		}
+	}
+	if v := os.Getenv("MAX_BODY_BYTES"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			c.Server.MaxBodyBytes = n
+		}
+	}
+	if v := os.Getenv("JSON_CONTENT_TYPES"); v != "" {
+		c.Server.JSONContentTypes = strings.Split(v, ",")
+	}
+	if v := os.Getenv("CORS_ALLOWED_ORIGINS"); v != "" {
+		c.Server.CORS.AllowedOrigins = strings.Split(v, ",")
+	}
+	if v := os.Getenv("CORS_ALLOWED_METHODS"); v != "" {
+		c.Server.CORS.AllowedMethods = strings.Split(v, ",")
+	}
+	if v := os.Getenv("CORS_ALLOWED_HEADERS"); v != "" {
+		c.Server.CORS.AllowedHeaders = strings.Split(v, ",")
+	}
 
 	if v := os.Getenv("STORE_TYPE"); v != "" {
 		c.Store.Type = v
 	}
+	if v := os.Getenv("MEMORY_GRACE_PERIOD"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			c.Store.Memory.GracePeriod = d
+		}
+	}
+	if v := os.Getenv("REDIS_MODE"); v != "" {
+		c.Store.Redis.Mode = v
+	}
 	if v := os.Getenv("REDIS_ADDR"); v != "" {
 		c.Store.Redis.Addr = v
 	}
+	if v := os.Getenv("REDIS_ADDRS"); v != "" {
+		c.Store.Redis.Addrs = strings.Split(v, ",")
+	}
+	if v := os.Getenv("REDIS_MASTER_NAME"); v != "" {
+		c.Store.Redis.MasterName = v
+	}
 	if v := os.Getenv("REDIS_PASSWORD"); v != "" {
 		c.Store.Redis.Password = v
 	}
@@ -148,6 +701,66 @@ func (c *Config) loadFromEnv() {
 			c.Store.Redis.DB = db
 		}
 	}
+	if v := os.Getenv("REDIS_FAILOVER_RETRIES"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			c.Store.Redis.FailoverRetries = n
+		}
+	}
+	if v := os.Getenv("REDIS_FAILOVER_BACKOFF"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			c.Store.Redis.FailoverBackoff = d
+		}
+	}
+	if v := os.Getenv("STORE_HEALTH_WINDOW"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			c.Store.Health.Window = d
+		}
+	}
+	if v := os.Getenv("STORE_HEALTH_MAX_ERROR_RATE"); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			c.Store.Health.MaxErrorRate = f
+		}
+	}
+	if v := os.Getenv("STORE_HEALTH_MAX_LATENCY"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			c.Store.Health.MaxLatency = d
+		}
+	}
+	if v := os.Getenv("STORE_HEALTH_MIN_SAMPLES"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			c.Store.Health.MinSamples = n
+		}
+	}
+	if v := os.Getenv("NEGATIVE_CACHE_TTL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			c.Store.NegativeCache.TTL = d
+		}
+	}
+	if v := os.Getenv("NEGATIVE_CACHE_SIZE"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			c.Store.NegativeCache.Size = n
+		}
+	}
+	if v := os.Getenv("STORE_COMPRESSION"); v != "" {
+		c.Store.Compression = v
+	}
+	if v := os.Getenv("STORE_MAX_INFLATED_BYTES"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			c.Store.MaxInflatedBytes = n
+		}
+	}
+	if v := os.Getenv("DYNAMODB_REGION"); v != "" {
+		c.Store.DynamoDB.Region = v
+	}
+	if v := os.Getenv("DYNAMODB_TABLE"); v != "" {
+		c.Store.DynamoDB.Table = v
+	}
+	if v := os.Getenv("DYNAMODB_ENDPOINT"); v != "" {
+		c.Store.DynamoDB.Endpoint = v
+	}
+	if v := os.Getenv("SQLITE_PATH"); v != "" {
+		c.Store.SQLite.Path = v
+	}
 
 	if v := os.Getenv("DEFAULT_TTL"); v != "" {
 		if ttl, err := time.ParseDuration(v); err == nil {
@@ -170,6 +783,110 @@ func (c *Config) loadFromEnv() {
 		}
 	}
 
+	if v := os.Getenv("OWNER_TOKENS"); v != "" {
+		c.Secrets.OwnerTokens = v == "true" || v == "1"
+	}
+	if v := os.Getenv("INTEGRITY_MAC"); v != "" {
+		c.Secrets.IntegrityMAC = v == "true" || v == "1"
+	}
+	if v := os.Getenv("ALLOW_PIN"); v != "" {
+		c.Secrets.AllowPIN = v == "true" || v == "1"
+	}
+	if v := os.Getenv("MAX_PIN_ATTEMPTS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			c.Secrets.MaxPINAttempts = n
+		}
+	}
+	if v := os.Getenv("REVEAL_CODE_TTL"); v != "" {
+		if ttl, err := time.ParseDuration(v); err == nil {
+			c.Secrets.RevealCodeTTL = ttl
+		}
+	}
+	if v := os.Getenv("ACK_TTL"); v != "" {
+		if ttl, err := time.ParseDuration(v); err == nil {
+			c.Secrets.AckTTL = ttl
+		}
+	}
+	if v := os.Getenv("DRAFT_TTL"); v != "" {
+		if ttl, err := time.ParseDuration(v); err == nil {
+			c.Secrets.DraftTTL = ttl
+		}
+	}
+	if v := os.Getenv("TTL_JITTER"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			c.Secrets.TTLJitter = d
+		}
+	}
+	if v, ok := os.LookupEnv("RENDERERS"); ok {
+		c.Secrets.Renderers = nil
+		if v != "" {
+			c.Secrets.Renderers = strings.Split(v, ",")
+		}
+	}
+	if v := os.Getenv("DUPLICATE_WINDOW"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			c.Secrets.DuplicateWindow = d
+		}
+	}
+	if v := os.Getenv("STRICT_TEXT"); v != "" {
+		c.Secrets.StrictText = v == "true" || v == "1"
+	}
+	if v := os.Getenv("CLIENT_ENCRYPTION"); v != "" {
+		c.Secrets.ClientEncryption = v == "true" || v == "1"
+	}
+	if v := os.Getenv("ALLOW_USER_PASSPHRASE"); v != "" {
+		c.Secrets.AllowUserPassphrase = v == "true" || v == "1"
+	}
+	if v := os.Getenv("ACCESS_LOG_ENTRIES"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			c.Secrets.AccessLogEntries = n
+		}
+	}
+	if v := os.Getenv("MAX_TOTAL_SECRETS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			c.Secrets.MaxTotal = n
+		}
+	}
+	if v := os.Getenv("REVEAL_NONCES"); v != "" {
+		c.Secrets.RevealNonces = v == "true" || v == "1"
+	}
+	if v := os.Getenv("STREAM_REVEAL"); v != "" {
+		c.Secrets.StreamReveal = v == "true" || v == "1"
+	}
+	if v := os.Getenv("DOWNLOAD_RATE"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			c.Secrets.DownloadRate = n
+		}
+	}
+	if v := os.Getenv("DOWNLOAD_RATE_SCOPE"); v != "" {
+		c.Secrets.DownloadRateScope = v
+	}
+	if v := os.Getenv("MAX_LINKS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			c.Secrets.MaxLinks = n
+		}
+	}
+	if v := os.Getenv("REVALIDATE_TTL"); v != "" {
+		if ttl, err := time.ParseDuration(v); err == nil {
+			c.Secrets.RevalidateTTL = ttl
+		}
+	}
+	if v := os.Getenv("MAX_ARCHIVE_BYTES"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			c.Secrets.MaxArchiveBytes = n
+		}
+	}
+	if v := os.Getenv("MAX_FILE_BYTES"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			c.Secrets.MaxFileBytes = n
+		}
+	}
+	if v := os.Getenv("TOMBSTONE_TTL"); v != "" {
+		if ttl, err := time.ParseDuration(v); err == nil {
+			c.Secrets.TombstoneTTL = ttl
+		}
+	}
+
 	if v := os.Getenv("RATE_LIMIT_ENABLED"); v != "" {
 		c.RateLimit.Enabled = v == "true" || v == "1"
 	}
@@ -183,6 +900,16 @@ func (c *Config) loadFromEnv() {
 			c.RateLimit.RevealPerMin = n
 		}
 	}
+	if v := os.Getenv("RATE_LIMIT_PER_SECRET"); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			c.RateLimit.PerSecretRate = f
+		}
+	}
+	if v := os.Getenv("RATE_LIMIT_PER_SECRET_BURST"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			c.RateLimit.PerSecretBurst = n
+		}
+	}
 
 	if v := os.Getenv("TLS_CERT_FILE"); v != "" {
 		c.TLS.CertFile = v
@@ -190,6 +917,173 @@ func (c *Config) loadFromEnv() {
 	if v := os.Getenv("TLS_KEY_FILE"); v != "" {
 		c.TLS.KeyFile = v
 	}
+	if v := os.Getenv("TLS_REDIRECT_ADDR"); v != "" {
+		c.TLS.RedirectAddr = v
+	}
+	if v := os.Getenv("TLS_CLIENT_CA_FILE"); v != "" {
+		c.TLS.ClientCAFile = v
+	}
+
+	if v := os.Getenv("ADMIN_TOKEN"); v != "" {
+		c.Admin.Token = v
+	}
+	if v := os.Getenv("ADMIN_CLIENT_SUBJECTS"); v != "" {
+		c.Admin.ClientSubjects = strings.Split(v, ",")
+	}
+	if v := os.Getenv("API_KEYS_ENABLED"); v != "" {
+		c.APIKeys.Enabled = v == "true" || v == "1"
+	}
+	if v := os.Getenv("API_KEYS_REQUIRED"); v != "" {
+		c.APIKeys.Required = v == "true" || v == "1"
+	}
+
+	if v := os.Getenv("GEOIP_DATABASE"); v != "" {
+		c.Geo.DatabasePath = v
+	}
+	if v := os.Getenv("OFFLOAD_BUCKET"); v != "" {
+		c.Offload.Bucket = v
+	}
+	if v := os.Getenv("OFFLOAD_REGION"); v != "" {
+		c.Offload.Region = v
+	}
+	if v := os.Getenv("OFFLOAD_ENDPOINT"); v != "" {
+		c.Offload.Endpoint = v
+	}
+	if v := os.Getenv("OFFLOAD_TTL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			c.Offload.TTL = d
+		}
+	}
+	if v := os.Getenv("OFFLOAD_MIN_BYTES"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			c.Offload.MinBytes = n
+		}
+	}
+	if v := os.Getenv("SIGNED_LINKS_KEY"); v != "" {
+		c.SignedLinks.Key = v
+	}
+	if v := os.Getenv("SIGNED_LINKS_TTL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			c.SignedLinks.TTL = d
+		}
+	}
+	if v := os.Getenv("STATS_ENABLED"); v != "" {
+		c.Stats.Enabled = v == "true" || v == "1"
+	}
+	if v := os.Getenv("STATS_CACHE_TTL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			c.Stats.CacheTTL = d
+		}
+	}
+	if v := os.Getenv("STATS_REQUESTS_PER_MIN"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			c.Stats.RequestsPerMin = n
+		}
+	}
+	if v := os.Getenv("METRICS_ENABLED"); v != "" {
+		c.Metrics.Enabled = v == "true" || v == "1"
+	}
+	if v := os.Getenv("METRICS_ADDR"); v != "" {
+		c.Metrics.Addr = v
+	}
+	if v := os.Getenv("LOG_FORMAT"); v != "" {
+		c.Logging.Format = v
+	}
+	if v := os.Getenv("LOG_LEVEL"); v != "" {
+		c.Logging.Level = v
+	}
+	if v := os.Getenv("HONEYPOT_DECOY_IDS"); v != "" {
+		c.Honeypot.DecoyIDs = strings.Split(v, ",")
+	}
+
+	if v := os.Getenv("HOOK_EXEC_COMMAND"); v != "" {
+		c.Hooks.ExecCommand = v
+	}
+	if v := os.Getenv("HOOK_EXEC_TIMEOUT"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			c.Hooks.ExecTimeout = d
+		}
+	}
+	if v := os.Getenv("HOOK_EXEC_MAX_CONCURRENT"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			c.Hooks.ExecMaxConcurrent = n
+		}
+	}
+
+	if v := os.Getenv("HOOK_CODE_COMMAND"); v != "" {
+		c.Hooks.CodeCommand = v
+	}
+	if v := os.Getenv("HOOK_EXEC_QUEUE_DEPTH"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			c.Hooks.ExecQueueDepth = n
+		}
+	}
+	if v := os.Getenv("HOOK_EXEC_OVERFLOW"); v != "" {
+		c.Hooks.ExecOverflow = v
+	}
+
+	if v := os.Getenv("ID_ENCODING"); v != "" {
+		c.Crypto.IDEncoding = v
+	}
+	if v := os.Getenv("LEGACY_ID_FORMATS"); v != "" {
+		c.Crypto.LegacyIDFormats = strings.Split(v, ",")
+	}
+	if v := os.Getenv("TRIM_PASSPHRASE"); v != "" {
+		c.Crypto.TrimPassphrase = v == "true" || v == "1"
+	}
+	if v := os.Getenv("CRYPTO_MAX_CONCURRENT_OPS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			c.Crypto.MaxConcurrentOps = n
+		}
+	}
+	if v := os.Getenv("CRYPTO_PAD_LENGTH"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			c.Crypto.PadLength = n
+		}
+	}
+	if v := os.Getenv("CRYPTO_COMPRESS"); v != "" {
+		c.Crypto.Compress = v == "true" || v == "1"
+	}
+	if v := os.Getenv("CRYPTO_KDF_MEMORY"); v != "" {
+		if n, err := strconv.ParseUint(v, 10, 32); err == nil {
+			c.Crypto.KDF.Memory = uint32(n)
+		}
+	}
+	if v := os.Getenv("CRYPTO_KDF_ITERATIONS"); v != "" {
+		if n, err := strconv.ParseUint(v, 10, 32); err == nil {
+			c.Crypto.KDF.Iterations = uint32(n)
+		}
+	}
+	if v := os.Getenv("CRYPTO_KDF_PARALLELISM"); v != "" {
+		if n, err := strconv.ParseUint(v, 10, 8); err == nil {
+			c.Crypto.KDF.Parallelism = uint8(n)
+		}
+	}
+	if v := os.Getenv("CRYPTO_QUEUE_TIMEOUT"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			c.Crypto.QueueTimeout = d
+		}
+	}
+	if v := os.Getenv("PASSPHRASE_MIN_LENGTH"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			c.Crypto.PassphrasePolicy.MinLength = n
+		}
+	}
+	if v := os.Getenv("PASSPHRASE_MAX_LENGTH"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			c.Crypto.PassphrasePolicy.MaxLength = n
+		}
+	}
+	if v := os.Getenv("PASSPHRASE_MIN_CLASSES"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			c.Crypto.PassphrasePolicy.MinClasses = n
+		}
+	}
+	if v := os.Getenv("PASSPHRASE_MIN_ENTROPY_BITS"); v != "" {
+		if bits, err := strconv.ParseFloat(v, 64); err == nil {
+			c.Crypto.PassphrasePolicy.MinEntropyBits = bits
+		}
+	}
 }
 
 func (c *Config) Validate() error {
@@ -200,13 +1094,116 @@ func (c *Config) Validate() error {
 	if c.Server.BaseURL == "" {
 		return fmt.Errorf("base_url is required")
 	}
+	if c.Server.SameOrigin {
+		if u, err := url.Parse(c.Server.BaseURL); err != nil || u.Host == "" {
+			return fmt.Errorf("same_origin requires base_url to be an absolute url")
+		}
+	}
 
-	if c.Store.Type != "memory" && c.Store.Type != "redis" {
-		return fmt.Errorf("invalid store type: %s (must be 'memory' or 'redis')", c.Store.Type)
+	for _, origin := range c.Server.CORS.AllowedOrigins {
+		if !validOrigin(origin) {
+			return fmt.Errorf("cors allowed_origins: %q is not \"*\" or a scheme://host[:port] origin", origin)
+		}
+	}
+
+	// sun_path is 104 bytes on BSD/macOS and 108 on Linux.
+	if len(c.Server.UnixSocket) > 103 {
+		return fmt.Errorf("unix_socket path must be at most 103 bytes")
 	}
 
-	if c.Store.Type == "redis" && c.Store.Redis.Addr == "" {
-		return fmt.Errorf("redis addr is required when store type is 'redis'")
+	if c.Store.Redis.FailoverRetries < 0 || c.Store.Redis.FailoverBackoff < 0 {
+		return fmt.Errorf("redis failover_retries and failover_backoff must not be negative")
+	}
+
+	if c.Store.Health.Window <= 0 {
+		return fmt.Errorf("store health window must be positive")
+	}
+	if c.Store.Health.MaxErrorRate < 0 || c.Store.Health.MaxErrorRate > 1 {
+		return fmt.Errorf("store health max_error_rate must be between 0 and 1")
+	}
+	if c.Store.Health.MaxLatency < 0 {
+		return fmt.Errorf("store health max_latency must not be negative")
+	}
+	if c.Store.Health.MinSamples < 1 {
+		return fmt.Errorf("store health min_samples must be at least 1")
+	}
+	if c.Store.NegativeCache.TTL < 0 {
+		return fmt.Errorf("negative_cache ttl must not be negative")
+	}
+	if c.Store.NegativeCache.TTL > 0 && c.Store.NegativeCache.Size < 1 {
+		return fmt.Errorf("negative_cache size must be at least 1")
+	}
+	switch c.Store.Compression {
+	case "", "none", "flate", "gzip":
+	default:
+		return fmt.Errorf("invalid store compression: %s (must be 'none', 'flate' or 'gzip')", c.Store.Compression)
+	}
+	if c.Store.MaxInflatedBytes < 1 {
+		return fmt.Errorf("max_inflated_bytes must be at least 1")
+	}
+
+	if c.Server.JSONMaxDepth < 1 || c.Server.JSONMaxFields < 1 {
+		return fmt.Errorf("json_max_depth and json_max_fields must be at least 1")
+	}
+	if c.Server.MaxBodyBytes < 1 {
+		return fmt.Errorf("max_body_bytes must be at least 1")
+	}
+
+	if c.Server.LogSampleRate < 1 {
+		return fmt.Errorf("log_sample_rate must be at least 1")
+	}
+	if c.Server.ShutdownTimeout <= 0 {
+		return fmt.Errorf("shutdown_timeout must be positive")
+	}
+	for _, ct := range c.Server.JSONContentTypes {
+		if mediaType, params, err := mime.ParseMediaType(ct); err != nil || len(params) > 0 || mediaType != ct {
+			return fmt.Errorf("json_content_types: %q must be a lower-case media type without parameters", ct)
+		}
+	}
+
+	if c.Server.RequestIDHeader == "" {
+		return fmt.Errorf("request_id_header is required")
+	}
+
+	switch c.Store.Type {
+	case "memory", "redis", "dynamodb", "sqlite":
+	default:
+		return fmt.Errorf("invalid store type: %s (must be 'memory', 'redis', 'dynamodb' or 'sqlite')", c.Store.Type)
+	}
+
+	if c.Store.Memory.GracePeriod < 0 {
+		return fmt.Errorf("memory grace_period must not be negative")
+	}
+
+	switch c.Store.Redis.Mode {
+	case "single", "sentinel", "cluster":
+	default:
+		return fmt.Errorf("invalid redis mode: %s (must be 'single', 'sentinel' or 'cluster')", c.Store.Redis.Mode)
+	}
+	if c.Store.Type == "redis" {
+		redis := c.Store.Redis
+		switch {
+		case redis.Mode == "single" && redis.Addr == "":
+			return fmt.Errorf("redis addr is required when store type is 'redis'")
+		case redis.Mode == "sentinel" && (len(redis.Addrs) == 0 || redis.MasterName == ""):
+			return fmt.Errorf("redis addrs and master_name are required in sentinel mode")
+		case redis.Mode == "cluster" && len(redis.Addrs) == 0:
+			return fmt.Errorf("redis addrs are required in cluster mode")
+		case redis.Mode == "cluster" && redis.DB != 0:
+			return fmt.Errorf("redis db must be 0 in cluster mode")
+		}
+		for _, addr := range redis.Addrs {
+			if _, _, err := net.SplitHostPort(addr); err != nil {
+				return fmt.Errorf("redis addrs must be host:port: %w", err)
+			}
+		}
+	}
+
+	if c.Store.Type == "dynamodb" && (c.Store.DynamoDB.Region == "" || c.Store.DynamoDB.Table == "") {
+		return fmt.Errorf("dynamodb region and table are required when store type is 'dynamodb'")
+	}
+	if c.Store.Type == "sqlite" && c.Store.SQLite.Path == "" {
+		return fmt.Errorf("sqlite path is required when store type is 'sqlite'")
 	}
 
 	if c.Secrets.DefaultTTL <= 0 {
@@ -225,12 +1222,291 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("max_views must be >= default_views")
 	}
 
+	// Wrong chosen passphrases burn the secret like wrong PINs do, so
+	// zero attempts would destroy it on the first typo.
+	if (c.Secrets.AllowPIN || c.Secrets.AllowUserPassphrase) && c.Secrets.MaxPINAttempts < 1 {
+		return fmt.Errorf("max_pin_attempts must be at least 1 when allow_pin or allow_user_passphrase is set")
+	}
+
+	if c.Secrets.AckTTL <= 0 {
+		return fmt.Errorf("ack_ttl must be positive")
+	}
+
+	if c.Secrets.DraftTTL < 0 {
+		return fmt.Errorf("draft_ttl must not be negative")
+	}
+
+	if c.Secrets.TTLJitter < 0 {
+		return fmt.Errorf("ttl_jitter must not be negative")
+	}
+
+	if c.Secrets.DuplicateWindow < 0 {
+		return fmt.Errorf("duplicate_window must not be negative")
+	}
+
+	for _, contentType := range c.Secrets.Renderers {
+		switch contentType {
+		case "application/json", "text/markdown":
+		default:
+			return fmt.Errorf("invalid renderer: %s (must be 'application/json' or 'text/markdown')", contentType)
+		}
+	}
+
+	if c.Secrets.MaxTotal < 0 {
+		return fmt.Errorf("max_total must not be negative")
+	}
+
+	if c.Secrets.MaxLinks < 2 || c.Secrets.MaxLinks > 255 {
+		return fmt.Errorf("max_links must be between 2 and 255")
+	}
+
+	if c.Secrets.MaxArchiveBytes < 0 {
+		return fmt.Errorf("max_archive_bytes must not be negative")
+	}
+	if c.Secrets.MaxFileBytes < 0 {
+		return fmt.Errorf("max_file_bytes must not be negative")
+	}
+	if c.Secrets.AccessLogEntries < 0 {
+		return fmt.Errorf("access_log_entries must not be negative")
+	}
+
+	if c.Secrets.RevalidateTTL < 0 {
+		return fmt.Errorf("revalidate_ttl must not be negative")
+	}
+
+	if c.Secrets.DownloadRate < 0 {
+		return fmt.Errorf("download_rate must not be negative")
+	}
+	if c.Secrets.DownloadRateScope != "download" && c.Secrets.DownloadRateScope != "global" {
+		return fmt.Errorf("invalid download_rate_scope: %s (must be 'download' or 'global')", c.Secrets.DownloadRateScope)
+	}
+
+	if c.Secrets.TombstoneTTL < 0 {
+		return fmt.Errorf("tombstone_ttl must not be negative")
+	}
+
+	for _, pattern := range c.Secrets.BlockedPatterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid blocked pattern %q: %w", pattern, err)
+		}
+	}
+
+	for _, pattern := range c.Honeypot.DecoyPatterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid decoy pattern %q: %w", pattern, err)
+		}
+	}
+
+	if c.Geo.DatabasePath != "" {
+		if _, err := os.Stat(c.Geo.DatabasePath); err != nil {
+			return fmt.Errorf("geo database_path: %w", err)
+		}
+	}
+
+	if c.Offload.Bucket != "" {
+		if c.Offload.Region == "" {
+			return fmt.Errorf("offload region is required with a bucket")
+		}
+		// Pre-signed URLs can't outlive a week.
+		if c.Offload.TTL < time.Second || c.Offload.TTL > 7*24*time.Hour {
+			return fmt.Errorf("offload ttl must be between 1s and 168h")
+		}
+		if c.Offload.MinBytes < 0 {
+			return fmt.Errorf("offload min_bytes must not be negative")
+		}
+	}
+
+	if c.SignedLinks.Key != "" {
+		if len(c.SignedLinks.Key) < 32 {
+			return fmt.Errorf("signed_links key must be at least 32 bytes")
+		}
+		if c.SignedLinks.TTL < time.Second {
+			return fmt.Errorf("signed_links ttl must be at least 1s")
+		}
+		if !c.Secrets.OwnerTokens {
+			return fmt.Errorf("signed_links need owner_tokens, which authorize minting links")
+		}
+	}
+
+	if c.Stats.CacheTTL < 0 {
+		return fmt.Errorf("stats cache_ttl must not be negative")
+	}
+	if c.Stats.RequestsPerMin < 0 {
+		return fmt.Errorf("stats requests_per_min must not be negative")
+	}
+
+	if c.Metrics.Addr != "" {
+		if _, _, err := net.SplitHostPort(c.Metrics.Addr); err != nil {
+			return fmt.Errorf("metrics addr must be host:port: %w", err)
+		}
+	}
+
+	if c.Logging.Format != "text" && c.Logging.Format != "json" {
+		return fmt.Errorf("logging format must be 'text' or 'json'")
+	}
+	switch strings.ToLower(c.Logging.Level) {
+	case "debug", "info", "warn", "error":
+	default:
+		return fmt.Errorf("logging level must be 'debug', 'info', 'warn' or 'error'")
+	}
+
 	if c.TLS.CertFile != "" && c.TLS.KeyFile == "" {
 		return fmt.Errorf("tls_key_file is required when tls_cert_file is set")
 	}
 	if c.TLS.KeyFile != "" && c.TLS.CertFile == "" {
 		return fmt.Errorf("tls_cert_file is required when tls_key_file is set")
 	}
+	// Missing files would otherwise only surface once the server starts
+	// serving.
+	for _, file := range []struct{ name, path string }{
+		{"tls_cert_file", c.TLS.CertFile},
+		{"tls_key_file", c.TLS.KeyFile},
+	} {
+		if file.path == "" {
+			continue
+		}
+		if _, err := os.Stat(file.path); err != nil {
+			return fmt.Errorf("%s: %w", file.name, err)
+		}
+	}
+	if c.TLS.RedirectAddr != "" {
+		if c.TLS.CertFile == "" {
+			return fmt.Errorf("tls_redirect_addr needs tls_cert_file and tls_key_file")
+		}
+		if _, _, err := net.SplitHostPort(c.TLS.RedirectAddr); err != nil {
+			return fmt.Errorf("tls_redirect_addr must be host:port: %w", err)
+		}
+		if !strings.HasPrefix(c.Server.BaseURL, "https://") {
+			return fmt.Errorf("tls_redirect_addr needs an https base_url to redirect to")
+		}
+	}
+
+	if c.RateLimit.PerSecretRate < 0 {
+		return fmt.Errorf("per_secret_rate must not be negative")
+	}
+	if c.RateLimit.PerSecretRate > 0 && c.RateLimit.PerSecretBurst < 1 {
+		return fmt.Errorf("per_secret_burst must be at least 1 when per_secret_rate is set")
+	}
+
+	if c.Admin.Token != "" && len(c.Admin.Token) < 16 {
+		return fmt.Errorf("admin token must be at least 16 characters")
+	}
+
+	if c.TLS.ClientCAFile != "" {
+		if c.TLS.CertFile == "" {
+			return fmt.Errorf("tls_client_ca_file needs tls_cert_file and tls_key_file")
+		}
+		if _, err := os.Stat(c.TLS.ClientCAFile); err != nil {
+			return fmt.Errorf("tls_client_ca_file: %w", err)
+		}
+	}
+	if len(c.Admin.ClientSubjects) > 0 && c.TLS.ClientCAFile == "" {
+		return fmt.Errorf("admin client_subjects need tls_client_ca_file")
+	}
+	if slices.Contains(c.Admin.ClientSubjects, "") {
+		return fmt.Errorf("admin client_subjects must not be empty")
+	}
+
+	if c.APIKeys.Required && !c.APIKeys.Enabled {
+		return fmt.Errorf("api_keys required needs api_keys enabled")
+	}
+	// Keys are managed through the admin endpoints.
+	if c.APIKeys.Enabled && c.Admin.Token == "" {
+		return fmt.Errorf("api_keys need an admin token")
+	}
+
+	if c.Hooks.ExecCommand != "" {
+		if err := validateExecutable(c.Hooks.ExecCommand); err != nil {
+			return err
+		}
+		if c.Hooks.ExecTimeout <= 0 {
+			return fmt.Errorf("hooks exec_timeout must be positive")
+		}
+		if c.Hooks.ExecMaxConcurrent < 1 {
+			return fmt.Errorf("hooks exec_max_concurrent must be at least 1")
+		}
+		if c.Hooks.ExecQueueDepth < 0 {
+			return fmt.Errorf("hooks exec_queue_depth must not be negative")
+		}
+		if c.Hooks.ExecOverflow != "drop" && c.Hooks.ExecOverflow != "block" {
+			return fmt.Errorf("invalid hooks exec_overflow: %s (must be 'drop' or 'block')", c.Hooks.ExecOverflow)
+		}
+	}
+
+	if c.Hooks.CodeCommand != "" {
+		if err := validateExecutable(c.Hooks.CodeCommand); err != nil {
+			return err
+		}
+		if c.Hooks.ExecTimeout <= 0 {
+			return fmt.Errorf("hooks exec_timeout must be positive")
+		}
+		if c.Secrets.RevealCodeTTL < time.Second {
+			return fmt.Errorf("reveal_code_ttl must be at least 1s")
+		}
+		if c.Secrets.MaxPINAttempts < 1 {
+			return fmt.Errorf("max_pin_attempts must be at least 1 when reveal codes are enabled")
+		}
+	}
+
+	switch c.Crypto.IDEncoding {
+	case "base64url", "base58", "base62":
+	default:
+		return fmt.Errorf("invalid id_encoding: %s (must be 'base64url', 'base58' or 'base62')", c.Crypto.IDEncoding)
+	}
+
+	for _, format := range c.Crypto.LegacyIDFormats {
+		switch format {
+		case "padded", "base64std":
+		default:
+			return fmt.Errorf("invalid legacy_id_formats entry: %s (must be 'padded' or 'base64std')", format)
+		}
+	}
+
+	if c.Crypto.MaxConcurrentOps < 0 {
+		return fmt.Errorf("max_concurrent_ops must not be negative")
+	}
+	if c.Crypto.QueueTimeout < 0 {
+		return fmt.Errorf("queue_timeout must not be negative")
+	}
+	// Mirrors crypto.MinPadBucket.
+	if c.Crypto.PadLength != 0 && c.Crypto.PadLength < 32 {
+		return fmt.Errorf("pad_length must be 0 or at least 32")
+	}
+
+	// Mirrors the bounds in crypto.KDFParams.Validate.
+	kdf := c.Crypto.KDF
+	if kdf.Memory < 8*1024 || kdf.Memory > 1024*1024 {
+		return fmt.Errorf("kdf memory must be between 8192 and 1048576 KiB")
+	}
+	if kdf.Iterations < 1 || kdf.Iterations > 32 {
+		return fmt.Errorf("kdf iterations must be between 1 and 32")
+	}
+	if kdf.Parallelism < 1 || kdf.Parallelism > 64 {
+		return fmt.Errorf("kdf parallelism must be between 1 and 64")
+	}
+
+	for name, profile := range c.Crypto.Profiles {
+		if profile.Cipher != "aes-128-gcm" && profile.Cipher != "aes-256-gcm" {
+			return fmt.Errorf("crypto profile %q: cipher must be 'aes-128-gcm' or 'aes-256-gcm'", name)
+		}
+		if profile.KDFIterations < 1000 || profile.KDFIterations > 10000000 {
+			return fmt.Errorf("crypto profile %q: kdf_iterations must be between 1000 and 10000000", name)
+		}
+	}
+
+	policy := c.Crypto.PassphrasePolicy
+	if policy.MinLength < 1 {
+		return fmt.Errorf("passphrase_policy.min_length must be at least 1")
+	}
+	if policy.MaxLength != 0 && policy.MaxLength < policy.MinLength {
+		return fmt.Errorf("passphrase_policy.max_length must be 0 or >= min_length")
+	}
+	if policy.MinClasses < 0 || policy.MinClasses > 4 {
+		return fmt.Errorf("passphrase_policy.min_classes must be between 0 and 4")
+	}
+	if policy.MinEntropyBits < 0 {
+		return fmt.Errorf("passphrase_policy.min_entropy_bits must not be negative")
+	}
 
 	return nil
 }
@@ -238,3 +1514,32 @@ func (c *Config) Validate() error {
 func (c *Config) Addr() string {
 	return fmt.Sprintf("%s:%d", c.Server.Host, c.Server.Port)
 }
+
+// validateExecutable checks the hook command up front so a typo fails at
+// startup rather than on the first event.
+func validateExecutable(path string) error {
+	if !filepath.IsAbs(path) {
+		return fmt.Errorf("hooks exec_command must be an absolute path: %s", path)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("hooks exec_command: %w", err)
+	}
+	if !info.Mode().IsRegular() || info.Mode().Perm()&0o111 == 0 {
+		return fmt.Errorf("hooks exec_command is not an executable file: %s", path)
+	}
+	return nil
+}
+
+// validOrigin reports whether origin is "*" or an http(s) origin as
+// browsers send it: scheme and host, optionally a port, and nothing else.
+func validOrigin(origin string) bool {
+	if origin == "*" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil || u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return false
+	}
+	return u.User == nil && u.Path == "" && !u.ForceQuery && u.RawQuery == "" && u.Fragment == "" && u.Scheme+"://"+u.Host == origin
+}
@@ -0,0 +1,22 @@
+package config
+
+import "testing"
+
+func TestValidateMaxPINAttempts(t *testing.T) {
+	for name, c := range map[string]struct {
+		allowPIN, allowUserPassphrase bool
+		ok                            bool
+	}{
+		"pin":             {allowPIN: true},
+		"user passphrase": {allowUserPassphrase: true},
+		"neither":         {ok: true},
+	} {
+		cfg := Default()
+		cfg.Secrets.AllowPIN = c.allowPIN
+		cfg.Secrets.AllowUserPassphrase = c.allowUserPassphrase
+		cfg.Secrets.MaxPINAttempts = 0
+		if err := cfg.Validate(); (err == nil) != c.ok {
+			t.Errorf("%s: Validate() = %v, want ok %v", name, err, c.ok)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const yamlConfig = `
+server:
+  port: 9090
+  base_url: "https://share.example.com"
+  same_origin: true
+  shutdown_timeout: 30s
+  cors:
+    allowed_origins: ["https://app.example.com", "https://admin.example.com"]
+store:
+  type: sqlite
+  sqlite:
+    path: /var/lib/secure-share/secrets.db
+  health:
+    max_error_rate: 0.25
+secrets:
+  default_ttl: 2h
+  max_views: 5
+crypto:
+  profiles:
+    paranoid:
+      cipher: aes-256-gcm
+      kdf_iterations: 1000000
+`
+
+const jsonConfig = `{
+  "server": {
+    "port": 9090,
+    "base_url": "https://share.example.com",
+    "same_origin": true,
+    "shutdown_timeout": "30s",
+    "cors": {"allowed_origins": ["https://app.example.com", "https://admin.example.com"]}
+  },
+  "store": {
+    "type": "sqlite",
+    "sqlite": {"path": "/var/lib/secure-share/secrets.db"},
+    "health": {"max_error_rate": 0.25}
+  },
+  "secrets": {"default_ttl": "2h", "max_views": 5},
+  "crypto": {
+    "profiles": {
+      "paranoid": {"cipher": "aes-256-gcm", "kdf_iterations": 1000000}
+    }
+  }
+}`
+
+const tomlConfig = `
+# Same settings as the YAML and JSON above.
+[server]
+port = 9090
+base_url = "https://share.example.com"
+same_origin = true
+shutdown_timeout = "30s"
+cors.allowed_origins = [
+  "https://app.example.com",
+  'https://admin.example.com', # trailing comma allowed
+]
+
+[store]
+type = "sqlite"
+sqlite = { path = "/var/lib/secure-share/secrets.db" }
+health.max_error_rate = 0.25
+
+[secrets]
+default_ttl = "2h"
+max_views = 5
+
+[crypto.profiles.paranoid]
+cipher = "aes-256-gcm"
+kdf_iterations = 1_000_000
+`
+
+func writeConfig(t *testing.T, name, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadFormats(t *testing.T) {
+	want, err := Load(writeConfig(t, "config.yaml", yamlConfig))
+	if err != nil {
+		t.Fatalf("yaml: %v", err)
+	}
+	if want.Server.Port != 9090 || want.Crypto.Profiles["paranoid"].KDFIterations != 1000000 {
+		t.Fatalf("yaml config not applied: %+v", want.Server)
+	}
+
+	for name, data := range map[string]string{
+		"config.yml":  yamlConfig,
+		"config.json": jsonConfig,
+		"config.toml": tomlConfig,
+		"config.TOML": tomlConfig,
+		// Unknown extensions are read as YAML.
+		"config.conf": yamlConfig,
+	} {
+		got, err := Load(writeConfig(t, name, data))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s loaded differently from config.yaml:\n got %+v\nwant %+v", name, got, want)
+		}
+	}
+}
+
+func TestLoadFormatErrors(t *testing.T) {
+	for name, data := range map[string]string{
+		"bad.json":       `{"server": {"port": }`,
+		"duplicate.toml": "[server]\nport = 1\nport = 2\n",
+		"table.toml":     "[server]\n[server]\n",
+		"date.toml":      "[secrets]\ndefault_ttl = 1979-05-27T07:32:00Z\n",
+		"string.toml":    "[server]\nhost = \"unterminated\n",
+		"trailing.toml":  "[server]\nport = 1 2\n",
+		"type.json":      `{"server": {"port": "eighty"}}`,
+	} {
+		_, err := Load(writeConfig(t, name, data))
+		if err == nil || !strings.Contains(err.Error(), "parsing config file") {
+			t.Errorf("%s: got %v, want a parse error", name, err)
+		}
+	}
+}
+
+func TestLoadTOMLStrings(t *testing.T) {
+	// Literal and multi-line strings reach Config like basic ones.
+	got, err := Load(writeConfig(t, "strings.toml", `
+[server]
+host = 'literal\no-escapes'
+base_url = """
+https://\
+    secrets.example.com"""
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Server.Host != `literal\no-escapes` || got.Server.BaseURL != "https://secrets.example.com" {
+		t.Errorf("got host %q, base_url %q", got.Server.Host, got.Server.BaseURL)
+	}
+}
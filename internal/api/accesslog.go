@@ -0,0 +1,90 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"secure.share/internal/models"
+	"secure.share/internal/store"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// maxAccessUserAgent caps how much of a User-Agent header is recorded.
+const maxAccessUserAgent = 256
+
+type AccessLogResponse struct {
+	ID      string               `json:"id"`
+	Entries []models.AccessEntry `json:"entries"`
+}
+
+// recordAccess appends a reveal to the secret's access log. A secret whose
+// last view was just used is already gone, and its log with it, so there is
+// nothing to record.
+func (h *Handler) recordAccess(r *http.Request, secret *models.Secret, currentViews int) {
+	if h.config.Secrets.AccessLogEntries == 0 || currentViews >= secret.MaxViews {
+		return
+	}
+
+	entry := models.AccessEntry{
+		Time:      time.Now().UTC(),
+		IP:        ipPrefix(getClientIP(r)),
+		UserAgent: truncateUserAgent(r.UserAgent()),
+	}
+	err := h.store.AppendAccess(r.Context(), secret.ID, entry, h.config.Secrets.AccessLogEntries)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		slog.WarnContext(r.Context(), "failed to record access", "error", err)
+	}
+}
+
+// ipPrefix keeps the /24 of an IPv4 address or the /48 of an IPv6 one:
+// enough to tell networks apart without recording who the reader was.
+func ipPrefix(addr string) string {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return ""
+	}
+	if v4 := ip.To4(); v4 != nil {
+		return (&net.IPNet{IP: v4.Mask(net.CIDRMask(24, 32)), Mask: net.CIDRMask(24, 32)}).String()
+	}
+	return (&net.IPNet{IP: ip.Mask(net.CIDRMask(48, 128)), Mask: net.CIDRMask(48, 128)}).String()
+}
+
+func truncateUserAgent(ua string) string {
+	if len(ua) > maxAccessUserAgent {
+		ua = strings.ToValidUTF8(ua[:maxAccessUserAgent], "")
+	}
+	return ua
+}
+
+// AccessLog lists the recorded reveals of a secret to its owner.
+func (h *Handler) AccessLog(w http.ResponseWriter, r *http.Request) {
+	secret, ok := h.ownedSecret(w, r)
+	if !ok {
+		return
+	}
+	h.writeAccessLog(w, r, secret.ID)
+}
+
+// AdminAccessLog lists the recorded reveals of any secret.
+func (h *Handler) AdminAccessLog(w http.ResponseWriter, r *http.Request) {
+	secret, err := h.lookup(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		h.handleStoreError(w, r, err)
+		return
+	}
+	h.writeAccessLog(w, r, secret.ID)
+}
+
+func (h *Handler) writeAccessLog(w http.ResponseWriter, r *http.Request, id string) {
+	entries, err := h.store.AccessLog(r.Context(), id)
+	if err != nil {
+		h.handleStoreError(w, r, err)
+		return
+	}
+	h.json(w, http.StatusOK, AccessLogResponse{ID: id, Entries: entries})
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"secure.share/config"
+	"secure.share/internal/store"
+)
+
+func TestAccessLog(t *testing.T) {
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	cfg := config.Default()
+	cfg.Admin.Token = testAdminToken
+	cfg.Secrets.AccessLogEntries = 5
+	router := SetupRouter(st, cfg)
+
+	created, passphrase := createSecret(t, router, `{"content": "s3cret", "max_views": 3}`)
+	if rec := revealSecret(router, created.ID, passphrase); rec.Code != http.StatusOK {
+		t.Fatalf("reveal failed: %d: %s", rec.Code, rec.Body.String())
+	}
+
+	path := "/api/secrets/" + created.ID + "/log"
+	if rec := ownerRequest(router, http.MethodGet, path, "", ""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("missing owner token: got %d, want 401", rec.Code)
+	}
+
+	rec := ownerRequest(router, http.MethodGet, path, created.OwnerToken, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("access log: got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp AccessLogResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode access log: %v", err)
+	}
+	// httptest requests come from 192.0.2.1.
+	if len(resp.Entries) != 1 || resp.Entries[0].IP != "192.0.2.0/24" || resp.Entries[0].Time.IsZero() {
+		t.Fatalf("entries = %+v, want one from 192.0.2.0/24", resp.Entries)
+	}
+
+	if rec := adminRequest(router, http.MethodGet, "/api/admin/secrets/"+created.ID+"/log", ""); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "192.0.2.0/24") {
+		t.Fatalf("admin access log: got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// The last view deletes the secret and its log together.
+	revealSecret(router, created.ID, passphrase)
+	revealSecret(router, created.ID, passphrase)
+	if rec := adminRequest(router, http.MethodGet, "/api/admin/secrets/"+created.ID+"/log", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("access log of a consumed secret: got %d, want 404", rec.Code)
+	}
+}
+
+func TestAccessLogDisabled(t *testing.T) {
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	router := SetupRouter(st, config.Default())
+
+	created, _ := createSecret(t, router, `{"content": "s3cret", "max_views": 3}`)
+	if rec := ownerRequest(router, http.MethodGet, "/api/secrets/"+created.ID+"/log", created.OwnerToken, ""); rec.Code == http.StatusOK {
+		t.Fatalf("access log served while disabled: %s", rec.Body.String())
+	}
+}
+
+func TestIPPrefix(t *testing.T) {
+	for addr, want := range map[string]string{
+		"203.0.113.77":        "203.0.113.0/24",
+		"2001:db8:abcd:12::1": "2001:db8:abcd::/48",
+		"::ffff:198.51.100.9": "198.51.100.0/24",
+		"not an address":      "",
+		"":                    "",
+	} {
+		if got := ipPrefix(addr); got != want {
+			t.Errorf("ipPrefix(%q) = %q, want %q", addr, got, want)
+		}
+	}
+
+	long := strings.Repeat("é", maxAccessUserAgent)
+	if got := truncateUserAgent(long); len(got) > maxAccessUserAgent || !strings.HasPrefix(long, got) {
+		t.Fatalf("truncated user agent is %d bytes or not a prefix", len(got))
+	}
+}
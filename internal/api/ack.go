@@ -0,0 +1,82 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"net/http"
+	"strconv"
+	"time"
+
+	"secure.share/internal/models"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type AckRequest struct {
+	Accepted bool `json:"accepted"`
+}
+
+type AckResponse struct {
+	AckToken  string    `json:"ack_token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Acknowledge records the recipient's consent for a require_ack secret and
+// returns a short-lived token the reveal call must present. It neither
+// consumes a view nor touches the content.
+func (h *Handler) Acknowledge(w http.ResponseWriter, r *http.Request) {
+	var req AckRequest
+	if !h.decode(w, r, &req) {
+		return
+	}
+	if !req.Accepted {
+		h.error(w, r, http.StatusBadRequest, "accepted must be true")
+		return
+	}
+
+	secret, err := h.lookup(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		h.handleStoreError(w, r, err)
+		return
+	}
+	if !secret.RequireAck {
+		h.error(w, r, http.StatusBadRequest, "secret does not require acknowledgment")
+		return
+	}
+
+	expiresAt := time.Now().Add(h.config.Secrets.AckTTL)
+	h.json(w, http.StatusOK, AckResponse{
+		AckToken:  ackToken(secret, expiresAt),
+		ExpiresAt: expiresAt,
+	})
+}
+
+// ackToken is the expiry followed by an HMAC over the secret ID and expiry,
+// keyed by the stored ciphertext. That key never leaves the server and is
+// shared by every instance reading the same store, so no extra state is
+// needed to verify the token later.
+func ackToken(secret *models.Secret, expiresAt time.Time) string {
+	buf := binary.BigEndian.AppendUint64(nil, uint64(expiresAt.Unix()))
+	buf = append(buf, ackMAC(secret, expiresAt.Unix())...)
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
+
+func validAckToken(secret *models.Secret, token string) bool {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(raw) != 8+sha256.Size {
+		return false
+	}
+	expires := int64(binary.BigEndian.Uint64(raw[:8]))
+	if time.Now().Unix() > expires {
+		return false
+	}
+	return hmac.Equal(raw[8:], ackMAC(secret, expires))
+}
+
+func ackMAC(secret *models.Secret, expires int64) []byte {
+	mac := hmac.New(sha256.New, secret.EncryptedData)
+	mac.Write([]byte("ack:" + secret.ID + ":" + strconv.FormatInt(expires, 10)))
+	return mac.Sum(nil)
+}
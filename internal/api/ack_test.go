@@ -0,0 +1,83 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"secure.share/config"
+	"secure.share/internal/store"
+)
+
+func acknowledge(t *testing.T, router http.Handler, id, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/api/secrets/"+id+"/ack", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRevealRequiresAck(t *testing.T) {
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	router := SetupRouter(st, config.Default())
+
+	created, passphrase := createSecret(t, router, `{"content": "s3cret", "require_ack": true}`)
+
+	if rec := revealSecret(router, created.ID, passphrase); rec.Code != http.StatusForbidden {
+		t.Fatalf("reveal without ack: got %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	secret, err := st.Get(t.Context(), created.ID)
+	if err != nil {
+		t.Fatalf("secret gone after rejected reveal: %v", err)
+	}
+	if secret.CurrentViews != 0 {
+		t.Fatalf("rejected reveal consumed a view: %d", secret.CurrentViews)
+	}
+
+	if rec := acknowledge(t, router, created.ID, `{"accepted": false}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("ack without acceptance: got %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+
+	rec := acknowledge(t, router, created.ID, `{"accepted": true}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("ack failed: got %d: %s", rec.Code, rec.Body.String())
+	}
+	var ack AckResponse
+	if err := json.NewDecoder(rec.Body).Decode(&ack); err != nil {
+		t.Fatalf("failed to decode ack response: %v", err)
+	}
+
+	rec = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/secrets/"+created.ID+"?passphrase="+url.QueryEscape(passphrase)+"&ack="+ack.AckToken, nil)
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("reveal with ack failed: got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestAckTokenBoundToSecret(t *testing.T) {
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	router := SetupRouter(st, config.Default())
+
+	first, _ := createSecret(t, router, `{"content": "a", "require_ack": true}`)
+	second, passphrase := createSecret(t, router, `{"content": "b", "require_ack": true}`)
+
+	var ack AckResponse
+	if err := json.NewDecoder(acknowledge(t, router, first.ID, `{"accepted": true}`).Body).Decode(&ack); err != nil {
+		t.Fatalf("failed to decode ack response: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/secrets/"+second.ID+"?passphrase="+url.QueryEscape(passphrase)+"&ack="+ack.AckToken, nil)
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("reveal with another secret's ack: got %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
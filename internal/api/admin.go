@@ -0,0 +1,118 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"secure.share/internal/hooks"
+	"secure.share/internal/models"
+)
+
+type PurgeRequest struct {
+	Label         string    `json:"label,omitempty"`
+	CreatedAfter  time.Time `json:"created_after,omitempty"`
+	CreatedBefore time.Time `json:"created_before,omitempty"`
+}
+
+type PurgeResponse struct {
+	Deleted int `json:"deleted"`
+}
+
+// matches reports whether secret meets every criterion set on the request.
+func (p PurgeRequest) matches(secret *models.Secret) bool {
+	if p.Label != "" && secret.Label != p.Label {
+		return false
+	}
+	if !p.CreatedAfter.IsZero() && secret.CreatedAt.Before(p.CreatedAfter) {
+		return false
+	}
+	if !p.CreatedBefore.IsZero() && !secret.CreatedAt.Before(p.CreatedBefore) {
+		return false
+	}
+	return true
+}
+
+// Purge bulk-deletes secrets by label and/or creation time window for
+// incident response.
+func (h *Handler) Purge(w http.ResponseWriter, r *http.Request) {
+	var req PurgeRequest
+	if !h.decode(w, r, &req) {
+		return
+	}
+
+	if req.Label == "" && req.CreatedAfter.IsZero() && req.CreatedBefore.IsZero() {
+		h.error(w, r, http.StatusBadRequest, "label, created_after or created_before is required")
+		return
+	}
+
+	deleted, err := h.store.DeleteWhere(r.Context(), req.matches)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "purge failed",
+			"error", err,
+			"deleted", deleted,
+		)
+		h.error(w, r, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	slog.WarnContext(r.Context(), "secrets purged",
+		"deleted", deleted,
+		"label", req.Label,
+		"created_after", req.CreatedAfter,
+		"created_before", req.CreatedBefore,
+	)
+	h.json(w, http.StatusOK, PurgeResponse{Deleted: deleted})
+}
+
+// defaultForecastMinutes is the expiry window AdminStats reports when the
+// caller doesn't pass within_minutes.
+const defaultForecastMinutes = 60
+
+type AdminStatsResponse struct {
+	TotalReveals   int64               `json:"total_reveals"`
+	ExpiringWithin int                 `json:"expiring_within"`
+	WithinMinutes  int                 `json:"within_minutes"`
+	StoreHealth    StoreHealthResponse `json:"store_health"`
+	// Hooks is the exec hook's queue, when one is configured.
+	Hooks *hooks.QueueStats `json:"hooks,omitempty"`
+}
+
+// AdminStats reports operator-only figures, including how many secrets will
+// expire in the next within_minutes for capacity planning.
+func (h *Handler) AdminStats(w http.ResponseWriter, r *http.Request) {
+	minutes := defaultForecastMinutes
+	if v := r.URL.Query().Get("within_minutes"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			h.error(w, r, http.StatusBadRequest, "within_minutes must be a positive integer")
+			return
+		}
+		minutes = n
+	}
+
+	expiring, err := h.store.ExpiringWithin(r.Context(), time.Duration(minutes)*time.Minute)
+	if err != nil {
+		h.error(w, r, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	reveals, err := h.store.RevealCount(r.Context())
+	if err != nil {
+		h.error(w, r, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	resp := AdminStatsResponse{
+		TotalReveals:   reveals,
+		ExpiringWithin: expiring,
+		WithinMinutes:  minutes,
+		StoreHealth:    h.storeHealthResponse(),
+	}
+	if h.hook != nil {
+		stats := h.hook.Stats()
+		resp.Hooks = &stats
+	}
+	h.json(w, http.StatusOK, resp)
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"secure.share/config"
+	"secure.share/internal/store"
+)
+
+const testAdminToken = "test-admin-token-0123456789"
+
+func adminRequest(router http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+testAdminToken)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestPurgeByLabel(t *testing.T) {
+	cfg := config.Default()
+	cfg.Admin.Token = testAdminToken
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	router := SetupRouter(st, cfg)
+
+	leaked, _ := createSecret(t, router, `{"content": "a", "label": "leaked"}`)
+	kept, _ := createSecret(t, router, `{"content": "b", "label": "fine"}`)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/admin/purge", strings.NewReader(`{"label": "leaked"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("purge without token: got %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+
+	rec = adminRequest(router, http.MethodPost, "/api/admin/purge", `{"label": "leaked"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("purge failed: got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp PurgeResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode purge response: %v", err)
+	}
+	if resp.Deleted != 1 {
+		t.Fatalf("deleted count mismatch: got %d, want %d", resp.Deleted, 1)
+	}
+
+	if _, err := st.Get(context.Background(), leaked.ID); err == nil {
+		t.Fatalf("labelled secret should have been purged")
+	}
+	if _, err := st.Get(context.Background(), kept.ID); err != nil {
+		t.Fatalf("other secret should remain: %v", err)
+	}
+}
+
+func TestAdminStatsExpiringWithin(t *testing.T) {
+	cfg := config.Default()
+	cfg.Admin.Token = testAdminToken
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	router := SetupRouter(st, cfg)
+
+	createSecret(t, router, `{"content": "a", "ttl_minutes": 10}`)
+	createSecret(t, router, `{"content": "b", "ttl_minutes": 20}`)
+	createSecret(t, router, `{"content": "c", "ttl_minutes": 120}`)
+
+	rec := adminRequest(router, http.MethodGet, "/api/admin/stats?within_minutes=30", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("stats failed: got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp AdminStatsResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode stats: %v", err)
+	}
+	if resp.ExpiringWithin != 2 || resp.WithinMinutes != 30 {
+		t.Fatalf("stats mismatch: got %+v, want 2 expiring within 30 minutes", resp)
+	}
+
+	if rec := adminRequest(router, http.MethodGet, "/api/admin/stats?within_minutes=0", ""); rec.Code != http.StatusBadRequest {
+		t.Fatalf("invalid window: got %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,173 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"secure.share/internal/crypto"
+	"secure.share/internal/models"
+	"secure.share/internal/store"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	apiKeyHeader = "X-API-Key"
+	// API keys read "ssk_<id>.<secret>"; IDs are base64url, which has no dot.
+	apiKeyPrefix = "ssk_"
+
+	maxAPIKeyNameLength = 64
+)
+
+// API key scopes. Each grants only its own routes.
+const (
+	scopeCreate = "create"
+	scopeReveal = "reveal"
+	scopeAdmin  = "admin"
+)
+
+var apiKeyScopes = []string{scopeCreate, scopeReveal, scopeAdmin}
+
+const apiKeyContextKey contextKey = "api_key"
+
+type CreateAPIKeyRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+type APIKeyResponse struct {
+	ID     string   `json:"id"`
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+	// Key is only returned when the key is created; the server keeps a hash.
+	Key       string    `json:"key"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func parseAPIKey(key string) (id, secret string, ok bool) {
+	rest, ok := strings.CutPrefix(key, apiKeyPrefix)
+	if !ok {
+		return "", "", false
+	}
+	id, secret, ok = strings.Cut(rest, ".")
+	return id, secret, ok && id != "" && secret != ""
+}
+
+// apiKeyFrom returns the key the request authenticated with, if any.
+func apiKeyFrom(r *http.Request) (*models.APIKey, bool) {
+	key, ok := r.Context().Value(apiKeyContextKey).(*models.APIKey)
+	return key, ok
+}
+
+// authenticateAPIKey checks the X-API-Key header, if present, and records
+// the key for the scope checks, rate limiter and same-origin check further
+// down. A key that is malformed, unknown or revoked gets a 401; requests
+// without one carry on anonymously.
+func (h *Handler) authenticateAPIKey(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		supplied := r.Header.Get(apiKeyHeader)
+		if supplied == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		id, secret, ok := parseAPIKey(supplied)
+		if !ok {
+			h.error(w, r, http.StatusUnauthorized, "invalid api key")
+			return
+		}
+		key, err := h.store.GetAPIKey(r.Context(), id)
+		if errors.Is(err, store.ErrNotFound) || (err == nil && !crypto.VerifyOwnerToken(secret, key.Hash)) {
+			h.error(w, r, http.StatusUnauthorized, "invalid api key")
+			return
+		}
+		if err != nil {
+			h.handleStoreError(w, r, err)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), apiKeyContextKey, key)))
+	})
+}
+
+// requireScope refuses requests made with a key that lacks scope, and,
+// when keys are required, requests made without one.
+func (h *Handler) requireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key, ok := apiKeyFrom(r)
+			if !ok && h.config.APIKeys.Required {
+				h.error(w, r, http.StatusUnauthorized, "api key required")
+				return
+			}
+			if ok && !key.HasScope(scope) {
+				h.error(w, r, http.StatusForbidden, fmt.Sprintf("api key lacks the %s scope", scope))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// CreateAPIKey issues a key with the requested scopes. The full key is only
+// in this response.
+func (h *Handler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var req CreateAPIKeyRequest
+	if !h.decode(w, r, &req) {
+		return
+	}
+	if req.Name == "" || len(req.Name) > maxAPIKeyNameLength {
+		h.error(w, r, http.StatusBadRequest, fmt.Sprintf("name is required and must be at most %d bytes", maxAPIKeyNameLength))
+		return
+	}
+	if len(req.Scopes) == 0 {
+		h.error(w, r, http.StatusBadRequest, "at least one scope is required")
+		return
+	}
+	for _, scope := range req.Scopes {
+		if !slices.Contains(apiKeyScopes, scope) {
+			h.error(w, r, http.StatusBadRequest, fmt.Sprintf("unknown scope %q (must be one of %s)", scope, strings.Join(apiKeyScopes, ", ")))
+			return
+		}
+	}
+
+	secret := crypto.GenerateOwnerToken()
+	key := &models.APIKey{
+		ID:        crypto.GenerateID(),
+		Name:      req.Name,
+		Scopes:    slices.Compact(slices.Sorted(slices.Values(req.Scopes))),
+		Hash:      crypto.HashOwnerToken(secret),
+		CreatedAt: time.Now(),
+	}
+	if err := h.store.SaveAPIKey(r.Context(), key); err != nil {
+		h.handleStoreError(w, r, err)
+		return
+	}
+
+	h.json(w, http.StatusCreated, APIKeyResponse{
+		ID:        key.ID,
+		Name:      key.Name,
+		Scopes:    key.Scopes,
+		Key:       apiKeyPrefix + key.ID + "." + secret,
+		CreatedAt: key.CreatedAt,
+	})
+}
+
+// RevokeAPIKey deletes a key; requests using it fail from then on.
+func (h *Handler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	err := h.store.DeleteAPIKey(r.Context(), chi.URLParam(r, "keyID"))
+	if errors.Is(err, store.ErrNotFound) {
+		h.error(w, r, http.StatusNotFound, "api key not found")
+		return
+	}
+	if err != nil {
+		h.handleStoreError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
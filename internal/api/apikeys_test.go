@@ -0,0 +1,161 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"secure.share/config"
+	"secure.share/internal/store"
+)
+
+func newAPIKeyRouter(t *testing.T, cfg *config.Config) http.Handler {
+	t.Helper()
+	cfg.Admin.Token = testAdminToken
+	cfg.APIKeys.Enabled = true
+	st := store.NewMemoryStore(time.Minute)
+	t.Cleanup(func() { st.Close() })
+	return SetupRouter(st, cfg)
+}
+
+// withKey sends a request with an API key, or anonymously if key is "".
+func withKey(router http.Handler, method, path, body, key string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set(apiKeyHeader, key)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func issueKey(t *testing.T, router http.Handler, scopes ...string) APIKeyResponse {
+	t.Helper()
+	body, _ := json.Marshal(CreateAPIKeyRequest{Name: "integration", Scopes: scopes})
+	rec := adminRequest(router, http.MethodPost, "/api/admin/keys", string(body))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("key create failed: got %d: %s", rec.Code, rec.Body.String())
+	}
+	var key APIKeyResponse
+	if err := json.NewDecoder(rec.Body).Decode(&key); err != nil {
+		t.Fatalf("failed to decode key response: %v", err)
+	}
+	return key
+}
+
+func createWithKey(t *testing.T, router http.Handler, key string) (CreateResponse, string) {
+	t.Helper()
+	rec := withKey(router, http.MethodPost, "/api/secrets", `{"content": "s3cret"}`, key)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create failed: got %d: %s", rec.Code, rec.Body.String())
+	}
+	var created CreateResponse
+	if err := json.NewDecoder(rec.Body).Decode(&created); err != nil {
+		t.Fatalf("failed to decode create response: %v", err)
+	}
+	return created, created.URL[strings.Index(created.URL, "#")+1:]
+}
+
+func revealPath(id, passphrase string) string {
+	return "/api/secrets/" + id + "?passphrase=" + url.QueryEscape(passphrase)
+}
+
+func TestAPIKeyScopes(t *testing.T) {
+	router := newAPIKeyRouter(t, config.Default())
+	creator := issueKey(t, router, scopeCreate)
+	revealer := issueKey(t, router, scopeReveal)
+
+	if rec := withKey(router, http.MethodPost, "/api/secrets", `{"content": "s3cret"}`, revealer.Key); rec.Code != http.StatusForbidden {
+		t.Fatalf("create with reveal-only key: got %d, want %d", rec.Code, http.StatusForbidden)
+	}
+
+	created, passphrase := createWithKey(t, router, creator.Key)
+	if rec := withKey(router, http.MethodGet, revealPath(created.ID, passphrase), "", creator.Key); rec.Code != http.StatusForbidden {
+		t.Fatalf("reveal with create-only key: got %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	if rec := withKey(router, http.MethodGet, revealPath(created.ID, passphrase), "", revealer.Key); rec.Code != http.StatusOK {
+		t.Fatalf("reveal with reveal key: got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// Keys don't open the admin endpoints without the admin scope.
+	if rec := withKey(router, http.MethodPost, "/api/admin/keys", `{"name": "x", "scopes": ["admin"]}`, creator.Key); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("key create with create key: got %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+	admin := issueKey(t, router, scopeAdmin)
+	if rec := withKey(router, http.MethodPost, "/api/admin/keys", `{"name": "x", "scopes": ["reveal"]}`, admin.Key); rec.Code != http.StatusCreated {
+		t.Fatalf("key create with admin key: got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestAPIKeyRevoked(t *testing.T) {
+	router := newAPIKeyRouter(t, config.Default())
+	key := issueKey(t, router, scopeCreate)
+	createWithKey(t, router, key.Key)
+
+	revoke := func() int {
+		return adminRequest(router, http.MethodDelete, "/api/admin/keys/"+key.ID, "").Code
+	}
+	if code := revoke(); code != http.StatusNoContent {
+		t.Fatalf("revoke: got %d, want %d", code, http.StatusNoContent)
+	}
+	if code := revoke(); code != http.StatusNotFound {
+		t.Fatalf("second revoke: got %d, want %d", code, http.StatusNotFound)
+	}
+
+	for _, supplied := range []string{key.Key, "ssk_" + key.ID + ".wrong", "not-a-key"} {
+		if rec := withKey(router, http.MethodPost, "/api/secrets", `{"content": "s3cret"}`, supplied); rec.Code != http.StatusUnauthorized {
+			t.Fatalf("create with %q: got %d, want %d", supplied, rec.Code, http.StatusUnauthorized)
+		}
+	}
+}
+
+func TestAPIKeyRequired(t *testing.T) {
+	cfg := config.Default()
+	cfg.APIKeys.Required = true
+	router := newAPIKeyRouter(t, cfg)
+
+	if rec := withKey(router, http.MethodPost, "/api/secrets", `{"content": "s3cret"}`, ""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("anonymous create: got %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+	created, passphrase := createWithKey(t, router, issueKey(t, router, scopeCreate).Key)
+	if rec := revealSecret(router, created.ID, passphrase); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("anonymous reveal: got %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestAPIKeyRateLimitAndOrigin(t *testing.T) {
+	cfg := config.Default()
+	cfg.RateLimit.Enabled = true
+	cfg.RateLimit.RequestsPerMin = 2
+	cfg.Server.SameOrigin = true
+	cfg.Server.BaseURL = "https://share.example.com"
+	router := newAPIKeyRouter(t, cfg)
+
+	// Issuing keys is rate limited too, by IP.
+	first := issueKey(t, router, scopeCreate)
+	second := issueKey(t, router, scopeCreate)
+
+	// Each key has its own allowance, even from the same IP, and server-
+	// to-server calls aren't held to the browser origin check.
+	for _, key := range []string{first.Key, second.Key} {
+		for range 2 {
+			req := httptest.NewRequest(http.MethodPost, "/api/secrets", strings.NewReader(`{"content": "s3cret"}`))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Origin", "https://elsewhere.example.net")
+			req.Header.Set(apiKeyHeader, key)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			if rec.Code != http.StatusCreated {
+				t.Fatalf("create with key: got %d: %s", rec.Code, rec.Body.String())
+			}
+		}
+	}
+	if rec := withKey(router, http.MethodPost, "/api/secrets", `{"content": "s3cret"}`, first.Key); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("create past the key's limit: got %d, want %d", rec.Code, http.StatusTooManyRequests)
+	}
+}
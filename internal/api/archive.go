@@ -0,0 +1,173 @@
+package api
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	maxArchiveEntries   = 32
+	maxArchiveNameBytes = 255
+)
+
+// ArchiveFile is one named entry of a multi-file secret.
+type ArchiveFile struct {
+	Name    string `json:"name"`
+	Content string `json:"content"`
+}
+
+// validateArchive checks a create request's files against the entry, name
+// and total size limits and returns a client-facing reason if they fail.
+func (h *Handler) validateArchive(files []ArchiveFile) string {
+	if h.config.Secrets.MaxArchiveBytes == 0 {
+		return "archives are not enabled"
+	}
+	if len(files) > maxArchiveEntries {
+		return fmt.Sprintf("at most %d files are allowed", maxArchiveEntries)
+	}
+
+	seen := make(map[string]bool, len(files))
+	total := 0
+	for _, f := range files {
+		if !validArchiveName(f.Name) {
+			return fmt.Sprintf("invalid file name %q", f.Name)
+		}
+		if seen[f.Name] {
+			return fmt.Sprintf("duplicate file name %q", f.Name)
+		}
+		seen[f.Name] = true
+		total += len(f.Content)
+	}
+	if total > h.config.Secrets.MaxArchiveBytes {
+		return fmt.Sprintf("files must total at most %d bytes", h.config.Secrets.MaxArchiveBytes)
+	}
+	return ""
+}
+
+// validArchiveName accepts plain file names only, so an entry can't be
+// extracted outside the directory it is unpacked into.
+func validArchiveName(name string) bool {
+	if name == "" || len(name) > maxArchiveNameBytes || name == "." || name == ".." {
+		return false
+	}
+	if strings.ContainsAny(name, `/\:`) {
+		return false
+	}
+	for _, r := range name {
+		if unicode.IsControl(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// downloadWriter applies the configured download bandwidth limit to w:
+// one limiter shared by every download, or a fresh one per download. The
+// write deadline moves along with each chunk, and the route is exempt from
+// the request timeout (see isThrottledDownload), so a slow download isn't
+// cut off after its view is used.
+func (h *Handler) downloadWriter(r *http.Request, w http.ResponseWriter) io.Writer {
+	rate := h.config.Secrets.DownloadRate
+	if rate == 0 {
+		return w
+	}
+	limiter := h.downloadLimiter
+	if limiter == nil {
+		limiter = newBandwidthLimiter(rate)
+	}
+	return &throttledWriter{ctx: r.Context(), w: w, rc: http.NewResponseController(w), limiter: limiter}
+}
+
+// isThrottledDownload reports an archive or file download that
+// download_rate paces, which may take longer than any fixed request
+// timeout. Files download from the plain reveal route, so JSON reveals are
+// exempted as well; the server's write timeout still bounds those.
+func (h *Handler) isThrottledDownload(r *http.Request) bool {
+	if h.config.Secrets.DownloadRate == 0 || r.Method != http.MethodGet {
+		return false
+	}
+	id, ok := strings.CutPrefix(r.URL.Path, "/api/secrets/")
+	if !ok {
+		return false
+	}
+	id = strings.TrimSuffix(id, "/archive")
+	return id != "" && !strings.Contains(id, "/")
+}
+
+// DownloadArchive reveals a multi-file secret as a zip. Like RevealSecret it
+// uses exactly one view, however many files the archive holds.
+func (h *Handler) DownloadArchive(w http.ResponseWriter, r *http.Request) {
+	if h.reservations != nil {
+		if etag, ok := h.reservations.revalidate(r, chi.URLParam(r, "id")); ok {
+			w.Header().Set("Cache-Control", "no-store, private")
+			w.Header().Set("ETag", etag)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+
+	secret, content, currentViews, ok := h.reveal(w, r, true)
+	if !ok {
+		return
+	}
+
+	var files []ArchiveFile
+	if err := json.Unmarshal(content, &files); err != nil {
+		h.error(w, r, http.StatusInternalServerError, "failed to read archive")
+		return
+	}
+
+	if !secret.HideViews {
+		w.Header().Set("X-Views-Remaining", strconv.Itoa(secret.MaxViews-currentViews))
+	}
+	if nonce := nextRevealNonce(secret, currentViews); nonce != "" {
+		w.Header().Set(revealNonceHeader, nonce)
+	}
+	if h.offload != nil && len(content) >= h.config.Offload.MinBytes && h.offloadArchive(w, r, files) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="secret.zip"`)
+	if h.reservations != nil {
+		if token, etag, ok := h.reservations.reserve(secret.ID, content); ok {
+			w.Header().Set("ETag", etag)
+			w.Header().Set(reservationHeader, token)
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+
+	// The view is already used, so a failure past this point can only be
+	// logged; the client sees a truncated zip.
+	if err := writeArchive(h.downloadWriter(r, w), files); err != nil {
+		slog.WarnContext(r.Context(), "failed to write archive", "error", err)
+	}
+}
+
+func writeArchive(w io.Writer, files []ArchiveFile) error {
+	zw := zip.NewWriter(w)
+	for _, f := range files {
+		entry, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     f.Name,
+			Method:   zip.Deflate,
+			Modified: time.Now(),
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := entry.Write([]byte(f.Content)); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
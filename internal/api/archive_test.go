@@ -0,0 +1,192 @@
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"secure.share/config"
+	"secure.share/internal/store"
+)
+
+func TestDownloadArchive(t *testing.T) {
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	router := SetupRouter(st, config.Default())
+
+	created, passphrase := createSecret(t, router, `{"max_views": 2, "files": [
+		{"name": "id_ed25519", "content": "private key"},
+		{"name": "notes.txt", "content": "rotate on friday"}
+	]}`)
+
+	// The JSON reveal refuses an archive without using a view.
+	if rec := revealSecret(router, created.ID, passphrase); rec.Code != http.StatusBadRequest {
+		t.Fatalf("json reveal of archive: got %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/secrets/"+created.ID+"/archive?passphrase="+url.QueryEscape(passphrase), nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("download failed: got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Content-Type"); got != "application/zip" {
+		t.Fatalf("content type mismatch: got %q", got)
+	}
+
+	body := rec.Body.Bytes()
+	zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		t.Fatalf("response is not a zip: %v", err)
+	}
+	want := map[string]string{"id_ed25519": "private key", "notes.txt": "rotate on friday"}
+	if len(zr.File) != len(want) {
+		t.Fatalf("entry count mismatch: got %d, want %d", len(zr.File), len(want))
+	}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("failed to open %s: %v", f.Name, err)
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		if string(data) != want[f.Name] {
+			t.Fatalf("entry %q mismatch: got %q, want %q", f.Name, data, want[f.Name])
+		}
+	}
+
+	secret, err := st.Get(t.Context(), created.ID)
+	if err != nil {
+		t.Fatalf("secret gone after one of two views: %v", err)
+	}
+	if secret.CurrentViews != 1 {
+		t.Fatalf("download used %d views, want 1", secret.CurrentViews)
+	}
+}
+
+func TestCreateArchiveLimits(t *testing.T) {
+	cfg := config.Default()
+	cfg.Secrets.MaxArchiveBytes = 8
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	h := NewHandler(st, cfg)
+
+	cases := map[string]string{
+		"too large":  `{"files": [{"name": "a", "content": "12345"}, {"name": "b", "content": "6789"}]}`,
+		"path":       `{"files": [{"name": "../etc/passwd", "content": "x"}]}`,
+		"duplicate":  `{"files": [{"name": "a", "content": "x"}, {"name": "a", "content": "y"}]}`,
+		"both":       `{"content": "x", "files": [{"name": "a", "content": "y"}]}`,
+		"empty name": `{"files": [{"name": "", "content": "x"}]}`,
+	}
+	for name, body := range cases {
+		rec := httptest.NewRecorder()
+		h.CreateSecret(rec, httptest.NewRequest(http.MethodPost, "/api/secrets", strings.NewReader(body)))
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("%s: got %d, want %d", name, rec.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+// deadlineRecorder records the write deadlines set through an
+// http.ResponseController.
+type deadlineRecorder struct {
+	*httptest.ResponseRecorder
+	deadlines []time.Time
+}
+
+func (d *deadlineRecorder) SetWriteDeadline(deadline time.Time) error {
+	d.deadlines = append(d.deadlines, deadline)
+	return nil
+}
+
+func TestDownloadArchiveThrottled(t *testing.T) {
+	cfg := config.Default()
+	cfg.Secrets.DownloadRate = 20000
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	router := SetupRouter(st, cfg)
+
+	// Random content, so compression can't shrink the download much.
+	raw := make([]byte, 30000)
+	rand.Read(raw)
+	created, passphrase := createSecret(t, router, `{"files": [{"name": "blob", "content": "`+base64.StdEncoding.EncodeToString(raw)+`"}]}`)
+
+	start := time.Now()
+	rec := &deadlineRecorder{ResponseRecorder: httptest.NewRecorder()}
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/secrets/"+created.ID+"/archive?passphrase="+url.QueryEscape(passphrase), nil))
+	elapsed := time.Since(start)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("download failed: got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// The first second's worth goes out at once; the rest at the rate.
+	size := rec.Body.Len()
+	want := time.Duration(float64(size-cfg.Secrets.DownloadRate) / float64(cfg.Secrets.DownloadRate) * float64(time.Second))
+	if want < 200*time.Millisecond {
+		t.Fatalf("download of %d bytes too small to measure throttling", size)
+	}
+	if elapsed < want {
+		t.Fatalf("download of %d bytes took %s, want at least %s", size, elapsed, want)
+	}
+
+	// Every chunk moves the write deadline on, so the server's WriteTimeout
+	// doesn't cut the download short.
+	if len(rec.deadlines) < 2 {
+		t.Fatalf("write deadline set %d times, want once per chunk", len(rec.deadlines))
+	}
+	if last := rec.deadlines[len(rec.deadlines)-1]; !last.After(rec.deadlines[0]) {
+		t.Fatalf("write deadline never moved past %s", rec.deadlines[0])
+	}
+}
+
+func TestDownloadArchiveRevalidate(t *testing.T) {
+	cfg := config.Default()
+	cfg.Secrets.RevalidateTTL = time.Minute
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	router := SetupRouter(st, cfg)
+
+	created, passphrase := createSecret(t, router, `{"max_views": 2, "files": [{"name": "a.txt", "content": "alpha"}]}`)
+	path := "/api/secrets/" + created.ID + "/archive?passphrase=" + url.QueryEscape(passphrase)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("download failed: got %d: %s", rec.Code, rec.Body.String())
+	}
+	etag, token := rec.Header().Get("ETag"), rec.Header().Get(reservationHeader)
+	if etag == "" || token == "" {
+		t.Fatalf("missing ETag or reservation token: %v", rec.Header())
+	}
+
+	refetch := func(etag, token string) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("If-None-Match", etag)
+		req.Header.Set(reservationHeader, token)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	for i := 0; i < 2; i++ {
+		if code := refetch(etag, token); code != http.StatusNotModified {
+			t.Fatalf("revalidation %d: got %d, want %d", i, code, http.StatusNotModified)
+		}
+	}
+	secret, err := st.Get(t.Context(), created.ID)
+	if err != nil || secret.CurrentViews != 1 {
+		t.Fatalf("revalidation used a view: %+v, %v", secret, err)
+	}
+
+	// Without the token the ETag alone is just a normal download.
+	if code := refetch(etag, "wrong"); code != http.StatusOK {
+		t.Fatalf("unreserved refetch: got %d, want %d", code, http.StatusOK)
+	}
+}
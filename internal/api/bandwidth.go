@@ -0,0 +1,87 @@
+package api
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// chunkWriteTimeout is how long each throttled chunk may take to write. A
+// chunk is at most a second's worth at the limited rate, so this is as
+// lenient as the server's own WriteTimeout, which a paced download as a
+// whole may well outlast.
+const chunkWriteTimeout = 15 * time.Second
+
+// bandwidthLimiter is a token bucket over bytes, refilled at rate per second
+// up to one second's worth. One limiter can be shared by many writers.
+type bandwidthLimiter struct {
+	mu      sync.Mutex
+	rate    float64
+	tokens  float64
+	updated time.Time
+}
+
+func newBandwidthLimiter(bytesPerSec int) *bandwidthLimiter {
+	return &bandwidthLimiter{
+		rate:    float64(bytesPerSec),
+		tokens:  float64(bytesPerSec),
+		updated: time.Now(),
+	}
+}
+
+// take reserves n bytes (n must not exceed the rate) and returns how long
+// to wait before sending them. Reservations may run the bucket negative, so
+// concurrent writers queue up fairly instead of all waking at once.
+func (l *bandwidthLimiter) take(n int) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.tokens = min(l.rate, l.tokens+now.Sub(l.updated).Seconds()*l.rate)
+	l.updated = now
+	l.tokens -= float64(n)
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+// throttledWriter paces writes to w through limiter, in chunks of at most
+// one second's worth, and stops early if ctx is done. With rc set, each
+// chunk gets a fresh write deadline of chunkWriteTimeout.
+type throttledWriter struct {
+	ctx     context.Context
+	w       io.Writer
+	rc      *http.ResponseController
+	limiter *bandwidthLimiter
+}
+
+func (t *throttledWriter) Write(p []byte) (int, error) {
+	written := 0
+	chunk := int(t.limiter.rate)
+	for len(p) > 0 {
+		n := min(len(p), chunk)
+		if wait := t.limiter.take(n); wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-timer.C:
+			case <-t.ctx.Done():
+				timer.Stop()
+				return written, t.ctx.Err()
+			}
+		}
+		if t.rc != nil {
+			// Writers that can't take deadlines have no timeout to extend.
+			_ = t.rc.SetWriteDeadline(time.Now().Add(chunkWriteTimeout))
+		}
+		m, err := t.w.Write(p[:n])
+		written += m
+		if err != nil {
+			return written, err
+		}
+		p = p[n:]
+	}
+	return written, nil
+}
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+
+	"secure.share/internal/models"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// canBurn reports whether a recipient can burn secret: its key has to be
+// checkable by comparison, as a generated passphrase's hash or a
+// client-encrypted secret's auth hash are. Chosen passphrases are only
+// checked by decrypting, which a burn has no reason to do. Neither are
+// secrets migrated by store.DropPassphrases, which kept no hash, so those
+// can't be burned by their recipients.
+func canBurn(secret *models.Secret) bool {
+	if secret.ClientEncrypted {
+		return len(secret.AuthHash) > 0
+	}
+	return hasPassphraseCheck(secret)
+}
+
+// BurnSecret deletes a secret for its recipient, who proves they hold it
+// with the key from the link rather than the owner token. Like a reveal it
+// shares the per-secret throttle, since each try is a guess at the key.
+func (h *Handler) BurnSecret(w http.ResponseWriter, r *http.Request) {
+	secret, err := h.lookup(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		h.handleStoreError(w, r, err)
+		return
+	}
+	if !canBurn(secret) {
+		h.error(w, r, http.StatusBadRequest, "secret can't be burned by its recipient")
+		return
+	}
+	if !secret.ClientEncrypted && !hasKeyMaterial(r) {
+		h.error(w, r, http.StatusBadRequest, "passphrase is required")
+		return
+	}
+
+	if !h.allowReveal(w, r, secret.ID) {
+		return
+	}
+	if _, ok := h.resolvePassphrase(w, r, secret); !ok {
+		return
+	}
+
+	if err := h.store.Delete(r.Context(), secret.ID); err != nil {
+		h.handleStoreError(w, r, err)
+		return
+	}
+	if h.config.Secrets.TombstoneTTL > 0 {
+		if err := h.store.SaveTombstone(r.Context(), secret.ID, h.config.Secrets.TombstoneTTL); err != nil {
+			slog.WarnContext(r.Context(), "failed to save tombstone", "error", err)
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"secure.share/config"
+	"secure.share/internal/crypto"
+	"secure.share/internal/store"
+)
+
+func burnSecret(router http.Handler, id, query string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/secrets/"+id+"/burn?"+query, strings.NewReader("{}"))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestBurnSecret(t *testing.T) {
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	router := SetupRouter(st, config.Default())
+
+	created, passphrase := createSecret(t, router, `{"content": "s3cret", "max_views": 3}`)
+
+	rec := revealSecret(router, created.ID, passphrase)
+	var resp RevealResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode reveal response: %v", err)
+	}
+	if !resp.CanBurn {
+		t.Fatalf("reveal with views left doesn't offer a burn: %+v", resp)
+	}
+
+	if rec := burnSecret(router, created.ID, ""); rec.Code != http.StatusBadRequest {
+		t.Fatalf("burn without passphrase: got %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	// A wrong passphrase still has to be well formed to reach the comparison.
+	wrong := crypto.GeneratePassphrase()
+	if rec := burnSecret(router, created.ID, "passphrase="+url.QueryEscape(wrong)); rec.Code != http.StatusForbidden {
+		t.Fatalf("burn with wrong passphrase: got %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	if _, err := st.Get(t.Context(), created.ID); err != nil {
+		t.Fatalf("secret gone after rejected burn: %v", err)
+	}
+
+	if rec := burnSecret(router, created.ID, "passphrase="+url.QueryEscape(passphrase)); rec.Code != http.StatusNoContent {
+		t.Fatalf("burn failed: got %d: %s", rec.Code, rec.Body.String())
+	}
+	if _, err := st.Get(t.Context(), created.ID); !errors.Is(err, store.ErrNotFound) {
+		t.Fatalf("secret still there after burn: %v", err)
+	}
+	if rec := revealSecret(router, created.ID, passphrase); rec.Code == http.StatusOK {
+		t.Fatal("burned secret revealed")
+	}
+}
+
+func TestBurnNotOfferedOnLastView(t *testing.T) {
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	router := SetupRouter(st, config.Default())
+
+	created, passphrase := createSecret(t, router, `{"content": "s3cret"}`)
+	var resp RevealResponse
+	if err := json.NewDecoder(revealSecret(router, created.ID, passphrase).Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode reveal response: %v", err)
+	}
+	if !resp.Burned || resp.CanBurn {
+		t.Fatalf("last view offers a burn: %+v", resp)
+	}
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"maps"
+	"net/http"
+	"slices"
+	"time"
+
+	"secure.share/config"
+)
+
+type CapabilitiesResponse struct {
+	Crypto   CryptoCapabilities  `json:"crypto"`
+	Limits   LimitCapabilities   `json:"limits"`
+	Features FeatureCapabilities `json:"features"`
+}
+
+type CryptoCapabilities struct {
+	Cipher string `json:"cipher"`
+	KDF    string `json:"kdf"`
+	// Profiles are the names accepted in the X-Crypto-Profile header.
+	Profiles []string `json:"profiles,omitempty"`
+}
+
+type LimitCapabilities struct {
+	MinTTLMinutes     int `json:"min_ttl_minutes"`
+	DefaultTTLMinutes int `json:"default_ttl_minutes"`
+	MaxTTLMinutes     int `json:"max_ttl_minutes"`
+	DefaultViews      int `json:"default_views"`
+	MaxViews          int `json:"max_views"`
+	MaxLinks          int `json:"max_links"`
+}
+
+type FeatureCapabilities struct {
+	RateLimit       bool `json:"rate_limit"`
+	ContentFilter   bool `json:"content_filter"`
+	FileUploads     bool `json:"file_uploads"`
+	UserPassphrases bool `json:"user_passphrases"`
+	KeySplitting    bool `json:"key_splitting"`
+	PIN             bool `json:"pin"`
+	Archives        bool `json:"archives"`
+	IntegrityMAC    bool `json:"integrity_mac"`
+	RevealCodes     bool `json:"reveal_codes"`
+	GeoRestrictions bool `json:"geo_restrictions"`
+	RawReveal       bool `json:"raw_reveal"`
+	APIKeys         bool `json:"api_keys"`
+	SignedLinks     bool `json:"signed_links"`
+	// ClientEncryption accepts content encrypted before it is sent.
+	ClientEncryption bool `json:"client_encryption"`
+	// Renderers are the content types /render formats.
+	Renderers []string `json:"renderers,omitempty"`
+}
+
+// buildCapabilities derives what clients may rely on from config. Only
+// limits and feature switches are reported, never keys or addresses.
+func buildCapabilities(cfg *config.Config) CapabilitiesResponse {
+	return CapabilitiesResponse{
+		Crypto: CryptoCapabilities{
+			Cipher:   "AES-256-GCM",
+			KDF:      "Argon2id",
+			Profiles: slices.Sorted(maps.Keys(cfg.Crypto.Profiles)),
+		},
+		Limits: LimitCapabilities{
+			MinTTLMinutes:     1,
+			DefaultTTLMinutes: int(cfg.Secrets.DefaultTTL / time.Minute),
+			MaxTTLMinutes:     int(cfg.Secrets.MaxTTL / time.Minute),
+			DefaultViews:      cfg.Secrets.DefaultViews,
+			MaxViews:          cfg.Secrets.MaxViews,
+			MaxLinks:          cfg.Secrets.MaxLinks,
+		},
+		Features: FeatureCapabilities{
+			RateLimit:       cfg.RateLimit.Enabled,
+			ContentFilter:   len(cfg.Secrets.BlockedPatterns) > 0,
+			FileUploads:     cfg.Secrets.MaxFileBytes > 0,
+			UserPassphrases: cfg.Secrets.AllowUserPassphrase,
+			KeySplitting:    true,
+			PIN:             cfg.Secrets.AllowPIN,
+			Archives:        cfg.Secrets.MaxArchiveBytes > 0,
+			IntegrityMAC:    cfg.Secrets.IntegrityMAC,
+			RevealCodes:     cfg.Hooks.CodeCommand != "",
+			GeoRestrictions: cfg.Geo.DatabasePath != "",
+			RawReveal:       cfg.Secrets.StreamReveal,
+			APIKeys:         cfg.APIKeys.Enabled,
+			SignedLinks:     cfg.SignedLinks.Key != "",
+			Renderers:       cfg.Secrets.Renderers,
+
+			ClientEncryption: cfg.Secrets.ClientEncryption,
+		},
+	}
+}
+
+func (h *Handler) Capabilities(w http.ResponseWriter, r *http.Request) {
+	// Config is fixed for the process lifetime, so the response is built
+	// once in NewHandler and clients may cache it too.
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	h.json(w, http.StatusOK, h.capabilities)
+}
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"secure.share/config"
+	"secure.share/internal/store"
+)
+
+func getCapabilities(t *testing.T, cfg *config.Config) CapabilitiesResponse {
+	t.Helper()
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+
+	rec := httptest.NewRecorder()
+	SetupRouter(st, cfg).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/capabilities", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status mismatch: got %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	var caps CapabilitiesResponse
+	if err := json.NewDecoder(rec.Body).Decode(&caps); err != nil {
+		t.Fatalf("failed to decode capabilities: %v", err)
+	}
+	return caps
+}
+
+func TestCapabilities(t *testing.T) {
+	cfg := config.Default()
+	caps := getCapabilities(t, cfg)
+	if !caps.Features.RateLimit {
+		t.Fatalf("rate limit should be reported as enabled")
+	}
+	if caps.Limits.MaxViews != cfg.Secrets.MaxViews || caps.Limits.MaxTTLMinutes != 24*60 {
+		t.Fatalf("limits mismatch: got %+v", caps.Limits)
+	}
+
+	cfg = config.Default()
+	cfg.RateLimit.Enabled = false
+	cfg.Secrets.BlockedPatterns = []string{"forbidden"}
+	caps = getCapabilities(t, cfg)
+	if caps.Features.RateLimit {
+		t.Fatalf("rate limit should be reported as disabled")
+	}
+	if !caps.Features.ContentFilter {
+		t.Fatalf("content filter should be reported as enabled")
+	}
+}
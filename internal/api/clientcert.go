@@ -0,0 +1,40 @@
+package api
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+)
+
+// ClientCATLSConfig verifies client certificates against the CAs in
+// caFile. Clients that offer none still connect; only admin routes look
+// at the certificate.
+func ClientCATLSConfig(caFile string) (*tls.Config, error) {
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("%s: no certificates found", caFile)
+	}
+	return &tls.Config{
+		ClientCAs:  pool,
+		ClientAuth: tls.VerifyClientCertIfGiven,
+		MinVersion: tls.VersionTLS12,
+	}, nil
+}
+
+// clientSubjectAllowed reports whether the request's client certificate
+// was verified and its subject common name or distinguished name is in
+// subjects.
+func clientSubjectAllowed(r *http.Request, subjects []string) bool {
+	if len(subjects) == 0 || r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+		return false
+	}
+	subject := r.TLS.VerifiedChains[0][0].Subject
+	return slices.Contains(subjects, subject.CommonName) || slices.Contains(subjects, subject.String())
+}
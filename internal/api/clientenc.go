@@ -0,0 +1,47 @@
+package api
+
+import (
+	"encoding/base64"
+	"fmt"
+)
+
+// maxAuthHashLength bounds the auth value a client-encrypted secret's
+// creator sends; a base64 SHA-512 fits.
+const maxAuthHashLength = 128
+
+// clientCiphertext checks a create request for a client-encrypted secret and
+// decodes its ciphertext. reason is non-empty if the request can't be
+// accepted. Options that need the server to read or re-encrypt the content
+// can't be combined with it.
+func (h *Handler) clientCiphertext(req *CreateRequest) (ciphertext []byte, reason string) {
+	if !h.config.Secrets.ClientEncryption {
+		return nil, "client-side encryption is not enabled"
+	}
+
+	for _, opt := range []struct {
+		name string
+		set  bool
+	}{
+		{"files", len(req.Files) > 0},
+		{"note", req.Note != ""},
+		{"code_contact", req.CodeContact != ""},
+		{"pin", req.PIN != ""},
+		{"shares", req.Shares > 0},
+		{"integrity", req.Integrity},
+		{"signed_links", req.SignedLinks},
+	} {
+		if opt.set {
+			return nil, fmt.Sprintf("client_encrypted and %s cannot both be set", opt.name)
+		}
+	}
+
+	if len(req.AuthHash) > maxAuthHashLength {
+		return nil, fmt.Sprintf("auth_hash must be at most %d bytes", maxAuthHashLength)
+	}
+
+	ciphertext, err := base64.StdEncoding.DecodeString(req.Content)
+	if err != nil || len(ciphertext) == 0 {
+		return nil, "content must be base64-encoded ciphertext"
+	}
+	return ciphertext, ""
+}
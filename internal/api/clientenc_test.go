@@ -0,0 +1,119 @@
+package api
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"secure.share/config"
+	"secure.share/internal/store"
+)
+
+func TestClientEncryptedStoredAsSent(t *testing.T) {
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	router := SetupRouter(st, config.Default())
+
+	ciphertext := []byte("\x00\x01opaque ciphertext\xff")
+	encoded := base64.StdEncoding.EncodeToString(ciphertext)
+	created, fragment := createSecret(t, router, `{"content":"`+encoded+`","max_views":2,"client_encrypted":true}`)
+	if fragment != "" {
+		t.Fatalf("server put a key in the URL: %s", created.URL)
+	}
+
+	stored, err := st.Get(t.Context(), created.ID)
+	if err != nil {
+		t.Fatalf("get failed: %v", err)
+	}
+	if !bytes.Equal(stored.EncryptedData, ciphertext) || stored.Passphrase != "" || !stored.ClientEncrypted {
+		t.Fatalf("stored %+v, want the ciphertext as sent and no passphrase", stored)
+	}
+
+	if status := statusOf(t, router, created.ID); !status.ClientEncrypted || status.ViewsRemaining != 2 {
+		t.Fatalf("status = %+v", status)
+	}
+
+	for i, wantBurned := range []bool{false, true} {
+		rec := getPath(router, "/api/secrets/"+created.ID)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("reveal %d failed: got %d: %s", i, rec.Code, rec.Body.String())
+		}
+		var resp RevealResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode reveal: %v", err)
+		}
+		if resp.Content != encoded || !resp.ClientEncrypted || resp.Burned != wantBurned {
+			t.Fatalf("reveal %d = %+v", i, resp)
+		}
+	}
+	if rec := getPath(router, "/api/secrets/"+created.ID); rec.Code != http.StatusNotFound {
+		t.Fatalf("reveal after last view: got %d, want 404", rec.Code)
+	}
+}
+
+func TestClientEncryptedAuth(t *testing.T) {
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	router := SetupRouter(st, config.Default())
+
+	encoded := base64.StdEncoding.EncodeToString([]byte("ciphertext"))
+	created, _ := createSecret(t, router, `{"content":"`+encoded+`","client_encrypted":true,"auth_hash":"a1b2c3"}`)
+
+	for _, path := range []string{
+		"/api/secrets/" + created.ID,
+		"/api/secrets/" + created.ID + "?auth=wrong",
+	} {
+		if rec := getPath(router, path); rec.Code != http.StatusForbidden {
+			t.Fatalf("%s: got %d, want 403", path, rec.Code)
+		}
+	}
+	if status := statusOf(t, router, created.ID); status.ViewsRemaining != 1 {
+		t.Fatalf("failed auth used a view: %+v", status)
+	}
+
+	if rec := getPath(router, "/api/secrets/"+created.ID+"?auth=a1b2c3"); rec.Code != http.StatusOK {
+		t.Fatalf("reveal with auth failed: got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestClientEncryptedRejects(t *testing.T) {
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	router := SetupRouter(st, config.Default())
+
+	encoded := base64.StdEncoding.EncodeToString([]byte("ciphertext"))
+	for _, body := range []string{
+		`{"content":"not base64!","client_encrypted":true}`,
+		`{"content":"` + encoded + `","client_encrypted":true,"note":"hi"}`,
+		`{"content":"` + encoded + `","client_encrypted":true,"pin":"1234"}`,
+		`{"content":"` + encoded + `","client_encrypted":true,"shares":3,"threshold":2}`,
+		`{"content":"` + encoded + `","client_encrypted":true,"auth_hash":"` + strings.Repeat("a", maxAuthHashLength+1) + `"}`,
+		`{"content":"plain","auth_hash":"a1b2c3"}`,
+	} {
+		if rec := postJSON(router, "/api/secrets", body); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: got %d, want 400", body, rec.Code)
+		}
+	}
+
+	cfg := config.Default()
+	cfg.Secrets.ClientEncryption = false
+	disabled := SetupRouter(st, cfg)
+	if rec := postJSON(disabled, "/api/secrets", `{"content":"`+encoded+`","client_encrypted":true}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("disabled: got %d, want 400", rec.Code)
+	}
+}
+
+func TestServerEncryptedStillNeedsPassphrase(t *testing.T) {
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	router := SetupRouter(st, config.Default())
+
+	created, _ := createSecret(t, router, `{"content":"s3cret"}`)
+	if rec := getPath(router, "/api/secrets/"+created.ID); rec.Code != http.StatusBadRequest {
+		t.Fatalf("reveal without passphrase: got %d, want 400", rec.Code)
+	}
+}
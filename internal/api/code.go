@@ -0,0 +1,127 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+	"unicode"
+
+	"secure.share/internal/models"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const maxContactLength = 254
+
+type RequestCodeResponse struct {
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// validContact accepts a short single-line address; what it addresses is
+// up to the configured code command.
+func validContact(contact string) bool {
+	if len(contact) > maxContactLength {
+		return false
+	}
+	for _, r := range contact {
+		if unicode.IsControl(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// RequestCode sends a one-time reveal code to the contact the sender named.
+// The key is required, as for a reveal, because the contact is encrypted
+// with it. No view is used.
+func (h *Handler) RequestCode(w http.ResponseWriter, r *http.Request) {
+	if h.codeSender == nil {
+		h.error(w, r, http.StatusNotFound, "reveal codes are not enabled")
+		return
+	}
+	if !hasKeyMaterial(r) {
+		h.error(w, r, http.StatusBadRequest, "passphrase is required")
+		return
+	}
+
+	secret, err := h.lookup(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		h.handleStoreError(w, r, err)
+		return
+	}
+	if len(secret.EncryptedContact) == 0 {
+		h.error(w, r, http.StatusBadRequest, "secret does not require a code")
+		return
+	}
+
+	// Each request sends a message, so it shares the reveal throttle.
+	if !h.allowReveal(w, r, secret.ID) {
+		return
+	}
+
+	passphrase, ok := h.resolvePassphrase(w, r, secret)
+	if !ok {
+		return
+	}
+	contact, err := h.cryptoOps.DecryptWithContext(r.Context(), secret.EncryptedContact, passphrase, []byte(secret.Context))
+	if err != nil {
+		if secret.Threshold > 0 {
+			h.cryptoError(w, r, err, http.StatusForbidden, "invalid shares")
+		} else {
+			h.cryptoError(w, r, err, http.StatusInternalServerError, "decryption failed")
+		}
+		return
+	}
+
+	window := h.codeWindow(time.Now())
+	if err := h.codeSender.SendCode(r.Context(), string(contact), revealCode(secret, window)); err != nil {
+		slog.ErrorContext(r.Context(), "failed to send reveal code", "error", err)
+		h.error(w, r, http.StatusBadGateway, "failed to send code")
+		return
+	}
+
+	ttl := h.config.Secrets.RevealCodeTTL
+	h.json(w, http.StatusAccepted, RequestCodeResponse{
+		ExpiresAt: time.Unix((window+2)*int64(ttl/time.Second), 0),
+	})
+}
+
+// codeWindow numbers the RevealCodeTTL-long slot t falls in. A code is
+// accepted in its own slot and the next, so it lives between one and two
+// TTLs.
+func (h *Handler) codeWindow(t time.Time) int64 {
+	return t.Unix() / int64(h.config.Secrets.RevealCodeTTL/time.Second)
+}
+
+// checkRevealCode verifies the code query parameter of a reveal. Wrong
+// codes count against the same attempt limit as wrong PINs.
+func (h *Handler) checkRevealCode(w http.ResponseWriter, r *http.Request, secret *models.Secret) bool {
+	code := r.URL.Query().Get("code")
+	if code == "" {
+		h.error(w, r, http.StatusBadRequest, "code is required")
+		return false
+	}
+
+	window := h.codeWindow(time.Now())
+	if hmac.Equal([]byte(code), []byte(revealCode(secret, window))) ||
+		hmac.Equal([]byte(code), []byte(revealCode(secret, window-1))) {
+		return true
+	}
+	h.attemptFailure(w, r, secret, "code")
+	return false
+}
+
+// revealCode is a six-digit code for window, derived like the ack token
+// from an HMAC keyed by the stored ciphertext, so every instance can check
+// it without storing it.
+func revealCode(secret *models.Secret, window int64) string {
+	mac := hmac.New(sha256.New, secret.EncryptedData)
+	mac.Write([]byte("code:" + secret.ID + ":" + strconv.FormatInt(window, 10)))
+	n := binary.BigEndian.Uint32(mac.Sum(nil)) % 1_000_000
+	return fmt.Sprintf("%06d", n)
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"secure.share/config"
+	"secure.share/internal/store"
+)
+
+// stubCodeSender records the last code sent instead of delivering it.
+type stubCodeSender struct {
+	contact, code string
+}
+
+func (s *stubCodeSender) SendCode(ctx context.Context, contact, code string) error {
+	s.contact, s.code = contact, code
+	return nil
+}
+
+func newCodeRouter(t *testing.T, cfg *config.Config) (http.Handler, *stubCodeSender) {
+	t.Helper()
+	st := store.NewMemoryStore(time.Minute)
+	t.Cleanup(func() { st.Close() })
+	h := NewHandler(st, cfg)
+	sender := &stubCodeSender{}
+	h.codeSender = sender
+	return newRouter(h, cfg), sender
+}
+
+func revealWithCode(router http.Handler, id, passphrase, code string) int {
+	rec := httptest.NewRecorder()
+	path := "/api/secrets/" + id + "?passphrase=" + url.QueryEscape(passphrase) + "&code=" + url.QueryEscape(code)
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	return rec.Code
+}
+
+func TestRevealCode(t *testing.T) {
+	router, sender := newCodeRouter(t, config.Default())
+
+	created, passphrase := createSecret(t, router, `{"content": "s3cret", "code_contact": "bob@example.com"}`)
+
+	if rec := revealSecret(router, created.ID, passphrase); rec.Code != http.StatusBadRequest {
+		t.Fatalf("reveal without code: got %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+
+	rec := postJSON(router, "/api/secrets/"+created.ID+"/request-code?passphrase="+url.QueryEscape(passphrase), `{}`)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("request-code failed: got %d: %s", rec.Code, rec.Body.String())
+	}
+	if sender.contact != "bob@example.com" || len(sender.code) != 6 {
+		t.Fatalf("code not sent to the contact: %+v", sender)
+	}
+
+	wrong := "000000"
+	if sender.code == wrong {
+		wrong = "111111"
+	}
+	if code := revealWithCode(router, created.ID, passphrase, wrong); code != http.StatusForbidden {
+		t.Fatalf("reveal with wrong code: got %d, want %d", code, http.StatusForbidden)
+	}
+	if code := revealWithCode(router, created.ID, passphrase, sender.code); code != http.StatusOK {
+		t.Fatalf("reveal with sent code: got %d, want %d", code, http.StatusOK)
+	}
+}
+
+func TestRevealCodeAttemptLimit(t *testing.T) {
+	cfg := config.Default()
+	cfg.Secrets.MaxPINAttempts = 2
+	router, _ := newCodeRouter(t, cfg)
+
+	created, passphrase := createSecret(t, router, `{"content": "s3cret", "code_contact": "bob@example.com"}`)
+	if code := revealWithCode(router, created.ID, passphrase, "abc"); code != http.StatusForbidden {
+		t.Fatalf("first wrong code: got %d, want %d", code, http.StatusForbidden)
+	}
+	if code := revealWithCode(router, created.ID, passphrase, "abc"); code != http.StatusGone {
+		t.Fatalf("last wrong code: got %d, want %d", code, http.StatusGone)
+	}
+	if code := revealWithCode(router, created.ID, passphrase, "abc"); code != http.StatusNotFound {
+		t.Fatalf("reveal after burn: got %d, want %d", code, http.StatusNotFound)
+	}
+}
+
+func TestRevealCodeDisabled(t *testing.T) {
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	router := SetupRouter(st, config.Default())
+
+	if rec := postJSON(router, "/api/secrets", `{"content": "s3cret", "code_contact": "bob@example.com"}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("create with contact: got %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+var (
+	errJSONTooDeep     = errors.New("request body is nested too deeply")
+	errJSONTooManyKeys = errors.New("request body has too many fields")
+	errJSONInvalid     = errors.New("invalid request body")
+)
+
+// decode reads a JSON request body into v within the configured nesting and
+// field limits. On failure it writes a 400, or a 413 if the body ran past
+// JSONOnlyFor's limit, and returns false.
+func (h *Handler) decode(w http.ResponseWriter, r *http.Request, v any) bool {
+	err := decodeJSON(r.Body, v, h.config.Server.JSONMaxDepth, h.config.Server.JSONMaxFields)
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		writeErrorCode(w, r, http.StatusRequestEntityTooLarge, "payload_too_large", fmt.Sprintf("request body must be at most %d bytes", tooLarge.Limit))
+		return false
+	case err != nil:
+		h.error(w, r, http.StatusBadRequest, err.Error())
+		return false
+	}
+	return true
+}
+
+// decodeJSON first walks the body token by token, without building any
+// values, and gives up as soon as it nests deeper than maxDepth or holds
+// more than maxFields object keys and array elements. Only a body that
+// passes is decoded into v.
+func decodeJSON(body io.Reader, v any, maxDepth, maxFields int) error {
+	var buf bytes.Buffer
+	dec := json.NewDecoder(io.TeeReader(body, &buf))
+
+	type frame struct{ object, wantKey bool }
+	var stack []frame
+	// valueDone moves an enclosing object on to its next key.
+	valueDone := func() {
+		if n := len(stack); n > 0 && stack[n-1].object {
+			stack[n-1].wantKey = true
+		}
+	}
+
+	fields := 0
+	for {
+		tok, err := dec.Token()
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return err
+		}
+		if err != nil {
+			return errJSONInvalid
+		}
+
+		if d, ok := tok.(json.Delim); ok && (d == '}' || d == ']') {
+			stack = stack[:len(stack)-1]
+			valueDone()
+		} else {
+			if n := len(stack); n > 0 {
+				top := &stack[n-1]
+				isKey := top.object && top.wantKey
+				if isKey || !top.object {
+					if fields++; fields > maxFields {
+						return errJSONTooManyKeys
+					}
+				}
+				if isKey {
+					top.wantKey = false
+					continue
+				}
+			}
+			if d, ok := tok.(json.Delim); ok {
+				stack = append(stack, frame{object: d == '{', wantKey: d == '{'})
+				if len(stack) > maxDepth {
+					return errJSONTooDeep
+				}
+				continue
+			}
+			valueDone()
+		}
+
+		if len(stack) == 0 {
+			break
+		}
+	}
+
+	if err := json.NewDecoder(&buf).Decode(v); err != nil {
+		return errJSONInvalid
+	}
+	return nil
+}
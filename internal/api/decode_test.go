@@ -0,0 +1,79 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"secure.share/config"
+	"secure.share/internal/store"
+)
+
+// countingReader serves n bytes of '[' and records how many were read.
+type countingReader struct {
+	n, read int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	if c.read >= c.n {
+		return 0, io.EOF
+	}
+	k := min(len(p), c.n-c.read)
+	for i := range p[:k] {
+		p[i] = '['
+	}
+	c.read += k
+	return k, nil
+}
+
+func TestDecodeJSONRejectsDeepNestingEarly(t *testing.T) {
+	body := &countingReader{n: 64 << 20}
+	var v any
+	if err := decodeJSON(body, &v, 32, 1024); !errors.Is(err, errJSONTooDeep) {
+		t.Fatalf("expected errJSONTooDeep, got %v", err)
+	}
+	if body.read > 1<<20 {
+		t.Fatalf("read %d bytes before rejecting, expected to stop early", body.read)
+	}
+}
+
+func TestDecodeJSONLimits(t *testing.T) {
+	var v map[string]any
+	cases := []struct {
+		body string
+		want error
+	}{
+		{`{"a": {"b": {"c": 1}}}`, nil},
+		{`{"a": {"b": {"c": {"d": 1}}}}`, errJSONTooDeep},
+		{`{"a": [1, 2, 3], "b": 4}`, nil},
+		{`{"a": [1, 2, 3, 4, 5], "b": 6}`, errJSONTooManyKeys},
+		{`{"a": 1`, errJSONInvalid},
+	}
+	for _, c := range cases {
+		if err := decodeJSON(strings.NewReader(c.body), &v, 3, 6); !errors.Is(err, c.want) {
+			t.Fatalf("%s: got %v, want %v", c.body, err, c.want)
+		}
+	}
+}
+
+func TestCreateSecretRejectsNestedJSON(t *testing.T) {
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	router := SetupRouter(st, config.Default())
+
+	body := `{"content": "x", "files": ` + strings.Repeat("[", 10000) + strings.Repeat("]", 10000) + `}`
+	req := httptest.NewRequest(http.MethodPost, "/api/secrets", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status mismatch: got %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if !strings.Contains(rec.Body.String(), errJSONTooDeep.Error()) {
+		t.Fatalf("unexpected error body: %s", rec.Body.String())
+	}
+}
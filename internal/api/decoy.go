@@ -0,0 +1,81 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"time"
+
+	"secure.share/config"
+	"secure.share/internal/hooks"
+	"secure.share/internal/models"
+)
+
+// decoys recognizes honeypot IDs: links that look live but were planted to
+// catch whoever tries them. They never reach the store.
+type decoys struct {
+	ids      map[string]bool
+	patterns []*regexp.Regexp
+	// key makes the fake content stable per ID for this process without
+	// being computable from the ID alone.
+	key []byte
+}
+
+func newDecoys(cfg config.HoneypotConfig) decoys {
+	d := decoys{ids: make(map[string]bool, len(cfg.DecoyIDs)), key: make([]byte, 32)}
+	for _, id := range cfg.DecoyIDs {
+		d.ids[id] = true
+	}
+	for _, pattern := range cfg.DecoyPatterns {
+		d.patterns = append(d.patterns, regexp.MustCompile(pattern))
+	}
+	rand.Read(d.key)
+	return d
+}
+
+func (d decoys) match(id string) bool {
+	if d.ids[id] {
+		return true
+	}
+	for _, re := range d.patterns {
+		if re.MatchString(id) {
+			return true
+		}
+	}
+	return false
+}
+
+// content is a believable credential: the same shape as a generated
+// passphrase, derived from the ID so repeat visits see the same value.
+func (d decoys) content(id string) string {
+	mac := hmac.New(sha256.New, d.key)
+	mac.Write([]byte(id))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// secret is what a decoy looks like to status and reveal: live, one view
+// left, expiring well in the future.
+func (h *Handler) decoySecret(id string) *models.Secret {
+	return &models.Secret{
+		ID:        id,
+		MaxViews:  1,
+		ExpiresAt: time.Now().Add(h.config.Secrets.DefaultTTL),
+		CreatedAt: time.Now(),
+	}
+}
+
+// revealDecoy raises the alarm for a decoy reveal and hands back fake
+// content shaped like the real thing.
+func (h *Handler) revealDecoy(r *http.Request, id string) (*models.Secret, []byte, int, bool) {
+	slog.WarnContext(r.Context(), "decoy secret revealed",
+		"id", id,
+		"remote_addr", r.RemoteAddr,
+		"user_agent", r.UserAgent(),
+	)
+	h.emit(hooks.EventDecoy, id)
+	return h.decoySecret(id), []byte(h.decoys.content(id)), 1, true
+}
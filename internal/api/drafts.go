@@ -0,0 +1,165 @@
+package api
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"secure.share/internal/crypto"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// maxDrafts and maxDraftBytes bound the staging area so unauthenticated
+// draft creates can't grow it without limit.
+const (
+	maxDrafts     = 10000
+	maxDraftBytes = 64 << 20
+)
+
+type DraftResponse struct {
+	DraftToken string    `json:"draft_token"`
+	ID         string    `json:"id"`
+	URL        string    `json:"url"`
+	ShareURLs  []string  `json:"share_urls,omitempty"`
+	MaxViews   int       `json:"max_views"`
+	ExpiresAt  time.Time `json:"draft_expires_at"` // commit before this or the draft is gone
+}
+
+// drafts stages prepared secrets in process memory until they are committed
+// to the store or expire. A draft is only visible to the instance that
+// created it, so commits need the same sticky routing as the draft create.
+type drafts struct {
+	mu    sync.Mutex
+	ttl   time.Duration
+	items map[string]draft
+	bytes int
+	// order holds tokens oldest first. Every draft lives for the same ttl,
+	// so that is also expiry order; committed drafts linger here until
+	// pruned.
+	order []string
+}
+
+type draft struct {
+	prepared *preparedSecret
+	expires  time.Time
+	size     int
+}
+
+func newDrafts(ttl time.Duration) *drafts {
+	return &drafts{ttl: ttl, items: make(map[string]draft)}
+}
+
+// put stages p and returns the token that commits it, or false when the
+// staging area is full.
+func (d *drafts) put(p *preparedSecret) (string, time.Time, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	d.prune(now)
+	size := draftSize(p)
+	if len(d.items) >= maxDrafts || d.bytes+size > maxDraftBytes {
+		return "", time.Time{}, false
+	}
+
+	token := crypto.GenerateOwnerToken()
+	expires := now.Add(d.ttl)
+	d.items[token] = draft{prepared: p, expires: expires, size: size}
+	d.bytes += size
+	d.order = append(d.order, token)
+	return token, expires, true
+}
+
+// take removes and returns the draft for token, if it hasn't expired.
+func (d *drafts) take(token string) (*preparedSecret, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	item, ok := d.items[token]
+	if !ok || time.Now().After(item.expires) {
+		return nil, false
+	}
+	d.remove(token, item)
+	return item.prepared, true
+}
+
+// prune drops expired drafts from the front of order, stopping at the
+// first one still pending, so it only walks what it removes.
+func (d *drafts) prune(now time.Time) {
+	for len(d.order) > 0 {
+		token := d.order[0]
+		if item, ok := d.items[token]; ok {
+			if !now.After(item.expires) {
+				break
+			}
+			d.remove(token, item)
+		}
+		d.order[0] = ""
+		d.order = d.order[1:]
+	}
+}
+
+func (d *drafts) remove(token string, item draft) {
+	delete(d.items, token)
+	d.bytes -= item.size
+}
+
+// draftSize is what a staged secret holds in memory, near enough: its
+// ciphertexts, which dwarf everything else.
+func draftSize(p *preparedSecret) int {
+	s := p.secret
+	return len(s.EncryptedData) + len(s.EncryptedNote) + len(s.EncryptedContact)
+}
+
+// CreateDraft prepares a secret exactly like CreateSecret, including its
+// links, but only stages it. Nothing reaches the store until CommitDraft.
+func (h *Handler) CreateDraft(w http.ResponseWriter, r *http.Request) {
+	if h.drafts == nil {
+		h.error(w, r, http.StatusNotFound, "drafts are not enabled")
+		return
+	}
+
+	p, ok := h.prepareSecret(w, r)
+	if !ok {
+		return
+	}
+
+	token, expires, ok := h.drafts.put(p)
+	if !ok {
+		w.Header().Set("Retry-After", "60")
+		h.error(w, r, http.StatusServiceUnavailable, "too many pending drafts, try again later")
+		return
+	}
+
+	h.json(w, http.StatusCreated, DraftResponse{
+		DraftToken: token,
+		ID:         p.secret.ID,
+		URL:        p.url,
+		ShareURLs:  p.shareURLs,
+		MaxViews:   p.secret.MaxViews,
+		ExpiresAt:  expires,
+	})
+}
+
+// CommitDraft stores a staged secret. Its TTL starts now, not at draft
+// time, and the owner token and integrity MAC are only handed out here.
+func (h *Handler) CommitDraft(w http.ResponseWriter, r *http.Request) {
+	if h.drafts == nil {
+		h.error(w, r, http.StatusNotFound, "drafts are not enabled")
+		return
+	}
+
+	p, ok := h.drafts.take(chi.URLParam(r, "token"))
+	if !ok {
+		h.error(w, r, http.StatusNotFound, "draft not found or expired")
+		return
+	}
+
+	appliedTTL, ok := h.saveSecret(w, r, p)
+	if !ok {
+		return
+	}
+
+	h.json(w, http.StatusCreated, p.response(appliedTTL))
+}
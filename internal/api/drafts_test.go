@@ -0,0 +1,120 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"secure.share/config"
+	"secure.share/internal/models"
+	"secure.share/internal/store"
+)
+
+func postJSON(router http.Handler, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func createDraft(t *testing.T, router http.Handler, body string) DraftResponse {
+	t.Helper()
+	rec := postJSON(router, "/api/secrets/draft", body)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("draft create failed: got %d: %s", rec.Code, rec.Body.String())
+	}
+	var draft DraftResponse
+	if err := json.NewDecoder(rec.Body).Decode(&draft); err != nil {
+		t.Fatalf("failed to decode draft response: %v", err)
+	}
+	return draft
+}
+
+func TestDraftCommit(t *testing.T) {
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	cfg := config.Default()
+	cfg.Secrets.DraftTTL = 10 * time.Minute
+	router := SetupRouter(st, cfg)
+
+	draft := createDraft(t, router, `{"content": "s3cret"}`)
+	if _, err := st.Get(t.Context(), draft.ID); !errors.Is(err, store.ErrNotFound) {
+		t.Fatalf("uncommitted draft reached the store: %v", err)
+	}
+
+	rec := postJSON(router, "/api/secrets/draft/"+draft.DraftToken+"/commit", `{}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("commit failed: got %d: %s", rec.Code, rec.Body.String())
+	}
+	var created CreateResponse
+	if err := json.NewDecoder(rec.Body).Decode(&created); err != nil {
+		t.Fatalf("failed to decode commit response: %v", err)
+	}
+	if created.ID != draft.ID || created.URL != draft.URL {
+		t.Fatalf("committed secret differs from its draft: %+v vs %+v", created, draft)
+	}
+
+	passphrase := draft.URL[strings.Index(draft.URL, "#")+1:]
+	if rec := revealSecret(router, draft.ID, passphrase); rec.Code != http.StatusOK {
+		t.Fatalf("reveal of committed draft failed: got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// A draft commits once.
+	if rec := postJSON(router, "/api/secrets/draft/"+draft.DraftToken+"/commit", `{}`); rec.Code != http.StatusNotFound {
+		t.Fatalf("second commit: got %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestDraftExpires(t *testing.T) {
+	cfg := config.Default()
+	cfg.Secrets.DraftTTL = 20 * time.Millisecond
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	router := SetupRouter(st, cfg)
+
+	draft := createDraft(t, router, `{"content": "s3cret"}`)
+	time.Sleep(50 * time.Millisecond)
+
+	if rec := postJSON(router, "/api/secrets/draft/"+draft.DraftToken+"/commit", `{}`); rec.Code != http.StatusNotFound {
+		t.Fatalf("commit of expired draft: got %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	if _, err := st.Get(t.Context(), draft.ID); !errors.Is(err, store.ErrNotFound) {
+		t.Fatalf("expired draft reached the store: %v", err)
+	}
+}
+
+func TestDraftsDisabledByDefault(t *testing.T) {
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	router := SetupRouter(st, config.Default())
+
+	if rec := postJSON(router, "/api/secrets/draft", `{"content": "s3cret"}`); rec.Code != http.StatusNotFound {
+		t.Fatalf("draft create with drafts disabled: got %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestDraftsByteLimit(t *testing.T) {
+	d := newDrafts(time.Minute)
+	big := func() *preparedSecret {
+		return &preparedSecret{secret: &models.Secret{EncryptedData: make([]byte, maxDraftBytes/2+1)}}
+	}
+
+	token, _, ok := d.put(big())
+	if !ok {
+		t.Fatal("first draft refused")
+	}
+	if _, _, ok := d.put(big()); ok {
+		t.Fatal("draft past maxDraftBytes accepted")
+	}
+	if _, ok := d.take(token); !ok {
+		t.Fatal("take failed")
+	}
+	if _, _, ok := d.put(big()); !ok {
+		t.Fatal("committing a draft didn't free its bytes")
+	}
+}
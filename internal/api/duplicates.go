@@ -0,0 +1,45 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"time"
+)
+
+// maxRecentContent bounds how many content hashes are remembered.
+const maxRecentContent = 100000
+
+// recentContent remembers what each client recently shared, so a bot
+// re-posting one payload can be turned away. Only an HMAC of the client and
+// content under a per-process salt is kept, never the content itself.
+type recentContent struct {
+	*ttlSet
+	salt []byte
+}
+
+func newRecentContent(window time.Duration) *recentContent {
+	salt := make([]byte, 32)
+	rand.Read(salt)
+	return &recentContent{ttlSet: newTTLSet(window, maxRecentContent), salt: salt}
+}
+
+// key hashes content as shared by client.
+func (c *recentContent) key(client string, content []byte) string {
+	mac := hmac.New(sha256.New, c.salt)
+	mac.Write([]byte(client))
+	mac.Write([]byte{0})
+	mac.Write(content)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// clientKey identifies who a request is from: its API key if it has one,
+// otherwise its IP.
+func clientKey(r *http.Request) string {
+	if key, ok := apiKeyFrom(r); ok {
+		return "apikey:" + key.ID
+	}
+	return getClientIP(r)
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"secure.share/config"
+	"secure.share/internal/store"
+)
+
+func TestDuplicateContentRejected(t *testing.T) {
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	cfg := config.Default()
+	cfg.Secrets.DuplicateWindow = time.Minute
+	h := NewHandler(st, cfg)
+	router := newRouter(h, cfg)
+
+	createSecret(t, router, `{"content":"buy now"}`)
+
+	if rec := postJSON(router, "/api/secrets", `{"content":"buy now"}`); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("duplicate: got %d, want 429", rec.Code)
+	}
+	createSecret(t, router, `{"content":"something else"}`)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/secrets", strings.NewReader(`{"content":"buy now"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Forwarded-For", "198.51.100.7")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("same content from another client: got %d, want 201", rec.Code)
+	}
+
+	// Only salted hashes are kept.
+	for key := range h.recent.items {
+		if strings.Contains(key, "buy now") || strings.Contains(key, "192.0.2.1") {
+			t.Fatalf("recent content key leaks its input: %s", key)
+		}
+	}
+}
+
+func TestDuplicateContentWindowExpires(t *testing.T) {
+	recent := newRecentContent(10 * time.Millisecond)
+	key := recent.key("192.0.2.1", []byte("buy now"))
+	recent.add(key)
+	if !recent.has(key) {
+		t.Fatal("content not remembered")
+	}
+	if recent.has(recent.key("192.0.2.1", []byte("buy later"))) {
+		t.Fatal("different content remembered")
+	}
+	time.Sleep(20 * time.Millisecond)
+	if recent.has(key) {
+		t.Fatal("content remembered past the window")
+	}
+}
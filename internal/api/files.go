@@ -0,0 +1,160 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"secure.share/internal/models"
+)
+
+const (
+	// maxFormFieldLength bounds each form field of an upload other than
+	// the file itself.
+	maxFormFieldLength = 1024
+	// maxFilenameLength bounds the stored filename.
+	maxFilenameLength = 255
+	// multipartOverhead is what an upload's body may hold on top of the
+	// file: part headers, boundaries and the other form fields.
+	multipartOverhead = 64 * 1024
+)
+
+// CreateFileSecret creates a secret from a multipart/form-data upload. The
+// "file" part is encrypted like text content and revealed as a download;
+// the max_views, ttl_minutes and label fields work as in a JSON create.
+func (h *Handler) CreateFileSecret(w http.ResponseWriter, r *http.Request) {
+	limit := h.config.Secrets.MaxFileBytes
+	r.Body = http.MaxBytesReader(w, r.Body, int64(limit+multipartOverhead))
+
+	mr, err := r.MultipartReader()
+	if err != nil {
+		h.error(w, r, http.StatusBadRequest, "request must be multipart/form-data")
+		return
+	}
+
+	var req CreateRequest
+	var filename string
+	var haveFile bool
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			h.uploadError(w, r, err)
+			return
+		}
+
+		if part.FormName() == "file" {
+			data, err := io.ReadAll(io.LimitReader(part, int64(limit)+1))
+			if err != nil {
+				h.uploadError(w, r, err)
+				return
+			}
+			if len(data) > limit {
+				h.error(w, r, http.StatusRequestEntityTooLarge, fmt.Sprintf("file must be at most %d bytes", limit))
+				return
+			}
+			req.Content = string(data)
+			req.ContentType = part.Header.Get("Content-Type")
+			filename = cleanFilename(part.FileName())
+			haveFile = true
+			continue
+		}
+
+		value, err := io.ReadAll(io.LimitReader(part, maxFormFieldLength+1))
+		if err != nil {
+			h.uploadError(w, r, err)
+			return
+		}
+		if len(value) > maxFormFieldLength {
+			h.error(w, r, http.StatusBadRequest, fmt.Sprintf("%s must be at most %d bytes", part.FormName(), maxFormFieldLength))
+			return
+		}
+		switch part.FormName() {
+		case "max_views", "ttl_minutes":
+			n, err := strconv.Atoi(string(value))
+			if err != nil {
+				h.error(w, r, http.StatusBadRequest, part.FormName()+" must be a number")
+				return
+			}
+			if part.FormName() == "max_views" {
+				req.MaxViews = n
+			} else {
+				req.TTLMinutes = n
+			}
+		case "label":
+			req.Label = string(value)
+		}
+	}
+
+	if !haveFile {
+		h.error(w, r, http.StatusBadRequest, "file is required")
+		return
+	}
+	if req.ContentType == "" {
+		req.ContentType = "application/octet-stream"
+	}
+
+	p, ok := h.prepareRequest(w, r, req)
+	if !ok {
+		return
+	}
+	p.secret.Filename = filename
+
+	appliedTTL, ok := h.saveSecret(w, r, p)
+	if !ok {
+		return
+	}
+
+	h.json(w, http.StatusCreated, p.response(appliedTTL))
+}
+
+// uploadError answers a failed read of an upload: 413 if the body was over
+// the limit, 400 otherwise.
+func (h *Handler) uploadError(w http.ResponseWriter, r *http.Request, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		h.error(w, r, http.StatusRequestEntityTooLarge, fmt.Sprintf("file must be at most %d bytes", h.config.Secrets.MaxFileBytes))
+		return
+	}
+	h.error(w, r, http.StatusBadRequest, "invalid multipart body")
+}
+
+// cleanFilename keeps the last path element of an uploaded file's name,
+// without control characters, so it is safe to send back in a
+// Content-Disposition header.
+func cleanFilename(name string) string {
+	name = path.Base(strings.ReplaceAll(name, `\`, "/"))
+	name = strings.Map(func(c rune) rune {
+		if unicode.IsControl(c) {
+			return -1
+		}
+		return c
+	}, name)
+	if len(name) > maxFilenameLength {
+		name = strings.ToValidUTF8(name[:maxFilenameLength], "")
+	}
+	if name == "" || name == "." || name == "/" {
+		return "secret"
+	}
+	return name
+}
+
+// serveFileSecret sends a revealed file secret as a download.
+func (h *Handler) serveFileSecret(w http.ResponseWriter, r *http.Request, secret *models.Secret, content []byte, currentViews int) {
+	disposition := mime.FormatMediaType("attachment", map[string]string{"filename": secret.Filename})
+	if disposition == "" {
+		disposition = "attachment"
+	}
+	w.Header().Set("Content-Disposition", disposition)
+	// The file is served from our origin; it must not run as a page.
+	w.Header().Set("Content-Security-Policy", "sandbox")
+	h.writeRaw(w, h.downloadWriter(r, w), r, secret, content, currentViews, secret.ContentType)
+}
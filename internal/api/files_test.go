@@ -0,0 +1,157 @@
+package api
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"testing"
+	"time"
+
+	"secure.share/config"
+	"secure.share/internal/store"
+)
+
+func uploadFile(router http.Handler, filename, contentType string, data []byte, fields map[string]string) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for name, value := range fields {
+		mw.WriteField(name, value)
+	}
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Disposition", `form-data; name="file"; filename="`+filename+`"`)
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	part, _ := mw.CreatePart(header)
+	part.Write(data)
+	mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/secrets/file", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func decodeCreated(t *testing.T, rec *httptest.ResponseRecorder) (CreateResponse, string) {
+	t.Helper()
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("upload failed: got %d: %s", rec.Code, rec.Body.String())
+	}
+	var created CreateResponse
+	if err := json.NewDecoder(rec.Body).Decode(&created); err != nil {
+		t.Fatalf("failed to decode create response: %v", err)
+	}
+	_, passphrase, _ := bytes.Cut([]byte(created.URL), []byte("#"))
+	return created, string(passphrase)
+}
+
+func TestFileSecretDownloads(t *testing.T) {
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	router := SetupRouter(st, config.Default())
+
+	data := []byte("\x89PNG\r\n\x1a\n\x00binary")
+	created, passphrase := decodeCreated(t, uploadFile(router, `C:\tmp\photo.png`, "image/png", data, map[string]string{"max_views": "2"}))
+	if created.MaxViews != 2 {
+		t.Fatalf("max_views = %d, want 2", created.MaxViews)
+	}
+
+	rec := revealSecret(router, created.ID, passphrase)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("reveal failed: got %d: %s", rec.Code, rec.Body.String())
+	}
+	if !bytes.Equal(rec.Body.Bytes(), data) {
+		t.Fatalf("downloaded %q, want %q", rec.Body.Bytes(), data)
+	}
+	if got := rec.Header().Get("Content-Type"); got != "image/png" {
+		t.Fatalf("Content-Type = %q", got)
+	}
+	if got := rec.Header().Get("Content-Disposition"); got != `attachment; filename=photo.png` {
+		t.Fatalf("Content-Disposition = %q", got)
+	}
+
+	rec = getPath(router, "/api/secrets/"+created.ID+"?format=json&passphrase="+passphrase)
+	var resp RevealResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode reveal: %v", err)
+	}
+	if resp.Content != base64.StdEncoding.EncodeToString(data) || resp.Filename != "photo.png" || resp.ContentType != "image/png" || !resp.Burned {
+		t.Fatalf("json reveal = %+v", resp)
+	}
+}
+
+func TestFileSecretDownloadThrottled(t *testing.T) {
+	cfg := config.Default()
+	cfg.Secrets.DownloadRate = 20000
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	router := SetupRouter(st, cfg)
+
+	data := bytes.Repeat([]byte{0xa5}, 30000)
+	created, passphrase := decodeCreated(t, uploadFile(router, "blob.bin", "application/octet-stream", data, nil))
+
+	start := time.Now()
+	rec := &deadlineRecorder{ResponseRecorder: httptest.NewRecorder()}
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/secrets/"+created.ID+"?passphrase="+passphrase, nil))
+	elapsed := time.Since(start)
+	if rec.Code != http.StatusOK || !bytes.Equal(rec.Body.Bytes(), data) {
+		t.Fatalf("download failed: got %d, %d bytes", rec.Code, rec.Body.Len())
+	}
+
+	// The first second's worth goes out at once; the rest at the rate.
+	want := time.Duration(float64(len(data)-cfg.Secrets.DownloadRate) / float64(cfg.Secrets.DownloadRate) * float64(time.Second))
+	if elapsed < want {
+		t.Fatalf("download of %d bytes took %s, want at least %s", len(data), elapsed, want)
+	}
+	if len(rec.deadlines) < 2 {
+		t.Fatalf("write deadline set %d times, want once per chunk", len(rec.deadlines))
+	}
+}
+
+func TestFileSecretLimits(t *testing.T) {
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	cfg := config.Default()
+	cfg.Secrets.MaxFileBytes = 16
+	router := SetupRouter(st, cfg)
+
+	if rec := uploadFile(router, "big.bin", "", bytes.Repeat([]byte("x"), 17), nil); rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("oversized upload: got %d, want 413", rec.Code)
+	}
+	if rec := uploadFile(router, "big.bin", "", bytes.Repeat([]byte("x"), 16), nil); rec.Code != http.StatusCreated {
+		t.Fatalf("upload at the limit: got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := uploadFile(router, "a.bin", "", []byte("x"), map[string]string{"max_views": "many"}); rec.Code != http.StatusBadRequest {
+		t.Fatalf("bad max_views: got %d, want 400", rec.Code)
+	}
+	if rec := postJSON(router, "/api/secrets/file", `{"content":"s3cret"}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("json upload: got %d, want 400", rec.Code)
+	}
+
+	cfg = config.Default()
+	cfg.Secrets.MaxFileBytes = 0
+	disabled := SetupRouter(st, cfg)
+	if rec := uploadFile(disabled, "a.bin", "", []byte("x"), nil); rec.Code == http.StatusCreated {
+		t.Fatal("upload accepted with file uploads disabled")
+	}
+}
+
+func TestCleanFilename(t *testing.T) {
+	for in, want := range map[string]string{
+		"report.pdf":          "report.pdf",
+		"../../etc/passwd":    "passwd",
+		`C:\Users\me\key.pem`: "key.pem",
+		"bad\r\nname.txt":     "badname.txt",
+		"":                    "secret",
+		"/":                   "secret",
+	} {
+		if got := cleanFilename(in); got != want {
+			t.Errorf("cleanFilename(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strings"
+
+	"secure.share/internal/models"
+)
+
+// maxCountries bounds each country list a secret can carry.
+const maxCountries = 64
+
+// normalizeCountries upper-cases ISO 3166-1 alpha-2 codes. It reports false
+// when a code isn't two letters or there are too many.
+func normalizeCountries(codes []string) ([]string, bool) {
+	if len(codes) > maxCountries {
+		return nil, false
+	}
+	normalized := make([]string, 0, len(codes))
+	for _, code := range codes {
+		code = strings.ToUpper(code)
+		if len(code) != 2 || code[0] < 'A' || code[0] > 'Z' || code[1] < 'A' || code[1] > 'Z' {
+			return nil, false
+		}
+		normalized = append(normalized, code)
+	}
+	return normalized, true
+}
+
+// allowCountry checks the requester's country against secret's allowed or
+// blocked countries and answers 403 if it may not read it. A country that
+// can't be determined passes a block list but never an allow list.
+func (h *Handler) allowCountry(w http.ResponseWriter, r *http.Request, secret *models.Secret) bool {
+	if len(secret.AllowedCountries) == 0 && len(secret.BlockedCountries) == 0 {
+		return true
+	}
+
+	country := h.clientCountry(r)
+	allowed := !slices.Contains(secret.BlockedCountries, country)
+	if len(secret.AllowedCountries) > 0 {
+		allowed = country != "" && slices.Contains(secret.AllowedCountries, country)
+	}
+	if !allowed {
+		h.error(w, r, http.StatusForbidden, "secret cannot be revealed from your location")
+		return false
+	}
+	return true
+}
+
+// clientCountry is the requester's country, or "" without a GeoIP
+// database or when the lookup finds nothing.
+func (h *Handler) clientCountry(r *http.Request) string {
+	if h.geo == nil {
+		return ""
+	}
+	ip, err := netip.ParseAddr(getClientIP(r))
+	if err != nil {
+		addrPort, err := netip.ParseAddrPort(r.RemoteAddr)
+		if err != nil {
+			return ""
+		}
+		ip = addrPort.Addr()
+	}
+	country, err := h.geo.Country(ip)
+	if err != nil {
+		slog.WarnContext(r.Context(), "geoip lookup failed", "error", err)
+		return ""
+	}
+	return country
+}
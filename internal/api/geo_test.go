@@ -0,0 +1,93 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"testing"
+	"time"
+
+	"secure.share/config"
+	"secure.share/internal/store"
+)
+
+// stubGeo resolves addresses from a fixed table; anything else is unknown.
+type stubGeo map[string]string
+
+func (g stubGeo) Country(ip netip.Addr) (string, error) {
+	return g[ip.String()], nil
+}
+
+func newGeoRouter(t *testing.T) http.Handler {
+	t.Helper()
+	cfg := config.Default()
+	st := store.NewMemoryStore(time.Minute)
+	t.Cleanup(func() { st.Close() })
+	h := NewHandler(st, cfg)
+	h.geo = stubGeo{"203.0.113.1": "DE", "198.51.100.1": "US"}
+	return newRouter(h, cfg)
+}
+
+func revealFrom(router http.Handler, id, passphrase, ip string) int {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/secrets/"+id+"?passphrase="+url.QueryEscape(passphrase), nil)
+	req.Header.Set("X-Forwarded-For", ip)
+	router.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestRevealAllowedCountries(t *testing.T) {
+	router := newGeoRouter(t)
+
+	created, passphrase := createSecret(t, router, `{"content": "s3cret", "allowed_countries": ["de"]}`)
+
+	// Neither a listed-out country nor an unknown one uses the only view.
+	if code := revealFrom(router, created.ID, passphrase, "198.51.100.1"); code != http.StatusForbidden {
+		t.Fatalf("reveal from US: got %d, want %d", code, http.StatusForbidden)
+	}
+	if code := revealFrom(router, created.ID, passphrase, "192.0.2.1"); code != http.StatusForbidden {
+		t.Fatalf("reveal from unknown country: got %d, want %d", code, http.StatusForbidden)
+	}
+	if code := revealFrom(router, created.ID, passphrase, "203.0.113.1"); code != http.StatusOK {
+		t.Fatalf("reveal from DE: got %d, want %d", code, http.StatusOK)
+	}
+}
+
+func TestRevealBlockedCountries(t *testing.T) {
+	router := newGeoRouter(t)
+
+	created, passphrase := createSecret(t, router, `{"content": "s3cret", "max_views": 2, "blocked_countries": ["US"]}`)
+
+	if code := revealFrom(router, created.ID, passphrase, "198.51.100.1"); code != http.StatusForbidden {
+		t.Fatalf("reveal from US: got %d, want %d", code, http.StatusForbidden)
+	}
+	if code := revealFrom(router, created.ID, passphrase, "203.0.113.1"); code != http.StatusOK {
+		t.Fatalf("reveal from DE: got %d, want %d", code, http.StatusOK)
+	}
+	if code := revealFrom(router, created.ID, passphrase, "192.0.2.1"); code != http.StatusOK {
+		t.Fatalf("reveal from unknown country: got %d, want %d", code, http.StatusOK)
+	}
+}
+
+func TestCreateCountriesValidation(t *testing.T) {
+	router := newGeoRouter(t)
+	for _, body := range []string{
+		`{"content": "x", "allowed_countries": ["DEU"]}`,
+		`{"content": "x", "blocked_countries": ["1A"]}`,
+		`{"content": "x", "allowed_countries": ["DE"], "blocked_countries": ["US"]}`,
+	} {
+		if rec := postJSON(router, "/api/secrets", body); rec.Code != http.StatusBadRequest {
+			t.Fatalf("%s: got %d, want %d", body, rec.Code, http.StatusBadRequest)
+		}
+	}
+
+	// Without a GeoIP database restrictions can't be enforced, so they are
+	// refused rather than ignored.
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	plain := SetupRouter(st, config.Default())
+	if rec := postJSON(plain, "/api/secrets", `{"content": "x", "allowed_countries": ["DE"]}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("create without geoip: got %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
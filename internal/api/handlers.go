@@ -1,29 +1,201 @@
 package api
 
 import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"math/rand/v2"
 	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"secure.share/config"
 	"secure.share/internal/crypto"
+	"secure.share/internal/geo"
+	"secure.share/internal/hooks"
 	"secure.share/internal/models"
+	"secure.share/internal/offload"
 	"secure.share/internal/store"
 	"secure.share/web"
 
 	"github.com/go-chi/chi/v5"
 )
 
+const cryptoProfileHeader = "X-Crypto-Profile"
+
+const (
+	maxLabelLength   = 128
+	maxNoteLength    = 1024
+	maxContextLength = 128
+)
+
 type Handler struct {
-	store  store.Store
-	config *config.Config
+	store        store.Store
+	storeHealth  *store.MonitoredStore
+	config       *config.Config
+	blocklist    []*regexp.Regexp
+	capabilities CapabilitiesResponse
+	cryptoOps    *crypto.Limiter
+	hook         *hooks.ExecHook // nil when no hook is configured
+	profiles     map[string]crypto.Profile
+	// passphrasePolicy checks passphrases creators choose themselves.
+	passphrasePolicy crypto.PassphrasePolicy
+	decoys           decoys
+	// downloadLimiter is shared by all downloads when the limit is global.
+	downloadLimiter *bandwidthLimiter
+	drafts          *drafts          // nil when drafts are disabled
+	reservations    *reservations    // nil when download revalidation is off
+	misses          *negativeCache   // nil when the negative cache is off
+	recent          *recentContent   // nil when duplicate content is allowed
+	codeSender      hooks.CodeSender // nil when reveal codes are disabled
+	geo             geo.Resolver     // nil without a GeoIP database
+	linkKeys        *crypto.LinkKeys // nil when signed links are disabled
+	renderers       map[string]renderer
+	stats           *statsCache
+	metrics         *metrics        // nil when metrics are disabled
+	offload         offload.Storage // nil unless archives are offloaded
+	revealPage      []byte
+	revealCSP       string
 }
 
 func NewHandler(s store.Store, cfg *config.Config) *Handler {
+	blocklist := make([]*regexp.Regexp, 0, len(cfg.Secrets.BlockedPatterns))
+	for _, pattern := range cfg.Secrets.BlockedPatterns {
+		blocklist = append(blocklist, regexp.MustCompile(pattern))
+	}
+
+	revealPage, revealCSP := buildRevealPage()
+
+	profiles := make(map[string]crypto.Profile, len(cfg.Crypto.Profiles))
+	for name, p := range cfg.Crypto.Profiles {
+		profiles[name] = crypto.Profile{Cipher: p.Cipher, KDFIterations: p.KDFIterations}
+	}
+
+	var downloadLimiter *bandwidthLimiter
+	if cfg.Secrets.DownloadRate > 0 && cfg.Secrets.DownloadRateScope == "global" {
+		downloadLimiter = newBandwidthLimiter(cfg.Secrets.DownloadRate)
+	}
+
+	var staged *drafts
+	if cfg.Secrets.DraftTTL > 0 {
+		staged = newDrafts(cfg.Secrets.DraftTTL)
+	}
+
+	monitored := store.NewMonitoredStore(s, store.HealthPolicy{
+		Window:       cfg.Store.Health.Window,
+		MaxErrorRate: cfg.Store.Health.MaxErrorRate,
+		MaxLatency:   cfg.Store.Health.MaxLatency,
+		MinSamples:   cfg.Store.Health.MinSamples,
+	})
+
+	var reserved *reservations
+	if cfg.Secrets.RevalidateTTL > 0 {
+		reserved = newReservations(cfg.Secrets.RevalidateTTL)
+	}
+
+	// A retry within the memory store's grace period must reach the store
+	// even though the secret now looks missing to everyone else.
+	var misses *negativeCache
+	graceRetries := cfg.Store.Type == "memory" && cfg.Store.Memory.GracePeriod > 0
+	if cfg.Store.NegativeCache.TTL > 0 && !graceRetries {
+		misses = newNegativeCache(cfg.Store.NegativeCache.TTL, cfg.Store.NegativeCache.Size)
+	}
+
+	var recent *recentContent
+	if cfg.Secrets.DuplicateWindow > 0 {
+		recent = newRecentContent(cfg.Secrets.DuplicateWindow)
+	}
+
+	var codeSender hooks.CodeSender
+	if cfg.Hooks.CodeCommand != "" {
+		codeSender = hooks.NewExecCodeSender(cfg.Hooks.CodeCommand, cfg.Hooks.ExecTimeout)
+	}
+
+	var resolver geo.Resolver
+	if cfg.Geo.DatabasePath != "" {
+		db, err := geo.Open(cfg.Geo.DatabasePath)
+		if err != nil {
+			slog.Error("failed to load geoip database, geo restrictions disabled", "error", err)
+		} else {
+			resolver = db
+		}
+	}
+
+	var offloadStorage offload.Storage
+	if cfg.Offload.Bucket != "" {
+		s3, err := offload.NewS3(offload.S3Options{
+			Bucket:   cfg.Offload.Bucket,
+			Region:   cfg.Offload.Region,
+			Endpoint: cfg.Offload.Endpoint,
+		})
+		if err != nil {
+			slog.Error("failed to set up offload storage, archives are sent directly", "error", err)
+		} else {
+			offloadStorage = s3
+		}
+	}
+
+	enabledRenderers := make(map[string]renderer, len(cfg.Secrets.Renderers))
+	for _, contentType := range cfg.Secrets.Renderers {
+		if rr, ok := renderers[contentType]; ok {
+			enabledRenderers[contentType] = rr
+		}
+	}
+
+	var linkKeys *crypto.LinkKeys
+	if cfg.SignedLinks.Key != "" {
+		keys := crypto.NewLinkKeys(cfg.SignedLinks.Key)
+		linkKeys = &keys
+	}
+
+	var m *metrics
+	if cfg.Metrics.Enabled {
+		m = newMetrics()
+	}
+
+	var hook *hooks.ExecHook
+	if cfg.Hooks.ExecCommand != "" {
+		hook = hooks.NewExecHookWithQueue(cfg.Hooks.ExecCommand, cfg.Hooks.ExecTimeout, hooks.QueueOptions{
+			Workers:  cfg.Hooks.ExecMaxConcurrent,
+			Depth:    cfg.Hooks.ExecQueueDepth,
+			Overflow: cfg.Hooks.ExecOverflow,
+		})
+	}
+
 	return &Handler{
-		store:  s,
-		config: cfg,
+		store:            monitored,
+		storeHealth:      monitored,
+		config:           cfg,
+		blocklist:        blocklist,
+		capabilities:     buildCapabilities(cfg),
+		cryptoOps:        crypto.NewLimiter(cfg.Crypto.MaxConcurrentOps, cfg.Crypto.QueueTimeout),
+		hook:             hook,
+		profiles:         profiles,
+		passphrasePolicy: newPassphrasePolicy(cfg.Crypto.PassphrasePolicy),
+		decoys:           newDecoys(cfg.Honeypot),
+		downloadLimiter:  downloadLimiter,
+		drafts:           staged,
+		codeSender:       codeSender,
+		geo:              resolver,
+		renderers:        enabledRenderers,
+		offload:          offloadStorage,
+		linkKeys:         linkKeys,
+		reservations:     reserved,
+		misses:           misses,
+		recent:           recent,
+		stats:            &statsCache{ttl: cfg.Stats.CacheTTL},
+		metrics:          m,
+		revealPage:       revealPage,
+		revealCSP:        revealCSP,
 	}
 }
 
@@ -31,48 +203,377 @@ type CreateRequest struct {
 	Content    string `json:"content"`
 	MaxViews   int    `json:"max_views,omitempty"`
 	TTLMinutes int    `json:"ttl_minutes,omitempty"`
+	// Label is a non-secret tag operators can use to find the secret, e.g.
+	// when purging during an incident.
+	Label string `json:"label,omitempty"`
+	// Note is shown to the recipient before they reveal. It is encrypted
+	// with the same passphrase as the content.
+	Note string `json:"note,omitempty"`
+	// CodeContact is an address a one-time code is sent to, which the
+	// recipient must enter to reveal. It is encrypted like the note.
+	CodeContact string `json:"code_contact,omitempty"`
+	// Shares and Threshold split the key into Shamir shares, one link per
+	// share; any Threshold of them are needed to reveal.
+	Shares    int `json:"shares,omitempty"`
+	Threshold int `json:"threshold,omitempty"`
+	// Context is a non-secret label bound into the encryption, so the
+	// ciphertext can't be moved to a secret with a different context.
+	Context string `json:"context,omitempty"`
+	// ContentType is the content's media type, e.g. "text/markdown". It
+	// decides how /render formats the content.
+	ContentType string `json:"content_type,omitempty"`
+	// RequireAck makes the recipient acknowledge (POST /ack) before the
+	// content is delivered.
+	RequireAck bool `json:"require_ack,omitempty"`
+	// HideViews leaves the number of views remaining out of reveal and
+	// status responses. The limit still applies.
+	HideViews bool `json:"hide_views,omitempty"`
+	// PIN adds a short numeric code needed on top of the link to reveal,
+	// for reading it out over the phone.
+	PIN string `json:"pin,omitempty"`
+	// Files bundles several named contents into one secret, revealed as a
+	// zip from /archive. It replaces Content.
+	Files []ArchiveFile `json:"files,omitempty"`
+	// Integrity returns a detached MAC over the content and the key for it,
+	// for checking later with /api/integrity/verify.
+	Integrity bool `json:"integrity,omitempty"`
+	// AllowedCountries or BlockedCountries (ISO 3166-1 alpha-2 codes)
+	// restrict where the secret can be revealed from. They need a GeoIP
+	// database.
+	AllowedCountries []string `json:"allowed_countries,omitempty"`
+	BlockedCountries []string `json:"blocked_countries,omitempty"`
+	// SignedLinks keeps the passphrase wrapped under a server key, so the
+	// owner can mint reveal links that work without it.
+	SignedLinks bool `json:"signed_links,omitempty"`
+	// ClientEncrypted says Content is ciphertext the creator made,
+	// base64 encoded. It is stored and revealed as sent; the key stays in
+	// the creator's URL fragment.
+	ClientEncrypted bool `json:"client_encrypted,omitempty"`
+	// AuthHash, for a client-encrypted secret, is a value derived from the
+	// key that a reveal must present as auth, so the id alone can't use up
+	// its views.
+	AuthHash string `json:"auth_hash,omitempty"`
+	// Passphrase replaces the generated one. It is left out of the URL,
+	// so it can be sent over another channel, and the server keeps no
+	// copy of it.
+	Passphrase string `json:"passphrase,omitempty"`
 }
 
 type CreateResponse struct {
 	ID        string    `json:"id"`
 	URL       string    `json:"url"`
+	ShareURLs []string  `json:"share_urls,omitempty"`
 	ExpiresAt time.Time `json:"expires_at"`
+	ExpiresIn string    `json:"expires_in"`
 	MaxViews  int       `json:"max_views"`
+	// OwnerToken authorizes delete and extend. It is only returned here.
+	OwnerToken string `json:"owner_token,omitempty"`
+	// IntegrityKey and IntegrityMAC are only kept by the sender; the server
+	// stores neither.
+	IntegrityKey string `json:"integrity_key,omitempty"`
+	IntegrityMAC string `json:"integrity_mac,omitempty"`
+	// UserPassphrase means URL carries no key: the recipient needs the
+	// passphrase the creator chose.
+	UserPassphrase bool `json:"user_passphrase,omitempty"`
+}
+
+type NoteResponse struct {
+	Note string `json:"note"`
 }
 
 type RevealResponse struct {
-	Content        string `json:"content"`
-	ViewsRemaining int    `json:"views_remaining"`
+	Content string `json:"content"`
+	// ViewsRemaining is omitted for secrets created with hide_views.
+	ViewsRemaining *int `json:"views_remaining,omitempty"`
+	// Burned is true when this reveal used the last view and the secret
+	// has been deleted, whether or not views are hidden.
+	Burned bool `json:"burned"`
+	// NextNonce replaces the reveal URL's nonce for the next view.
+	NextNonce string `json:"next_nonce,omitempty"`
+	// CanBurn means the secret is still there and the recipient can delete
+	// it now with POST /api/secrets/{id}/burn and the same key.
+	CanBurn bool `json:"can_burn,omitempty"`
+	// ClientEncrypted means Content is the creator's ciphertext, base64
+	// encoded, for the browser to decrypt.
+	ClientEncrypted bool `json:"client_encrypted,omitempty"`
+	// Filename and ContentType are set for file secrets revealed with
+	// format=json, whose Content is then base64 encoded.
+	Filename    string `json:"filename,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
 }
 
 type StatusResponse struct {
 	ID             string    `json:"id"`
 	Exists         bool      `json:"exists"`
 	Expired        bool      `json:"expired"`
+	Revealed       bool      `json:"revealed,omitempty"` // consumed within the tombstone window
+	PINRequired    bool      `json:"pin_required,omitempty"`
+	AckRequired    bool      `json:"ack_required,omitempty"`
+	CodeRequired   bool      `json:"code_required,omitempty"`
+	Archive        bool      `json:"archive,omitempty"` // reveal through /archive
 	ViewsRemaining int       `json:"views_remaining,omitempty"`
 	ExpiresAt      time.Time `json:"expires_at,omitempty"`
+	ExpiresIn      string    `json:"expires_in,omitempty"`
+	// ClientEncrypted secrets are revealed without a passphrase and
+	// decrypted by the browser.
+	ClientEncrypted bool `json:"client_encrypted,omitempty"`
+	// UserPassphrase secrets need the passphrase their creator chose,
+	// which the link doesn't carry.
+	UserPassphrase bool `json:"user_passphrase,omitempty"`
 }
 
 type ErrorResponse struct {
 	Error string `json:"error"`
+	// Code names the failure for errors raised before a request reaches
+	// its handler, such as "unsupported_media_type".
+	Code string `json:"code,omitempty"`
 }
 
+// healthPingTimeout bounds the store ping, so a hung store fails the health
+// check instead of holding it open past the load balancer's own timeout.
+const healthPingTimeout = time.Second
+
+// Health answers 503 when the store can't be reached at all. Ready covers a
+// store that answers but is failing or slow.
 func (h *Handler) Health(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), healthPingTimeout)
+	defer cancel()
+
+	if err := h.store.Ping(ctx); err != nil {
+		slog.WarnContext(r.Context(), "store ping failed", "error", err)
+		h.json(w, http.StatusServiceUnavailable, map[string]string{"status": "degraded"})
+		return
+	}
 	h.json(w, http.StatusOK, map[string]string{"status": "ok"})
 }
 
-func (h *Handler) CreateSecret(w http.ResponseWriter, r *http.Request) {
-	var req CreateRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.error(w, http.StatusBadRequest, "invalid request body")
+type StoreHealthResponse struct {
+	Score     float64 `json:"score"`
+	ErrorRate float64 `json:"error_rate"`
+	LatencyMS float64 `json:"latency_ms"`
+	Samples   int     `json:"samples"`
+	Degraded  bool    `json:"degraded"`
+}
+
+type ReadyResponse struct {
+	Status string              `json:"status"`
+	Store  StoreHealthResponse `json:"store"`
+}
+
+func (h *Handler) storeHealthResponse() StoreHealthResponse {
+	health := h.storeHealth.Health()
+	return StoreHealthResponse{
+		Score:     health.Score,
+		ErrorRate: health.ErrorRate,
+		LatencyMS: float64(health.Latency) / float64(time.Millisecond),
+		Samples:   health.Samples,
+		Degraded:  health.Degraded,
+	}
+}
+
+// Ready answers 503 while recent store operations are failing or slow past
+// the configured thresholds, so load balancers can route around this
+// instance before the store fails outright.
+func (h *Handler) Ready(w http.ResponseWriter, r *http.Request) {
+	health := h.storeHealthResponse()
+	if health.Degraded {
+		h.json(w, http.StatusServiceUnavailable, ReadyResponse{Status: "degraded", Store: health})
 		return
 	}
+	h.json(w, http.StatusOK, ReadyResponse{Status: "ok", Store: health})
+}
 
-	if req.Content == "" {
-		h.error(w, http.StatusBadRequest, "content is required")
+func (h *Handler) CreateSecret(w http.ResponseWriter, r *http.Request) {
+	p, ok := h.prepareSecret(w, r)
+	if !ok {
+		return
+	}
+	appliedTTL, ok := h.saveSecret(w, r, p)
+	if !ok {
 		return
 	}
 
+	h.json(w, http.StatusCreated, p.response(appliedTTL))
+}
+
+// preparedSecret is a secret ready to store along with everything the
+// creator gets back about it.
+type preparedSecret struct {
+	secret       *models.Secret
+	ttl          time.Duration
+	url          string
+	shareURLs    []string
+	ownerToken   string
+	integrityKey string
+	integrityMAC string
+	// contentKey marks the content as recently shared once saved.
+	contentKey string
+}
+
+func (p *preparedSecret) response(appliedTTL time.Duration) CreateResponse {
+	return CreateResponse{
+		ID:           p.secret.ID,
+		URL:          p.url,
+		ShareURLs:    p.shareURLs,
+		ExpiresAt:    time.Now().Add(appliedTTL),
+		ExpiresIn:    humanizeDuration(appliedTTL),
+		MaxViews:     p.secret.MaxViews,
+		OwnerToken:   p.ownerToken,
+		IntegrityKey: p.integrityKey,
+		IntegrityMAC: p.integrityMAC,
+
+		UserPassphrase: p.secret.UserPassphrase,
+	}
+}
+
+// prepareSecret decodes a JSON create request and prepares it with
+// prepareRequest.
+func (h *Handler) prepareSecret(w http.ResponseWriter, r *http.Request) (prepared *preparedSecret, ok bool) {
+	var req CreateRequest
+	if !h.decode(w, r, &req) {
+		return nil, false
+	}
+	return h.prepareRequest(w, r, req)
+}
+
+// prepareRequest validates a create request and builds the encrypted secret
+// and its links, without storing anything. On failure the response has
+// been written and ok is false.
+func (h *Handler) prepareRequest(w http.ResponseWriter, r *http.Request, req CreateRequest) (prepared *preparedSecret, ok bool) {
+	if req.Content == "" && len(req.Files) == 0 {
+		h.error(w, r, http.StatusBadRequest, "content is required")
+		return nil, false
+	}
+
+	if req.Content != "" && len(req.Files) > 0 {
+		h.error(w, r, http.StatusBadRequest, "content and files cannot both be set")
+		return nil, false
+	}
+
+	if len(req.Files) > 0 {
+		if reason := h.validateArchive(req.Files); reason != "" {
+			h.error(w, r, http.StatusBadRequest, reason)
+			return nil, false
+		}
+	}
+
+	var ciphertext []byte
+	if req.ClientEncrypted {
+		var reason string
+		if ciphertext, reason = h.clientCiphertext(&req); reason != "" {
+			h.error(w, r, http.StatusBadRequest, reason)
+			return nil, false
+		}
+	} else if req.AuthHash != "" {
+		h.error(w, r, http.StatusBadRequest, "auth_hash is only for client_encrypted secrets")
+		return nil, false
+	}
+
+	if !req.ClientEncrypted && h.isBlocked(req.Content) {
+		h.error(w, r, http.StatusUnprocessableEntity, "content is not allowed")
+		return nil, false
+	}
+	for _, f := range req.Files {
+		if h.isBlocked(f.Content) {
+			h.error(w, r, http.StatusUnprocessableEntity, "content is not allowed")
+			return nil, false
+		}
+	}
+
+	if len(req.Label) > maxLabelLength {
+		h.error(w, r, http.StatusBadRequest, fmt.Sprintf("label must be at most %d bytes", maxLabelLength))
+		return nil, false
+	}
+
+	if len(req.Note) > maxNoteLength {
+		h.error(w, r, http.StatusBadRequest, fmt.Sprintf("note must be at most %d bytes", maxNoteLength))
+		return nil, false
+	}
+
+	if req.CodeContact != "" && h.codeSender == nil {
+		h.error(w, r, http.StatusBadRequest, "reveal codes are not enabled")
+		return nil, false
+	}
+	if !validContact(req.CodeContact) {
+		h.error(w, r, http.StatusBadRequest, fmt.Sprintf("code_contact must be a single line of at most %d bytes", maxContactLength))
+		return nil, false
+	}
+
+	if req.Shares > h.config.Secrets.MaxLinks {
+		h.error(w, r, http.StatusBadRequest, fmt.Sprintf("at most %d shares are allowed", h.config.Secrets.MaxLinks))
+		return nil, false
+	}
+	if req.Shares > 0 && (req.Threshold < 2 || req.Threshold > req.Shares) {
+		h.error(w, r, http.StatusBadRequest, "threshold must be between 2 and shares")
+		return nil, false
+	}
+
+	if req.SignedLinks && h.linkKeys == nil {
+		h.error(w, r, http.StatusBadRequest, "signed links are not enabled")
+		return nil, false
+	}
+	if req.SignedLinks && req.Shares > 0 {
+		h.error(w, r, http.StatusBadRequest, "signed_links and shares cannot both be set")
+		return nil, false
+	}
+
+	if len(req.Context) > maxContextLength {
+		h.error(w, r, http.StatusBadRequest, fmt.Sprintf("context must be at most %d bytes", maxContextLength))
+		return nil, false
+	}
+
+	contentType, ok := normalizeContentType(req.ContentType)
+	if !ok {
+		h.error(w, r, http.StatusBadRequest, fmt.Sprintf("content_type must be a media type of at most %d bytes", maxContentTypeLength))
+		return nil, false
+	}
+	if h.config.Secrets.StrictText && (contentType == "" || contentType == "text/plain") && !isText(req.Content) {
+		h.error(w, r, http.StatusBadRequest, "content looks like binary data; send it as an entry in files instead, or set content_type")
+		return nil, false
+	}
+
+	if (len(req.AllowedCountries) > 0 || len(req.BlockedCountries) > 0) && h.geo == nil {
+		h.error(w, r, http.StatusBadRequest, "geo restrictions are not enabled")
+		return nil, false
+	}
+	if len(req.AllowedCountries) > 0 && len(req.BlockedCountries) > 0 {
+		h.error(w, r, http.StatusBadRequest, "allowed_countries and blocked_countries cannot both be set")
+		return nil, false
+	}
+	allowedCountries, okAllowed := normalizeCountries(req.AllowedCountries)
+	blockedCountries, okBlocked := normalizeCountries(req.BlockedCountries)
+	if !okAllowed || !okBlocked {
+		h.error(w, r, http.StatusBadRequest, fmt.Sprintf("countries must be at most %d two-letter ISO 3166-1 codes", maxCountries))
+		return nil, false
+	}
+
+	if req.Integrity && !h.config.Secrets.IntegrityMAC {
+		h.error(w, r, http.StatusBadRequest, "integrity macs are not enabled")
+		return nil, false
+	}
+
+	if req.PIN != "" && !h.config.Secrets.AllowPIN {
+		h.error(w, r, http.StatusBadRequest, "pins are not enabled")
+		return nil, false
+	}
+	if req.PIN != "" && !validPIN(req.PIN) {
+		h.error(w, r, http.StatusBadRequest, "pin must be 4 to 8 digits")
+		return nil, false
+	}
+
+	userPassphrase := h.normalizePassphrase(req.Passphrase)
+	if req.Passphrase != "" {
+		if reason := h.checkUserPassphrase(&req, userPassphrase); reason != "" {
+			h.error(w, r, http.StatusBadRequest, reason)
+			return nil, false
+		}
+	}
+
+	profile, ok := h.cryptoProfile(w, r)
+	if !ok {
+		return nil, false
+	}
+
 	maxViews := clamp(
 		req.MaxViews,
 		h.config.Secrets.DefaultViews,
@@ -84,142 +585,819 @@ func (h *Handler) CreateSecret(w http.ResponseWriter, r *http.Request) {
 		h.config.Secrets.DefaultTTL,
 		h.config.Secrets.MaxTTL,
 	)
+	ttl = jitterTTL(ttl, h.config.Secrets.TTLJitter, h.config.Secrets.MaxTTL)
 
-	id := crypto.GenerateID()
+	id := crypto.GenerateIDWithEncoding(crypto.IDEncoding(h.config.Crypto.IDEncoding))
 	passphrase := crypto.GeneratePassphrase()
+	if req.Passphrase != "" {
+		passphrase = userPassphrase
+	}
 
-	encrypted, err := crypto.Encrypt([]byte(req.Content), passphrase)
-	if err != nil {
-		h.error(w, http.StatusInternalServerError, "encryption failed")
-		return
+	plaintext := []byte(req.Content)
+	if len(req.Files) > 0 {
+		var err error
+		if plaintext, err = json.Marshal(req.Files); err != nil {
+			h.error(w, r, http.StatusInternalServerError, "encryption failed")
+			return nil, false
+		}
+	}
+	var contentKey string
+	if h.recent != nil {
+		contentKey = h.recent.key(clientKey(r), plaintext)
+		if h.recent.has(contentKey) {
+			h.error(w, r, http.StatusTooManyRequests, "the same content was shared moments ago")
+			return nil, false
+		}
+	}
+	var integrityKey, integrityMAC string
+	if req.Integrity {
+		integrityKey = crypto.GenerateMACKey()
+		integrityMAC = crypto.ContentMAC(integrityKey, plaintext)
+	}
+	if req.PIN != "" {
+		inner, err := h.cryptoOps.Encrypt(r.Context(), plaintext, pinKey(id, req.PIN))
+		if err != nil {
+			h.cryptoError(w, r, err, http.StatusInternalServerError, "encryption failed")
+			return nil, false
+		}
+		plaintext = inner
+	}
+
+	encrypted := ciphertext
+	var err error
+	if !req.ClientEncrypted {
+		if encrypted, err = h.seal(r.Context(), plaintext, passphrase, []byte(req.Context), profile); err != nil {
+			h.cryptoError(w, r, err, http.StatusInternalServerError, "encryption failed")
+			return nil, false
+		}
+	}
+
+	var encryptedNote []byte
+	if req.Note != "" {
+		encryptedNote, err = h.seal(r.Context(), []byte(req.Note), passphrase, []byte(req.Context), profile)
+		if err != nil {
+			h.cryptoError(w, r, err, http.StatusInternalServerError, "encryption failed")
+			return nil, false
+		}
+	}
+
+	var encryptedContact []byte
+	if req.CodeContact != "" {
+		encryptedContact, err = h.seal(r.Context(), []byte(req.CodeContact), passphrase, []byte(req.Context), profile)
+		if err != nil {
+			h.cryptoError(w, r, err, http.StatusInternalServerError, "encryption failed")
+			return nil, false
+		}
 	}
 
 	secret := &models.Secret{
 		ID:            id,
 		EncryptedData: encrypted,
-		Passphrase:    passphrase,
+		EncryptedNote: encryptedNote,
 		MaxViews:      maxViews,
 		CurrentViews:  0,
 		ExpiresAt:     time.Now().Add(ttl),
 		CreatedAt:     time.Now(),
+		Label:         req.Label,
+		Context:       req.Context,
+		ContentType:   contentType,
+		HasPIN:        req.PIN != "",
+		RequireAck:    req.RequireAck,
+		HideViews:     req.HideViews,
+		Archive:       len(req.Files) > 0,
+		RequireNonce:  h.config.Secrets.RevealNonces,
+
+		EncryptedContact: encryptedContact,
+		AllowedCountries: allowedCountries,
+		BlockedCountries: blockedCountries,
 	}
 
-	if err := h.store.Save(r.Context(), secret); err != nil {
-		h.error(w, http.StatusInternalServerError, "failed to save secret")
-		return
+	if req.ClientEncrypted {
+		secret.ClientEncrypted = true
+		if req.AuthHash != "" {
+			secret.AuthHash = crypto.HashOwnerToken(req.AuthHash)
+		}
+	}
+	if req.Passphrase != "" {
+		secret.UserPassphrase = true
 	}
 
-	url := h.config.Server.BaseURL + "/s/" + id + "#" + passphrase
+	var ownerToken string
+	if h.config.Secrets.OwnerTokens {
+		ownerToken = crypto.GenerateOwnerToken()
+		secret.OwnerHash = crypto.HashOwnerToken(ownerToken)
+	}
 
-	h.json(w, http.StatusCreated, CreateResponse{
-		ID:        id,
-		URL:       url,
-		ExpiresAt: secret.ExpiresAt,
-		MaxViews:  maxViews,
-	})
+	url := h.config.Server.BaseURL + "/s/" + id
+	if secret.RequireNonce {
+		url += "?n=" + revealNonce(secret, 0)
+	}
+	var shareURLs []string
+	if req.Shares > 0 {
+		shares, err := crypto.SplitKey([]byte(passphrase), req.Threshold, req.Shares)
+		if err != nil {
+			h.error(w, r, http.StatusInternalServerError, "key splitting failed")
+			return nil, false
+		}
+		for _, share := range shares {
+			shareURLs = append(shareURLs, url+"#"+base64.RawURLEncoding.EncodeToString(share))
+		}
+		secret.Threshold = req.Threshold
+	} else if !req.ClientEncrypted && !secret.UserPassphrase {
+		// A client-encrypted secret's creator appends their own key, and
+		// a chosen passphrase travels separately. The passphrase itself is
+		// never stored; its hash lets reveals and burns check it cheaply.
+		url += "#" + passphrase
+		secret.PassphraseHash = crypto.HashOwnerToken(passphrase)
+	}
+	if req.SignedLinks {
+		if secret.WrappedKey, err = h.linkKeys.WrapPassphrase(id, passphrase); err != nil {
+			h.error(w, r, http.StatusInternalServerError, "encryption failed")
+			return nil, false
+		}
+	}
+
+	return &preparedSecret{
+		secret:       secret,
+		ttl:          ttl,
+		url:          url,
+		shareURLs:    shareURLs,
+		ownerToken:   ownerToken,
+		integrityKey: integrityKey,
+		integrityMAC: integrityMAC,
+		contentKey:   contentKey,
+	}, true
+}
+
+// saveSecret starts the secret's lifetime now and stores it. On failure the
+// response has been written and ok is false.
+func (h *Handler) saveSecret(w http.ResponseWriter, r *http.Request, p *preparedSecret) (appliedTTL time.Duration, ok bool) {
+	secret := p.secret
+	secret.CreatedAt = time.Now()
+	secret.ExpiresAt = secret.CreatedAt.Add(p.ttl)
+
+	var err error
+	if h.config.Secrets.MaxTotal > 0 {
+		err = h.store.SaveWithinQuota(r.Context(), secret, h.config.Secrets.MaxTotal)
+		appliedTTL = time.Until(secret.ExpiresAt)
+	} else {
+		appliedTTL, err = h.store.SaveReturningTTL(r.Context(), secret)
+	}
+	if errors.Is(err, store.ErrQuotaExceeded) {
+		h.error(w, r, http.StatusServiceUnavailable, "secret storage is full, try again later")
+		return 0, false
+	}
+	if errors.Is(err, store.ErrUnavailable) {
+		h.handleStoreError(w, r, err)
+		return 0, false
+	}
+	if err != nil {
+		h.error(w, r, http.StatusInternalServerError, "failed to save secret")
+		return 0, false
+	}
+	if h.misses != nil {
+		h.misses.remove(secret.ID)
+	}
+	if h.recent != nil {
+		h.recent.add(p.contentKey)
+	}
+	h.emit(hooks.EventCreate, secret.ID)
+	return appliedTTL, true
 }
 
 func (h *Handler) RevealSecret(w http.ResponseWriter, r *http.Request) {
-	id := chi.URLParam(r, "id")
-	passphrase := r.URL.Query().Get("passphrase")
+	secret, content, currentViews, ok := h.reveal(w, r, false)
+	if !ok {
+		return
+	}
 
-	if passphrase == "" {
-		h.error(w, http.StatusBadRequest, "passphrase is required")
+	// Files download as themselves unless the caller asks for JSON.
+	if secret.Filename != "" && r.URL.Query().Get("format") != "json" {
+		h.serveFileSecret(w, r, secret, content, currentViews)
 		return
 	}
 
-	secret, err := h.store.Get(r.Context(), id)
+	resp := RevealResponse{
+		Content:   string(content),
+		Burned:    currentViews >= secret.MaxViews,
+		NextNonce: nextRevealNonce(secret, currentViews),
+		CanBurn:   currentViews < secret.MaxViews && canBurn(secret),
+	}
+	if secret.ClientEncrypted {
+		resp.Content = base64.StdEncoding.EncodeToString(content)
+		resp.ClientEncrypted = true
+	}
+	if secret.Filename != "" {
+		resp.Content = base64.StdEncoding.EncodeToString(content)
+		resp.Filename = secret.Filename
+		resp.ContentType = secret.ContentType
+	}
+	if !secret.HideViews {
+		remaining := secret.MaxViews - currentViews
+		resp.ViewsRemaining = &remaining
+	}
+	h.json(w, http.StatusOK, resp)
+}
+
+// nextRevealNonce is the nonce for the view after currentViews, or empty
+// when the secret has no nonce or no views left.
+func nextRevealNonce(secret *models.Secret, currentViews int) string {
+	if !secret.RequireNonce || currentViews >= secret.MaxViews {
+		return ""
+	}
+	return revealNonce(secret, currentViews)
+}
+
+// reveal runs the checks shared by every way of reading a secret, uses one
+// view and returns the decrypted content. archive says which endpoint the
+// caller is, so a secret read through the wrong one fails before a view is
+// used. On failure the response has been written and ok is false.
+func (h *Handler) reveal(w http.ResponseWriter, r *http.Request, archive bool) (secret *models.Secret, content []byte, currentViews int, ok bool) {
+	id := chi.URLParam(r, "id")
+	// Lets a store with a grace window serve a retry of this same request
+	// after the last view was consumed.
+	ctx := store.WithRetryToken(r.Context(), GetRequestID(r))
+
+	// Content must not outlive the response in any cache, error or not.
+	w.Header().Set("Cache-Control", "no-store, private")
+	defer h.metrics.observeReveal(time.Now())
+
+	keyMaterial := hasKeyMaterial(r) || signedLink(r)
+	if h.decoys.match(id) {
+		if !keyMaterial {
+			h.error(w, r, http.StatusBadRequest, "passphrase is required")
+			return nil, nil, 0, false
+		}
+		if archive {
+			h.error(w, r, http.StatusBadRequest, "secret is not an archive")
+			return nil, nil, 0, false
+		}
+		return h.revealDecoy(r, id)
+	}
+
+	secret, err := h.lookup(ctx, id)
+	if err != nil {
+		if errors.Is(err, store.ErrExpired) {
+			h.emit(hooks.EventExpire, id)
+		}
+		h.handleStoreError(w, r, err)
+		return nil, nil, 0, false
+	}
+	id = secret.ID
+
+	// Client-encrypted secrets have no key on the server to ask for.
+	if !keyMaterial && !secret.ClientEncrypted {
+		h.error(w, r, http.StatusBadRequest, "passphrase is required")
+		return nil, nil, 0, false
+	}
+
+	if secret.Archive != archive {
+		if secret.Archive {
+			h.error(w, r, http.StatusBadRequest, "secret is an archive, download it from /archive")
+		} else {
+			h.error(w, r, http.StatusBadRequest, "secret is not an archive")
+		}
+		return nil, nil, 0, false
+	}
+
+	if !h.allowReveal(w, r, id) {
+		return nil, nil, 0, false
+	}
+
+	if !h.allowCountry(w, r, secret) {
+		return nil, nil, 0, false
+	}
+
+	passphrase, ok := h.resolvePassphrase(w, r, secret)
+	if !ok {
+		return nil, nil, 0, false
+	}
+
+	// No ack, no content and no view used.
+	if secret.RequireAck && !validAckToken(secret, r.URL.Query().Get("ack")) {
+		h.error(w, r, http.StatusForbidden, "acknowledgment required")
+		return nil, nil, 0, false
+	}
+
+	if secret.RequireNonce && !validRevealNonce(secret, r.URL.Query().Get("nonce")) {
+		h.error(w, r, http.StatusForbidden, "nonce is invalid or already used")
+		return nil, nil, 0, false
+	}
+
+	if secret.HasPIN && !validPIN(r.URL.Query().Get("pin")) {
+		h.error(w, r, http.StatusBadRequest, "pin is required")
+		return nil, nil, 0, false
+	}
+
+	if len(secret.EncryptedContact) > 0 && !h.checkRevealCode(w, r, secret) {
+		return nil, nil, 0, false
+	}
+
+	if !hasPassphraseCheck(secret) || secret.HasPIN {
+		// Split and migrated secrets have nothing to compare the passphrase
+		// against and a PIN can only be checked by opening the inner layer,
+		// so these are verified by decrypting before a view is used.
+		if content, ok = h.openContent(w, r, secret, passphrase); !ok {
+			return nil, nil, 0, false
+		}
+	}
+
+	viewsBefore := secret.CurrentViews
+	currentViews, maxViews, err := h.consumeView(ctx, id, secret)
+	if err != nil {
+		h.handleStoreError(w, r, err)
+		return nil, nil, 0, false
+	}
+	if maxViews != secret.MaxViews {
+		// secret was read before the view was used; the store's count is
+		// the one views remaining and burning are worked out against.
+		updated := *secret
+		updated.MaxViews = maxViews
+		secret = &updated
+	}
+	h.emit(hooks.EventReveal, id)
+
+	// Two requests with the same nonce can both pass the check above; only
+	// the one whose view immediately follows the nonce's gets the content.
+	if secret.RequireNonce && currentViews != viewsBefore+1 {
+		h.error(w, r, http.StatusForbidden, "nonce is invalid or already used")
+		return nil, nil, 0, false
+	}
+
+	if currentViews >= secret.MaxViews {
+		h.metrics.burn()
+	}
+	if currentViews >= secret.MaxViews && h.config.Secrets.TombstoneTTL > 0 {
+		if err := h.store.SaveTombstone(r.Context(), id, h.config.Secrets.TombstoneTTL); err != nil {
+			slog.WarnContext(r.Context(), "failed to save tombstone", "error", err)
+		}
+	}
+
+	if content == nil {
+		if content, ok = h.openContent(w, r, secret, passphrase); !ok {
+			return nil, nil, 0, false
+		}
+	}
+
+	h.recordAccess(r, secret, currentViews)
+
+	if _, err := h.store.IncrementRevealCount(r.Context()); err != nil {
+		slog.WarnContext(r.Context(), "failed to increment reveal counter", "error", err)
+	}
+
+	return secret, content, currentViews, true
+}
+
+// consumeView uses one view of secret. A single-view secret is fetched and
+// deleted in one step, so no crash or race between a read and a delete can
+// leave it readable twice.
+func (h *Handler) consumeView(ctx context.Context, id string, secret *models.Secret) (currentViews, maxViews int, err error) {
+	if secret.MaxViews != 1 {
+		return h.store.IncrementViews(ctx, id)
+	}
+	consumed, err := h.store.GetAndDelete(ctx, id)
+	if err != nil {
+		return 0, 0, err
+	}
+	return consumed.CurrentViews + 1, consumed.MaxViews, nil
+}
+
+// allowReveal applies the per-secret throttle, so one hot link can't
+// dominate store traffic whatever the per-IP limits allow.
+func (h *Handler) allowReveal(w http.ResponseWriter, r *http.Request, id string) bool {
+	rate := h.config.RateLimit.PerSecretRate
+	if rate <= 0 {
+		return true
+	}
+
+	ok, wait, err := h.store.AllowReveal(r.Context(), id, rate, h.config.RateLimit.PerSecretBurst)
+	if err != nil {
+		// Fail open: throttling protects the store, it is not access control.
+		slog.WarnContext(r.Context(), "per-secret throttle failed", "error", err)
+		return true
+	}
+	if !ok {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		h.error(w, r, http.StatusTooManyRequests, "too many reveals of this secret, try again later")
+		return false
+	}
+	return true
+}
+
+// openContent decrypts the secret's content, including the inner PIN layer
+// if it has one. A wrong PIN counts towards the burn limit. Client-encrypted
+// content is returned as stored.
+func (h *Handler) openContent(w http.ResponseWriter, r *http.Request, secret *models.Secret, passphrase string) ([]byte, bool) {
+	if secret.ClientEncrypted {
+		return secret.EncryptedData, true
+	}
+	content, err := h.cryptoOps.DecryptWithContext(r.Context(), secret.EncryptedData, passphrase, []byte(secret.Context))
+	if err != nil {
+		if secret.Threshold > 0 {
+			h.cryptoError(w, r, err, http.StatusForbidden, "invalid shares")
+		} else if secret.UserPassphrase && !isBusy(err) {
+			// A chosen passphrase may be guessable; wrong ones count
+			// like wrong PINs.
+			h.attemptFailure(w, r, secret, "passphrase")
+		} else if !hasPassphraseCheck(secret) {
+			h.cryptoError(w, r, err, http.StatusForbidden, "invalid passphrase")
+		} else {
+			h.cryptoError(w, r, err, http.StatusInternalServerError, "decryption failed")
+		}
+		return nil, false
+	}
+	if !secret.HasPIN {
+		return content, true
+	}
+
+	content, err = h.cryptoOps.Decrypt(r.Context(), content, pinKey(secret.ID, r.URL.Query().Get("pin")))
+	if err != nil {
+		if isBusy(err) {
+			h.cryptoError(w, r, err, http.StatusInternalServerError, "decryption failed")
+		} else {
+			h.attemptFailure(w, r, secret, "pin")
+		}
+		return nil, false
+	}
+	return content, true
+}
+
+// attemptFailure counts a wrong PIN, reveal code or chosen passphrase and
+// burns the secret once max_pin_attempts is hit, which is what makes a
+// short PIN or code safe against guessing. All three share one counter.
+// what names the failed factor in the response.
+func (h *Handler) attemptFailure(w http.ResponseWriter, r *http.Request, secret *models.Secret, what string) {
+	failures, err := h.store.IncrementPINFailures(r.Context(), secret.ID)
 	if err != nil {
-		h.handleStoreError(w, err)
+		h.handleStoreError(w, r, err)
 		return
 	}
 
-	if passphrase != secret.Passphrase {
-		h.error(w, http.StatusForbidden, "invalid passphrase")
+	if failures >= h.config.Secrets.MaxPINAttempts {
+		if err := h.store.Delete(r.Context(), secret.ID); err != nil {
+			h.handleStoreError(w, r, err)
+			return
+		}
+		slog.WarnContext(r.Context(), "secret burned after wrong "+what+"s",
+			"failures", failures,
+			"ip", getClientIP(r),
+		)
+		h.error(w, r, http.StatusGone, "secret burned after too many wrong "+what+"s")
 		return
 	}
 
-	currentViews, err := h.store.IncrementViews(r.Context(), id)
+	h.error(w, r, http.StatusForbidden, "invalid "+what)
+}
+
+// PeekNote returns the sender's note for the recipient without consuming a
+// view. The note is encrypted like the content, so the key is still needed.
+func (h *Handler) PeekNote(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	if !hasKeyMaterial(r) {
+		h.error(w, r, http.StatusBadRequest, "passphrase is required")
+		return
+	}
+
+	secret, err := h.lookup(r.Context(), id)
 	if err != nil {
-		h.handleStoreError(w, err)
+		h.handleStoreError(w, r, err)
+		return
+	}
+
+	if !h.allowCountry(w, r, secret) {
+		return
+	}
+
+	passphrase, ok := h.resolvePassphrase(w, r, secret)
+	if !ok {
 		return
 	}
 
-	content, err := crypto.Decrypt(secret.EncryptedData, passphrase)
+	if len(secret.EncryptedNote) == 0 {
+		h.error(w, r, http.StatusNotFound, "secret has no note")
+		return
+	}
+
+	note, err := h.cryptoOps.DecryptWithContext(r.Context(), secret.EncryptedNote, passphrase, []byte(secret.Context))
 	if err != nil {
-		h.error(w, http.StatusInternalServerError, "decryption failed")
+		if secret.Threshold > 0 {
+			h.cryptoError(w, r, err, http.StatusForbidden, "invalid shares")
+		} else {
+			h.cryptoError(w, r, err, http.StatusInternalServerError, "decryption failed")
+		}
 		return
 	}
 
-	h.json(w, http.StatusOK, RevealResponse{
-		Content:        string(content),
-		ViewsRemaining: secret.MaxViews - currentViews,
-	})
+	h.json(w, http.StatusOK, NoteResponse{Note: string(note)})
+}
+
+func hasKeyMaterial(r *http.Request) bool {
+	q := r.URL.Query()
+	return q.Get("passphrase") != "" || len(q["share"]) > 0
+}
+
+// resolvePassphrase works out the decryption passphrase for secret from the
+// request, writing an error response and returning false if it cannot. For
+// split secrets the result is only verified once something is decrypted.
+// Client-encrypted secrets have no passphrase, only their optional auth.
+func (h *Handler) resolvePassphrase(w http.ResponseWriter, r *http.Request, secret *models.Secret) (string, bool) {
+	if secret.ClientEncrypted {
+		if len(secret.AuthHash) > 0 && !crypto.VerifyOwnerToken(r.URL.Query().Get("auth"), secret.AuthHash) {
+			h.error(w, r, http.StatusForbidden, "invalid auth")
+			return "", false
+		}
+		return "", true
+	}
+	if signedLink(r) {
+		passphrase, err := h.linkKeys.UnwrapPassphrase(secret.ID, secret.WrappedKey)
+		if err != nil {
+			h.error(w, r, http.StatusForbidden, "secret can't be revealed with a signed link")
+			return "", false
+		}
+		return passphrase, true
+	}
+	if secret.Threshold > 0 {
+		shares := r.URL.Query()["share"]
+		if len(shares) < secret.Threshold {
+			h.error(w, r, http.StatusBadRequest, fmt.Sprintf("at least %d shares are required", secret.Threshold))
+			return "", false
+		}
+		passphrase, err := combineShares(shares)
+		if err != nil {
+			h.error(w, r, http.StatusBadRequest, "invalid share")
+			return "", false
+		}
+		return passphrase, true
+	}
+
+	passphrase := h.normalizePassphrase(r.URL.Query().Get("passphrase"))
+	if secret.UserPassphrase {
+		// Only decrypting can tell whether a chosen passphrase is right;
+		// the policy's length cap bounds the KDF work a guess costs.
+		maxLength := h.config.Crypto.PassphrasePolicy.MaxLength
+		if passphrase == "" || (maxLength > 0 && utf8.RuneCountInString(passphrase) > maxLength) {
+			h.error(w, r, http.StatusBadRequest, "malformed passphrase")
+			return "", false
+		}
+	} else if err := crypto.CheckPassphraseFormat(passphrase); err != nil {
+		h.error(w, r, http.StatusBadRequest, "malformed passphrase")
+		return "", false
+	}
+	// Secrets migrated by store.DropPassphrases have nothing to compare
+	// against; reveal checks those by decrypting.
+	valid := true
+	if len(secret.PassphraseHash) > 0 {
+		valid = crypto.VerifyOwnerToken(passphrase, secret.PassphraseHash)
+	} else if secret.Passphrase != "" {
+		valid = subtle.ConstantTimeCompare([]byte(passphrase), []byte(secret.Passphrase)) == 1
+	}
+	if !valid {
+		h.error(w, r, http.StatusForbidden, "invalid passphrase")
+		return "", false
+	}
+	return passphrase, true
+}
+
+// hasPassphraseCheck reports whether secret's passphrase can be checked by
+// comparison: against its hash or, for secrets created before passphrases
+// stopped being stored and not yet migrated, the passphrase itself.
+func hasPassphraseCheck(secret *models.Secret) bool {
+	return len(secret.PassphraseHash) > 0 || secret.Passphrase != ""
 }
 
 func (h *Handler) GetStatus(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 
-	secret, err := h.store.Get(r.Context(), id)
+	var secret *models.Secret
+	var err error
+	if h.decoys.match(id) {
+		secret = h.decoySecret(id)
+	} else {
+		secret, err = h.lookup(r.Context(), id)
+	}
 	if err != nil {
 		status := StatusResponse{ID: id, Exists: false}
 		if errors.Is(err, store.ErrExpired) {
 			status.Expired = true
+			h.emit(hooks.EventExpire, id)
+		} else if h.config.Secrets.TombstoneTTL > 0 {
+			status.Revealed, _ = h.store.HasTombstone(r.Context(), id)
 		}
 		h.json(w, http.StatusOK, status)
 		return
 	}
 
-	h.json(w, http.StatusOK, StatusResponse{
-		ID:             id,
-		Exists:         true,
-		Expired:        false,
-		ViewsRemaining: secret.MaxViews - secret.CurrentViews,
-		ExpiresAt:      secret.ExpiresAt,
-	})
+	status := StatusResponse{
+		ID:           secret.ID,
+		Exists:       true,
+		Expired:      false,
+		ExpiresAt:    secret.ExpiresAt,
+		ExpiresIn:    humanizeDuration(time.Until(secret.ExpiresAt)),
+		PINRequired:  secret.HasPIN,
+		AckRequired:  secret.RequireAck,
+		CodeRequired: len(secret.EncryptedContact) > 0,
+		Archive:      secret.Archive,
+
+		ClientEncrypted: secret.ClientEncrypted,
+		UserPassphrase:  secret.UserPassphrase,
+	}
+	if !secret.HideViews {
+		status.ViewsRemaining = secret.MaxViews - secret.CurrentViews
+	}
+	h.json(w, http.StatusOK, status)
 }
 
 func (h *Handler) Index(w http.ResponseWriter, r *http.Request) {
-	h.serveFile(w, "index.html")
+	w.Header().Set("Content-Security-Policy", indexCSP)
+	h.serveFile(w, "index.html", "text/html; charset=utf-8")
 }
 
+// RevealPage serves reveal.html with a CSP that only allows the SRI-pinned
+// reveal.js to run, since the page holds the passphrase in its URL fragment.
 func (h *Handler) RevealPage(w http.ResponseWriter, r *http.Request) {
-	h.serveFile(w, "reveal.html")
+	if h.revealPage == nil {
+		http.Error(w, "file not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Security-Policy", h.revealCSP)
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.Header().Set("Cache-Control", "no-store, private")
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(h.revealPage)
 }
 
-func (h *Handler) serveFile(w http.ResponseWriter, filename string) {
+func (h *Handler) RevealScript(w http.ResponseWriter, r *http.Request) {
+	h.serveFile(w, "reveal.js", "text/javascript; charset=utf-8")
+}
+
+func (h *Handler) serveFile(w http.ResponseWriter, filename, contentType string) {
 	content, err := web.GetFile(filename)
 	if err != nil {
 		http.Error(w, "file not found", http.StatusNotFound)
 		return
 	}
 
-	contentType := "text/html; charset=utf-8"
 	w.Header().Set("Content-Type", contentType)
 	w.Write(content)
 }
 
+const indexCSP = "default-src 'self'; script-src 'self' 'unsafe-inline'; " +
+	"style-src 'self' 'unsafe-inline'; img-src 'self' data:; connect-src 'self'; frame-ancestors 'none'"
+
+// buildRevealPage fills reveal.js's integrity into reveal.html and returns
+// the page with the matching CSP. A nil page means the assets are missing.
+func buildRevealPage() ([]byte, string) {
+	page, err := web.GetFile("reveal.html")
+	if err != nil {
+		return nil, ""
+	}
+	integrity, err := web.Integrity("reveal.js")
+	if err != nil {
+		return nil, ""
+	}
+
+	page = bytes.ReplaceAll(page, []byte("{{REVEAL_JS_INTEGRITY}}"), []byte(integrity))
+	csp := "default-src 'none'; script-src '" + integrity + "'; style-src 'unsafe-inline'; " +
+		"connect-src 'self'; base-uri 'none'; form-action 'none'; frame-ancestors 'none'"
+	return page, csp
+}
+
 func (h *Handler) json(w http.ResponseWriter, status int, data any) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
 	json.NewEncoder(w).Encode(data)
 }
 
-func (h *Handler) error(w http.ResponseWriter, status int, message string) {
-	h.json(w, status, ErrorResponse{Error: message})
+func (h *Handler) error(w http.ResponseWriter, r *http.Request, status int, message string) {
+	writeError(w, r, status, message)
 }
 
-func (h *Handler) handleStoreError(w http.ResponseWriter, err error) {
+func (h *Handler) handleStoreError(w http.ResponseWriter, r *http.Request, err error) {
 	switch {
 	case errors.Is(err, store.ErrNotFound):
-		h.error(w, http.StatusNotFound, "secret not found")
+		h.error(w, r, http.StatusNotFound, "secret not found")
 	case errors.Is(err, store.ErrExpired):
-		h.error(w, http.StatusGone, "secret has expired")
+		h.error(w, r, http.StatusGone, "secret has expired")
 	case errors.Is(err, store.ErrMaxViews):
-		h.error(w, http.StatusGone, "secret has reached maximum views")
+		h.error(w, r, http.StatusGone, "secret has reached maximum views")
+	case errors.Is(err, store.ErrUnavailable):
+		w.Header().Set("Retry-After", "1")
+		h.error(w, r, http.StatusServiceUnavailable, "storage is temporarily unavailable, try again")
 	default:
-		h.error(w, http.StatusInternalServerError, "internal error")
+		h.error(w, r, http.StatusInternalServerError, "internal error")
+	}
+}
+
+// cryptoError reports a failed encrypt/decrypt. Running out of crypto slots
+// is a load problem rather than a bad request, so it maps to 503 regardless
+// of the status the caller would otherwise use.
+func (h *Handler) cryptoError(w http.ResponseWriter, r *http.Request, err error, status int, message string) {
+	if isBusy(err) {
+		w.Header().Set("Retry-After", "1")
+		h.error(w, r, http.StatusServiceUnavailable, "server is busy, try again")
+		return
 	}
+	h.error(w, r, status, message)
+}
+
+// emit sends an event to the exec hook, if one is configured.
+func (h *Handler) emit(event, id string) {
+	h.metrics.event(event)
+	if h.hook == nil {
+		return
+	}
+	h.hook.Fire(hooks.Event{Name: event, SecretID: id, Time: time.Now()})
+}
+
+func isBusy(err error) bool {
+	return errors.Is(err, crypto.ErrBusy) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// validPIN accepts 4 to 8 ASCII digits.
+func validPIN(pin string) bool {
+	if len(pin) < 4 || len(pin) > 8 {
+		return false
+	}
+	for _, c := range pin {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// pinKey mixes the secret ID into the PIN so the same PIN keys a different
+// inner layer for every secret.
+func pinKey(id, pin string) string {
+	return id + ":" + pin
+}
+
+// normalizePassphrase applies crypto.trim_passphrase. Generated passphrases
+// never contain whitespace, so trimming only matters for pasted input.
+func (h *Handler) normalizePassphrase(passphrase string) string {
+	if h.config.Crypto.TrimPassphrase {
+		return strings.TrimSpace(passphrase)
+	}
+	return passphrase
+}
+
+func combineShares(encoded []string) (string, error) {
+	shares := make([][]byte, len(encoded))
+	for i, e := range encoded {
+		share, err := base64.RawURLEncoding.DecodeString(e)
+		if err != nil {
+			return "", err
+		}
+		shares[i] = share
+	}
+
+	key, err := crypto.CombineKey(shares)
+	if err != nil {
+		return "", err
+	}
+	return string(key), nil
+}
+
+// cryptoProfile resolves the X-Crypto-Profile header. A nil profile means
+// none was requested. Unknown names are rejected rather than ignored, so a
+// client never silently gets weaker settings than it asked for.
+func (h *Handler) cryptoProfile(w http.ResponseWriter, r *http.Request) (*crypto.Profile, bool) {
+	name := r.Header.Get(cryptoProfileHeader)
+	if name == "" {
+		return nil, true
+	}
+	profile, ok := h.profiles[name]
+	if !ok {
+		h.error(w, r, http.StatusBadRequest, fmt.Sprintf("unknown crypto profile %q", name))
+		return nil, false
+	}
+	return &profile, true
+}
+
+// seal encrypts with profile, or with the original scheme when it is nil,
+// padding first if configured. Decryption needs no such choice: padded and
+// profile blobs describe themselves.
+func (h *Handler) seal(ctx context.Context, plaintext []byte, passphrase string, encContext []byte, profile *crypto.Profile) ([]byte, error) {
+	if h.config.Crypto.PadLength > 0 {
+		return h.cryptoOps.EncryptPadded(ctx, plaintext, passphrase, encContext, profile, h.config.Crypto.PadLength)
+	}
+	if profile == nil {
+		return h.cryptoOps.EncryptWithContext(ctx, plaintext, passphrase, encContext)
+	}
+	return h.cryptoOps.EncryptWithProfile(ctx, plaintext, passphrase, encContext, *profile)
+}
+
+// isBlocked reports whether content matches a configured blocked pattern.
+// Callers must not log the content or the pattern that matched it.
+func (h *Handler) isBlocked(content string) bool {
+	for _, re := range h.blocklist {
+		if re.MatchString(content) {
+			return true
+		}
+	}
+	return false
 }
 
 func clamp(val, defaultVal, maxVal int) int {
@@ -241,3 +1419,54 @@ func clampDuration(val, defaultVal, maxVal time.Duration) time.Duration {
 	}
 	return val
 }
+
+// jitterTTL lengthens ttl by a random amount up to maxJitter, but not past
+// maxTTL, so a burst of secrets created alike doesn't all expire at once.
+func jitterTTL(ttl, maxJitter, maxTTL time.Duration) time.Duration {
+	room := min(maxJitter, maxTTL-ttl)
+	if room <= 0 {
+		return ttl
+	}
+	return ttl + rand.N(room+1)
+}
+
+// humanizeDuration renders d as short English text with at most two units,
+// e.g. "59 minutes" or "1 day 2 hours". Partial minutes are dropped after
+// rounding to the second, so a TTL read back a few milliseconds short of an
+// hour still reads "1 hour".
+func humanizeDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	if d < time.Minute {
+		return "less than a minute"
+	}
+
+	units := []struct {
+		name string
+		size time.Duration
+	}{
+		{"day", 24 * time.Hour},
+		{"hour", time.Hour},
+		{"minute", time.Minute},
+	}
+
+	var parts []string
+	for _, u := range units {
+		if len(parts) == 2 {
+			break
+		}
+		n := int(d / u.size)
+		if n == 0 {
+			if len(parts) > 0 {
+				break
+			}
+			continue
+		}
+		d -= time.Duration(n) * u.size
+		if n == 1 {
+			parts = append(parts, "1 "+u.name)
+		} else {
+			parts = append(parts, fmt.Sprintf("%d %ss", n, u.name))
+		}
+	}
+	return strings.Join(parts, " ")
+}
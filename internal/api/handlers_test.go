@@ -0,0 +1,1090 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"secure.share/config"
+	"secure.share/internal/crypto"
+	"secure.share/internal/models"
+	"secure.share/internal/store"
+	"secure.share/web"
+)
+
+func TestHumanizeDuration(t *testing.T) {
+	cases := map[time.Duration]string{
+		30 * time.Second:                "less than a minute",
+		time.Minute:                     "1 minute",
+		59*time.Minute + 59*time.Second: "59 minutes",
+		time.Hour:                       "1 hour",
+		2*time.Hour + 30*time.Minute:    "2 hours 30 minutes",
+		24 * time.Hour:                  "1 day",
+		26*time.Hour + 5*time.Minute:    "1 day 2 hours",
+		48*time.Hour + 5*time.Minute:    "2 days",
+	}
+	for d, want := range cases {
+		if got := humanizeDuration(d); got != want {
+			t.Fatalf("humanizeDuration(%s): got %q, want %q", d, got, want)
+		}
+	}
+}
+
+func TestJitterTTL(t *testing.T) {
+	const maxTTL = 24 * time.Hour
+	seen := make(map[time.Duration]bool)
+	for range 1000 {
+		got := jitterTTL(time.Hour, time.Minute, maxTTL)
+		if got < time.Hour || got > time.Hour+time.Minute {
+			t.Fatalf("jittered ttl %v outside [1h, 1h1m]", got)
+		}
+		seen[got] = true
+	}
+	if len(seen) < 2 {
+		t.Fatal("jitter never varied the ttl")
+	}
+
+	for range 1000 {
+		if got := jitterTTL(maxTTL-time.Second, time.Minute, maxTTL); got > maxTTL {
+			t.Fatalf("jittered ttl %v exceeds max %v", got, maxTTL)
+		}
+	}
+	if got := jitterTTL(maxTTL, time.Minute, maxTTL); got != maxTTL {
+		t.Fatalf("ttl at max was jittered to %v", got)
+	}
+	if got := jitterTTL(time.Hour, 0, maxTTL); got != time.Hour {
+		t.Fatalf("ttl jittered to %v with jitter off", got)
+	}
+}
+
+func TestCreateTTLJitter(t *testing.T) {
+	cfg := config.Default()
+	cfg.Secrets.TTLJitter = 10 * time.Minute
+	cfg.Secrets.MaxTTL = 2 * time.Hour
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	router := SetupRouter(st, cfg)
+
+	before := time.Now()
+	for _, minutes := range []int{60, 115, 120} {
+		created, _ := createSecret(t, router, fmt.Sprintf(`{"content": "s3cret", "ttl_minutes": %d}`, minutes))
+		ttl := time.Duration(minutes) * time.Minute
+		earliest := before.Add(ttl)
+		latest := time.Now().Add(min(ttl+cfg.Secrets.TTLJitter, cfg.Secrets.MaxTTL))
+		if created.ExpiresAt.Before(earliest) || created.ExpiresAt.After(latest) {
+			t.Fatalf("ttl %v: expires at %v, want within [%v, %v]", ttl, created.ExpiresAt, earliest, latest)
+		}
+	}
+}
+
+func TestCreateSecretBlocklist(t *testing.T) {
+	cfg := config.Default()
+	cfg.Secrets.BlockedPatterns = []string{`(?i)x5o!p%@ap\[4\\pzx54\(p\^\)7cc\)7\}\$eicar`}
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	h := NewHandler(st, cfg)
+
+	body := `{"content": "X5O!P%@AP[4\\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*"}`
+	rec := httptest.NewRecorder()
+	h.CreateSecret(rec, httptest.NewRequest(http.MethodPost, "/api/secrets", strings.NewReader(body)))
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status mismatch: got %d, want %d", rec.Code, http.StatusUnprocessableEntity)
+	}
+
+	rec = httptest.NewRecorder()
+	h.CreateSecret(rec, httptest.NewRequest(http.MethodPost, "/api/secrets", strings.NewReader(`{"content": "hunter2"}`)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status mismatch: got %d, want %d", rec.Code, http.StatusCreated)
+	}
+}
+
+func TestRevealPageCSP(t *testing.T) {
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	router := SetupRouter(st, config.Default())
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	indexCSP := rec.Header().Get("Content-Security-Policy")
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/s/abc", nil))
+	revealCSP := rec.Header().Get("Content-Security-Policy")
+
+	if indexCSP == "" || revealCSP == "" {
+		t.Fatalf("both pages must send a CSP: index=%q reveal=%q", indexCSP, revealCSP)
+	}
+	if !strings.Contains(indexCSP, "'unsafe-inline'") {
+		t.Fatalf("index CSP expected to allow its inline script: %q", indexCSP)
+	}
+	if strings.Contains(revealCSP, "script-src 'self'") || strings.Contains(revealCSP, "script-src 'unsafe-inline'") {
+		t.Fatalf("reveal CSP must only allow the hashed script: %q", revealCSP)
+	}
+	if !strings.Contains(revealCSP, "default-src 'none'") || !strings.Contains(revealCSP, "script-src 'sha384-") {
+		t.Fatalf("reveal CSP is not strict enough: %q", revealCSP)
+	}
+
+	integrity, err := web.Integrity("reveal.js")
+	if err != nil {
+		t.Fatalf("failed to hash reveal.js: %v", err)
+	}
+	if !strings.Contains(rec.Body.String(), `integrity="`+integrity+`"`) {
+		t.Fatalf("reveal page does not pin reveal.js integrity %s", integrity)
+	}
+	if strings.Contains(rec.Body.String(), "onclick=") {
+		t.Fatalf("reveal page must not contain inline event handlers")
+	}
+}
+
+// createSecret posts body to the create endpoint and returns the response
+// together with the passphrase taken from the URL fragment.
+func createSecret(t *testing.T, router http.Handler, body string) (CreateResponse, string) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/api/secrets", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create failed: got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp CreateResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode create response: %v", err)
+	}
+	_, passphrase, _ := strings.Cut(resp.URL, "#")
+	return resp, passphrase
+}
+
+func revealSecret(router http.Handler, id, passphrase string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/secrets/"+id+"?passphrase="+url.QueryEscape(passphrase), nil)
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestStatsCountsReveals(t *testing.T) {
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	router := SetupRouter(st, statsConfig())
+
+	for i := 0; i < 2; i++ {
+		created, passphrase := createSecret(t, router, `{"content": "s3cret"}`)
+		if rec := revealSecret(router, created.ID, passphrase); rec.Code != http.StatusOK {
+			t.Fatalf("reveal failed: got %d: %s", rec.Code, rec.Body.String())
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/stats", nil))
+	var stats StatsResponse
+	if err := json.NewDecoder(rec.Body).Decode(&stats); err != nil {
+		t.Fatalf("failed to decode stats: %v", err)
+	}
+	if stats.TotalReveals != 2 {
+		t.Fatalf("total reveals mismatch: got %d, want %d", stats.TotalReveals, 2)
+	}
+}
+
+func TestRevealWithShares(t *testing.T) {
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	router := SetupRouter(st, config.Default())
+
+	created, _ := createSecret(t, router, `{"content": "launch codes", "shares": 3, "threshold": 2}`)
+	if len(created.ShareURLs) != 3 || strings.Contains(created.URL, "#") {
+		t.Fatalf("expected 3 share links and no fragment on url: %+v", created)
+	}
+
+	saved, err := st.Get(context.Background(), created.ID)
+	if err != nil {
+		t.Fatalf("failed to get secret: %v", err)
+	}
+	if saved.Passphrase != "" {
+		t.Fatalf("split secret must not store its passphrase")
+	}
+
+	share := func(i int) string {
+		_, fragment, _ := strings.Cut(created.ShareURLs[i], "#")
+		return fragment
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/secrets/"+created.ID+"?share="+share(0), nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("single share status mismatch: got %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/secrets/"+created.ID+"?share="+share(0)+"&share="+share(2), nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("reveal with 2 shares failed: got %d: %s", rec.Code, rec.Body.String())
+	}
+	var revealed RevealResponse
+	if err := json.NewDecoder(rec.Body).Decode(&revealed); err != nil {
+		t.Fatalf("failed to decode reveal response: %v", err)
+	}
+	if revealed.Content != "launch codes" {
+		t.Fatalf("content mismatch: got %q", revealed.Content)
+	}
+}
+
+func TestCreateMaxLinks(t *testing.T) {
+	cfg := config.Default()
+	cfg.Secrets.MaxLinks = 4
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	router := SetupRouter(st, cfg)
+
+	created, _ := createSecret(t, router, `{"content": "launch codes", "shares": 4, "threshold": 2}`)
+	if len(created.ShareURLs) != 4 {
+		t.Fatalf("expected 4 share links, got %d", len(created.ShareURLs))
+	}
+
+	rec := postJSON(router, "/api/secrets", `{"content": "launch codes", "shares": 5, "threshold": 2}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("shares over max_links: got %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestRevealTrimPassphrase(t *testing.T) {
+	for _, trim := range []bool{true, false} {
+		cfg := config.Default()
+		cfg.Crypto.TrimPassphrase = trim
+		st := store.NewMemoryStore(time.Minute)
+		router := SetupRouter(st, cfg)
+
+		created, passphrase := createSecret(t, router, `{"content": "s3cret"}`)
+		rec := revealSecret(router, created.ID, " \t"+passphrase+"\n ")
+
+		// Untrimmed whitespace makes the passphrase malformed, not just wrong.
+		want := http.StatusBadRequest
+		if trim {
+			want = http.StatusOK
+		}
+		if rec.Code != want {
+			t.Fatalf("trim=%v: status mismatch: got %d, want %d", trim, rec.Code, want)
+		}
+		st.Close()
+	}
+}
+
+func TestRevealMalformedVersusWrongPassphrase(t *testing.T) {
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	router := SetupRouter(st, config.Default())
+
+	created, passphrase := createSecret(t, router, `{"content": "s3cret"}`)
+
+	if rec := revealSecret(router, created.ID, passphrase[:len(passphrase)-3]+"!!!"); rec.Code != http.StatusBadRequest {
+		t.Fatalf("malformed passphrase: got %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if rec := revealSecret(router, created.ID, crypto.GeneratePassphrase()); rec.Code != http.StatusForbidden {
+		t.Fatalf("wrong passphrase: got %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	if rec := revealSecret(router, created.ID, passphrase); rec.Code != http.StatusOK {
+		t.Fatalf("correct passphrase: got %d, want %d", rec.Code, http.StatusOK)
+	}
+}
+
+func TestPeekNote(t *testing.T) {
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	router := SetupRouter(st, config.Default())
+
+	created, passphrase := createSecret(t, router, `{"content": "s3cret", "note": "staging DB password"}`)
+
+	peek := func(query string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/secrets/"+created.ID+"/note"+query, nil))
+		return rec
+	}
+
+	if rec := peek(""); rec.Code != http.StatusBadRequest {
+		t.Fatalf("peek without passphrase: got %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if rec := peek("?passphrase=" + crypto.GeneratePassphrase()); rec.Code != http.StatusForbidden {
+		t.Fatalf("peek with wrong passphrase: got %d, want %d", rec.Code, http.StatusForbidden)
+	}
+
+	for i := 0; i < 2; i++ {
+		rec := peek("?passphrase=" + url.QueryEscape(passphrase))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("peek failed: got %d: %s", rec.Code, rec.Body.String())
+		}
+		var note NoteResponse
+		if err := json.NewDecoder(rec.Body).Decode(&note); err != nil {
+			t.Fatalf("failed to decode note: %v", err)
+		}
+		if note.Note != "staging DB password" {
+			t.Fatalf("note mismatch: got %q", note.Note)
+		}
+	}
+
+	// The default single view must still be available after peeking.
+	if rec := revealSecret(router, created.ID, passphrase); rec.Code != http.StatusOK {
+		t.Fatalf("reveal after peek failed: got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestStatusShowsTombstone(t *testing.T) {
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	cfg := config.Default()
+	cfg.Secrets.TombstoneTTL = time.Minute
+	router := SetupRouter(st, cfg)
+
+	getStatus := func(id string) StatusResponse {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/secrets/"+id+"/status", nil))
+		var status StatusResponse
+		if err := json.NewDecoder(rec.Body).Decode(&status); err != nil {
+			t.Fatalf("failed to decode status: %v", err)
+		}
+		return status
+	}
+
+	created, passphrase := createSecret(t, router, `{"content": "s3cret", "max_views": 1}`)
+	if rec := revealSecret(router, created.ID, passphrase); rec.Code != http.StatusOK {
+		t.Fatalf("reveal failed: got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	if status := getStatus(created.ID); status.Exists || !status.Revealed {
+		t.Fatalf("expected revealed tombstone, got %+v", status)
+	}
+	if status := getStatus("never-existed"); status.Exists || status.Revealed {
+		t.Fatalf("expected plain not-found, got %+v", status)
+	}
+}
+
+func revealWithPIN(router http.Handler, id, passphrase, pin string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/secrets/"+id+"?passphrase="+url.QueryEscape(passphrase)+"&pin="+pin, nil)
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRevealWithPIN(t *testing.T) {
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	cfg := config.Default()
+	cfg.Secrets.MaxPINAttempts = 3
+	router := SetupRouter(st, cfg)
+
+	created, passphrase := createSecret(t, router, `{"content": "s3cret", "pin": "4821", "max_views": 2}`)
+
+	if rec := revealSecret(router, created.ID, passphrase); rec.Code != http.StatusBadRequest {
+		t.Fatalf("reveal without pin: got %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if rec := revealWithPIN(router, created.ID, passphrase, "0000"); rec.Code != http.StatusForbidden {
+		t.Fatalf("reveal with wrong pin: got %d, want %d", rec.Code, http.StatusForbidden)
+	}
+
+	rec := revealWithPIN(router, created.ID, passphrase, "4821")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("reveal with pin failed: got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp RevealResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode reveal response: %v", err)
+	}
+	if resp.Content != "s3cret" || resp.ViewsRemaining == nil || *resp.ViewsRemaining != 1 {
+		t.Fatalf("reveal mismatch: got %+v", resp)
+	}
+}
+
+// TestConcurrentRevealsCountDown fires max_views reveals at once: each must
+// report a different number of views remaining, and the one after them
+// must find the secret gone.
+func TestConcurrentRevealsCountDown(t *testing.T) {
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	cfg := config.Default()
+	cfg.RateLimit.Enabled = false
+	router := SetupRouter(st, cfg)
+
+	const views = 8
+	created, passphrase := createSecret(t, router, fmt.Sprintf(`{"content": "s3cret", "max_views": %d}`, views))
+
+	remaining := make(chan int, views)
+	var wg sync.WaitGroup
+	for i := 0; i < views; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rec := revealSecret(router, created.ID, passphrase)
+			if rec.Code != http.StatusOK {
+				t.Errorf("reveal failed: got %d: %s", rec.Code, rec.Body.String())
+				return
+			}
+			var resp RevealResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil || resp.ViewsRemaining == nil {
+				t.Errorf("reveal response without views remaining: %v", err)
+				return
+			}
+			remaining <- *resp.ViewsRemaining
+		}()
+	}
+	wg.Wait()
+	close(remaining)
+
+	seen := make(map[int]bool)
+	for n := range remaining {
+		if seen[n] {
+			t.Errorf("two reveals reported %d views remaining", n)
+		}
+		seen[n] = true
+	}
+	for n := 0; n < views; n++ {
+		if !seen[n] {
+			t.Errorf("no reveal reported %d views remaining", n)
+		}
+	}
+
+	if rec := revealSecret(router, created.ID, passphrase); rec.Code == http.StatusOK {
+		t.Fatalf("reveal past max views succeeded: %s", rec.Body.String())
+	}
+}
+
+func TestRevealPINBurnsAfterMaxAttempts(t *testing.T) {
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	cfg := config.Default()
+	cfg.Secrets.MaxPINAttempts = 3
+	router := SetupRouter(st, cfg)
+
+	created, passphrase := createSecret(t, router, `{"content": "s3cret", "pin": "4821"}`)
+
+	for i := 1; i < 3; i++ {
+		if rec := revealWithPIN(router, created.ID, passphrase, "1111"); rec.Code != http.StatusForbidden {
+			t.Fatalf("wrong pin %d: got %d, want %d", i, rec.Code, http.StatusForbidden)
+		}
+	}
+	if rec := revealWithPIN(router, created.ID, passphrase, "1111"); rec.Code != http.StatusGone {
+		t.Fatalf("final wrong pin: got %d, want %d", rec.Code, http.StatusGone)
+	}
+	if rec := revealWithPIN(router, created.ID, passphrase, "4821"); rec.Code != http.StatusNotFound {
+		t.Fatalf("reveal after burn: got %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestRevealRetryWithinGracePeriod(t *testing.T) {
+	st := store.NewMemoryStoreWithGrace(time.Minute, time.Minute)
+	defer st.Close()
+	router := SetupRouter(st, config.Default())
+
+	created, passphrase := createSecret(t, router, `{"content": "s3cret", "max_views": 1}`)
+
+	reveal := func(requestID string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/secrets/"+created.ID+"?passphrase="+url.QueryEscape(passphrase), nil)
+		req.Header.Set("X-Request-ID", requestID)
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := reveal("retry-1"); rec.Code != http.StatusOK {
+		t.Fatalf("first reveal failed: got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec := reveal("retry-1")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("retry with same request id failed: got %d: %s", rec.Code, rec.Body.String())
+	}
+	if !strings.Contains(rec.Body.String(), "s3cret") {
+		t.Fatalf("retry did not return content: %s", rec.Body.String())
+	}
+	if rec := reveal("someone-else"); rec.Code != http.StatusNotFound {
+		t.Fatalf("reveal by another request: got %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestRevealFailsWhenContextAltered(t *testing.T) {
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	router := SetupRouter(st, config.Default())
+
+	created, passphrase := createSecret(t, router, `{"content": "s3cret", "context": "billing", "max_views": 2}`)
+	if rec := revealSecret(router, created.ID, passphrase); rec.Code != http.StatusOK {
+		t.Fatalf("reveal with intact context failed: got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	secret, err := st.Get(t.Context(), created.ID)
+	if err != nil {
+		t.Fatalf("failed to load secret: %v", err)
+	}
+	secret.Context = "payroll"
+
+	rec := revealSecret(router, created.ID, passphrase)
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("reveal with altered context: got %d, want %d", rec.Code, http.StatusInternalServerError)
+	}
+	if strings.Contains(rec.Body.String(), "s3cret") {
+		t.Fatalf("altered context still revealed content: %s", rec.Body.String())
+	}
+}
+
+func TestLegacyIDResolves(t *testing.T) {
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	router := SetupRouter(st, config.Default())
+
+	// A secret stored under the current URL-safe ID, reached through an old
+	// link that used standard base64 with padding.
+	secret := &models.Secret{
+		ID:            "ab-cd_ef",
+		EncryptedData: []byte("x"),
+		MaxViews:      1,
+		ExpiresAt:     time.Now().Add(time.Hour),
+		CreatedAt:     time.Now(),
+	}
+	if err := st.Save(t.Context(), secret); err != nil {
+		t.Fatalf("failed to save secret: %v", err)
+	}
+
+	created, _ := createSecret(t, router, `{"content": "s3cret"}`)
+
+	for _, id := range []string{created.ID, "ab+cd_ef==", "ab-cd_ef="} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/secrets/"+id+"/status", nil))
+		var status StatusResponse
+		if err := json.NewDecoder(rec.Body).Decode(&status); err != nil {
+			t.Fatalf("failed to decode status: %v", err)
+		}
+		if !status.Exists {
+			t.Fatalf("id %q did not resolve", id)
+		}
+	}
+
+	cfg := config.Default()
+	cfg.Crypto.LegacyIDFormats = nil
+	strict := SetupRouter(st, cfg)
+	rec := httptest.NewRecorder()
+	strict.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/secrets/ab+cd_ef==/status", nil))
+	if strings.Contains(rec.Body.String(), `"exists":true`) {
+		t.Fatalf("legacy id resolved with fallbacks disabled: %s", rec.Body.String())
+	}
+}
+
+func TestRevealThrottledPerSecret(t *testing.T) {
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	cfg := config.Default()
+	cfg.RateLimit.Enabled = false
+	cfg.RateLimit.PerSecretRate = 0.5
+	cfg.RateLimit.PerSecretBurst = 2
+	router := SetupRouter(st, cfg)
+
+	hot, hotPassphrase := createSecret(t, router, `{"content": "hot", "max_views": 10}`)
+	cold, coldPassphrase := createSecret(t, router, `{"content": "cold", "max_views": 10}`)
+
+	for i := 0; i < 2; i++ {
+		if rec := revealSecret(router, hot.ID, hotPassphrase); rec.Code != http.StatusOK {
+			t.Fatalf("reveal %d within burst: got %d: %s", i, rec.Code, rec.Body.String())
+		}
+	}
+	rec := revealSecret(router, hot.ID, hotPassphrase)
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("reveal beyond burst: got %d, want %d", rec.Code, http.StatusTooManyRequests)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "2" {
+		t.Fatalf("Retry-After mismatch: got %q, want %q", got, "2")
+	}
+
+	if rec := revealSecret(router, cold.ID, coldPassphrase); rec.Code != http.StatusOK {
+		t.Fatalf("other secret throttled: got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestExecHookReceivesEvents(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "events.log")
+	script := filepath.Join(dir, "hook.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\necho \"$SECRET_EVENT\" >> "+out+"\n"), 0o755); err != nil {
+		t.Fatalf("failed to write hook script: %v", err)
+	}
+
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	cfg := config.Default()
+	cfg.Hooks.ExecCommand = script
+	cfg.Hooks.ExecMaxConcurrent = 1
+	router := SetupRouter(st, cfg)
+
+	created, passphrase := createSecret(t, router, `{"content": "s3cret"}`)
+	waitForLines(t, out, 1)
+	if rec := revealSecret(router, created.ID, passphrase); rec.Code != http.StatusOK {
+		t.Fatalf("reveal failed: got %d: %s", rec.Code, rec.Body.String())
+	}
+	lines := waitForLines(t, out, 2)
+
+	if lines[0] != "create" || lines[1] != "reveal" {
+		t.Fatalf("hook events mismatch: got %q", lines)
+	}
+}
+
+func waitForLines(t *testing.T, path string, n int) []string {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		data, _ := os.ReadFile(path)
+		lines := strings.Fields(string(data))
+		if len(lines) >= n {
+			return lines
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %d hook events, got %q", n, lines)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRevealNonceRotates(t *testing.T) {
+	cfg := config.Default()
+	cfg.Secrets.RevealNonces = true
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	router := SetupRouter(st, cfg)
+
+	created, passphrase := createSecret(t, router, `{"content": "s3cret", "max_views": 3}`)
+	u, err := url.Parse(created.URL)
+	if err != nil {
+		t.Fatalf("invalid url %q: %v", created.URL, err)
+	}
+	nonce := u.Query().Get("n")
+	if nonce == "" {
+		t.Fatalf("create url has no nonce: %s", created.URL)
+	}
+
+	reveal := func(nonce string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/secrets/"+created.ID+"?passphrase="+url.QueryEscape(passphrase)+"&nonce="+nonce, nil)
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := revealSecret(router, created.ID, passphrase); rec.Code != http.StatusForbidden {
+		t.Fatalf("reveal without nonce: got %d, want %d", rec.Code, http.StatusForbidden)
+	}
+
+	rec := reveal(nonce)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("reveal failed: got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Cache-Control"); got != "no-store, private" {
+		t.Fatalf("Cache-Control mismatch: got %q", got)
+	}
+	var resp RevealResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode reveal response: %v", err)
+	}
+	if resp.NextNonce == "" || resp.NextNonce == nonce {
+		t.Fatalf("nonce not rotated: %q", resp.NextNonce)
+	}
+
+	rec = reveal(nonce)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("replayed nonce: got %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	if got := rec.Header().Get("Cache-Control"); got != "no-store, private" {
+		t.Fatalf("Cache-Control mismatch on error: got %q", got)
+	}
+
+	if rec := reveal(resp.NextNonce); rec.Code != http.StatusOK {
+		t.Fatalf("reveal with rotated nonce failed: got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestCreateSecretQuota(t *testing.T) {
+	cfg := config.Default()
+	cfg.Secrets.MaxTotal = 1
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	router := SetupRouter(st, cfg)
+
+	createSecret(t, router, `{"content": "first"}`)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/secrets", strings.NewReader(`{"content": "second"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("create over quota: got %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+}
+
+func TestCreateWithCryptoProfile(t *testing.T) {
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	router := SetupRouter(st, config.Default())
+
+	for _, profile := range []string{"fast", "strong"} {
+		req := httptest.NewRequest(http.MethodPost, "/api/secrets", strings.NewReader(`{"content": "s3cret"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(cryptoProfileHeader, profile)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("%s: create failed: got %d: %s", profile, rec.Code, rec.Body.String())
+		}
+		var created CreateResponse
+		if err := json.NewDecoder(rec.Body).Decode(&created); err != nil {
+			t.Fatalf("%s: failed to decode create response: %v", profile, err)
+		}
+		passphrase := created.URL[strings.Index(created.URL, "#")+1:]
+
+		rec = revealSecret(router, created.ID, passphrase)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: reveal failed: got %d: %s", profile, rec.Code, rec.Body.String())
+		}
+		var revealed RevealResponse
+		if err := json.NewDecoder(rec.Body).Decode(&revealed); err != nil {
+			t.Fatalf("%s: failed to decode reveal response: %v", profile, err)
+		}
+		if revealed.Content != "s3cret" {
+			t.Fatalf("%s: content mismatch: %q", profile, revealed.Content)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/secrets", strings.NewReader(`{"content": "s3cret"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(cryptoProfileHeader, "quantum")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("unknown profile: got %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestRevealDecoy(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "events.log")
+	script := filepath.Join(dir, "hook.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\necho \"$SECRET_EVENT\" >> "+out+"\n"), 0o755); err != nil {
+		t.Fatalf("failed to write hook script: %v", err)
+	}
+
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	cfg := config.Default()
+	cfg.Hooks.ExecCommand = script
+	cfg.Honeypot.DecoyIDs = []string{"prod-db-root"}
+	cfg.Honeypot.DecoyPatterns = []string{`^admin-`}
+	router := SetupRouter(st, cfg)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/secrets/prod-db-root/status", nil))
+	var status StatusResponse
+	if err := json.NewDecoder(rec.Body).Decode(&status); err != nil {
+		t.Fatalf("failed to decode status: %v", err)
+	}
+	if !status.Exists || status.ViewsRemaining != 1 {
+		t.Fatalf("decoy status should look live: %+v", status)
+	}
+
+	var first RevealResponse
+	for _, id := range []string{"prod-db-root", "admin-vpn"} {
+		rec := revealSecret(router, id, "anything")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: decoy reveal failed: got %d: %s", id, rec.Code, rec.Body.String())
+		}
+		var resp RevealResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("%s: failed to decode reveal: %v", id, err)
+		}
+		if len(resp.Content) < 16 {
+			t.Fatalf("%s: decoy content not believable: %q", id, resp.Content)
+		}
+		if first.Content == "" {
+			first = resp
+		}
+	}
+
+	lines := waitForLines(t, out, 2)
+	if lines[0] != "decoy" || lines[1] != "decoy" {
+		t.Fatalf("hook events mismatch: got %q", lines)
+	}
+
+	// Same ID, same fake content, so a repeat visit doesn't give it away.
+	var again RevealResponse
+	if err := json.NewDecoder(revealSecret(router, "prod-db-root", "other").Body).Decode(&again); err != nil {
+		t.Fatalf("failed to decode reveal: %v", err)
+	}
+	if again.Content != first.Content {
+		t.Fatalf("decoy content changed between reveals: %q vs %q", first.Content, again.Content)
+	}
+
+	if n, _ := st.RevealCount(t.Context()); n != 0 {
+		t.Fatalf("decoy reveal touched the store: reveal count %d", n)
+	}
+}
+
+// unavailableStore fails every view as if mid-failover.
+type unavailableStore struct {
+	*store.MemoryStore
+}
+
+func (unavailableStore) IncrementViews(ctx context.Context, id string) (int, int, error) {
+	return 0, 0, store.ErrUnavailable
+}
+
+func (unavailableStore) GetAndDelete(ctx context.Context, id string) (*models.Secret, error) {
+	return nil, store.ErrUnavailable
+}
+
+func TestRevealStoreUnavailable(t *testing.T) {
+	st := unavailableStore{store.NewMemoryStore(time.Minute)}
+	defer st.Close()
+	router := SetupRouter(st, config.Default())
+
+	created, passphrase := createSecret(t, router, `{"content": "s3cret"}`)
+	rec := revealSecret(router, created.ID, passphrase)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status mismatch: got %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Fatal("503 without Retry-After")
+	}
+}
+
+// failingGetStore fails every lookup as if the store were unreachable.
+type failingGetStore struct {
+	*store.MemoryStore
+}
+
+func (failingGetStore) Get(ctx context.Context, id string) (*models.Secret, error) {
+	return nil, errors.New("dial tcp 10.0.0.1:6379: i/o timeout")
+}
+
+func TestReadyzDegrades(t *testing.T) {
+	cfg := config.Default()
+	cfg.Store.Health.MinSamples = 3
+	st := failingGetStore{store.NewMemoryStore(time.Minute)}
+	defer st.Close()
+	router := SetupRouter(st, cfg)
+
+	ready := func() int {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		return rec.Code
+	}
+
+	if code := ready(); code != http.StatusOK {
+		t.Fatalf("fresh instance: got %d, want %d", code, http.StatusOK)
+	}
+	for i := 0; i < 3; i++ {
+		revealSecret(router, "missing", "passphrase")
+	}
+	if code := ready(); code != http.StatusServiceUnavailable {
+		t.Fatalf("after store failures: got %d, want %d", code, http.StatusServiceUnavailable)
+	}
+}
+
+func TestRevealSameOrigin(t *testing.T) {
+	cfg := config.Default()
+	cfg.Server.SameOrigin = true
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	router := SetupRouter(st, cfg)
+
+	created, passphrase := createSecret(t, router, `{"content": "s3cret"}`)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/secrets/"+created.ID+"?passphrase="+url.QueryEscape(passphrase), nil)
+	req.Header.Set("Origin", "https://lookalike.example")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("cross-origin reveal: got %d, want %d", rec.Code, http.StatusForbidden)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/secrets/"+created.ID+"?passphrase="+url.QueryEscape(passphrase), nil)
+	req.Header.Set("Origin", cfg.Server.BaseURL)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("same-origin reveal: got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestRevealPadded(t *testing.T) {
+	cfg := config.Default()
+	cfg.Crypto.PadLength = 1024
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	router := SetupRouter(st, cfg)
+
+	short, passphrase := createSecret(t, router, `{"content": "a"}`)
+	long, _ := createSecret(t, router, `{"content": "a much longer secret"}`)
+
+	a, _ := st.Get(t.Context(), short.ID)
+	b, _ := st.Get(t.Context(), long.ID)
+	if len(a.EncryptedData) != len(b.EncryptedData) {
+		t.Fatalf("stored sizes differ: %d vs %d", len(a.EncryptedData), len(b.EncryptedData))
+	}
+
+	rec := revealSecret(router, short.ID, passphrase)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"content":"a"`) {
+		t.Fatalf("padded reveal mismatch: got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestRevealOneTimeConcurrent(t *testing.T) {
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	router := SetupRouter(st, config.Default())
+
+	created, passphrase := createSecret(t, router, `{"content": "s3cret", "max_views": 1}`)
+
+	codes := make(chan int, 10)
+	for i := 0; i < cap(codes); i++ {
+		go func() { codes <- revealSecret(router, created.ID, passphrase).Code }()
+	}
+	ok := 0
+	for i := 0; i < cap(codes); i++ {
+		if <-codes == http.StatusOK {
+			ok++
+		}
+	}
+	if ok != 1 {
+		t.Fatalf("successful reveals of a one-time secret: got %d, want 1", ok)
+	}
+	if _, err := st.Get(t.Context(), created.ID); !errors.Is(err, store.ErrNotFound) {
+		t.Fatalf("one-time secret still stored: %v", err)
+	}
+}
+
+func TestHideViews(t *testing.T) {
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	router := SetupRouter(st, config.Default())
+
+	created, passphrase := createSecret(t, router, `{"content":"s3cret","max_views":2,"hide_views":true}`)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/secrets/"+created.ID+"/status", nil))
+	if strings.Contains(rec.Body.String(), "views_remaining") {
+		t.Fatalf("status reveals views remaining: %s", rec.Body.String())
+	}
+
+	for i := range 2 {
+		rec := revealSecret(router, created.ID, passphrase)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("reveal %d failed: got %d: %s", i+1, rec.Code, rec.Body.String())
+		}
+		var resp RevealResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode reveal response: %v", err)
+		}
+		if resp.Content != "s3cret" || resp.ViewsRemaining != nil {
+			t.Fatalf("reveal %d: got %+v", i+1, resp)
+		}
+	}
+
+	if rec := revealSecret(router, created.ID, passphrase); rec.Code != http.StatusNotFound {
+		t.Fatalf("reveal past the limit: got %d, want 404", rec.Code)
+	}
+}
+
+func TestRevealAfterDropPassphrases(t *testing.T) {
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	router := SetupRouter(st, config.Default())
+
+	created, passphrase := createSecret(t, router, `{"content":"s3cret","max_views":2}`)
+	stored, err := st.Get(context.Background(), created.ID)
+	if err != nil {
+		t.Fatalf("failed to get secret: %v", err)
+	}
+	if stored.Passphrase != "" || len(stored.PassphraseHash) == 0 {
+		t.Fatalf("new secret stored passphrase %q, hash %x", stored.Passphrase, stored.PassphraseHash)
+	}
+
+	// Make it look like a secret from before passphrases stopped being
+	// stored, then migrate it.
+	st.UpdateWhere(context.Background(), func(secret *models.Secret) bool {
+		secret.Passphrase, secret.PassphraseHash = passphrase, nil
+		return true
+	})
+	if n, err := store.DropPassphrases(context.Background(), st); err != nil || n != 1 {
+		t.Fatalf("DropPassphrases: %d, %v", n, err)
+	}
+	if rec := burnSecret(router, created.ID, "passphrase="+url.QueryEscape(passphrase)); rec.Code != http.StatusBadRequest {
+		t.Fatalf("burn of a migrated secret: got %d, want 400", rec.Code)
+	}
+
+	wrong := crypto.GeneratePassphrase()
+	if rec := revealSecret(router, created.ID, wrong); rec.Code != http.StatusForbidden {
+		t.Fatalf("wrong passphrase: got %d, want 403: %s", rec.Code, rec.Body.String())
+	}
+	rec := revealSecret(router, created.ID, passphrase)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("reveal failed: got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp RevealResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode reveal response: %v", err)
+	}
+	// The wrong passphrase didn't use a view.
+	if resp.Content != "s3cret" || resp.ViewsRemaining == nil || *resp.ViewsRemaining != 1 {
+		t.Fatalf("reveal mismatch: got %+v", resp)
+	}
+}
+
+func TestRevealReportsBurned(t *testing.T) {
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	router := SetupRouter(st, config.Default())
+
+	for _, body := range []string{`{"content":"s3cret","max_views":2}`, `{"content":"s3cret","max_views":2,"hide_views":true}`} {
+		created, passphrase := createSecret(t, router, body)
+		for i, want := range []bool{false, true} {
+			rec := revealSecret(router, created.ID, passphrase)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("reveal %d failed: got %d: %s", i+1, rec.Code, rec.Body.String())
+			}
+			if !strings.Contains(rec.Body.String(), fmt.Sprintf(`"burned":%t`, want)) {
+				t.Fatalf("reveal %d of %s: want burned %t, got %s", i+1, body, want, rec.Body.String())
+			}
+		}
+		if _, err := st.Get(context.Background(), created.ID); !errors.Is(err, store.ErrNotFound) {
+			t.Fatalf("burned secret still stored: %v", err)
+		}
+	}
+}
+
+// unreachableStore fails pings as if the store were down.
+type unreachableStore struct {
+	*store.MemoryStore
+}
+
+func (unreachableStore) Ping(ctx context.Context) error {
+	return errors.New("dial tcp 10.0.0.1:6379: connect: connection refused")
+}
+
+func TestHealthPingsStore(t *testing.T) {
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+
+	for name, tt := range map[string]struct {
+		store  store.Store
+		code   int
+		status string
+	}{
+		"reachable":   {st, http.StatusOK, "ok"},
+		"unreachable": {unreachableStore{st}, http.StatusServiceUnavailable, "degraded"},
+	} {
+		rec := getPath(SetupRouter(tt.store, config.Default()), "/health")
+		if rec.Code != tt.code || !strings.Contains(rec.Body.String(), `"status":"`+tt.status+`"`) {
+			t.Errorf("%s: got %d: %s", name, rec.Code, rec.Body.String())
+		}
+	}
+}
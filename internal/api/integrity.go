@@ -0,0 +1,56 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"secure.share/internal/crypto"
+)
+
+type VerifyIntegrityRequest struct {
+	Content      string        `json:"content,omitempty"`
+	Files        []ArchiveFile `json:"files,omitempty"`
+	IntegrityKey string        `json:"integrity_key"`
+	IntegrityMAC string        `json:"integrity_mac"`
+}
+
+type VerifyIntegrityResponse struct {
+	Valid bool `json:"valid"`
+}
+
+// VerifyIntegrity checks content against the detached MAC returned at
+// create time. It needs nothing from the store, so it still answers after
+// the secret is gone; the key is the sender's and is never kept.
+func (h *Handler) VerifyIntegrity(w http.ResponseWriter, r *http.Request) {
+	if !h.config.Secrets.IntegrityMAC {
+		h.error(w, r, http.StatusNotFound, "integrity macs are not enabled")
+		return
+	}
+
+	var req VerifyIntegrityRequest
+	if !h.decode(w, r, &req) {
+		return
+	}
+	if req.IntegrityKey == "" || req.IntegrityMAC == "" {
+		h.error(w, r, http.StatusBadRequest, "integrity_key and integrity_mac are required")
+		return
+	}
+	if req.Content != "" && len(req.Files) > 0 {
+		h.error(w, r, http.StatusBadRequest, "content and files cannot both be set")
+		return
+	}
+
+	// Same bytes the MAC was computed over in prepareSecret.
+	content := []byte(req.Content)
+	if len(req.Files) > 0 {
+		var err error
+		if content, err = json.Marshal(req.Files); err != nil {
+			h.error(w, r, http.StatusBadRequest, "invalid files")
+			return
+		}
+	}
+
+	h.json(w, http.StatusOK, VerifyIntegrityResponse{
+		Valid: crypto.VerifyContentMAC(req.IntegrityKey, content, req.IntegrityMAC),
+	})
+}
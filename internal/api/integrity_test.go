@@ -0,0 +1,72 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"secure.share/config"
+	"secure.share/internal/store"
+)
+
+func verifyIntegrity(t *testing.T, router http.Handler, content, key, mac string) bool {
+	t.Helper()
+	body := `{"content": ` + strconv.Quote(content) + `, "integrity_key": ` + strconv.Quote(key) +
+		`, "integrity_mac": ` + strconv.Quote(mac) + `}`
+	rec := postJSON(router, "/api/integrity/verify", body)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("verify failed: got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp VerifyIntegrityResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode verify response: %v", err)
+	}
+	return resp.Valid
+}
+
+func TestIntegrityMAC(t *testing.T) {
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	router := SetupRouter(st, config.Default())
+
+	created, passphrase := createSecret(t, router, `{"content": "s3cret", "integrity": true}`)
+	if created.IntegrityKey == "" || created.IntegrityMAC == "" {
+		t.Fatalf("create did not return an integrity mac: %+v", created)
+	}
+
+	rec := revealSecret(router, created.ID, passphrase)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("reveal failed: got %d: %s", rec.Code, rec.Body.String())
+	}
+	var revealed RevealResponse
+	if err := json.NewDecoder(rec.Body).Decode(&revealed); err != nil {
+		t.Fatalf("failed to decode reveal response: %v", err)
+	}
+
+	if !verifyIntegrity(t, router, revealed.Content, created.IntegrityKey, created.IntegrityMAC) {
+		t.Fatal("revealed content did not verify")
+	}
+	if verifyIntegrity(t, router, revealed.Content+"x", created.IntegrityKey, created.IntegrityMAC) {
+		t.Fatal("tampered content verified")
+	}
+
+	// Without the opt-in nothing is returned.
+	plain, _ := createSecret(t, router, `{"content": "s3cret"}`)
+	if plain.IntegrityKey != "" || plain.IntegrityMAC != "" {
+		t.Fatalf("integrity mac returned without being requested: %+v", plain)
+	}
+}
+
+func TestIntegrityMACDisabled(t *testing.T) {
+	cfg := config.Default()
+	cfg.Secrets.IntegrityMAC = false
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	router := SetupRouter(st, cfg)
+
+	if rec := postJSON(router, "/api/secrets", `{"content": "s3cret", "integrity": true}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("got %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,158 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"secure.share/internal/hooks"
+)
+
+// revealBuckets are the upper bounds, in seconds, of the reveal latency
+// histogram. Reveals include a key derivation, so they start around 50ms.
+var revealBuckets = []float64{0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5}
+
+// metrics holds what /metrics exposes in the Prometheus text format. The
+// counters are per instance; Prometheus sums them across instances. A nil
+// *metrics records nothing.
+type metrics struct {
+	created        atomic.Int64
+	revealed       atomic.Int64
+	expiredLookups atomic.Int64
+	burned         atomic.Int64
+
+	mu             sync.Mutex
+	revealCounts   []uint64 // per bucket, not cumulative; the last is +Inf
+	revealSum      float64
+	requestsByCode map[int]uint64
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		revealCounts:   make([]uint64, len(revealBuckets)+1),
+		requestsByCode: make(map[int]uint64),
+	}
+}
+
+// event counts a hook event as the matching business counter.
+func (m *metrics) event(name string) {
+	if m == nil {
+		return
+	}
+	switch name {
+	case hooks.EventCreate:
+		m.created.Add(1)
+	case hooks.EventReveal:
+		m.revealed.Add(1)
+	case hooks.EventExpire:
+		m.expiredLookups.Add(1)
+	}
+}
+
+func (m *metrics) burn() {
+	if m == nil {
+		return
+	}
+	m.burned.Add(1)
+}
+
+// observeReveal records the latency of a reveal that started at start,
+// whatever its outcome.
+func (m *metrics) observeReveal(start time.Time) {
+	if m == nil {
+		return
+	}
+	seconds := time.Since(start).Seconds()
+	i := sort.SearchFloat64s(revealBuckets, seconds)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.revealCounts[i]++
+	m.revealSum += seconds
+}
+
+// Middleware counts every request by response status.
+func (m *metrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		wrapped := &responseWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(wrapped, r)
+
+		m.mu.Lock()
+		m.requestsByCode[wrapped.status]++
+		m.mu.Unlock()
+	})
+}
+
+// Metrics serves the metrics in the Prometheus text exposition format.
+// The stored secrets gauge and the swept expiries are read from the store
+// on each scrape.
+func (h *Handler) Metrics(w http.ResponseWriter, r *http.Request) {
+	// Nothing outlives MaxTTL from now, so this counts every live secret.
+	stored, err := h.store.ExpiringWithin(r.Context(), h.config.Secrets.MaxTTL+time.Minute)
+	if err != nil {
+		slog.WarnContext(r.Context(), "failed to count stored secrets", "error", err)
+		stored = -1
+	}
+
+	swept, err := h.store.SweptExpired(r.Context())
+	if err != nil {
+		if !errors.Is(err, errors.ErrUnsupported) {
+			slog.WarnContext(r.Context(), "failed to count expired secrets", "error", err)
+		}
+		swept = -1
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	h.metrics.write(w, stored, swept)
+}
+
+// write renders the metrics. A negative stored leaves the gauge out, and a
+// negative swept the expired counter, which stores that expire secrets
+// natively can't keep.
+func (m *metrics) write(w io.Writer, stored int, swept int64) {
+	counter := func(name, help string, value int64) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n%s %d\n", name, help, name, name, value)
+	}
+	counter("secure_share_secrets_created_total", "Secrets created.", m.created.Load())
+	counter("secure_share_secrets_revealed_total", "Views of secrets used.", m.revealed.Load())
+	counter("secure_share_secrets_expired_lookups_total", "Lookups that found a secret expired. Expiries themselves are secure_share_secrets_expired_total.", m.expiredLookups.Load())
+	counter("secure_share_secrets_burned_total", "Secrets deleted by their last view.", m.burned.Load())
+	if swept >= 0 {
+		counter("secure_share_secrets_expired_total", "Expired secrets deleted by the store's sweeper.", swept)
+	}
+
+	if stored >= 0 {
+		fmt.Fprintf(w, "# HELP secure_share_secrets_stored Live secrets in the store.\n# TYPE secure_share_secrets_stored gauge\nsecure_share_secrets_stored %d\n", stored)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	const hist = "secure_share_reveal_duration_seconds"
+	fmt.Fprintf(w, "# HELP %s Time taken by reveals, successful or not.\n# TYPE %s histogram\n", hist, hist)
+	var cumulative uint64
+	for i, le := range revealBuckets {
+		cumulative += m.revealCounts[i]
+		fmt.Fprintf(w, "%s_bucket{le=\"%s\"} %d\n", hist, strconv.FormatFloat(le, 'g', -1, 64), cumulative)
+	}
+	cumulative += m.revealCounts[len(revealBuckets)]
+	fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n%s_sum %g\n%s_count %d\n", hist, cumulative, hist, m.revealSum, hist, cumulative)
+
+	const requests = "secure_share_http_requests_total"
+	fmt.Fprintf(w, "# HELP %s HTTP requests by response status.\n# TYPE %s counter\n", requests, requests)
+	codes := make([]int, 0, len(m.requestsByCode))
+	for code := range m.requestsByCode {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	for _, code := range codes {
+		fmt.Fprintf(w, "%s{code=\"%d\"} %d\n", requests, code, m.requestsByCode[code])
+	}
+}
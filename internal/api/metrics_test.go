@@ -0,0 +1,69 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"secure.share/config"
+	"secure.share/internal/store"
+)
+
+func TestMetrics(t *testing.T) {
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	cfg := config.Default()
+	cfg.Metrics.Enabled = true
+	router := SetupRouter(st, cfg)
+
+	created, passphrase := createSecret(t, router, `{"content":"s3cret","max_views":1}`)
+	createSecret(t, router, `{"content":"kept"}`)
+	if rec := revealSecret(router, created.ID, passphrase); rec.Code != http.StatusOK {
+		t.Fatalf("reveal failed: got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec := getPath(router, "/metrics")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("metrics: got %d", rec.Code)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{
+		"secure_share_secrets_created_total 2\n",
+		"secure_share_secrets_revealed_total 1\n",
+		"secure_share_secrets_burned_total 1\n",
+		"secure_share_secrets_expired_lookups_total 0\n",
+		"secure_share_secrets_expired_total 0\n",
+		"secure_share_secrets_stored 1\n",
+		"secure_share_reveal_duration_seconds_count 1\n",
+		`secure_share_reveal_duration_seconds_bucket{le="+Inf"} 1` + "\n",
+		`secure_share_http_requests_total{code="201"} 2` + "\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics missing %q:\n%s", want, body)
+		}
+	}
+}
+
+func TestMetricsRouting(t *testing.T) {
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+
+	if rec := getPath(SetupRouter(st, config.Default()), "/metrics"); rec.Code == http.StatusOK {
+		t.Fatal("metrics served while disabled")
+	}
+
+	cfg := config.Default()
+	cfg.Metrics.Enabled = true
+	cfg.Metrics.Addr = "127.0.0.1:9090"
+	app, metrics := SetupRouters(st, cfg)
+	if metrics == nil {
+		t.Fatal("no metrics router with metrics addr set")
+	}
+	if rec := getPath(app, "/metrics"); rec.Code == http.StatusOK {
+		t.Fatal("metrics served on the main router with their own addr")
+	}
+	if rec := getPath(metrics, "/metrics"); rec.Code != http.StatusOK {
+		t.Fatalf("metrics router: got %d", rec.Code)
+	}
+}
@@ -2,13 +2,21 @@ package api
 
 import (
 	"context"
+	"crypto/subtle"
+	"encoding/hex"
 	"fmt"
 	"log/slog"
+	"mime"
 	"net/http"
+	"net/url"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"secure.share/internal/logging"
+
+	"github.com/go-chi/chi/v5/middleware"
 	"github.com/google/uuid"
 )
 
@@ -19,16 +27,67 @@ const (
 	ClientIPKey  contextKey = "client_ip"
 )
 
+const maxRequestIDLength = 64
+
+// RequestID is RequestIDWithHeader for X-Request-ID.
 func RequestID(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		requestID := r.Header.Get("X-Request-ID")
-		if requestID == "" {
-			requestID = uuid.New().String()[:8]
+	return RequestIDWithHeader("X-Request-ID")(next)
+}
+
+// RequestIDWithHeader reuses a well-formed id from header, then the trace id
+// of a W3C traceparent, and only generates a fresh id when neither is usable.
+// The chosen id is always echoed back under header.
+func RequestIDWithHeader(header string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requestID := r.Header.Get(header)
+			if !validRequestID(requestID) {
+				requestID = traceIDFromParent(r.Header.Get("traceparent"))
+			}
+			if requestID == "" {
+				requestID = uuid.New().String()[:8]
+			}
+			ctx := context.WithValue(r.Context(), RequestIDKey, requestID)
+			ctx = logging.With(ctx, slog.String("request_id", requestID))
+			w.Header().Set(header, requestID)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// validRequestID accepts short ids made of characters that are safe to put
+// in logs and response headers.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
 		}
-		ctx := context.WithValue(r.Context(), RequestIDKey, requestID)
-		w.Header().Set("X-Request-ID", requestID)
-		next.ServeHTTP(w, r.WithContext(ctx))
-	})
+	}
+	return true
+}
+
+// traceIDFromParent extracts the trace id from a traceparent header
+// ("00-<32 hex>-<16 hex>-<2 hex>"), or returns "" if it is malformed.
+func traceIDFromParent(traceparent string) string {
+	parts := strings.Split(strings.TrimSpace(traceparent), "-")
+	if len(parts) != 4 || len(parts[0]) != 2 || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return ""
+	}
+	for _, part := range parts {
+		if _, err := hex.DecodeString(part); err != nil {
+			return ""
+		}
+	}
+	if parts[1] == strings.Repeat("0", 32) {
+		return ""
+	}
+	return strings.ToLower(parts[1])
 }
 
 func GetRequestID(r *http.Request) string {
@@ -103,16 +162,19 @@ func (rl *RateLimiter) isAllowed(ip string) bool {
 	return true
 }
 
+// Middleware limits each client IP, or each API key for requests made with
+// one, so integrations behind a shared egress IP don't starve each other.
 func (rl *RateLimiter) Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		ip := getClientIP(r)
+		client := clientKey(r)
 
-		if !rl.isAllowed(ip) {
-			slog.Warn("rate limit exceeded",
+		if !rl.isAllowed(client) {
+			slog.WarnContext(r.Context(), "rate limit exceeded",
 				"ip", ip,
-				"request_id", GetRequestID(r),
+				"client", client,
 			)
-			http.Error(w, `{"error": "rate limit exceeded"}`, http.StatusTooManyRequests)
+			writeError(w, r, http.StatusTooManyRequests, "rate limit exceeded")
 			return
 		}
 
@@ -133,17 +195,25 @@ func CORS(cfg CORSConfig) func(http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			origin := r.Header.Get("Origin")
 
-			// Check if origin is allowed
-			allowed := false
+			// Check if origin is allowed. A wildcard answers "*", the same
+			// for every origin; a listed origin is echoed, so the response
+			// varies by Origin whether or not this one matched.
+			allowOrigin := ""
 			for _, o := range cfg.AllowedOrigins {
-				if o == "*" || o == origin {
-					allowed = true
+				if o == "*" {
+					allowOrigin = "*"
 					break
 				}
+				if o == origin {
+					allowOrigin = origin
+				}
+			}
+			if allowOrigin != "*" && len(cfg.AllowedOrigins) > 0 {
+				w.Header().Add("Vary", "Origin")
 			}
 
-			if allowed {
-				w.Header().Set("Access-Control-Allow-Origin", origin)
+			if origin != "" && allowOrigin != "" {
+				w.Header().Set("Access-Control-Allow-Origin", allowOrigin)
 				w.Header().Set("Access-Control-Allow-Methods", strings.Join(cfg.AllowedMethods, ", "))
 				w.Header().Set("Access-Control-Allow-Headers", strings.Join(cfg.AllowedHeaders, ", "))
 				w.Header().Set("Access-Control-Max-Age", fmt.Sprintf("%d", cfg.MaxAge))
@@ -159,40 +229,180 @@ func CORS(cfg CORSConfig) func(http.Handler) http.Handler {
 	}
 }
 
+// DefaultMaxBodyBytes is JSONOnly's body limit, room for a secret of
+// MaxArchiveBytes' default once base64 encoded.
+const DefaultMaxBodyBytes = 2 << 20
+
+// JSONOnly is JSONOnlyFor with no alternative media types and a body limit
+// of DefaultMaxBodyBytes.
 func JSONOnly(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodGet || r.Method == http.MethodOptions {
+	return JSONOnlyFor(nil, DefaultMaxBodyBytes)(next)
+}
+
+// JSONOnlyFor refuses request bodies whose Content-Type isn't
+// application/json or one of alternatives with 415. The media type is
+// compared case-insensitively and parameters such as charset are ignored.
+// GET, HEAD, OPTIONS and DELETE requests without a body are let through.
+//
+// Bodies over maxBytes get a 413: up front when Content-Length says so,
+// otherwise, as with chunked bodies, once reading passes the limit, which
+// Handler.decode reports. maxBytes <= 0 leaves bodies unbounded.
+func JSONOnlyFor(alternatives []string, maxBytes int64) func(http.Handler) http.Handler {
+	allowed := map[string]bool{"application/json": true}
+	for _, alt := range alternatives {
+		allowed[strings.ToLower(alt)] = true
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodDelete:
+				if r.ContentLength == 0 && len(r.TransferEncoding) == 0 {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			// ParseMediaType lower-cases the media type.
+			mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			if err != nil || !allowed[mediaType] {
+				writeErrorCode(w, r, http.StatusUnsupportedMediaType, "unsupported_media_type", "Content-Type must be application/json")
+				return
+			}
+
+			if maxBytes > 0 {
+				if r.ContentLength > maxBytes {
+					writeErrorCode(w, r, http.StatusRequestEntityTooLarge, "payload_too_large", "request body too large")
+					return
+				}
+				r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+			}
+
 			next.ServeHTTP(w, r)
-			return
-		}
+		})
+	}
+}
 
-		ct := r.Header.Get("Content-Type")
-		if !strings.HasPrefix(ct, "application/json") {
-			http.Error(w, `{"error": "Content-Type must be application/json"}`, http.StatusUnsupportedMediaType)
-			return
-		}
+// SameOrigin rejects browser requests whose Origin, or failing that
+// Referer, is not baseURL's host, so a lookalike page elsewhere can't drive
+// creates and reveals through this API. Requests with neither header, such
+// as from curl or the CLI, and requests made with an API key are let
+// through.
+func SameOrigin(baseURL string) func(http.Handler) http.Handler {
+	var host string
+	if u, err := url.Parse(baseURL); err == nil {
+		host = u.Host
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := apiKeyFrom(r); ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			source := r.Header.Get("Origin")
+			if source == "" {
+				source = r.Header.Get("Referer")
+			}
+			if source != "" {
+				// An opaque "null" origin doesn't parse to a host and is refused.
+				u, err := url.Parse(source)
+				if err != nil || u.Host != host {
+					slog.WarnContext(r.Context(), "cross-origin request refused",
+						"origin", source,
+						"ip", getClientIP(r),
+					)
+					writeError(w, r, http.StatusForbidden, "cross-origin request refused")
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
 
-		next.ServeHTTP(w, r)
-	})
+// AdminAuth requires "Authorization: Bearer <token>" matching the configured
+// admin token, or an API key with the admin scope.
+func AdminAuth(token string) func(http.Handler) http.Handler {
+	return AdminAuthWithSubjects(token, nil)
+}
+
+// AdminAuthWithSubjects also admits requests with a verified client
+// certificate whose subject is one of subjects. An empty token admits no
+// one by token.
+func AdminAuthWithSubjects(token string, subjects []string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if key, ok := apiKeyFrom(r); ok && key.HasScope(scopeAdmin) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if clientSubjectAllowed(r, subjects) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			supplied, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || token == "" || subtle.ConstantTimeCompare([]byte(supplied), []byte(token)) != 1 {
+				slog.WarnContext(r.Context(), "admin auth failed",
+					"ip", getClientIP(r),
+				)
+				writeError(w, r, http.StatusUnauthorized, "unauthorized")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// TimeoutUnless is chi's middleware.Timeout for every request but those
+// exempt reports, which run without a deadline.
+func TimeoutUnless(d time.Duration, exempt func(*http.Request) bool) func(http.Handler) http.Handler {
+	timeout := middleware.Timeout(d)
+	return func(next http.Handler) http.Handler {
+		limited := timeout(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if exempt(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			limited.ServeHTTP(w, r)
+		})
+	}
 }
 
+// Logger logs every request once it completes: method, path, status,
+// duration, client IP and, through the request context, its request id.
 func Logger(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		start := time.Now()
+	return LoggerWithSampling(1)(next)
+}
+
+// LoggerWithSampling logs one in every n successful requests. Errors,
+// including rate limited requests, are always logged. n <= 1 logs
+// everything.
+func LoggerWithSampling(n int) func(http.Handler) http.Handler {
+	var seen atomic.Uint64
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
 
-		wrapped := &responseWriter{ResponseWriter: w, status: http.StatusOK}
+			wrapped := &responseWriter{ResponseWriter: w, status: http.StatusOK}
 
-		next.ServeHTTP(wrapped, r)
+			next.ServeHTTP(wrapped, r)
 
-		slog.Info("request completed",
-			"method", r.Method,
-			"path", r.URL.Path,
-			"status", wrapped.status,
-			"duration_ms", time.Since(start).Milliseconds(),
-			"request_id", GetRequestID(r),
-			"ip", r.Context().Value(ClientIPKey),
-		)
-	})
+			attrs := []any{
+				"method", r.Method,
+				"path", r.URL.Path,
+				"status", wrapped.status,
+				"duration_ms", time.Since(start).Milliseconds(),
+				"ip", getClientIP(r),
+			}
+			if wrapped.status < http.StatusBadRequest && n > 1 {
+				if seen.Add(1)%uint64(n) != 1 {
+					return
+				}
+				attrs = append(attrs, "sample_rate", n)
+			}
+			slog.InfoContext(r.Context(), "request completed", attrs...)
+		})
+	}
 }
 
 type responseWriter struct {
@@ -205,6 +415,12 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.ResponseWriter.WriteHeader(code)
 }
 
+// Unwrap lets http.ResponseController reach the underlying writer, e.g. to
+// flush.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
 func RevealRateLimiter() *RateLimiter {
 	return NewRateLimiter(10, time.Minute)
 }
@@ -0,0 +1,409 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"secure.share/config"
+	"secure.share/internal/logging"
+	"secure.share/internal/store"
+)
+
+func requestIDFor(t *testing.T, header string, set map[string]string) (seen, echoed string) {
+	t.Helper()
+	handler := RequestIDWithHeader(header)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = GetRequestID(r)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	for k, v := range set {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return seen, rec.Header().Get(header)
+}
+
+func TestRequestIDHonorsIncoming(t *testing.T) {
+	seen, echoed := requestIDFor(t, "X-Correlation-ID", map[string]string{"X-Correlation-ID": "abc-123"})
+	if seen != "abc-123" || echoed != "abc-123" {
+		t.Fatalf("request id not honored: seen %q, echoed %q", seen, echoed)
+	}
+
+	traceparent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	seen, echoed = requestIDFor(t, "X-Request-ID", map[string]string{"traceparent": traceparent})
+	if seen != "4bf92f3577b34da6a3ce929d0e0e4736" || echoed != seen {
+		t.Fatalf("trace id not used: seen %q, echoed %q", seen, echoed)
+	}
+}
+
+func TestRequestIDGeneratesWhenAbsentOrInvalid(t *testing.T) {
+	seen, echoed := requestIDFor(t, "X-Request-ID", nil)
+	if seen == "" || seen == "unknown" || echoed != seen {
+		t.Fatalf("expected generated id: seen %q, echoed %q", seen, echoed)
+	}
+
+	invalid := map[string]string{
+		"X-Request-ID": "bad id\r\nX-Injected: 1",
+		"traceparent":  "00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+	}
+	seen, echoed = requestIDFor(t, "X-Request-ID", invalid)
+	if seen == invalid["X-Request-ID"] || len(seen) != 8 || echoed != seen {
+		t.Fatalf("expected invalid id to be replaced: seen %q, echoed %q", seen, echoed)
+	}
+}
+
+func TestSameOrigin(t *testing.T) {
+	handler := SameOrigin("https://share.example.com")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    int
+	}{
+		{"matching origin", map[string]string{"Origin": "https://share.example.com"}, http.StatusOK},
+		{"matching referer", map[string]string{"Referer": "https://share.example.com/s/abc"}, http.StatusOK},
+		{"mismatching origin", map[string]string{"Origin": "https://share.example.com.evil.test"}, http.StatusForbidden},
+		{"mismatching referer", map[string]string{"Referer": "https://evil.test/s/abc"}, http.StatusForbidden},
+		{"origin wins over referer", map[string]string{"Origin": "https://evil.test", "Referer": "https://share.example.com/"}, http.StatusForbidden},
+		{"opaque origin", map[string]string{"Origin": "null"}, http.StatusForbidden},
+		{"no browser headers", nil, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/secrets/abc", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Fatalf("got %d, want %d", rec.Code, tt.want)
+			}
+			if tt.want == http.StatusForbidden && rec.Header().Get("Content-Type") != "application/json" {
+				t.Fatalf("refusal isn't JSON: %s", rec.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func TestCORS(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name    string
+		allowed []string
+		origin  string
+		want    string
+	}{
+		{"listed origin", []string{"https://app.example.com"}, "https://app.example.com", "https://app.example.com"},
+		{"unlisted origin", []string{"https://app.example.com"}, "https://evil.test", ""},
+		{"wildcard", []string{"*"}, "https://evil.test", "*"},
+		{"no origin header", []string{"*"}, "", ""},
+		{"nothing allowed", nil, "https://app.example.com", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := CORS(CORSConfig{AllowedOrigins: tt.allowed, AllowedMethods: []string{"GET"}})(ok)
+			req := httptest.NewRequest(http.MethodOptions, "/api/secrets", nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if got := rec.Header().Get("Access-Control-Allow-Origin"); got != tt.want {
+				t.Fatalf("Access-Control-Allow-Origin = %q, want %q", got, tt.want)
+			}
+			if vary := rec.Header().Get("Vary") == "Origin"; vary != (len(tt.allowed) > 0 && tt.allowed[0] != "*") {
+				t.Fatalf("Vary: Origin set = %v", vary)
+			}
+		})
+	}
+}
+
+func TestJSONOnly(t *testing.T) {
+	handler := JSONOnlyFor([]string{"application/merge-patch+json"}, DefaultMaxBodyBytes)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name        string
+		method      string
+		body        string
+		contentType string
+		want        int
+	}{
+		{"json", http.MethodPost, "{}", "application/json", http.StatusOK},
+		{"charset suffix", http.MethodPost, "{}", "application/json; charset=utf-8", http.StatusOK},
+		{"upper case", http.MethodPost, "{}", "Application/JSON", http.StatusOK},
+		{"configured alternative", http.MethodPost, "{}", "application/merge-patch+json", http.StatusOK},
+		{"prefix only", http.MethodPost, "{}", "application/jsonp", http.StatusUnsupportedMediaType},
+		{"wrong type", http.MethodPost, "{}", "text/plain", http.StatusUnsupportedMediaType},
+		{"missing", http.MethodPost, "{}", "", http.StatusUnsupportedMediaType},
+		{"malformed", http.MethodPost, "{}", "application/json; charset", http.StatusUnsupportedMediaType},
+		{"get without body", http.MethodGet, "", "", http.StatusOK},
+		{"get with body", http.MethodGet, "{}", "text/plain", http.StatusUnsupportedMediaType},
+		{"delete without body", http.MethodDelete, "", "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/api/secrets", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Fatalf("got %d, want %d", rec.Code, tt.want)
+			}
+			if tt.want == http.StatusUnsupportedMediaType && !strings.Contains(rec.Body.String(), `"code":"unsupported_media_type"`) {
+				t.Fatalf("body lacks the error code: %s", rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestLoggerSampling(t *testing.T) {
+	var buf bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+
+	handler := LoggerWithSampling(4)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if status := r.URL.Query().Get("status"); status == "429" {
+			w.WriteHeader(http.StatusTooManyRequests)
+		} else if status == "500" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	serve := func(target string) {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+	}
+
+	for range 12 {
+		serve("/ok")
+	}
+	serve("/?status=429")
+	serve("/?status=500")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	var successes, errors int
+	for _, line := range lines {
+		switch {
+		case strings.Contains(line, "status=200"):
+			successes++
+			if !strings.Contains(line, "sample_rate=4") {
+				t.Errorf("sampled line lacks sample_rate: %s", line)
+			}
+		case strings.Contains(line, "status=429"), strings.Contains(line, "status=500"):
+			errors++
+		}
+	}
+	if successes != 3 {
+		t.Errorf("logged %d of 12 successes, want 3", successes)
+	}
+	if errors != 2 {
+		t.Errorf("logged %d of 2 errors, want 2", errors)
+	}
+}
+
+func TestLoggerWithoutSamplingLogsEverything(t *testing.T) {
+	var buf bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+
+	handler := Logger(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	for range 5 {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+	if n := strings.Count(buf.String(), "request completed"); n != 5 {
+		t.Fatalf("logged %d of 5 requests", n)
+	}
+	if strings.Contains(buf.String(), "sample_rate") {
+		t.Fatal("unsampled lines carry sample_rate")
+	}
+}
+
+func TestLogLinesShareRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := logging.New(&buf, "json", "info")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(logger)
+
+	handler := RequestID(Logger(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		slog.WarnContext(r.Context(), "inside handler")
+	})))
+	req := httptest.NewRequest(http.MethodGet, "/api/secrets", nil)
+	req.Header.Set("X-Request-ID", "req-42")
+	req.RemoteAddr = "203.0.113.7:1234"
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2:\n%s", len(lines), buf.String())
+	}
+	for _, line := range lines {
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("not JSON: %s", line)
+		}
+		if entry["request_id"] != "req-42" {
+			t.Errorf("line lacks the request id: %s", line)
+		}
+	}
+	var completed map[string]any
+	_ = json.Unmarshal([]byte(lines[1]), &completed)
+	for key, want := range map[string]any{"method": "GET", "path": "/api/secrets", "status": float64(200), "ip": "203.0.113.7"} {
+		if completed[key] != want {
+			t.Errorf("%s = %v, want %v", key, completed[key], want)
+		}
+	}
+	if _, ok := completed["duration_ms"]; !ok {
+		t.Error("request line lacks duration_ms")
+	}
+}
+
+func TestJSONOnlyBodyLimit(t *testing.T) {
+	var readErr error
+	var read int
+	handler := JSONOnlyFor(nil, 16)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body []byte
+		body, readErr = io.ReadAll(r.Body)
+		read = len(body)
+	}))
+
+	// Declared oversized bodies are refused before the handler runs; others
+	// fail the handler's read once past the limit.
+	tests := []struct {
+		name     string
+		body     string
+		chunked  bool
+		rejected bool
+		readErr  bool
+	}{
+		{"within limit", `{"a":"12345678"}`, false, false, false},
+		{"declared too large", `{"a":"123456789"}`, false, true, false},
+		{"chunked within limit", `{"a":"12345678"}`, true, false, false},
+		{"chunked too large", `{"a":"1234567890123"}`, true, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			readErr, read = nil, -1
+			req := httptest.NewRequest(http.MethodPost, "/api/secrets", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.chunked {
+				req.ContentLength = -1
+				req.TransferEncoding = []string{"chunked"}
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if tt.rejected {
+				if rec.Code != http.StatusRequestEntityTooLarge || read != -1 {
+					t.Fatalf("got %d, handler ran: %v", rec.Code, read != -1)
+				}
+				if !strings.Contains(rec.Body.String(), `"code":"payload_too_large"`) {
+					t.Fatalf("body lacks the error code: %s", rec.Body.String())
+				}
+				return
+			}
+			var tooLarge *http.MaxBytesError
+			if gotErr := errors.As(readErr, &tooLarge); gotErr != tt.readErr {
+				t.Fatalf("read error = %v, want a MaxBytesError: %v", readErr, tt.readErr)
+			}
+			if !tt.readErr && read != len(tt.body) {
+				t.Fatalf("handler read %d of %d bytes", read, len(tt.body))
+			}
+		})
+	}
+}
+
+func TestCreateBodyLimit(t *testing.T) {
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	cfg := config.Default()
+	cfg.Server.MaxBodyBytes = 256
+	router := SetupRouter(st, cfg)
+
+	small := `{"content":"s3cret"}`
+	if rec := postJSON(router, "/api/secrets", small); rec.Code != http.StatusCreated {
+		t.Fatalf("small create: got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	large := `{"content":"` + strings.Repeat("x", 300) + `"}`
+	if rec := postJSON(router, "/api/secrets", large); rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("large create: got %d, want 413", rec.Code)
+	}
+
+	// Without a Content-Length the limit applies as the body is read.
+	req := httptest.NewRequest(http.MethodPost, "/api/secrets", strings.NewReader(large))
+	req.Header.Set("Content-Type", "application/json")
+	req.ContentLength = -1
+	req.TransferEncoding = []string{"chunked"}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusRequestEntityTooLarge || !strings.Contains(rec.Body.String(), "at most 256 bytes") {
+		t.Fatalf("chunked large create: got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestTimeoutUnless(t *testing.T) {
+	handler := TimeoutUnless(time.Minute, func(r *http.Request) bool {
+		return r.URL.Path == "/slow"
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Deadline(); ok {
+			w.WriteHeader(http.StatusGatewayTimeout)
+		}
+	}))
+
+	for path, want := range map[string]int{"/slow": http.StatusOK, "/fast": http.StatusGatewayTimeout} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != want {
+			t.Errorf("%s: got %d, want %d", path, rec.Code, want)
+		}
+	}
+}
+
+func TestIsThrottledDownload(t *testing.T) {
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	cfg := config.Default()
+	cfg.Secrets.DownloadRate = 1000
+	h := NewHandler(st, cfg)
+
+	for path, want := range map[string]bool{
+		"/api/secrets/abc/archive":   true,
+		"/api/secrets/abc/archive/x": false,
+		"/api/secrets//archive":      false,
+		"/api/secrets/a/b/archive":   false,
+		"/api/secrets/abc":           true,
+		"/api/secrets/abc/raw":       false,
+		"/api/secrets/":              false,
+		"/other/secrets/abc/archive": false,
+	} {
+		if got := h.isThrottledDownload(httptest.NewRequest(http.MethodGet, path, nil)); got != want {
+			t.Errorf("%s: got %v, want %v", path, got, want)
+		}
+	}
+
+	cfg.Secrets.DownloadRate = 0
+	unthrottled := NewHandler(st, cfg)
+	if unthrottled.isThrottledDownload(httptest.NewRequest(http.MethodGet, "/api/secrets/abc/archive", nil)) {
+		t.Error("download exempt from the timeout without a download_rate")
+	}
+}
@@ -0,0 +1,16 @@
+package api
+
+import "time"
+
+// negativeCache remembers IDs the store recently didn't have, so repeated
+// lookups of the same missing ID (scanners, retrying clients, stale links)
+// are answered without a store round trip. Entries are short-lived: another
+// instance may create a custom ID this one has cached as missing. IDs saved
+// through this instance are evicted at once.
+type negativeCache struct {
+	*ttlSet
+}
+
+func newNegativeCache(ttl time.Duration, size int) *negativeCache {
+	return &negativeCache{newTTLSet(ttl, size)}
+}
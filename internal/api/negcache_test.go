@@ -0,0 +1,90 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"secure.share/config"
+	"secure.share/internal/models"
+	"secure.share/internal/store"
+)
+
+// countingStore counts the Gets that reach the store.
+type countingStore struct {
+	*store.MemoryStore
+	gets atomic.Int64
+}
+
+func (s *countingStore) Get(ctx context.Context, id string) (*models.Secret, error) {
+	s.gets.Add(1)
+	return s.MemoryStore.Get(ctx, id)
+}
+
+func statusOf(t *testing.T, router http.Handler, id string) StatusResponse {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/secrets/"+id+"/status", nil))
+	var status StatusResponse
+	if err := json.NewDecoder(rec.Body).Decode(&status); err != nil {
+		t.Fatalf("failed to decode status: %v", err)
+	}
+	return status
+}
+
+func TestNegativeCache(t *testing.T) {
+	st := &countingStore{MemoryStore: store.NewMemoryStore(time.Minute)}
+	defer st.Close()
+	cfg := config.Default()
+	cfg.Secrets.DraftTTL = 10 * time.Minute
+	router := SetupRouter(st, cfg)
+
+	if statusOf(t, router, "no-such-id").Exists {
+		t.Fatal("missing id exists")
+	}
+	gets := st.gets.Load()
+	for range 5 {
+		if rec := revealSecret(router, "no-such-id", "passphrase"); rec.Code != http.StatusNotFound {
+			t.Fatalf("reveal of missing id: got %d, want %d", rec.Code, http.StatusNotFound)
+		}
+	}
+	if got := st.gets.Load(); got != gets {
+		t.Fatalf("repeated misses reached the store %d more times", got-gets)
+	}
+
+	// A draft's ID is known before the secret is stored, so it can be
+	// cached as missing and must show up once committed.
+	draft := createDraft(t, router, `{"content": "s3cret"}`)
+	if statusOf(t, router, draft.ID).Exists {
+		t.Fatal("uncommitted draft exists")
+	}
+	if rec := postJSON(router, "/api/secrets/draft/"+draft.DraftToken+"/commit", `{}`); rec.Code != http.StatusCreated {
+		t.Fatalf("commit failed: got %d: %s", rec.Code, rec.Body.String())
+	}
+	if !statusOf(t, router, draft.ID).Exists {
+		t.Fatal("committed secret is masked by the negative cache")
+	}
+}
+
+func TestNegativeCacheExpiry(t *testing.T) {
+	c := newNegativeCache(20*time.Millisecond, 2)
+	c.add("a")
+	if !c.has("a") {
+		t.Fatal("miss not cached")
+	}
+	time.Sleep(30 * time.Millisecond)
+	if c.has("a") {
+		t.Fatal("miss cached past its ttl")
+	}
+
+	for _, id := range []string{"a", "b", "c"} {
+		c.add(id)
+	}
+	if len(c.items) != 2 || !c.has("c") {
+		t.Fatalf("cache holds %d entries, want the latest within size 2", len(c.items))
+	}
+}
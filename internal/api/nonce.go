@@ -0,0 +1,27 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strconv"
+
+	"secure.share/internal/models"
+)
+
+// revealNonceHeader carries the next nonce on responses that aren't JSON.
+const revealNonceHeader = "X-Next-Nonce"
+
+// revealNonce is the one-time token a reveal URL must carry when the
+// secret was created with reveal nonces. It is bound to the view count, so
+// it changes with every view and a replayed URL no longer matches; like
+// ack tokens it is keyed by the stored ciphertext and needs no extra state.
+func revealNonce(secret *models.Secret, views int) string {
+	mac := hmac.New(sha256.New, secret.EncryptedData)
+	mac.Write([]byte("nonce:" + secret.ID + ":" + strconv.Itoa(views)))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:16])
+}
+
+func validRevealNonce(secret *models.Secret, nonce string) bool {
+	return hmac.Equal([]byte(nonce), []byte(revealNonce(secret, secret.CurrentViews)))
+}
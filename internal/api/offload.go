@@ -0,0 +1,55 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"secure.share/internal/crypto"
+)
+
+// OffloadResponse replaces the zip when an archive download is offloaded.
+type OffloadResponse struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// offloadArchive writes the zip to object storage and answers with a
+// pre-signed URL to it, deleting the object once the URL has expired. It
+// reports false, with nothing written, when the zip should be sent
+// directly instead.
+func (h *Handler) offloadArchive(w http.ResponseWriter, r *http.Request, files []ArchiveFile) bool {
+	var buf bytes.Buffer
+	if err := writeArchive(&buf, files); err != nil {
+		slog.WarnContext(r.Context(), "failed to build archive for offload", "error", err)
+		return false
+	}
+
+	ttl := h.config.Offload.TTL
+	key := "reveals/" + crypto.GenerateID() + ".zip"
+	if err := h.offload.Put(r.Context(), key, buf.Bytes(), "application/zip"); err != nil {
+		slog.WarnContext(r.Context(), "failed to offload archive, sending it directly", "error", err)
+		return false
+	}
+	url, err := h.offload.PresignGet(r.Context(), key, ttl)
+	if err != nil {
+		slog.WarnContext(r.Context(), "failed to presign offloaded archive, sending it directly", "error", err)
+		h.deleteOffloaded(key)
+		return false
+	}
+	time.AfterFunc(ttl, func() { h.deleteOffloaded(key) })
+
+	w.Header().Set("Cache-Control", "no-store, private")
+	h.json(w, http.StatusOK, OffloadResponse{URL: url, ExpiresAt: time.Now().Add(ttl)})
+	return true
+}
+
+func (h *Handler) deleteOffloaded(key string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := h.offload.Delete(ctx, key); err != nil {
+		slog.Error("failed to delete offloaded archive", "key", key, "error", err)
+	}
+}
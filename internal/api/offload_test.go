@@ -0,0 +1,113 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"secure.share/config"
+	"secure.share/internal/store"
+)
+
+type stubStorage struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	deleted []string
+	failPut bool
+}
+
+func (s *stubStorage) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failPut {
+		return errors.New("storage unavailable")
+	}
+	s.objects[key] = data
+	return nil
+}
+
+func (s *stubStorage) PresignGet(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	return "https://bucket.example/" + key + "?X-Amz-Expires=" + ttl.String(), nil
+}
+
+func (s *stubStorage) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.objects, key)
+	s.deleted = append(s.deleted, key)
+	return nil
+}
+
+func offloadRouter(t *testing.T, storage *stubStorage) http.Handler {
+	t.Helper()
+	st := store.NewMemoryStore(time.Minute)
+	t.Cleanup(func() { st.Close() })
+	cfg := config.Default()
+	cfg.Offload.TTL = 50 * time.Millisecond
+	cfg.Offload.MinBytes = 0
+	h := NewHandler(st, cfg)
+	h.offload = storage
+	return newRouter(h, cfg)
+}
+
+func downloadArchive(router http.Handler, id, passphrase string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/secrets/"+id+"/archive?passphrase="+url.QueryEscape(passphrase), nil))
+	return rec
+}
+
+func TestOffloadArchive(t *testing.T) {
+	storage := &stubStorage{objects: make(map[string][]byte)}
+	router := offloadRouter(t, storage)
+
+	created, passphrase := createSecret(t, router, `{"files": [{"name": "dump.sql", "content": "big"}]}`)
+	rec := downloadArchive(router, created.ID, passphrase)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("download failed: got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp OffloadResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode offload response: %v", err)
+	}
+	if !strings.HasPrefix(resp.URL, "https://bucket.example/reveals/") {
+		t.Fatalf("unexpected url %q", resp.URL)
+	}
+
+	storage.mu.Lock()
+	if len(storage.objects) != 1 {
+		t.Fatalf("stored %d objects, want 1", len(storage.objects))
+	}
+	storage.mu.Unlock()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		storage.mu.Lock()
+		deleted := len(storage.deleted) == 1 && len(storage.objects) == 0
+		storage.mu.Unlock()
+		if deleted {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("offloaded archive was not deleted after its ttl")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestOffloadFailureSendsArchiveDirectly(t *testing.T) {
+	storage := &stubStorage{objects: make(map[string][]byte), failPut: true}
+	router := offloadRouter(t, storage)
+
+	created, passphrase := createSecret(t, router, `{"files": [{"name": "dump.sql", "content": "big"}]}`)
+	rec := downloadArchive(router, created.ID, passphrase)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/zip" {
+		t.Fatalf("got %d %q, want the zip", rec.Code, rec.Header().Get("Content-Type"))
+	}
+}
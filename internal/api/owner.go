@@ -0,0 +1,117 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"secure.share/internal/crypto"
+	"secure.share/internal/models"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// ownerTokenHeader carries the owner token issued at create. It is kept out
+// of the URL so it doesn't end up in access logs.
+const ownerTokenHeader = "X-Owner-Token"
+
+// ExtendRequest pushes the current expiry forward by Minutes or, if
+// Minutes is unset, to TTLMinutes from now if that is later.
+type ExtendRequest struct {
+	Minutes    int `json:"minutes,omitempty"`
+	TTLMinutes int `json:"ttl_minutes"`
+}
+
+type ExtendResponse struct {
+	ExpiresAt time.Time `json:"expires_at"`
+	ExpiresIn string    `json:"expires_in"`
+}
+
+// ownedSecret loads the secret named in the URL and checks the owner token.
+// The passphrase is never involved, so owners can manage but not read.
+func (h *Handler) ownedSecret(w http.ResponseWriter, r *http.Request) (*models.Secret, bool) {
+	token := r.Header.Get(ownerTokenHeader)
+	if token == "" {
+		h.error(w, r, http.StatusUnauthorized, "owner token is required")
+		return nil, false
+	}
+
+	secret, err := h.lookup(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		h.handleStoreError(w, r, err)
+		return nil, false
+	}
+
+	if !crypto.VerifyOwnerToken(token, secret.OwnerHash) {
+		slog.WarnContext(r.Context(), "owner auth failed",
+			"ip", getClientIP(r),
+		)
+		h.error(w, r, http.StatusForbidden, "invalid owner token")
+		return nil, false
+	}
+
+	return secret, true
+}
+
+func (h *Handler) DeleteSecret(w http.ResponseWriter, r *http.Request) {
+	secret, ok := h.ownedSecret(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.store.Delete(r.Context(), secret.ID); err != nil {
+		h.handleStoreError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ExtendSecret gives a secret more time, e.g. for a recipient who hasn't
+// got to it yet. A ttl_minutes that would end sooner than the secret
+// already does leaves its expiry alone, so extending never shortens it.
+// Either way the secret never outlives MaxTTL from its creation, however
+// often it is extended. Secrets already used up or expired can't be looked
+// up, so can't be extended.
+func (h *Handler) ExtendSecret(w http.ResponseWriter, r *http.Request) {
+	var req ExtendRequest
+	if !h.decode(w, r, &req) {
+		return
+	}
+	if req.Minutes < 0 {
+		h.error(w, r, http.StatusBadRequest, "minutes must not be negative")
+		return
+	}
+
+	secret, ok := h.ownedSecret(w, r)
+	if !ok {
+		return
+	}
+
+	var expiresAt time.Time
+	if req.Minutes > 0 {
+		expiresAt = secret.ExpiresAt.Add(time.Duration(req.Minutes) * time.Minute)
+	} else {
+		expiresAt = time.Now().Add(clampDuration(
+			time.Duration(req.TTLMinutes)*time.Minute,
+			h.config.Secrets.DefaultTTL,
+			h.config.Secrets.MaxTTL,
+		))
+		if expiresAt.Before(secret.ExpiresAt) {
+			expiresAt = secret.ExpiresAt
+		}
+	}
+	if limit := secret.CreatedAt.Add(h.config.Secrets.MaxTTL); !secret.CreatedAt.IsZero() && expiresAt.After(limit) {
+		expiresAt = limit
+	}
+
+	if err := h.store.Extend(r.Context(), secret.ID, expiresAt); err != nil {
+		h.handleStoreError(w, r, err)
+		return
+	}
+
+	h.json(w, http.StatusOK, ExtendResponse{
+		ExpiresAt: expiresAt,
+		ExpiresIn: humanizeDuration(time.Until(expiresAt)),
+	})
+}
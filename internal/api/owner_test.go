@@ -0,0 +1,182 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"secure.share/config"
+	"secure.share/internal/store"
+)
+
+func ownerRequest(router http.Handler, method, path, token, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set(ownerTokenHeader, token)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestOwnerTokenDelete(t *testing.T) {
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	router := SetupRouter(st, config.Default())
+
+	created, _ := createSecret(t, router, `{"content": "s3cret"}`)
+	if created.OwnerToken == "" {
+		t.Fatalf("expected an owner token in the create response")
+	}
+
+	path := "/api/secrets/" + created.ID
+	if rec := ownerRequest(router, http.MethodDelete, path, "", ""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("missing token: got %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+	other, _ := createSecret(t, router, `{"content": "other"}`)
+	if rec := ownerRequest(router, http.MethodDelete, path, other.OwnerToken, ""); rec.Code != http.StatusForbidden {
+		t.Fatalf("wrong token: got %d, want %d", rec.Code, http.StatusForbidden)
+	}
+
+	if rec := ownerRequest(router, http.MethodDelete, path, created.OwnerToken, ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("delete failed: got %d: %s", rec.Code, rec.Body.String())
+	}
+	if _, err := st.Get(t.Context(), created.ID); err == nil {
+		t.Fatalf("secret still exists after delete")
+	}
+}
+
+func TestOwnerTokenExtend(t *testing.T) {
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	router := SetupRouter(st, config.Default())
+
+	created, passphrase := createSecret(t, router, `{"content": "s3cret", "ttl_minutes": 5}`)
+
+	rec := ownerRequest(router, http.MethodPost, "/api/secrets/"+created.ID+"/extend", created.OwnerToken, `{"ttl_minutes": 120}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("extend failed: got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp ExtendResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode extend response: %v", err)
+	}
+	if !resp.ExpiresAt.After(created.ExpiresAt) {
+		t.Fatalf("expiry not extended: got %s, was %s", resp.ExpiresAt, created.ExpiresAt)
+	}
+	if resp.ExpiresIn != "2 hours" {
+		t.Fatalf("expires_in mismatch: got %q, want %q", resp.ExpiresIn, "2 hours")
+	}
+
+	if rec := revealSecret(router, created.ID, passphrase); rec.Code != http.StatusOK {
+		t.Fatalf("reveal after extend failed: got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestOwnerTokenExtendBy(t *testing.T) {
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	cfg := config.Default()
+	cfg.Secrets.MaxTTL = 2 * time.Hour
+	router := SetupRouter(st, cfg)
+
+	created, passphrase := createSecret(t, router, `{"content": "s3cret", "ttl_minutes": 5}`)
+	path := "/api/secrets/" + created.ID + "/extend"
+	extend := func(body string) ExtendResponse {
+		t.Helper()
+		rec := ownerRequest(router, http.MethodPost, path, created.OwnerToken, body)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("extend %s: got %d: %s", body, rec.Code, rec.Body.String())
+		}
+		var resp ExtendResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode extend response: %v", err)
+		}
+		return resp
+	}
+
+	stored, err := st.Get(t.Context(), created.ID)
+	if err != nil {
+		t.Fatalf("failed to get secret: %v", err)
+	}
+	want := stored.ExpiresAt.Add(30 * time.Minute)
+	if resp := extend(`{"minutes": 30}`); !resp.ExpiresAt.Equal(want) {
+		t.Fatalf("extended to %s, want %s", resp.ExpiresAt, want)
+	}
+
+	limit := stored.CreatedAt.Add(cfg.Secrets.MaxTTL)
+	for _, body := range []string{`{"minutes": 600}`, `{"minutes": 600}`, `{"ttl_minutes": 120}`} {
+		if resp := extend(body); !resp.ExpiresAt.Equal(limit) {
+			t.Fatalf("extend %s: got %s, want the creation cap %s", body, resp.ExpiresAt, limit)
+		}
+	}
+
+	if rec := ownerRequest(router, http.MethodPost, path, created.OwnerToken, `{"minutes": -5}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("negative minutes: got %d, want 400", rec.Code)
+	}
+
+	revealSecret(router, created.ID, passphrase)
+	if rec := ownerRequest(router, http.MethodPost, path, created.OwnerToken, `{"minutes": 5}`); rec.Code == http.StatusOK {
+		t.Fatalf("extended a used up secret: %s", rec.Body.String())
+	}
+}
+
+func TestOwnerTokenExtendNeverShortens(t *testing.T) {
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	router := SetupRouter(st, config.Default())
+
+	created, _ := createSecret(t, router, `{"content": "s3cret", "ttl_minutes": 600}`)
+	stored, err := st.Get(t.Context(), created.ID)
+	if err != nil {
+		t.Fatalf("failed to get secret: %v", err)
+	}
+
+	rec := ownerRequest(router, http.MethodPost, "/api/secrets/"+created.ID+"/extend", created.OwnerToken, `{"ttl_minutes": 5}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("extend failed: got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp ExtendResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode extend response: %v", err)
+	}
+	if !resp.ExpiresAt.Equal(stored.ExpiresAt) {
+		t.Fatalf("extend to 5 minutes moved expiry from %s to %s", stored.ExpiresAt, resp.ExpiresAt)
+	}
+	if after, _ := st.Get(t.Context(), created.ID); !after.ExpiresAt.Equal(stored.ExpiresAt) {
+		t.Fatalf("stored expiry moved from %s to %s", stored.ExpiresAt, after.ExpiresAt)
+	}
+}
+
+func TestOwnerTokenCannotDecrypt(t *testing.T) {
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	router := SetupRouter(st, config.Default())
+
+	created, _ := createSecret(t, router, `{"content": "s3cret"}`)
+
+	rec := revealSecret(router, created.ID, created.OwnerToken)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("reveal with owner token: got %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	if strings.Contains(rec.Body.String(), "s3cret") {
+		t.Fatalf("owner token revealed content: %s", rec.Body.String())
+	}
+}
+
+func TestOwnerTokensDisabled(t *testing.T) {
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	cfg := config.Default()
+	cfg.Secrets.OwnerTokens = false
+	router := SetupRouter(st, cfg)
+
+	created, _ := createSecret(t, router, `{"content": "s3cret"}`)
+	if created.OwnerToken != "" {
+		t.Fatalf("owner token issued while disabled")
+	}
+}
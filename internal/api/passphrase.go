@@ -0,0 +1,36 @@
+package api
+
+import (
+	"secure.share/config"
+	"secure.share/internal/crypto"
+)
+
+// newPassphrasePolicy builds the policy creators' chosen passphrases are
+// held to from crypto.passphrase_policy.
+func newPassphrasePolicy(cfg config.PassphrasePolicyConfig) crypto.PassphrasePolicy {
+	return crypto.DefaultPolicy{
+		MinLength:      cfg.MinLength,
+		MaxLength:      cfg.MaxLength,
+		MinClasses:     cfg.MinClasses,
+		MinEntropyBits: cfg.MinEntropyBits,
+	}
+}
+
+// checkUserPassphrase returns why req's chosen passphrase, already
+// normalized, can't be used, or "" if it can.
+func (h *Handler) checkUserPassphrase(req *CreateRequest, passphrase string) string {
+	switch {
+	case !h.config.Secrets.AllowUserPassphrase:
+		return "user passphrases are not enabled"
+	case req.ClientEncrypted:
+		return "passphrase and client_encrypted cannot both be set"
+	case req.Shares > 0:
+		return "passphrase and shares cannot both be set"
+	}
+
+	// The policy lists every reason, so the creator can fix them all at once.
+	if err := h.passphrasePolicy.Validate(passphrase); err != nil {
+		return err.Error()
+	}
+	return ""
+}
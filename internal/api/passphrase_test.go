@@ -0,0 +1,109 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"secure.share/config"
+	"secure.share/internal/store"
+)
+
+func TestUserPassphrase(t *testing.T) {
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	router := SetupRouter(st, config.Default())
+
+	const chosen = "correct horse battery"
+	rec := postJSON(router, "/api/secrets", `{"content":"s3cret","max_views":2,"passphrase":"`+chosen+`"}`)
+	created, fragment := decodeCreated(t, rec)
+	if fragment != "" || strings.Contains(created.URL, "#") {
+		t.Fatalf("chosen passphrase leaked into the url: %s", created.URL)
+	}
+	if !created.UserPassphrase {
+		t.Fatal("create response doesn't say the passphrase was chosen")
+	}
+
+	stored, err := st.Get(t.Context(), created.ID)
+	if err != nil {
+		t.Fatalf("failed to get secret: %v", err)
+	}
+	if stored.Passphrase != "" || !stored.UserPassphrase {
+		t.Fatalf("stored passphrase = %q, user_passphrase = %v", stored.Passphrase, stored.UserPassphrase)
+	}
+
+	if rec := getPath(router, "/api/secrets/"+created.ID+"/status"); !strings.Contains(rec.Body.String(), `"user_passphrase":true`) {
+		t.Fatalf("status doesn't ask for the passphrase: %s", rec.Body.String())
+	}
+
+	if rec := revealSecret(router, created.ID, "wrong horse battery"); rec.Code != http.StatusForbidden {
+		t.Fatalf("wrong passphrase: got %d, want 403", rec.Code)
+	}
+	rec = revealSecret(router, created.ID, chosen)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "s3cret") {
+		t.Fatalf("reveal with the chosen passphrase: got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestUserPassphraseBurnsAfterWrongGuesses(t *testing.T) {
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	cfg := config.Default()
+	cfg.Secrets.MaxPINAttempts = 2
+	router := SetupRouter(st, cfg)
+
+	created, _ := decodeCreated(t, postJSON(router, "/api/secrets", `{"content":"s3cret","passphrase":"correct horse battery"}`))
+	revealSecret(router, created.ID, "wrong-horse-battery")
+	if rec := revealSecret(router, created.ID, "wrong-horse-battery"); rec.Code != http.StatusGone {
+		t.Fatalf("second wrong guess: got %d, want 410", rec.Code)
+	}
+}
+
+func TestUserPassphraseRejects(t *testing.T) {
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	router := SetupRouter(st, config.Default())
+
+	for name, body := range map[string]string{
+		"short":      `{"content":"x","passphrase":"Tr0ub4dor&3"}`,
+		"repetitive": `{"content":"x","passphrase":"abababababababab"}`,
+		"run":        `{"content":"x","passphrase":"123456789012"}`,
+		"shares":     `{"content":"x","passphrase":"correct horse battery","shares":3,"threshold":2}`,
+	} {
+		if rec := postJSON(router, "/api/secrets", body); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: got %d, want 400", name, rec.Code)
+		}
+	}
+
+	// Every reason a passphrase is weak is listed.
+	rec := postJSON(router, "/api/secrets", `{"content":"x","passphrase":"ababab"}`)
+	for _, reason := range []string{"at least 12 characters", "too repetitive", "too predictable"} {
+		if !strings.Contains(rec.Body.String(), reason) {
+			t.Errorf("rejection doesn't say %q: %s", reason, rec.Body.String())
+		}
+	}
+
+	cfg := config.Default()
+	cfg.Crypto.PassphrasePolicy.MinClasses = 3
+	strict := SetupRouter(st, cfg)
+	rec = postJSON(strict, "/api/secrets", `{"content":"x","passphrase":"correct horse battery"}`)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "at least 3 of") {
+		t.Fatalf("passphrase_policy.min_classes not applied: got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	cfg = config.Default()
+	cfg.Crypto.PassphrasePolicy.MinEntropyBits = 200
+	strict = SetupRouter(st, cfg)
+	rec = postJSON(strict, "/api/secrets", `{"content":"x","passphrase":"correct horse battery"}`)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "need 200") {
+		t.Fatalf("passphrase_policy.min_entropy_bits not applied: got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	cfg = config.Default()
+	cfg.Secrets.AllowUserPassphrase = false
+	disabled := SetupRouter(st, cfg)
+	if rec := postJSON(disabled, "/api/secrets", `{"content":"x","passphrase":"correct horse battery"}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("chosen passphrase with the feature disabled: got %d, want 400", rec.Code)
+	}
+}
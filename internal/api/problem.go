@@ -0,0 +1,88 @@
+package api
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+const (
+	problemContentType = "application/problem+json"
+	problemTypeBase    = "https://secure.share/problems/"
+)
+
+// ProblemResponse is an RFC 7807 error body, sent instead of ErrorResponse
+// when the client asks for application/problem+json.
+type ProblemResponse struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	// Code is ErrorResponse's Code, as an extension member.
+	Code string `json:"code,omitempty"`
+}
+
+// problemTypes maps the status codes the API returns to stable type URIs.
+// Anything not listed falls back to "about:blank" as RFC 7807 suggests.
+var problemTypes = map[int]string{
+	http.StatusBadRequest:            "bad-request",
+	http.StatusUnauthorized:          "unauthorized",
+	http.StatusForbidden:             "forbidden",
+	http.StatusNotFound:              "not-found",
+	http.StatusGone:                  "gone",
+	http.StatusRequestEntityTooLarge: "payload-too-large",
+	http.StatusUnsupportedMediaType:  "unsupported-media-type",
+	http.StatusUnprocessableEntity:   "unprocessable-content",
+	http.StatusTooManyRequests:       "rate-limited",
+	http.StatusInternalServerError:   "internal-error",
+	http.StatusServiceUnavailable:    "unavailable",
+}
+
+func newProblem(status int, detail, instance string) ProblemResponse {
+	problemType := "about:blank"
+	if slug, ok := problemTypes[status]; ok {
+		problemType = problemTypeBase + slug
+	}
+	return ProblemResponse{
+		Type:     problemType,
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: instance,
+	}
+}
+
+// writeError sends message as an ErrorResponse, or as a problem when the
+// client asks for one. Handlers reach it through Handler.error; middleware,
+// which has no Handler, calls it directly so its errors look the same.
+func writeError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	writeErrorCode(w, r, status, "", message)
+}
+
+// writeErrorCode is writeError with a machine-readable code for clients
+// that branch on the failure rather than the status alone.
+func writeErrorCode(w http.ResponseWriter, r *http.Request, status int, code, message string) {
+	if wantsProblem(r) {
+		p := newProblem(status, message, r.URL.Path)
+		p.Code = code
+		w.Header().Set("Content-Type", problemContentType)
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(p)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{Error: message, Code: code})
+}
+
+func wantsProblem(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err == nil && mediaType == problemContentType {
+			return true
+		}
+	}
+	return false
+}
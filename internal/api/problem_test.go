@@ -0,0 +1,123 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"secure.share/config"
+	"secure.share/internal/store"
+)
+
+func TestErrorNegotiation(t *testing.T) {
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	router := SetupRouter(st, config.Default())
+
+	req := httptest.NewRequest(http.MethodGet, "/api/secrets/missing?passphrase=x", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("status mismatch: got %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("content type mismatch: got %s, want application/json", ct)
+	}
+	var plain ErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&plain); err != nil {
+		t.Fatalf("failed to decode error response: %v", err)
+	}
+	if plain.Error != "secret not found" {
+		t.Fatalf("error mismatch: got %q", plain.Error)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/secrets/missing?passphrase=x", nil)
+	req.Header.Set("Accept", "application/problem+json, application/json;q=0.5")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("status mismatch: got %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Fatalf("content type mismatch: got %s, want application/problem+json", ct)
+	}
+	var problem ProblemResponse
+	if err := json.NewDecoder(rec.Body).Decode(&problem); err != nil {
+		t.Fatalf("failed to decode problem response: %v", err)
+	}
+	if !strings.HasSuffix(problem.Type, "/not-found") {
+		t.Fatalf("problem type mismatch: got %s", problem.Type)
+	}
+	if problem.Status != http.StatusNotFound || problem.Title != "Not Found" {
+		t.Fatalf("problem status/title mismatch: got %d %q", problem.Status, problem.Title)
+	}
+	if problem.Detail != "secret not found" || problem.Instance != "/api/secrets/missing" {
+		t.Fatalf("problem detail/instance mismatch: got %q %q", problem.Detail, problem.Instance)
+	}
+}
+
+func TestMiddlewareErrorNegotiation(t *testing.T) {
+	limiter := NewRateLimiter(1, time.Minute)
+	handler := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	request := func(accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/secrets/abc/status", nil)
+		req.Header.Set("Accept", accept)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	request("application/json")
+
+	rec := request("application/json")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("status mismatch: got %d, want %d", rec.Code, http.StatusTooManyRequests)
+	}
+	var plain ErrorResponse
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("content type mismatch: got %s, want application/json", ct)
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&plain); err != nil || plain.Error != "rate limit exceeded" {
+		t.Fatalf("error mismatch: got %q, %v", plain.Error, err)
+	}
+
+	rec = request("application/problem+json")
+	if ct := rec.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Fatalf("content type mismatch: got %s, want application/problem+json", ct)
+	}
+	var problem ProblemResponse
+	if err := json.NewDecoder(rec.Body).Decode(&problem); err != nil {
+		t.Fatalf("failed to decode problem response: %v", err)
+	}
+	if !strings.HasSuffix(problem.Type, "/rate-limited") || problem.Status != http.StatusTooManyRequests {
+		t.Fatalf("problem mismatch: got %+v", problem)
+	}
+}
+
+func TestJSONOnlyProblem(t *testing.T) {
+	handler := JSONOnly(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	req := httptest.NewRequest(http.MethodPost, "/api/secrets", strings.NewReader("{}"))
+	req.Header.Set("Content-Type", "text/plain")
+	req.Header.Set("Accept", "application/problem+json")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if ct := rec.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Fatalf("content type mismatch: got %s, want application/problem+json", ct)
+	}
+	var problem ProblemResponse
+	if err := json.NewDecoder(rec.Body).Decode(&problem); err != nil {
+		t.Fatalf("failed to decode problem response: %v", err)
+	}
+	if !strings.HasSuffix(problem.Type, "/unsupported-media-type") || problem.Code != "unsupported_media_type" {
+		t.Fatalf("problem mismatch: got %+v", problem)
+	}
+}
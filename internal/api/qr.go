@@ -0,0 +1,69 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"secure.share/internal/qr"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	defaultQRSize = 256
+	maxQRSize     = 2048
+)
+
+// SecretQR renders a secret's reveal link as a PNG QR code. The server
+// never stores the fragment, so the caller passes the link it was given at
+// create as url, or the passphrase to append as passphrase; with neither
+// the code holds the bare link. Only links to this secret are rendered, so
+// the endpoint can't be used to put arbitrary URLs behind this origin.
+func (h *Handler) SecretQR(w http.ResponseWriter, r *http.Request) {
+	size := defaultQRSize
+	if v := r.URL.Query().Get("size"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxQRSize {
+			h.error(w, r, http.StatusBadRequest, "size must be between 1 and "+strconv.Itoa(maxQRSize))
+			return
+		}
+		size = n
+	}
+
+	secret, err := h.lookup(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		h.handleStoreError(w, r, err)
+		return
+	}
+
+	link := h.config.Server.BaseURL + "/s/" + secret.ID
+	if u := r.URL.Query().Get("url"); u != "" {
+		rest, ok := strings.CutPrefix(u, link)
+		if !ok || rest != "" && rest[0] != '?' && rest[0] != '#' {
+			h.error(w, r, http.StatusBadRequest, "url is not this secret's link")
+			return
+		}
+		link = u
+	} else if p := r.URL.Query().Get("passphrase"); p != "" {
+		link += "#" + p
+	}
+
+	img, err := qr.PNG(link, size)
+	if errors.Is(err, qr.ErrTooLong) {
+		h.error(w, r, http.StatusBadRequest, "link is too long for a QR code")
+		return
+	}
+	if err != nil {
+		h.error(w, r, http.StatusInternalServerError, "rendering failed")
+		return
+	}
+
+	// The image may hold the passphrase.
+	w.Header().Set("Cache-Control", "no-store, private")
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Content-Length", strconv.Itoa(len(img)))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(img)
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"secure.share/config"
+	"secure.share/internal/qr"
+	"secure.share/internal/store"
+)
+
+func TestSecretQR(t *testing.T) {
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	router := SetupRouter(st, config.Default())
+
+	created, passphrase := createSecret(t, router, `{"content": "s3cret"}`)
+	base := "/api/secrets/" + created.ID + "/qr?size=200&"
+
+	for name, query := range map[string]string{
+		"url":        "url=" + url.QueryEscape(created.URL),
+		"passphrase": "passphrase=" + url.QueryEscape(passphrase),
+	} {
+		rec := getPath(router, base+query)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: got %d: %s", name, rec.Code, rec.Body.String())
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != "image/png" {
+			t.Fatalf("%s: content type %q", name, ct)
+		}
+		want, err := qr.PNG(created.URL, 200)
+		if err != nil {
+			t.Fatalf("encode failed: %v", err)
+		}
+		if !bytes.Equal(rec.Body.Bytes(), want) {
+			t.Fatalf("%s: image doesn't encode the reveal link", name)
+		}
+	}
+
+	for name, path := range map[string]string{
+		"other url":    base + "url=" + url.QueryEscape("https://evil.example/s/"+created.ID),
+		"url prefix":   base + "url=" + url.QueryEscape(created.URL[:len(created.URL)-len(passphrase)-1]+"x"),
+		"size":         "/api/secrets/" + created.ID + "/qr?size=99999",
+		"unknown":      "/api/secrets/doesnotexist/qr",
+		"not a number": "/api/secrets/" + created.ID + "/qr?size=big",
+	} {
+		if rec := getPath(router, path); rec.Code == http.StatusOK {
+			t.Errorf("%s: got 200", name)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"secure.share/internal/models"
+)
+
+// rawChunkSize is how much of the content RevealRaw writes, and flushes,
+// at a time.
+const rawChunkSize = 32 * 1024
+
+// RevealRaw reveals a secret like RevealSecret but sends the content as-is
+// instead of in a JSON document, so large secrets are neither copied into a
+// string nor escaped. Decryption is still all at once: AES-GCM can't
+// authenticate part of a message.
+func (h *Handler) RevealRaw(w http.ResponseWriter, r *http.Request) {
+	if !h.config.Secrets.StreamReveal {
+		h.error(w, r, http.StatusNotFound, "raw reveal is not enabled")
+		return
+	}
+
+	secret, content, currentViews, ok := h.reveal(w, r, false)
+	if !ok {
+		return
+	}
+
+	h.writeRaw(w, w, r, secret, content, currentViews, "application/octet-stream")
+}
+
+// writeRaw sends revealed content as the response body in chunks, with the
+// view count and next nonce in headers. The body goes through out, which is
+// w itself or a throttled wrapper of it.
+func (h *Handler) writeRaw(w http.ResponseWriter, out io.Writer, r *http.Request, secret *models.Secret, content []byte, currentViews int, contentType string) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if !secret.HideViews {
+		w.Header().Set("X-Views-Remaining", strconv.Itoa(secret.MaxViews-currentViews))
+	}
+	if nonce := nextRevealNonce(secret, currentViews); nonce != "" {
+		w.Header().Set(revealNonceHeader, nonce)
+	}
+	w.WriteHeader(http.StatusOK)
+
+	// As with archives the view is already used, so a failure past this
+	// point can only be logged; the client sees fewer bytes than
+	// Content-Length promised.
+	rc := http.NewResponseController(w)
+	for len(content) > 0 {
+		n := min(len(content), rawChunkSize)
+		if _, err := out.Write(content[:n]); err != nil {
+			slog.WarnContext(r.Context(), "failed to write raw reveal", "error", err)
+			return
+		}
+		if err := rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			slog.WarnContext(r.Context(), "failed to flush raw reveal", "error", err)
+			return
+		}
+		content = content[n:]
+	}
+}
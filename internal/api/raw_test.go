@@ -0,0 +1,119 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"secure.share/config"
+	"secure.share/internal/store"
+)
+
+// chunkWriter is a ResponseWriter that refuses writes bigger than limit,
+// so a handler that buffers the whole content fails, and can be made to
+// fail outright after failAfter bytes.
+type chunkWriter struct {
+	header    http.Header
+	status    int
+	body      bytes.Buffer
+	limit     int
+	failAfter int
+	flushes   int
+}
+
+func (c *chunkWriter) Header() http.Header { return c.header }
+
+func (c *chunkWriter) WriteHeader(status int) { c.status = status }
+
+func (c *chunkWriter) Write(p []byte) (int, error) {
+	if c.status == 0 {
+		c.status = http.StatusOK
+	}
+	if len(p) > c.limit {
+		return 0, errors.New("write exceeds limit")
+	}
+	if c.failAfter > 0 && c.body.Len()+len(p) > c.failAfter {
+		return 0, errors.New("connection reset")
+	}
+	return c.body.Write(p)
+}
+
+func (c *chunkWriter) Flush() { c.flushes++ }
+
+func newRawRouter(t *testing.T) http.Handler {
+	t.Helper()
+	cfg := config.Default()
+	cfg.Secrets.StreamReveal = true
+	st := store.NewMemoryStore(time.Minute)
+	t.Cleanup(func() { st.Close() })
+	return SetupRouter(st, cfg)
+}
+
+func rawRequest(id, passphrase string) *http.Request {
+	return httptest.NewRequest(http.MethodGet, "/api/secrets/"+id+"/raw?passphrase="+url.QueryEscape(passphrase), nil)
+}
+
+func TestRevealRaw(t *testing.T) {
+	router := newRawRouter(t)
+
+	content := strings.Repeat("0123456789abcdef", 64*1024) // 1MiB
+	created, passphrase := createSecret(t, router, `{"content": "`+content+`", "max_views": 2}`)
+
+	w := &chunkWriter{header: http.Header{}, limit: rawChunkSize}
+	router.ServeHTTP(w, rawRequest(created.ID, passphrase))
+	if w.status != http.StatusOK {
+		t.Fatalf("raw reveal failed: got %d: %s", w.status, w.body.String())
+	}
+	if w.body.String() != content {
+		t.Fatalf("content mismatch: got %d bytes, want %d", w.body.Len(), len(content))
+	}
+	if w.flushes < len(content)/rawChunkSize {
+		t.Fatalf("content was flushed %d times, want at least %d", w.flushes, len(content)/rawChunkSize)
+	}
+	if got := w.header.Get("Content-Type"); got != "application/octet-stream" {
+		t.Fatalf("content type mismatch: got %q", got)
+	}
+	if got := w.header.Get("X-Views-Remaining"); got != "1" {
+		t.Fatalf("views remaining mismatch: got %q", got)
+	}
+}
+
+func TestRevealRawWriteError(t *testing.T) {
+	router := newRawRouter(t)
+
+	content := strings.Repeat("x", 4*rawChunkSize)
+	created, passphrase := createSecret(t, router, `{"content": "`+content+`"}`)
+
+	// The status is out before the connection drops, so the handler can
+	// only stop writing.
+	w := &chunkWriter{header: http.Header{}, limit: rawChunkSize, failAfter: rawChunkSize}
+	router.ServeHTTP(w, rawRequest(created.ID, passphrase))
+	if w.status != http.StatusOK || w.body.Len() != rawChunkSize {
+		t.Fatalf("got %d with %d bytes, want %d with %d", w.status, w.body.Len(), http.StatusOK, rawChunkSize)
+	}
+
+	if rec := revealSecret(router, created.ID, passphrase); rec.Code != http.StatusNotFound {
+		t.Fatalf("reveal after interrupted raw reveal: got %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestRevealRawDisabled(t *testing.T) {
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	router := SetupRouter(st, config.Default())
+
+	created, passphrase := createSecret(t, router, `{"content": "s3cret"}`)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, rawRequest(created.ID, passphrase))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("raw reveal while disabled: got %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	if rec := revealSecret(router, created.ID, passphrase); rec.Code != http.StatusOK {
+		t.Fatalf("reveal after refused raw reveal: got %d, want %d", rec.Code, http.StatusOK)
+	}
+}
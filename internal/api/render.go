@@ -0,0 +1,261 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"html"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const maxContentTypeLength = 127
+
+// renderer formats revealed content of one stored content type as output.
+type renderer struct {
+	output string
+	render func([]byte) ([]byte, error)
+}
+
+// plainRenderer is used for content without a type, of a type without a
+// renderer, or when the client doesn't accept the renderer's output.
+var plainRenderer = renderer{
+	output: "text/plain",
+	render: func(content []byte) ([]byte, error) { return content, nil },
+}
+
+// renderers are the built-in renderers by the content type they read;
+// Secrets.Renderers picks which are enabled.
+var renderers = map[string]renderer{
+	"application/json": {output: "application/json", render: renderJSON},
+	"text/markdown":    {output: "text/html", render: renderMarkdown},
+}
+
+// normalizeContentType reduces a create request's content type to its
+// lower-case media type, dropping parameters.
+func normalizeContentType(contentType string) (string, bool) {
+	if contentType == "" {
+		return "", true
+	}
+	if len(contentType) > maxContentTypeLength {
+		return "", false
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return mediaType, err == nil
+}
+
+// isText reports whether content is valid UTF-8 without NULs. JSON
+// decoding has already replaced invalid bytes with U+FFFD, so that counts
+// as invalid too.
+func isText(content string) bool {
+	return utf8.ValidString(content) && !strings.ContainsAny(content, "\x00\uFFFD")
+}
+
+// accepts reports whether the Accept header allows mediaType. A missing
+// header accepts anything.
+func accepts(r *http.Request, mediaType string) bool {
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return true
+	}
+	typ, _, _ := strings.Cut(mediaType, "/")
+	for _, part := range strings.Split(accept, ",") {
+		accepted, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		if q, err := strconv.ParseFloat(params["q"], 64); err == nil && q <= 0 {
+			continue
+		}
+		if accepted == mediaType || accepted == typ+"/*" || accepted == "*/*" {
+			return true
+		}
+	}
+	return false
+}
+
+// RevealRendered reveals a secret formatted for its content type: JSON is
+// indented and markdown becomes HTML. Content of other types, or that the
+// client won't accept formatted, comes back as plain text.
+func (h *Handler) RevealRendered(w http.ResponseWriter, r *http.Request) {
+	secret, content, currentViews, ok := h.reveal(w, r, false)
+	if !ok {
+		return
+	}
+
+	rr, ok := h.renderers[secret.ContentType]
+	if !ok || !accepts(r, rr.output) {
+		rr = plainRenderer
+	}
+	body, err := rr.render(content)
+	if err != nil {
+		// The view is used either way; the content is still worth having.
+		rr = plainRenderer
+		body = content
+	}
+
+	w.Header().Set("Content-Type", rr.output+"; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	// Rendered HTML has no scripts, styles or images; make sure a browser
+	// opening it directly wouldn't run any.
+	w.Header().Set("Content-Security-Policy", "default-src 'none'")
+	if !secret.HideViews {
+		w.Header().Set("X-Views-Remaining", strconv.Itoa(secret.MaxViews-currentViews))
+	}
+	if nonce := nextRevealNonce(secret, currentViews); nonce != "" {
+		w.Header().Set(revealNonceHeader, nonce)
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+func renderJSON(content []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, content, "", "  "); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+var (
+	mdHeading  = regexp.MustCompile(`^(#{1,6})\s+(.*)$`)
+	mdBullet   = regexp.MustCompile(`^\s*[-*+]\s+(.*)$`)
+	mdOrdered  = regexp.MustCompile(`^\s*\d+[.)]\s+(.*)$`)
+	mdLink     = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	mdStrong   = regexp.MustCompile(`\*\*([^*]+)\*\*`)
+	mdEmphasis = regexp.MustCompile(`\*([^*]+)\*`)
+)
+
+// renderMarkdown turns a subset of markdown (headings, paragraphs, lists,
+// fenced and inline code, emphasis and links) into HTML. Every piece of
+// the content is escaped before any tag is added, and links only keep
+// http, https and mailto URLs, so the only markup in the result is the
+// renderer's own.
+func renderMarkdown(content []byte) ([]byte, error) {
+	var b strings.Builder
+	var paragraph []string
+	list := ""
+
+	flushParagraph := func() {
+		if len(paragraph) > 0 {
+			b.WriteString("<p>" + renderInline(strings.Join(paragraph, "\n")) + "</p>\n")
+			paragraph = nil
+		}
+	}
+	closeList := func() {
+		if list != "" {
+			b.WriteString("</" + list + ">\n")
+			list = ""
+		}
+	}
+	openList := func(tag string) {
+		if list != tag {
+			closeList()
+			b.WriteString("<" + tag + ">\n")
+			list = tag
+		}
+	}
+
+	lines := strings.Split(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if strings.HasPrefix(line, "```") {
+			flushParagraph()
+			closeList()
+			b.WriteString("<pre><code>")
+			for i++; i < len(lines) && !strings.HasPrefix(lines[i], "```"); i++ {
+				b.WriteString(html.EscapeString(lines[i]) + "\n")
+			}
+			b.WriteString("</code></pre>\n")
+			continue
+		}
+		if m := mdHeading.FindStringSubmatch(line); m != nil {
+			flushParagraph()
+			closeList()
+			level := strconv.Itoa(len(m[1]))
+			b.WriteString("<h" + level + ">" + renderInline(m[2]) + "</h" + level + ">\n")
+			continue
+		}
+		if m := mdBullet.FindStringSubmatch(line); m != nil {
+			flushParagraph()
+			openList("ul")
+			b.WriteString("<li>" + renderInline(m[1]) + "</li>\n")
+			continue
+		}
+		if m := mdOrdered.FindStringSubmatch(line); m != nil {
+			flushParagraph()
+			openList("ol")
+			b.WriteString("<li>" + renderInline(m[1]) + "</li>\n")
+			continue
+		}
+		closeList()
+		if strings.TrimSpace(line) == "" {
+			flushParagraph()
+			continue
+		}
+		paragraph = append(paragraph, line)
+	}
+	flushParagraph()
+	closeList()
+	return []byte(b.String()), nil
+}
+
+// renderInline formats code spans, links and emphasis in one block of
+// text. Code spans are split out first so nothing inside them is
+// formatted.
+func renderInline(text string) string {
+	var b strings.Builder
+	parts := strings.Split(text, "`")
+	for i, part := range parts {
+		switch {
+		case i%2 == 1 && i < len(parts)-1:
+			b.WriteString("<code>" + html.EscapeString(part) + "</code>")
+		case i%2 == 1:
+			// An unmatched backtick is literal.
+			b.WriteString("`" + renderLinks(part))
+		default:
+			b.WriteString(renderLinks(part))
+		}
+	}
+	return b.String()
+}
+
+func renderLinks(text string) string {
+	var b strings.Builder
+	last := 0
+	for _, m := range mdLink.FindAllStringSubmatchIndex(text, -1) {
+		b.WriteString(renderEmphasis(text[last:m[0]]))
+		label, href := text[m[2]:m[3]], text[m[4]:m[5]]
+		if safeLink(href) {
+			b.WriteString(`<a href="` + html.EscapeString(href) + `" rel="nofollow noopener noreferrer">` + renderEmphasis(label) + "</a>")
+		} else {
+			b.WriteString(renderEmphasis(label))
+		}
+		last = m[1]
+	}
+	b.WriteString(renderEmphasis(text[last:]))
+	return b.String()
+}
+
+func renderEmphasis(text string) string {
+	escaped := html.EscapeString(text)
+	escaped = mdStrong.ReplaceAllString(escaped, "<strong>$1</strong>")
+	return mdEmphasis.ReplaceAllString(escaped, "<em>$1</em>")
+}
+
+func safeLink(href string) bool {
+	u, err := url.Parse(href)
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https", "mailto":
+		return true
+	}
+	return false
+}
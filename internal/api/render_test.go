@@ -0,0 +1,190 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"secure.share/config"
+	"secure.share/internal/store"
+)
+
+func revealRendered(router http.Handler, id, passphrase, accept string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/api/secrets/"+id+"/render?passphrase="+url.QueryEscape(passphrase), nil)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRenderMarkdownIsSanitized(t *testing.T) {
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	router := SetupRouter(st, config.Default())
+
+	content := "# Access <script>alert(1)</script>\n\n" +
+		"Use **this** and `<b>`.\n\n" +
+		"- [docs](https://example.com/?a=1&b=2)\n" +
+		"- [bad](javascript:alert%281%29)\n\n" +
+		`<img src=x onerror="alert(1)">`
+	body := `{"content":` + strconv.Quote(content) + `,"content_type":"text/markdown; charset=utf-8"}`
+	created, passphrase := createSecret(t, router, body)
+
+	rec := revealRendered(router, created.ID, passphrase, "text/html")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("render failed: got %d: %s", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "text/html; charset=utf-8" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+
+	got := rec.Body.String()
+	for _, want := range []string{
+		"<h1>Access &lt;script&gt;alert(1)&lt;/script&gt;</h1>",
+		"<strong>this</strong>",
+		"<code>&lt;b&gt;</code>",
+		`<a href="https://example.com/?a=1&amp;b=2" rel="nofollow noopener noreferrer">docs</a>`,
+		"<li>bad</li>",
+		"&lt;img src=x onerror=&#34;alert(1)&#34;&gt;",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("rendered HTML lacks %q:\n%s", want, got)
+		}
+	}
+	for _, bad := range []string{"<script", "<img", "javascript:"} {
+		if strings.Contains(got, bad) {
+			t.Errorf("rendered HTML contains %q:\n%s", bad, got)
+		}
+	}
+}
+
+func TestRenderFallsBackToPlain(t *testing.T) {
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	router := SetupRouter(st, config.Default())
+
+	tests := []struct {
+		name, contentType, accept string
+	}{
+		{"unknown type", "application/x-unknown", "*/*"},
+		{"no type", "", ""},
+		{"html not accepted", "text/markdown", "text/plain"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			created, passphrase := createSecret(t, router, `{"content":"# <b>hi</b>","content_type":"`+tt.contentType+`"}`)
+			rec := revealRendered(router, created.ID, passphrase, tt.accept)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("render failed: got %d: %s", rec.Code, rec.Body.String())
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "text/plain; charset=utf-8" {
+				t.Fatalf("Content-Type = %q", ct)
+			}
+			if rec.Body.String() != "# <b>hi</b>" {
+				t.Fatalf("body = %q", rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestRenderJSON(t *testing.T) {
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	router := SetupRouter(st, config.Default())
+
+	created, passphrase := createSecret(t, router, `{"content":"{\"user\":\"admin\",\"port\":5432}","content_type":"application/json"}`)
+	rec := revealRendered(router, created.ID, passphrase, "")
+	want := "{\n  \"user\": \"admin\",\n  \"port\": 5432\n}\n"
+	if rec.Code != http.StatusOK || rec.Body.String() != want {
+		t.Fatalf("got %d %q, want %q", rec.Code, rec.Body.String(), want)
+	}
+}
+
+func TestRenderDisabledRenderer(t *testing.T) {
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	cfg := config.Default()
+	cfg.Secrets.Renderers = []string{"application/json"}
+	router := SetupRouter(st, cfg)
+
+	created, passphrase := createSecret(t, router, `{"content":"**hi**","content_type":"text/markdown"}`)
+	rec := revealRendered(router, created.ID, passphrase, "text/html, */*")
+	if rec.Header().Get("Content-Type") != "text/plain; charset=utf-8" || rec.Body.String() != "**hi**" {
+		t.Fatalf("disabled renderer was used: %q %q", rec.Header().Get("Content-Type"), rec.Body.String())
+	}
+}
+
+func TestCreateRejectsInvalidContentType(t *testing.T) {
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	router := SetupRouter(st, config.Default())
+
+	rec := postJSON(router, "/api/secrets", `{"content":"x","content_type":"not a type"}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("got %d, want 400", rec.Code)
+	}
+}
+
+func TestIsText(t *testing.T) {
+	tests := []struct {
+		content string
+		want    bool
+	}{
+		{"plain ascii", true},
+		{"ünïcödé ✓ 密码", true},
+		{"tabs\tand\nnewlines\r\n", true},
+		{"nul\x00byte", false},
+		{"invalid \xff\xfe utf-8", false},
+		{"decoded \uFFFD replacement", false},
+	}
+	for _, tt := range tests {
+		if got := isText(tt.content); got != tt.want {
+			t.Errorf("isText(%q) = %v, want %v", tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestStrictTextRejectsBinary(t *testing.T) {
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	cfg := config.Default()
+	cfg.Secrets.StrictText = true
+	router := SetupRouter(st, cfg)
+
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"utf-8 text", `{"content":"pässwörd ✓"}`, http.StatusCreated},
+		{"nul", `{"content":"PK\u0003\u0004\u0000\u0000"}`, http.StatusBadRequest},
+		{"declared plain", `{"content":"a\u0000b","content_type":"text/plain; charset=utf-8"}`, http.StatusBadRequest},
+		{"invalid utf-8", "{\"content\":\"\x89PNG\xff\"}", http.StatusBadRequest},
+		{"other type", `{"content":"a\u0000b","content_type":"application/octet-stream"}`, http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := postJSON(router, "/api/secrets", tt.body)
+			if rec.Code != tt.want {
+				t.Fatalf("got %d, want %d: %s", rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+
+	rec := postJSON(router, "/api/secrets", `{"files":[{"name":"a.bin","content":"a\u0000b"}]}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("files were checked as text: got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// Off by default.
+	router = SetupRouter(st, config.Default())
+	if rec := postJSON(router, "/api/secrets", `{"content":"a\u0000b"}`); rec.Code != http.StatusCreated {
+		t.Fatalf("binary refused without strict_text: got %d", rec.Code)
+	}
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"secure.share/internal/crypto"
+)
+
+const reservationHeader = "X-Reservation-Token"
+
+// maxReservations bounds the reservations an instance holds, like maxDrafts.
+const maxReservations = 10000
+
+// reservations remember which archive downloads already used a view, so the
+// recipient holding the token can revalidate with If-None-Match and get a
+// 304 instead of spending another view. Like drafts they live in process
+// memory and only the instance that served the download knows them.
+type reservations struct {
+	mu    sync.Mutex
+	ttl   time.Duration
+	items map[string]reservation
+}
+
+type reservation struct {
+	id      string
+	etag    string
+	expires time.Time
+}
+
+func newReservations(ttl time.Duration) *reservations {
+	return &reservations{ttl: ttl, items: make(map[string]reservation)}
+}
+
+// reserve records a download of id with content and returns the token and
+// ETag to hand back, or false when the table is full. The ETag is keyed by
+// the token so it reveals nothing about the content to anyone without it.
+func (res *reservations) reserve(id string, content []byte) (token, etag string, ok bool) {
+	res.mu.Lock()
+	defer res.mu.Unlock()
+
+	now := time.Now()
+	for t, item := range res.items {
+		if now.After(item.expires) {
+			delete(res.items, t)
+		}
+	}
+	if len(res.items) >= maxReservations {
+		return "", "", false
+	}
+
+	token = crypto.GenerateOwnerToken()
+	mac := hmac.New(sha256.New, []byte(token))
+	mac.Write(content)
+	etag = `"` + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)) + `"`
+	res.items[token] = reservation{id: id, etag: etag, expires: now.Add(res.ttl)}
+	return token, etag, true
+}
+
+// revalidate reports whether r holds a live reservation for id whose ETag
+// is listed in If-None-Match, and returns that ETag.
+func (res *reservations) revalidate(r *http.Request, id string) (string, bool) {
+	token := r.Header.Get(reservationHeader)
+	match := r.Header.Get("If-None-Match")
+	if token == "" || match == "" {
+		return "", false
+	}
+
+	res.mu.Lock()
+	item, ok := res.items[token]
+	res.mu.Unlock()
+	if !ok || item.id != id || time.Now().After(item.expires) {
+		return "", false
+	}
+
+	for _, candidate := range strings.Split(match, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == item.etag {
+			return item.etag, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"secure.share/internal/models"
+	"secure.share/internal/store"
+)
+
+// legacyIDFormats map an ID as it appears in an old link to the form it is
+// stored under today. Each returns false if the ID can't be in that format.
+var legacyIDFormats = map[string]func(id string) (string, bool){
+	// Links whose IDs were rendered with padding.
+	"padded": func(id string) (string, bool) {
+		trimmed := strings.TrimRight(id, "=")
+		return trimmed, trimmed != id
+	},
+	// Links whose IDs used standard base64 (+ and /, padded) rather than
+	// the URL-safe alphabet.
+	"base64std": func(id string) (string, bool) {
+		converted := strings.NewReplacer("+", "-", "/", "_").Replace(strings.TrimRight(id, "="))
+		return converted, converted != id
+	},
+}
+
+// lookup fetches a secret by ID, falling back through the configured legacy
+// ID formats before giving up, so changing the ID scheme doesn't break links
+// that are already out there. Callers should use secret.ID from then on.
+// IDs found in none of them are cached as missing for a while, if enabled.
+func (h *Handler) lookup(ctx context.Context, id string) (*models.Secret, error) {
+	if h.misses != nil && h.misses.has(id) {
+		return nil, store.ErrNotFound
+	}
+
+	secret, err := h.store.Get(ctx, id)
+	if !errors.Is(err, store.ErrNotFound) {
+		return secret, err
+	}
+
+	for _, name := range h.config.Crypto.LegacyIDFormats {
+		candidate, ok := legacyIDFormats[name](id)
+		if !ok {
+			continue
+		}
+		if secret, legacyErr := h.store.Get(ctx, candidate); !errors.Is(legacyErr, store.ErrNotFound) {
+			return secret, legacyErr
+		}
+	}
+	if h.misses != nil {
+		h.misses.add(id)
+	}
+	return nil, err
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"net/http"
 	"time"
 
 	"secure.share/config"
@@ -11,50 +12,131 @@ import (
 )
 
 func SetupRouter(s store.Store, cfg *config.Config) *chi.Mux {
+	app, _ := SetupRouters(s, cfg)
+	return app
+}
+
+// SetupRouters is SetupRouter plus, when metrics are enabled on their own
+// address, the router to serve there; metrics is nil otherwise.
+func SetupRouters(s store.Store, cfg *config.Config) (app, metrics *chi.Mux) {
 	h := NewHandler(s, cfg)
+	app = newRouter(h, cfg)
+	if cfg.Metrics.Enabled && cfg.Metrics.Addr != "" {
+		metrics = chi.NewRouter()
+		metrics.Get("/metrics", h.Metrics)
+	}
+	return app, metrics
+}
 
+func newRouter(h *Handler, cfg *config.Config) *chi.Mux {
 	r := chi.NewRouter()
 
 	// Global middleware
 	r.Use(middleware.RealIP)
-	r.Use(RequestID)
-	r.Use(Logger)
+	r.Use(RequestIDWithHeader(cfg.Server.RequestIDHeader))
+	r.Use(LoggerWithSampling(cfg.Server.LogSampleRate))
 	r.Use(middleware.Recoverer)
-	r.Use(middleware.Timeout(30 * time.Second))
+	r.Use(TimeoutUnless(30*time.Second, h.isThrottledDownload))
+	if h.metrics != nil {
+		r.Use(h.metrics.Middleware)
+	}
 
 	// CORS
 	r.Use(CORS(CORSConfig{
-		AllowedOrigins: []string{"127.0.0.1"},
-		AllowedMethods: []string{"GET", "POST", "OPTIONS"},
-		AllowedHeaders: []string{"Content-Type", "X-Request-ID"},
+		AllowedOrigins: cfg.Server.CORS.AllowedOrigins,
+		AllowedMethods: cfg.Server.CORS.AllowedMethods,
+		AllowedHeaders: cfg.Server.CORS.AllowedHeaders,
 		MaxAge:         86400,
 	}))
 
 	// Health
 	r.Get("/health", h.Health)
+	r.Get("/readyz", h.Ready)
+	if h.metrics != nil && cfg.Metrics.Addr == "" {
+		r.Get("/metrics", h.Metrics)
+	}
 
 	// API routes
 	r.Route("/api", func(r chi.Router) {
+		// API keys are checked first, so the rate limiter can count them
+		// and the routes below can check their scopes.
+		var createMiddleware, revealMiddleware []func(http.Handler) http.Handler
+		if cfg.APIKeys.Enabled {
+			r.Use(h.authenticateAPIKey)
+			createMiddleware = append(createMiddleware, h.requireScope(scopeCreate))
+			revealMiddleware = append(revealMiddleware, h.requireScope(scopeReveal))
+		}
+
 		// Apply rate limiting if enabled
 		if cfg.RateLimit.Enabled {
 			apiLimiter := NewRateLimiter(cfg.RateLimit.RequestsPerMin, time.Minute)
 			revealLimiter := NewRateLimiter(cfg.RateLimit.RevealPerMin, time.Minute)
 
 			r.Use(apiLimiter.Middleware)
-			r.Use(JSONOnly)
+			revealMiddleware = append(revealMiddleware, revealLimiter.Middleware)
+		}
 
-			r.Route("/secrets", func(r chi.Router) {
-				r.Post("/", h.CreateSecret)
-				r.With(revealLimiter.Middleware).Get("/{id}", h.RevealSecret)
-				r.Get("/{id}/status", h.GetStatus)
-			})
-		} else {
-			r.Use(JSONOnly)
+		// Creates and reveals can be pinned to pages served from BaseURL.
+		var originMiddleware []func(http.Handler) http.Handler
+		if cfg.Server.SameOrigin {
+			originMiddleware = append(originMiddleware, SameOrigin(cfg.Server.BaseURL))
+		}
+
+		// File uploads are multipart; every route after them takes JSON only.
+		if cfg.Secrets.MaxFileBytes > 0 {
+			r.With(originMiddleware...).With(createMiddleware...).Post("/secrets/file", h.CreateFileSecret)
+		}
+		r = r.With(JSONOnlyFor(cfg.Server.JSONContentTypes, cfg.Server.MaxBodyBytes))
+
+		r.Get("/capabilities", h.Capabilities)
+		if cfg.Stats.Enabled {
+			var statsMiddleware []func(http.Handler) http.Handler
+			if cfg.Stats.RequestsPerMin > 0 {
+				statsMiddleware = append(statsMiddleware, NewRateLimiter(cfg.Stats.RequestsPerMin, time.Minute).Middleware)
+			}
+			r.With(statsMiddleware...).Get("/stats", h.Stats)
+		}
+		r.Post("/integrity/verify", h.VerifyIntegrity)
+
+		r.Route("/secrets", func(r chi.Router) {
+			origin := r.With(originMiddleware...)
+			origin.With(createMiddleware...).Post("/", h.CreateSecret)
+			origin.With(createMiddleware...).Post("/draft", h.CreateDraft)
+			origin.With(createMiddleware...).Post("/draft/{token}/commit", h.CommitDraft)
+			origin.With(revealMiddleware...).Get("/{id}", h.RevealSecret)
+			origin.With(revealMiddleware...).Get("/{id}/archive", h.DownloadArchive)
+			origin.With(revealMiddleware...).Get("/{id}/raw", h.RevealRaw)
+			origin.With(revealMiddleware...).Get("/{id}/render", h.RevealRendered)
+			r.With(revealMiddleware...).Get("/{id}/note", h.PeekNote)
+			r.Get("/{id}/status", h.GetStatus)
+			r.Get("/{id}/qr", h.SecretQR)
+			r.With(revealMiddleware...).Post("/{id}/ack", h.Acknowledge)
+			r.With(revealMiddleware...).Post("/{id}/request-code", h.RequestCode)
+			r.With(revealMiddleware...).Post("/{id}/burn", h.BurnSecret)
+			r.With(createMiddleware...).Delete("/{id}", h.DeleteSecret)
+			r.With(createMiddleware...).Post("/{id}/extend", h.ExtendSecret)
+			if cfg.Secrets.AccessLogEntries > 0 {
+				r.With(createMiddleware...).Get("/{id}/log", h.AccessLog)
+			}
+			if cfg.SignedLinks.Key != "" {
+				r.With(createMiddleware...).Post("/{id}/signed-link", h.CreateSignedLink)
+				origin.With(revealMiddleware...).Get("/{id}/signed", h.RevealSigned)
+			}
+		})
 
-			r.Route("/secrets", func(r chi.Router) {
-				r.Post("/", h.CreateSecret)
-				r.Get("/{id}", h.RevealSecret)
-				r.Get("/{id}/status", h.GetStatus)
+		// Admin routes only exist when an admin token is configured
+		if cfg.Admin.Token != "" || len(cfg.Admin.ClientSubjects) > 0 {
+			r.Route("/admin", func(r chi.Router) {
+				r.Use(AdminAuthWithSubjects(cfg.Admin.Token, cfg.Admin.ClientSubjects))
+				r.Post("/purge", h.Purge)
+				r.Get("/stats", h.AdminStats)
+				if cfg.Secrets.AccessLogEntries > 0 {
+					r.Get("/secrets/{id}/log", h.AdminAccessLog)
+				}
+				if cfg.APIKeys.Enabled {
+					r.Post("/keys", h.CreateAPIKey)
+					r.Delete("/keys/{keyID}", h.RevokeAPIKey)
+				}
 			})
 		}
 	})
@@ -62,6 +144,7 @@ func SetupRouter(s store.Store, cfg *config.Config) *chi.Mux {
 	// Frontend
 	r.Get("/", h.Index)
 	r.Get("/s/{id}", h.RevealPage)
+	r.Get("/static/reveal.js", h.RevealScript)
 
 	return r
 }
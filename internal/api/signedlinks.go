@@ -0,0 +1,88 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// signedLinkScope is the only scope links are signed for so far; it is part
+// of the signature so links for any later scope can't be used to reveal.
+const signedLinkScope = "reveal"
+
+const signedLinkContextKey contextKey = "signed_link"
+
+type SignedLinkRequest struct {
+	// TTLMinutes shortens the link's lifetime below signed_links.ttl.
+	TTLMinutes int `json:"ttl_minutes,omitempty"`
+}
+
+type SignedLinkResponse struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// signedLink reports whether the request came in through a verified signed
+// link, in which case the passphrase is unwrapped rather than supplied.
+func signedLink(r *http.Request) bool {
+	ok, _ := r.Context().Value(signedLinkContextKey).(bool)
+	return ok
+}
+
+// CreateSignedLink mints a link that reveals the secret without its
+// passphrase. It takes the owner token: the system holding it is trusted to
+// have authenticated whoever it hands the link to.
+func (h *Handler) CreateSignedLink(w http.ResponseWriter, r *http.Request) {
+	var req SignedLinkRequest
+	if !h.decode(w, r, &req) {
+		return
+	}
+
+	secret, ok := h.ownedSecret(w, r)
+	if !ok {
+		return
+	}
+	if len(secret.WrappedKey) == 0 {
+		h.error(w, r, http.StatusBadRequest, "secret was not created with signed_links")
+		return
+	}
+
+	ttl := clampDuration(
+		time.Duration(req.TTLMinutes)*time.Minute,
+		h.config.SignedLinks.TTL,
+		h.config.SignedLinks.TTL,
+	)
+	// Links carry whole seconds and never outlive the secret.
+	expires := time.Now().Add(ttl)
+	if expires.After(secret.ExpiresAt) {
+		expires = secret.ExpiresAt
+	}
+	expires = time.Unix(expires.Unix(), 0)
+
+	id := chi.URLParam(r, "id")
+	query := url.Values{
+		"expires": {strconv.FormatInt(expires.Unix(), 10)},
+		"sig":     {h.linkKeys.SignLink(id, signedLinkScope, expires)},
+	}
+	h.json(w, http.StatusCreated, SignedLinkResponse{
+		URL:       h.config.Server.BaseURL + "/api/secrets/" + id + "/signed?" + query.Encode(),
+		ExpiresAt: expires,
+	})
+}
+
+// RevealSigned reveals like RevealSecret for the holder of a valid signed
+// link. The signature is checked before the store is touched.
+func (h *Handler) RevealSigned(w http.ResponseWriter, r *http.Request) {
+	expires, err := strconv.ParseInt(r.URL.Query().Get("expires"), 10, 64)
+	if err != nil || !h.linkKeys.VerifyLink(chi.URLParam(r, "id"), signedLinkScope, time.Unix(expires, 0), r.URL.Query().Get("sig")) {
+		w.Header().Set("Cache-Control", "no-store, private")
+		h.error(w, r, http.StatusForbidden, "link is invalid or has expired")
+		return
+	}
+
+	h.RevealSecret(w, r.WithContext(context.WithValue(r.Context(), signedLinkContextKey, true)))
+}
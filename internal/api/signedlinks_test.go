@@ -0,0 +1,140 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"secure.share/config"
+	"secure.share/internal/store"
+)
+
+func signedLinksConfig() *config.Config {
+	cfg := config.Default()
+	cfg.SignedLinks.Key = "0123456789abcdef0123456789abcdef"
+	return cfg
+}
+
+func mintSignedLink(t *testing.T, router http.Handler, created CreateResponse, body string) SignedLinkResponse {
+	t.Helper()
+	rec := ownerRequest(router, http.MethodPost, "/api/secrets/"+created.ID+"/signed-link", created.OwnerToken, body)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("minting link failed: got %d: %s", rec.Code, rec.Body.String())
+	}
+	var link SignedLinkResponse
+	if err := json.NewDecoder(rec.Body).Decode(&link); err != nil {
+		t.Fatalf("failed to decode link: %v", err)
+	}
+	return link
+}
+
+func getPath(router http.Handler, path string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	return rec
+}
+
+func TestSignedLinkReveals(t *testing.T) {
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	cfg := signedLinksConfig()
+	router := SetupRouter(st, cfg)
+
+	created, _ := createSecret(t, router, `{"content":"s3cret","max_views":2,"signed_links":true}`)
+	link := mintSignedLink(t, router, created, `{"ttl_minutes":5}`)
+	if strings.Contains(link.URL, "passphrase") || strings.Contains(link.URL, "#") {
+		t.Fatalf("link carries key material: %s", link.URL)
+	}
+	if until := time.Until(link.ExpiresAt); until <= 4*time.Minute || until > 5*time.Minute {
+		t.Fatalf("link expires in %v, want about 5m", until)
+	}
+
+	rec := getPath(router, strings.TrimPrefix(link.URL, cfg.Server.BaseURL))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("signed reveal failed: got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp RevealResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode reveal: %v", err)
+	}
+	if resp.Content != "s3cret" {
+		t.Fatalf("content = %q", resp.Content)
+	}
+}
+
+func TestSignedLinkRejected(t *testing.T) {
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	cfg := signedLinksConfig()
+	router := SetupRouter(st, cfg)
+
+	created, _ := createSecret(t, router, `{"content":"s3cret","signed_links":true}`)
+	link := mintSignedLink(t, router, created, `{}`)
+	u, err := url.Parse(link.URL)
+	if err != nil {
+		t.Fatalf("bad link: %v", err)
+	}
+	other, _ := createSecret(t, router, `{"content":"other","signed_links":true}`)
+
+	with := func(id string, set map[string]string) string {
+		q := u.Query()
+		for k, v := range set {
+			q.Set(k, v)
+		}
+		return "/api/secrets/" + id + "/signed?" + q.Encode()
+	}
+	sig := []byte(u.Query().Get("sig"))
+	sig[0] ^= 1
+
+	tests := []struct {
+		name string
+		path string
+	}{
+		{"tampered signature", with(created.ID, map[string]string{"sig": string(sig)})},
+		{"extended expiry", with(created.ID, map[string]string{"expires": "9999999999"})},
+		{"expired", with(created.ID, map[string]string{"expires": "1"})},
+		{"other secret", with(other.ID, nil)},
+		{"missing signature", "/api/secrets/" + created.ID + "/signed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := getPath(router, tt.path); rec.Code != http.StatusForbidden {
+				t.Fatalf("got %d, want 403: %s", rec.Code, rec.Body.String())
+			}
+		})
+	}
+
+	// None of those used a view.
+	if rec := getPath(router, u.RequestURI()); rec.Code != http.StatusOK {
+		t.Fatalf("valid link failed after rejected ones: got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestSignedLinkNeedsOptIn(t *testing.T) {
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	router := SetupRouter(st, signedLinksConfig())
+
+	created, _ := createSecret(t, router, `{"content":"s3cret"}`)
+	rec := ownerRequest(router, http.MethodPost, "/api/secrets/"+created.ID+"/signed-link", created.OwnerToken, `{}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("got %d, want 400", rec.Code)
+	}
+	rec = ownerRequest(router, http.MethodPost, "/api/secrets/"+created.ID+"/signed-link", "", `{}`)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("without owner token: got %d, want 401", rec.Code)
+	}
+
+	if rec := postJSON(router, "/api/secrets", `{"content":"x","signed_links":true,"shares":3,"threshold":2}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("signed links with shares: got %d, want 400", rec.Code)
+	}
+
+	router = SetupRouter(st, config.Default())
+	if rec := postJSON(router, "/api/secrets", `{"content":"x","signed_links":true}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("signed links while disabled: got %d, want 400", rec.Code)
+	}
+}
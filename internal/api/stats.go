@@ -0,0 +1,63 @@
+package api
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// StatsResponse is public: it only ever holds instance-wide counts, never
+// anything about an individual secret.
+type StatsResponse struct {
+	TotalReveals  int64 `json:"total_reveals"`
+	ActiveSecrets int   `json:"active_secrets"`
+	// CreatedToday counts live secrets created since midnight UTC; ones
+	// already revealed or expired aren't included.
+	CreatedToday      int   `json:"created_today"`
+	AverageTTLSeconds int64 `json:"average_ttl_seconds"`
+}
+
+// statsCache holds the last StatsResponse for ttl. Callers arriving while
+// it is being refreshed wait for that refresh rather than scanning the
+// store themselves.
+type statsCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	resp    StatsResponse
+	expires time.Time
+}
+
+// Stats reports aggregate usage for a status or transparency page.
+func (h *Handler) Stats(w http.ResponseWriter, r *http.Request) {
+	c := h.stats
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if time.Now().Before(c.expires) {
+		h.json(w, http.StatusOK, c.resp)
+		return
+	}
+
+	reveals, err := h.store.RevealCount(r.Context())
+	if err != nil {
+		h.error(w, r, http.StatusInternalServerError, "internal error")
+		return
+	}
+	now := time.Now().UTC()
+	usage, err := h.store.Usage(r.Context(), now.Truncate(24*time.Hour))
+	if err != nil {
+		h.error(w, r, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	resp := StatsResponse{
+		TotalReveals:  reveals,
+		ActiveSecrets: usage.Active,
+		CreatedToday:  usage.CreatedSince,
+	}
+	if usage.Active > 0 {
+		resp.AverageTTLSeconds = int64((usage.TotalTTL / time.Duration(usage.Active)).Seconds())
+	}
+	c.resp, c.expires = resp, now.Add(c.ttl)
+	h.json(w, http.StatusOK, resp)
+}
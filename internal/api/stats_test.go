@@ -0,0 +1,124 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"secure.share/config"
+	"secure.share/internal/models"
+	"secure.share/internal/store"
+)
+
+func getStats(t *testing.T, router http.Handler) (StatsResponse, string) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/stats", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("stats failed: got %d: %s", rec.Code, rec.Body.String())
+	}
+	body := rec.Body.String()
+	var stats StatsResponse
+	if err := json.Unmarshal([]byte(body), &stats); err != nil {
+		t.Fatalf("failed to decode stats: %v", err)
+	}
+	return stats, body
+}
+
+// statsConfig is the default config with the stats endpoint, which is off
+// by default, enabled.
+func statsConfig() *config.Config {
+	cfg := config.Default()
+	cfg.Stats.Enabled = true
+	return cfg
+}
+
+func TestStatsAggregatesUsage(t *testing.T) {
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	cfg := statsConfig()
+	cfg.Stats.CacheTTL = 0
+	router := SetupRouter(st, cfg)
+
+	first, _ := createSecret(t, router, `{"content":"stats-secret-one","ttl_minutes":60}`)
+	createSecret(t, router, `{"content":"stats-secret-two","ttl_minutes":120}`)
+	// Expired and yesterday's secrets must not count as active or today.
+	now := time.Now()
+	st.Save(context.Background(), &models.Secret{
+		ID:        "expired",
+		MaxViews:  1,
+		CreatedAt: now.Add(-2 * time.Hour),
+		ExpiresAt: now.Add(-time.Hour),
+	})
+	st.Save(context.Background(), &models.Secret{
+		ID:        "yesterday",
+		MaxViews:  1,
+		CreatedAt: now.UTC().Truncate(24 * time.Hour).Add(-time.Hour),
+		ExpiresAt: now.Add(time.Hour),
+	})
+
+	stats, body := getStats(t, router)
+	if stats.ActiveSecrets != 3 || stats.CreatedToday != 2 {
+		t.Fatalf("got %+v, want 3 active and 2 created today", stats)
+	}
+	if stats.AverageTTLSeconds <= 0 {
+		t.Fatalf("average ttl = %d", stats.AverageTTLSeconds)
+	}
+	for _, leak := range []string{first.ID, "stats-secret", "expired", "yesterday"} {
+		if strings.Contains(body, leak) {
+			t.Fatalf("stats expose %q: %s", leak, body)
+		}
+	}
+
+	st.Delete(context.Background(), first.ID)
+	if stats, _ := getStats(t, router); stats.ActiveSecrets != 2 || stats.CreatedToday != 1 {
+		t.Fatalf("after delete got %+v, want 2 active and 1 created today", stats)
+	}
+}
+
+func TestStatsCached(t *testing.T) {
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	router := SetupRouter(st, statsConfig())
+
+	if stats, _ := getStats(t, router); stats.ActiveSecrets != 0 {
+		t.Fatalf("got %+v on an empty store", stats)
+	}
+	createSecret(t, router, `{"content":"s3cret"}`)
+	if stats, _ := getStats(t, router); stats.ActiveSecrets != 0 {
+		t.Fatalf("cached stats were recomputed: %+v", stats)
+	}
+}
+
+func TestStatsRateLimited(t *testing.T) {
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	cfg := statsConfig()
+	cfg.Stats.RequestsPerMin = 2
+	router := SetupRouter(st, cfg)
+
+	for i := 0; i < 2; i++ {
+		getStats(t, router)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/stats", nil))
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("got %d, want 429", rec.Code)
+	}
+}
+
+func TestStatsDisabledByDefault(t *testing.T) {
+	st := store.NewMemoryStore(time.Minute)
+	defer st.Close()
+	router := SetupRouter(st, config.Default())
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/stats", nil))
+	if rec.Code == http.StatusOK {
+		t.Fatalf("stats served while disabled: %s", rec.Body.String())
+	}
+}
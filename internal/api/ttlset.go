@@ -0,0 +1,61 @@
+package api
+
+import (
+	"sync"
+	"time"
+)
+
+// ttlSet is a bounded set whose keys drop out ttl after they were last
+// added. It backs the negative cache and the duplicate content check.
+type ttlSet struct {
+	mu    sync.Mutex
+	ttl   time.Duration
+	size  int
+	items map[string]time.Time
+}
+
+func newTTLSet(ttl time.Duration, size int) *ttlSet {
+	return &ttlSet{ttl: ttl, size: size, items: make(map[string]time.Time)}
+}
+
+// has reports whether key was added within the TTL.
+func (s *ttlSet) has(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expires, ok := s.items[key]
+	if ok && time.Now().After(expires) {
+		delete(s.items, key)
+		return false
+	}
+	return ok
+}
+
+// add starts key's TTL. When the set is full of live keys an arbitrary one
+// makes room.
+func (s *ttlSet) add(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if _, ok := s.items[key]; !ok && len(s.items) >= s.size {
+		for item, expires := range s.items {
+			if now.After(expires) {
+				delete(s.items, item)
+			}
+		}
+		for item := range s.items {
+			if len(s.items) < s.size {
+				break
+			}
+			delete(s.items, item)
+		}
+	}
+	s.items[key] = now.Add(s.ttl)
+}
+
+func (s *ttlSet) remove(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.items, key)
+}
@@ -0,0 +1,101 @@
+package crypto
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+)
+
+// A compressed blob holds a plaintext compressed before encryption:
+//
+//	magic(3) | algorithm(1) | inner blob
+//
+// The inner blob is an Argon2id or profile blob over the compressed
+// plaintext, with the header bound into its context. Padded blobs are never
+// compressed: the compressed size would give away what padding hides.
+var compressMagic = []byte{'s', 's', 'z'}
+
+const (
+	compressGzip       byte = 1
+	compressHeaderSize      = 3 + 1
+)
+
+// MinCompressSize is the shortest plaintext worth compressing; below it
+// gzip's own overhead eats most of the gain.
+const MinCompressSize = 256
+
+// maxInflated bounds how large a compressed plaintext may inflate. The
+// creator controls it, so it mustn't be able to make a reveal allocate
+// without limit.
+const maxInflated = 64 << 20
+
+var ErrInflatedTooLarge = errors.New("compressed plaintext inflates past the size limit")
+
+var compression atomic.Bool
+
+// SetCompression turns compression of new blobs on or off. Compressed
+// blobs stay readable either way.
+func SetCompression(enabled bool) {
+	compression.Store(enabled)
+}
+
+// sealCompressed calls seal on the plaintext compressed, if compression is
+// on and that makes it smaller, and on the plaintext as is otherwise.
+func sealCompressed(plaintext, encContext []byte, seal func(plaintext, encContext []byte) ([]byte, error)) ([]byte, error) {
+	if !compression.Load() || len(plaintext) < MinCompressSize {
+		return seal(plaintext, encContext)
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(plaintext); err != nil {
+		return nil, fmt.Errorf("compression failed: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("compression failed: %w", err)
+	}
+	if compressHeaderSize+buf.Len() >= len(plaintext) {
+		return seal(plaintext, encContext)
+	}
+
+	header := make([]byte, 0, compressHeaderSize)
+	header = append(header, compressMagic...)
+	header = append(header, compressGzip)
+	inner, err := seal(buf.Bytes(), padContext(header, encContext))
+	if err != nil {
+		return nil, err
+	}
+	return append(header, inner...), nil
+}
+
+// decryptCompressed opens a compressed blob. ok is false when the blob has
+// no compression header or doesn't open as one, in which case the caller
+// tries the other formats; err is only set for a blob that opened but
+// didn't inflate.
+func decryptCompressed(blob []byte, passphrase string, encContext []byte, d KeyDeriver) (plaintext []byte, ok bool, err error) {
+	if len(blob) < compressHeaderSize || !bytes.HasPrefix(blob, compressMagic) || blob[3] != compressGzip {
+		return nil, false, nil
+	}
+	header := blob[:compressHeaderSize]
+
+	compressed, err := decryptUnpadded(blob[compressHeaderSize:], passphrase, padContext(header, encContext), d)
+	if err != nil {
+		return nil, false, nil
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, true, fmt.Errorf("decompression failed: %w", err)
+	}
+	plaintext, err = io.ReadAll(io.LimitReader(zr, maxInflated+1))
+	if err != nil {
+		return nil, true, fmt.Errorf("decompression failed: %w", err)
+	}
+	if len(plaintext) > maxInflated {
+		return nil, true, ErrInflatedTooLarge
+	}
+	return plaintext, true, nil
+}
@@ -0,0 +1,93 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+)
+
+func withCompression(t *testing.T) {
+	t.Helper()
+	SetCompression(true)
+	t.Cleanup(func() { SetCompression(false) })
+}
+
+func TestCompressedRoundTrip(t *testing.T) {
+	withCompression(t)
+
+	compressible := bytes.Repeat([]byte("2024-01-01 INFO request served in 3ms\n"), 200)
+	random := make([]byte, 4096)
+	rand.Read(random)
+
+	profile := Profile{Cipher: CipherAES128GCM, KDFIterations: MinKDFIterations}
+	for name, encrypt := range map[string]func([]byte) ([]byte, error){
+		"argon2id": func(p []byte) ([]byte, error) { return EncryptWithContext(p, "pass", []byte("ctx")) },
+		"profile":  func(p []byte) ([]byte, error) { return EncryptWithProfile(p, "pass", []byte("ctx"), profile) },
+	} {
+		for _, tt := range []struct {
+			input      []byte
+			compressed bool
+		}{
+			{compressible, true},
+			{random, false},
+			{[]byte("short"), false},
+		} {
+			blob, err := encrypt(tt.input)
+			if err != nil {
+				t.Fatalf("%s: encrypt failed: %v", name, err)
+			}
+			if got := bytes.HasPrefix(blob, compressMagic); got != tt.compressed {
+				t.Fatalf("%s: %d-byte input compressed = %v, want %v", name, len(tt.input), got, tt.compressed)
+			}
+			if tt.compressed && len(blob) >= len(tt.input) {
+				t.Fatalf("%s: compressed blob is %d bytes for %d of plaintext", name, len(blob), len(tt.input))
+			}
+
+			got, err := DecryptWithContext(blob, "pass", []byte("ctx"))
+			if err != nil {
+				t.Fatalf("%s: decrypt failed: %v", name, err)
+			}
+			if !bytes.Equal(got, tt.input) {
+				t.Fatalf("%s: round trip mismatch for %d-byte input", name, len(tt.input))
+			}
+			if _, err := DecryptWithContext(blob, "wrong", []byte("ctx")); err == nil {
+				t.Fatalf("%s: decrypt with the wrong passphrase succeeded", name)
+			}
+		}
+	}
+}
+
+func TestCompressionHeaderIsAuthenticated(t *testing.T) {
+	withCompression(t)
+
+	blob, err := EncryptWithContext(bytes.Repeat([]byte("a"), 1024), "pass", nil)
+	if err != nil {
+		t.Fatalf("encrypt failed: %v", err)
+	}
+	blob[3] = 2
+	if _, err := DecryptWithContext(blob, "pass", nil); err == nil {
+		t.Fatal("decrypt succeeded with a tampered compression header")
+	}
+}
+
+func TestPaddedIsNeverCompressed(t *testing.T) {
+	withCompression(t)
+
+	small, _ := EncryptPadded(bytes.Repeat([]byte("a"), 3000), "pass", nil, nil, 4096)
+	random := make([]byte, 3000)
+	rand.Read(random)
+	large, _ := EncryptPadded(random, "pass", nil, nil, 4096)
+	if len(small) != len(large) {
+		t.Fatalf("padded blobs differ in size with compression on: %d and %d", len(small), len(large))
+	}
+}
+
+func TestCompressionOffByDefault(t *testing.T) {
+	blob, err := EncryptWithContext(bytes.Repeat([]byte("a"), 1024), "pass", nil)
+	if err != nil {
+		t.Fatalf("encrypt failed: %v", err)
+	}
+	if bytes.HasPrefix(blob, compressMagic) {
+		t.Fatal("blob compressed without SetCompression")
+	}
+}
@@ -0,0 +1,195 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// KeyDeriver turns a passphrase and salt into an AES key, whose length
+// picks AES-128 or AES-256. Each blob format pairs AES-GCM with one
+// deriver and records the deriver's parameters in its header, so blobs
+// made under old settings stay readable.
+type KeyDeriver interface {
+	Derive(passphrase string, salt []byte) ([]byte, error)
+}
+
+// SHA256Deriver is the original derivation: SHA-256 of the passphrase,
+// then a zero byte and the salt if there is one. Original blobs use their
+// context label as the salt. It is fast to brute force, so it only reads
+// blobs from before Argon2id.
+type SHA256Deriver struct{}
+
+func (SHA256Deriver) Derive(passphrase string, salt []byte) ([]byte, error) {
+	h := sha256.New()
+	h.Write([]byte(passphrase))
+	if len(salt) > 0 {
+		h.Write([]byte{0})
+		h.Write(salt)
+	}
+	return h.Sum(nil), nil
+}
+
+// Argon2Deriver derives a 32-byte key with Argon2id at Params.
+type Argon2Deriver struct {
+	Params KDFParams
+}
+
+func (d Argon2Deriver) Derive(passphrase string, salt []byte) ([]byte, error) {
+	return argon2.IDKey([]byte(passphrase), salt, d.Params.Iterations, d.Params.Memory, d.Params.Parallelism, 32), nil
+}
+
+// PBKDF2Deriver derives a KeyLen-byte key with PBKDF2-HMAC-SHA256, as
+// crypto profiles do.
+type PBKDF2Deriver struct {
+	Iterations int
+	KeyLen     int
+}
+
+func (d PBKDF2Deriver) Derive(passphrase string, salt []byte) ([]byte, error) {
+	return pbkdf2.Key(sha256.New, passphrase, salt, d.Iterations, d.KeyLen)
+}
+
+// DefaultDeriver is the deriver Encrypt, EncryptWithContext and
+// EncryptPadded without a profile use: Argon2id at the costs set by
+// SetKDFParams.
+func DefaultDeriver() KeyDeriver {
+	return Argon2Deriver{Params: currentKDFParams()}
+}
+
+// contextPassword binds a context label into the passphrase handed to a
+// salted deriver, separated by a zero byte.
+func contextPassword(passphrase string, encContext []byte) string {
+	if len(encContext) == 0 {
+		return passphrase
+	}
+	return passphrase + "\x00" + string(encContext)
+}
+
+// deriveGCM returns AES-GCM keyed by d from passphrase and salt.
+func deriveGCM(d KeyDeriver, passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := d.Derive(passphrase, salt)
+	if err != nil {
+		return nil, fmt.Errorf("key derivation failed: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("cipher creation failed: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("GCM creation failed: %w", err)
+	}
+	return gcm, nil
+}
+
+// EncryptWithDeriver encrypts like EncryptWithContext with a key from d.
+// Argon2Deriver and PBKDF2Deriver keys give the same blobs as
+// EncryptWithKDF and EncryptWithProfile, which DecryptWithContext opens.
+// Any other deriver's parameters can't be recorded, so its blobs only open
+// with DecryptWithDeriver and the same deriver.
+func EncryptWithDeriver(plaintext []byte, passphrase string, encContext []byte, d KeyDeriver) ([]byte, error) {
+	return sealCompressed(plaintext, encContext, func(plaintext, encContext []byte) ([]byte, error) {
+		return encryptWithDeriver(plaintext, passphrase, encContext, d)
+	})
+}
+
+// DecryptWithDeriver opens blobs from EncryptWithDeriver under d, as well
+// as everything DecryptWithContext opens.
+func DecryptWithDeriver(ciphertext []byte, passphrase string, encContext []byte, d KeyDeriver) ([]byte, error) {
+	return decryptWithDeriver(ciphertext, passphrase, encContext, d)
+}
+
+func encryptWithDeriver(plaintext []byte, passphrase string, encContext []byte, d KeyDeriver) ([]byte, error) {
+	switch d := d.(type) {
+	case Argon2Deriver:
+		if err := d.Params.Validate(); err != nil {
+			return nil, err
+		}
+		return encryptKDF(plaintext, passphrase, encContext, d)
+	case PBKDF2Deriver:
+		p := Profile{Cipher: keyLenCipher(d.KeyLen), KDFIterations: d.Iterations}
+		if err := p.Validate(); err != nil {
+			return nil, err
+		}
+		return encryptWithProfile(plaintext, passphrase, encContext, p)
+	}
+	return encryptDerived(plaintext, passphrase, encContext, d)
+}
+
+// keyLenCipher names the profile cipher taking keyLen-byte keys, or ""
+// if none does.
+func keyLenCipher(keyLen int) string {
+	for name, id := range cipherIDs {
+		if cipherKeySizes[id] == keyLen {
+			return name
+		}
+	}
+	return ""
+}
+
+// A blob from a deriver without a format of its own records only a salt:
+//
+//	magic(3) | version(1) | salt(16) | nonce(12) | ciphertext
+//
+// The header is authenticated as part of the GCM additional data.
+var derivedMagic = []byte{'s', 's', 'd'}
+
+const (
+	derivedVersion    byte = 1
+	derivedHeaderSize      = 3 + 1 + kdfSaltSize
+)
+
+func encryptDerived(plaintext []byte, passphrase string, encContext []byte, d KeyDeriver) ([]byte, error) {
+	header := make([]byte, 0, derivedHeaderSize)
+	header = append(header, derivedMagic...)
+	header = append(header, derivedVersion)
+	salt := make([]byte, kdfSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("salt generation failed: %w", err)
+	}
+	header = append(header, salt...)
+
+	gcm, err := deriveGCM(d, contextPassword(passphrase, encContext), salt)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, nonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("nonce generation failed: %w", err)
+	}
+
+	out := append(header, nonce...)
+	return gcm.Seal(out, nonce, plaintext, profileAAD(header, encContext)), nil
+}
+
+// decryptDerived opens a blob from encryptDerived with d. ok is false when
+// there is no deriver or the blob has no such header, in which case the
+// caller tries the other formats.
+func decryptDerived(blob []byte, passphrase string, encContext []byte, d KeyDeriver) (plaintext []byte, ok bool, err error) {
+	if d == nil || len(blob) < derivedHeaderSize+nonceSize || !bytes.HasPrefix(blob, derivedMagic) {
+		return nil, false, nil
+	}
+	header := blob[:derivedHeaderSize]
+	if header[3] != derivedVersion {
+		return nil, true, fmt.Errorf("%w: %d", ErrUnsupportedVersion, header[3])
+	}
+
+	gcm, err := deriveGCM(d, contextPassword(passphrase, encContext), header[4:])
+	if err != nil {
+		return nil, true, err
+	}
+	nonce := blob[derivedHeaderSize : derivedHeaderSize+nonceSize]
+	plaintext, err = gcm.Open(nil, nonce, blob[derivedHeaderSize+nonceSize:], profileAAD(header, encContext))
+	if err != nil {
+		return nil, true, fmt.Errorf("decryption failed: %w", err)
+	}
+	return plaintext, true, nil
+}
@@ -0,0 +1,121 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"testing"
+)
+
+// reverseDeriver is a toy deriver: the passphrase reversed, padded to 32 bytes.
+type reverseDeriver struct{}
+
+func (reverseDeriver) Derive(passphrase string, salt []byte) ([]byte, error) {
+	key := make([]byte, 32)
+	for i := range passphrase {
+		key[i%32] ^= passphrase[len(passphrase)-1-i]
+	}
+	return key, nil
+}
+
+func TestSHA256DeriverMatchesOriginal(t *testing.T) {
+	plain := sha256.Sum256([]byte("pass"))
+	if got, _ := (SHA256Deriver{}).Derive("pass", nil); !bytes.Equal(got, plain[:]) {
+		t.Fatalf("unsalted key isn't SHA-256 of the passphrase")
+	}
+	salted := sha256.Sum256([]byte("pass\x00ctx"))
+	if got, _ := (SHA256Deriver{}).Derive("pass", []byte("ctx")); !bytes.Equal(got, salted[:]) {
+		t.Fatalf("salted key isn't SHA-256 of passphrase, zero byte and salt")
+	}
+}
+
+func TestDeriverKeyLengths(t *testing.T) {
+	salt := []byte("0123456789abcdef")
+	for name, c := range map[string]struct {
+		d    KeyDeriver
+		want int
+	}{
+		"sha256":    {SHA256Deriver{}, 32},
+		"argon2":    {Argon2Deriver{Params: DefaultKDFParams}, 32},
+		"pbkdf2-16": {PBKDF2Deriver{Iterations: 1000, KeyLen: 16}, 16},
+		"pbkdf2-32": {PBKDF2Deriver{Iterations: 1000, KeyLen: 32}, 32},
+	} {
+		key, err := c.d.Derive("pass", salt)
+		if err != nil || len(key) != c.want {
+			t.Errorf("%s: key length %d, %v, want %d", name, len(key), err, c.want)
+		}
+	}
+	if _, err := (PBKDF2Deriver{Iterations: 1000}).Derive("pass", salt); err == nil {
+		t.Errorf("pbkdf2 derived a key of length 0 without an error")
+	}
+	if _, err := deriveGCM(PBKDF2Deriver{Iterations: 1000}, "pass", salt); err == nil {
+		t.Error("deriveGCM accepted a deriver that gives no key")
+	}
+}
+
+func TestDeriveGCMUsesDeriver(t *testing.T) {
+	seal, err := deriveGCM(reverseDeriver{}, "pass", nil)
+	if err != nil {
+		t.Fatalf("deriveGCM: %v", err)
+	}
+	nonce := make([]byte, seal.NonceSize())
+	sealed := seal.Seal(nil, nonce, []byte("hello"), nil)
+
+	open, _ := deriveGCM(reverseDeriver{}, "pass", nil)
+	if got, err := open.Open(nil, nonce, sealed, nil); err != nil || string(got) != "hello" {
+		t.Fatalf("open with the same deriver: %q, %v", got, err)
+	}
+	other, _ := deriveGCM(SHA256Deriver{}, "pass", nil)
+	if _, err := other.Open(nil, nonce, sealed, nil); err == nil {
+		t.Fatal("a different deriver opened the blob")
+	}
+}
+
+func TestEncryptWithDeriver(t *testing.T) {
+	passphrase := GeneratePassphrase()
+	blob, err := EncryptWithDeriver([]byte("hello"), passphrase, []byte("ctx"), reverseDeriver{})
+	if err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+	if !bytes.HasPrefix(blob, append(derivedMagic, derivedVersion)) {
+		t.Fatalf("blob lacks the derived header: %x", blob[:derivedHeaderSize])
+	}
+	if got, err := DecryptWithDeriver(blob, passphrase, []byte("ctx"), reverseDeriver{}); err != nil || string(got) != "hello" {
+		t.Fatalf("decrypt with the same deriver: %q, %v", got, err)
+	}
+	if _, err := DecryptWithDeriver(blob, passphrase, []byte("ctx"), SHA256Deriver{}); err == nil {
+		t.Fatal("a different deriver opened the blob")
+	}
+	if _, err := DecryptWithContext(blob, passphrase, []byte("ctx")); err == nil {
+		t.Fatal("DecryptWithContext opened a blob it has no deriver for")
+	}
+}
+
+func TestEncryptWithDeriverKnownFormats(t *testing.T) {
+	passphrase := GeneratePassphrase()
+	for name, c := range map[string]struct {
+		d     KeyDeriver
+		magic []byte
+	}{
+		"argon2": {Argon2Deriver{Params: DefaultKDFParams}, kdfMagic},
+		"pbkdf2": {PBKDF2Deriver{Iterations: 1000, KeyLen: 16}, profileMagic},
+	} {
+		blob, err := EncryptWithDeriver([]byte("hello"), passphrase, nil, c.d)
+		if err != nil {
+			t.Fatalf("%s: encrypt: %v", name, err)
+		}
+		if !bytes.HasPrefix(blob, c.magic) {
+			t.Errorf("%s: blob doesn't use the deriver's own format", name)
+		}
+		// The header records the parameters, so no deriver is needed.
+		if got, err := DecryptWithContext(blob, passphrase, nil); err != nil || string(got) != "hello" {
+			t.Errorf("%s: decrypt: %q, %v", name, got, err)
+		}
+	}
+
+	if _, err := EncryptWithDeriver([]byte("hello"), passphrase, nil, PBKDF2Deriver{Iterations: 1000, KeyLen: 24}); err == nil {
+		t.Error("a pbkdf2 key length no profile cipher takes was accepted")
+	}
+	if _, err := EncryptWithDeriver([]byte("hello"), passphrase, nil, Argon2Deriver{}); err == nil {
+		t.Error("argon2id with zero costs was accepted")
+	}
+}
@@ -0,0 +1,54 @@
+package crypto
+
+import (
+	"crypto/rand"
+	"math"
+	"math/big"
+	"strings"
+)
+
+type IDEncoding string
+
+const (
+	IDEncodingBase64URL IDEncoding = "base64url"
+	IDEncodingBase58    IDEncoding = "base58"
+	IDEncodingBase62    IDEncoding = "base62"
+)
+
+const (
+	// base58 drops 0, O, I and l so ids survive being read aloud.
+	base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+	base62Alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+)
+
+// GenerateIDWithEncoding returns an id with at least idLength*8 bits of
+// entropy drawn from the alphabet of enc. Unknown encodings fall back to
+// base64url.
+func GenerateIDWithEncoding(enc IDEncoding) string {
+	switch enc {
+	case IDEncodingBase58:
+		return randomString(base58Alphabet, idLength*8)
+	case IDEncodingBase62:
+		return randomString(base62Alphabet, idLength*8)
+	default:
+		return GenerateID()
+	}
+}
+
+// randomString picks enough uniformly random characters from alphabet to
+// carry at least bits of entropy.
+func randomString(alphabet string, bits int) string {
+	n := int(math.Ceil(float64(bits) / math.Log2(float64(len(alphabet)))))
+	size := big.NewInt(int64(len(alphabet)))
+
+	var sb strings.Builder
+	sb.Grow(n)
+	for i := 0; i < n; i++ {
+		idx, err := rand.Int(rand.Reader, size)
+		if err != nil {
+			panic("crypto/rand failed: " + err.Error())
+		}
+		sb.WriteByte(alphabet[idx.Int64()])
+	}
+	return sb.String()
+}
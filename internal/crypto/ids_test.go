@@ -0,0 +1,36 @@
+package crypto
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestGenerateIDWithEncoding(t *testing.T) {
+	alphabets := map[IDEncoding]string{
+		IDEncodingBase58:    base58Alphabet,
+		IDEncodingBase62:    base62Alphabet,
+		IDEncodingBase64URL: "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_",
+	}
+
+	for enc, alphabet := range alphabets {
+		for i := 0; i < 100; i++ {
+			id := GenerateIDWithEncoding(enc)
+			for _, c := range id {
+				if !strings.ContainsRune(alphabet, c) {
+					t.Fatalf("%s id %q contains %q outside its alphabet", enc, id, c)
+				}
+			}
+		}
+	}
+
+	if got := len(GenerateIDWithEncoding(IDEncodingBase58)); got != 17 {
+		t.Fatalf("base58 id length mismatch: got %d, want %d", got, 17)
+	}
+	if got := len(GenerateIDWithEncoding(IDEncodingBase62)); got != 17 {
+		t.Fatalf("base62 id length mismatch: got %d, want %d", got, 17)
+	}
+	if got := len(GenerateIDWithEncoding("unknown")); got != base64.RawURLEncoding.EncodedLen(idLength) {
+		t.Fatalf("fallback id length mismatch: got %d", got)
+	}
+}
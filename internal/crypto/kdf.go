@@ -0,0 +1,155 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// Bounds on Argon2id costs. Like the profile bounds they are also checked
+// when reading a header, so a stored blob can't make a reveal take
+// arbitrary memory or time.
+const (
+	MinKDFMemory      = 8 * 1024    // KiB
+	MaxKDFMemory      = 1024 * 1024 // KiB
+	MaxKDFPasses      = 32
+	MaxKDFParallelism = 64
+)
+
+// KDFParams are the Argon2id costs used by Encrypt and EncryptWithContext.
+// Memory is in KiB.
+type KDFParams struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+}
+
+// DefaultKDFParams follow the Argon2 RFC's recommendation for when memory
+// is plentiful but a single pass is all the latency allows.
+var DefaultKDFParams = KDFParams{Memory: 64 * 1024, Iterations: 1, Parallelism: 4}
+
+var ErrInvalidKDFParams = errors.New("invalid kdf parameters")
+
+func (p KDFParams) Validate() error {
+	if p.Memory < MinKDFMemory || p.Memory > MaxKDFMemory {
+		return fmt.Errorf("%w: memory must be between %d and %d KiB", ErrInvalidKDFParams, MinKDFMemory, MaxKDFMemory)
+	}
+	if p.Iterations < 1 || p.Iterations > MaxKDFPasses {
+		return fmt.Errorf("%w: iterations must be between 1 and %d", ErrInvalidKDFParams, MaxKDFPasses)
+	}
+	if p.Parallelism < 1 || p.Parallelism > MaxKDFParallelism {
+		return fmt.Errorf("%w: parallelism must be between 1 and %d", ErrInvalidKDFParams, MaxKDFParallelism)
+	}
+	return nil
+}
+
+var kdfParams atomic.Pointer[KDFParams]
+
+// SetKDFParams changes the costs new blobs are encrypted with. Existing
+// blobs record their own and stay readable.
+func SetKDFParams(p KDFParams) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	kdfParams.Store(&p)
+	return nil
+}
+
+func currentKDFParams() KDFParams {
+	if p := kdfParams.Load(); p != nil {
+		return *p
+	}
+	return DefaultKDFParams
+}
+
+// An Argon2id blob records the costs it was made with:
+//
+//	magic(3) | version(1) | memory(4) | iterations(4) | parallelism(1) | salt(16) | nonce(12) | ciphertext
+//
+// The header is authenticated as part of the GCM additional data. The
+// version byte leaves room for later formats; blobs without the magic are
+// the original SHA-256 format from before key stretching.
+var kdfMagic = []byte{'s', 's', 'k'}
+
+const (
+	kdfVersion    byte = 1
+	kdfSaltSize        = 16
+	kdfHeaderSize      = 3 + 1 + 4 + 4 + 1 + kdfSaltSize
+)
+
+var ErrUnsupportedVersion = errors.New("unsupported ciphertext version")
+
+// EncryptWithKDF encrypts with AES-256-GCM under an Argon2id key derived
+// with p and a random salt.
+func EncryptWithKDF(plaintext []byte, passphrase string, encContext []byte, p KDFParams) ([]byte, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return encryptKDF(plaintext, passphrase, encContext, Argon2Deriver{Params: p})
+}
+
+// encryptKDF writes the Argon2id format with d, whose costs are already
+// validated.
+func encryptKDF(plaintext []byte, passphrase string, encContext []byte, d Argon2Deriver) ([]byte, error) {
+	p := d.Params
+	header := make([]byte, 0, kdfHeaderSize)
+	header = append(header, kdfMagic...)
+	header = append(header, kdfVersion)
+	header = binary.BigEndian.AppendUint32(header, p.Memory)
+	header = binary.BigEndian.AppendUint32(header, p.Iterations)
+	header = append(header, p.Parallelism)
+	salt := make([]byte, kdfSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("salt generation failed: %w", err)
+	}
+	header = append(header, salt...)
+
+	gcm, err := deriveGCM(d, contextPassword(passphrase, encContext), salt)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, nonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("nonce generation failed: %w", err)
+	}
+
+	out := append(header, nonce...)
+	return gcm.Seal(out, nonce, plaintext, profileAAD(header, encContext)), nil
+}
+
+// decryptKDF opens an Argon2id blob. ok is false when the blob has no
+// Argon2id magic or its costs are out of bounds, in which case the caller
+// falls back to the original format. A blob with the magic but another
+// version gives ErrUnsupportedVersion.
+func decryptKDF(blob []byte, passphrase string, encContext []byte) (plaintext []byte, ok bool, err error) {
+	if len(blob) < kdfHeaderSize+nonceSize || !bytes.HasPrefix(blob, kdfMagic) {
+		return nil, false, nil
+	}
+	header := blob[:kdfHeaderSize]
+	if header[3] != kdfVersion {
+		return nil, true, fmt.Errorf("%w: %d", ErrUnsupportedVersion, header[3])
+	}
+	p := KDFParams{
+		Memory:      binary.BigEndian.Uint32(header[4:8]),
+		Iterations:  binary.BigEndian.Uint32(header[8:12]),
+		Parallelism: header[12],
+	}
+	if p.Validate() != nil {
+		return nil, false, nil
+	}
+
+	gcm, err := deriveGCM(Argon2Deriver{Params: p}, contextPassword(passphrase, encContext), header[13:])
+	if err != nil {
+		return nil, true, err
+	}
+	nonce := blob[kdfHeaderSize : kdfHeaderSize+nonceSize]
+	plaintext, err = gcm.Open(nil, nonce, blob[kdfHeaderSize+nonceSize:], profileAAD(header, encContext))
+	if err != nil {
+		return nil, true, fmt.Errorf("decryption failed: %w", err)
+	}
+	return plaintext, true, nil
+}
@@ -0,0 +1,132 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+// encryptOriginal writes the SHA-256 format from before Argon2id.
+func encryptOriginal(t *testing.T, plaintext []byte, passphrase string, encContext []byte) []byte {
+	t.Helper()
+	key, _ := SHA256Deriver{}.Derive(passphrase, encContext)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce := make([]byte, nonceSize)
+	rand.Read(nonce)
+	return gcm.Seal(nonce, nonce, plaintext, encContext)
+}
+
+func TestEncryptUsesArgon2id(t *testing.T) {
+	passphrase := GeneratePassphrase()
+	first, err := EncryptWithContext([]byte("data"), passphrase, []byte("billing"))
+	if err != nil {
+		t.Fatalf("encrypt failed: %v", err)
+	}
+	second, _ := EncryptWithContext([]byte("data"), passphrase, []byte("billing"))
+
+	if !bytes.HasPrefix(first, append(kdfMagic, kdfVersion)) {
+		t.Fatalf("blob lacks the argon2id header: %x", first[:kdfHeaderSize])
+	}
+	if got := binary.BigEndian.Uint32(first[4:8]); got != DefaultKDFParams.Memory {
+		t.Fatalf("recorded memory = %d, want %d", got, DefaultKDFParams.Memory)
+	}
+	if bytes.Equal(first[13:kdfHeaderSize], second[13:kdfHeaderSize]) {
+		t.Fatal("two blobs share a salt")
+	}
+
+	plaintext, err := DecryptWithContext(first, passphrase, []byte("billing"))
+	if err != nil || string(plaintext) != "data" {
+		t.Fatalf("decrypt: got %q, %v", plaintext, err)
+	}
+	if _, err := DecryptWithContext(first, GeneratePassphrase(), []byte("billing")); err == nil {
+		t.Fatal("decrypted with the wrong passphrase")
+	}
+}
+
+func TestDecryptReadsOriginalFormat(t *testing.T) {
+	passphrase := GeneratePassphrase()
+	for _, encContext := range [][]byte{nil, []byte("billing")} {
+		blob := encryptOriginal(t, []byte("legacy"), passphrase, encContext)
+		plaintext, err := DecryptWithContext(blob, passphrase, encContext)
+		if err != nil || string(plaintext) != "legacy" {
+			t.Fatalf("context %q: got %q, %v", encContext, plaintext, err)
+		}
+	}
+}
+
+func TestDecryptRejectsUnknownVersion(t *testing.T) {
+	passphrase := GeneratePassphrase()
+	blob, err := Encrypt([]byte("data"), passphrase)
+	if err != nil {
+		t.Fatalf("encrypt failed: %v", err)
+	}
+
+	future := bytes.Clone(blob)
+	future[3] = kdfVersion + 1
+	if _, err := Decrypt(future, passphrase); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Fatalf("got %v, want ErrUnsupportedVersion", err)
+	}
+
+	// Truncated blobs fail without panicking.
+	for n := 0; n < kdfHeaderSize+nonceSize+1; n++ {
+		if _, err := Decrypt(blob[:n], passphrase); err == nil {
+			t.Fatalf("%d-byte prefix decrypted", n)
+		}
+	}
+}
+
+func TestKDFHeaderIsAuthenticated(t *testing.T) {
+	passphrase := GeneratePassphrase()
+	blob, err := EncryptWithKDF([]byte("data"), passphrase, nil, KDFParams{Memory: MinKDFMemory, Iterations: 1, Parallelism: 1})
+	if err != nil {
+		t.Fatalf("encrypt failed: %v", err)
+	}
+	tampered := bytes.Clone(blob)
+	binary.BigEndian.PutUint32(tampered[8:12], 2)
+	if _, err := Decrypt(tampered, passphrase); err == nil {
+		t.Fatal("decrypted a blob with altered costs")
+	}
+}
+
+func TestSetKDFParams(t *testing.T) {
+	defer SetKDFParams(DefaultKDFParams)
+
+	for _, p := range []KDFParams{
+		{Memory: MinKDFMemory - 1, Iterations: 1, Parallelism: 1},
+		{Memory: MaxKDFMemory + 1, Iterations: 1, Parallelism: 1},
+		{Memory: MinKDFMemory, Iterations: 0, Parallelism: 1},
+		{Memory: MinKDFMemory, Iterations: 1, Parallelism: 0},
+	} {
+		if err := SetKDFParams(p); !errors.Is(err, ErrInvalidKDFParams) {
+			t.Errorf("%+v: got %v, want ErrInvalidKDFParams", p, err)
+		}
+	}
+
+	cheap := KDFParams{Memory: MinKDFMemory, Iterations: 2, Parallelism: 1}
+	if err := SetKDFParams(cheap); err != nil {
+		t.Fatalf("valid params rejected: %v", err)
+	}
+	blob, err := Encrypt([]byte("data"), "passphrase")
+	if err != nil {
+		t.Fatalf("encrypt failed: %v", err)
+	}
+	if got := binary.BigEndian.Uint32(blob[8:12]); got != cheap.Iterations {
+		t.Fatalf("recorded iterations = %d, want %d", got, cheap.Iterations)
+	}
+	// Blobs keep their own costs when the setting changes again.
+	SetKDFParams(DefaultKDFParams)
+	if plaintext, err := Decrypt(blob, "passphrase"); err != nil || string(plaintext) != "data" {
+		t.Fatalf("decrypt after changing params: got %q, %v", plaintext, err)
+	}
+}
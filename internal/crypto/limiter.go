@@ -0,0 +1,112 @@
+package crypto
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var ErrBusy = errors.New("too many concurrent crypto operations")
+
+// Limiter bounds how many Encrypt/Decrypt calls run at once so an expensive
+// KDF cannot exhaust CPU or memory under load. Callers beyond the limit
+// queue for up to the wait duration and then get ErrBusy.
+type Limiter struct {
+	slots chan struct{}
+	wait  time.Duration
+}
+
+// NewLimiter allows max concurrent operations; max <= 0 means unlimited.
+func NewLimiter(max int, wait time.Duration) *Limiter {
+	l := &Limiter{wait: wait}
+	if max > 0 {
+		l.slots = make(chan struct{}, max)
+	}
+	return l
+}
+
+func (l *Limiter) Encrypt(ctx context.Context, plaintext []byte, passphrase string) ([]byte, error) {
+	return l.EncryptWithContext(ctx, plaintext, passphrase, nil)
+}
+
+func (l *Limiter) Decrypt(ctx context.Context, ciphertext []byte, passphrase string) ([]byte, error) {
+	return l.DecryptWithContext(ctx, ciphertext, passphrase, nil)
+}
+
+func (l *Limiter) EncryptWithContext(ctx context.Context, plaintext []byte, passphrase string, encContext []byte) ([]byte, error) {
+	if err := l.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer l.release()
+	return EncryptWithContext(plaintext, passphrase, encContext)
+}
+
+func (l *Limiter) EncryptWithProfile(ctx context.Context, plaintext []byte, passphrase string, encContext []byte, p Profile) ([]byte, error) {
+	if err := l.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer l.release()
+	return EncryptWithProfile(plaintext, passphrase, encContext, p)
+}
+
+func (l *Limiter) EncryptPadded(ctx context.Context, plaintext []byte, passphrase string, encContext []byte, profile *Profile, maxBucket int) ([]byte, error) {
+	if err := l.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer l.release()
+	return EncryptPadded(plaintext, passphrase, encContext, profile, maxBucket)
+}
+
+func (l *Limiter) DecryptWithContext(ctx context.Context, ciphertext []byte, passphrase string, encContext []byte) ([]byte, error) {
+	if err := l.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer l.release()
+	return DecryptWithContext(ciphertext, passphrase, encContext)
+}
+
+func (l *Limiter) EncryptWithDeriver(ctx context.Context, plaintext []byte, passphrase string, encContext []byte, d KeyDeriver) ([]byte, error) {
+	if err := l.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer l.release()
+	return EncryptWithDeriver(plaintext, passphrase, encContext, d)
+}
+
+func (l *Limiter) DecryptWithDeriver(ctx context.Context, ciphertext []byte, passphrase string, encContext []byte, d KeyDeriver) ([]byte, error) {
+	if err := l.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer l.release()
+	return DecryptWithDeriver(ciphertext, passphrase, encContext, d)
+}
+
+func (l *Limiter) acquire(ctx context.Context) error {
+	if l.slots == nil {
+		return nil
+	}
+
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	default:
+	}
+
+	timer := time.NewTimer(l.wait)
+	defer timer.Stop()
+
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	case <-timer.C:
+		return ErrBusy
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (l *Limiter) release() {
+	if l.slots != nil {
+		<-l.slots
+	}
+}
@@ -0,0 +1,56 @@
+package crypto
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLimiterRejectsBeyondLimit(t *testing.T) {
+	l := NewLimiter(1, 10*time.Millisecond)
+	passphrase := GeneratePassphrase()
+
+	if err := l.acquire(context.Background()); err != nil {
+		t.Fatalf("failed to take the only slot: %v", err)
+	}
+	if _, err := l.Encrypt(context.Background(), []byte("data"), passphrase); !errors.Is(err, ErrBusy) {
+		t.Fatalf("expected ErrBusy while the slot is held, got %v", err)
+	}
+	l.release()
+
+	if _, err := l.Encrypt(context.Background(), []byte("data"), passphrase); err != nil {
+		t.Fatalf("expected encrypt to succeed after release, got %v", err)
+	}
+}
+
+func TestLimiterQueuesWithinWait(t *testing.T) {
+	l := NewLimiter(2, 5*time.Second)
+	passphrase := GeneratePassphrase()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ciphertext, err := l.Encrypt(context.Background(), []byte("data"), passphrase)
+			if err == nil {
+				_, err = l.Decrypt(context.Background(), ciphertext, passphrase)
+			}
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("queued operation failed: %v", err)
+		}
+	}
+	if len(l.slots) != 0 {
+		t.Fatalf("slots leaked: %d still held", len(l.slots))
+	}
+}
@@ -0,0 +1,96 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"time"
+)
+
+var errWrappedKey = errors.New("wrapped key is invalid")
+
+// LinkKeys back signed reveal links. Both keys are derived from one server
+// secret, each under its own label, so a link signature can never double
+// as a wrapping key or the other way round.
+type LinkKeys struct {
+	wrap []byte
+	sign []byte
+}
+
+func NewLinkKeys(secret string) LinkKeys {
+	return LinkKeys{
+		wrap: deriveLinkKey(secret, "secure.share link wrap v1"),
+		sign: deriveLinkKey(secret, "secure.share link sign v1"),
+	}
+}
+
+func deriveLinkKey(secret, label string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(label))
+	return mac.Sum(nil)
+}
+
+// WrapPassphrase seals a secret's passphrase with AES-256-GCM for storing
+// beside it. The ID is the additional data, so a wrapped passphrase copied
+// onto another secret doesn't open.
+func (k LinkKeys) WrapPassphrase(id, passphrase string) ([]byte, error) {
+	gcm, err := k.gcm()
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, []byte(passphrase), []byte(id)), nil
+}
+
+func (k LinkKeys) UnwrapPassphrase(id string, wrapped []byte) (string, error) {
+	gcm, err := k.gcm()
+	if err != nil {
+		return "", err
+	}
+	if len(wrapped) < gcm.NonceSize() {
+		return "", errWrappedKey
+	}
+	nonce, sealed := wrapped[:gcm.NonceSize()], wrapped[gcm.NonceSize():]
+	passphrase, err := gcm.Open(nil, nonce, sealed, []byte(id))
+	if err != nil {
+		return "", errWrappedKey
+	}
+	return string(passphrase), nil
+}
+
+func (k LinkKeys) gcm() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(k.wrap)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// SignLink returns the signature of a link granting scope on id until
+// expires, as an HMAC-SHA256 over all three.
+func (k LinkKeys) SignLink(id, scope string, expires time.Time) string {
+	mac := hmac.New(sha256.New, k.sign)
+	mac.Write([]byte(id + "\x00" + scope + "\x00" + strconv.FormatInt(expires.Unix(), 10)))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyLink checks sig and that the link hasn't expired.
+func (k LinkKeys) VerifyLink(id, scope string, expires time.Time, sig string) bool {
+	if !time.Now().Before(expires) {
+		return false
+	}
+	want, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+	got, _ := base64.RawURLEncoding.DecodeString(k.SignLink(id, scope, expires))
+	return hmac.Equal(got, want)
+}
@@ -0,0 +1,66 @@
+package crypto
+
+import (
+	"testing"
+	"time"
+)
+
+const testLinkSecret = "0123456789abcdef0123456789abcdef"
+
+func TestWrapPassphrase(t *testing.T) {
+	keys := NewLinkKeys(testLinkSecret)
+	passphrase := GeneratePassphrase()
+
+	wrapped, err := keys.WrapPassphrase("id1", passphrase)
+	if err != nil {
+		t.Fatalf("wrap failed: %v", err)
+	}
+	got, err := keys.UnwrapPassphrase("id1", wrapped)
+	if err != nil || got != passphrase {
+		t.Fatalf("unwrap: got %q, %v", got, err)
+	}
+
+	if _, err := keys.UnwrapPassphrase("id2", wrapped); err == nil {
+		t.Fatal("wrapped passphrase opened for another id")
+	}
+	if _, err := NewLinkKeys(testLinkSecret+"x").UnwrapPassphrase("id1", wrapped); err == nil {
+		t.Fatal("wrapped passphrase opened under another key")
+	}
+	if _, err := keys.UnwrapPassphrase("id1", wrapped[:4]); err == nil {
+		t.Fatal("truncated wrapped passphrase opened")
+	}
+}
+
+func TestSignLink(t *testing.T) {
+	keys := NewLinkKeys(testLinkSecret)
+	expires := time.Now().Add(time.Minute)
+	sig := keys.SignLink("id1", "reveal", expires)
+
+	if !keys.VerifyLink("id1", "reveal", expires, sig) {
+		t.Fatal("valid link rejected")
+	}
+
+	tests := []struct {
+		name    string
+		id      string
+		scope   string
+		expires time.Time
+		sig     string
+	}{
+		{"other id", "id2", "reveal", expires, sig},
+		{"other scope", "id1", "admin", expires, sig},
+		{"extended expiry", "id1", "reveal", expires.Add(time.Hour), sig},
+		{"malformed", "id1", "reveal", expires, "not base64!"},
+		{"other key", "id1", "reveal", expires, NewLinkKeys(testLinkSecret+"x").SignLink("id1", "reveal", expires)},
+	}
+	for _, tt := range tests {
+		if keys.VerifyLink(tt.id, tt.scope, tt.expires, tt.sig) {
+			t.Errorf("%s: link accepted", tt.name)
+		}
+	}
+
+	expired := time.Now().Add(-time.Second)
+	if keys.VerifyLink("id1", "reveal", expired, keys.SignLink("id1", "reveal", expired)) {
+		t.Fatal("expired link accepted")
+	}
+}
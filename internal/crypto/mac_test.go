@@ -0,0 +1,31 @@
+package crypto
+
+import "testing"
+
+func TestContentMAC(t *testing.T) {
+	key := GenerateMACKey()
+	content := []byte("db password: hunter2")
+	mac := ContentMAC(key, content)
+
+	if mac != ContentMAC(key, content) {
+		t.Fatal("MAC is not deterministic for the same key and content")
+	}
+	if !VerifyContentMAC(key, content, mac) {
+		t.Fatal("valid MAC rejected")
+	}
+
+	if VerifyContentMAC(key, []byte("db password: hunter3"), mac) {
+		t.Fatal("MAC accepted altered content")
+	}
+	if VerifyContentMAC(GenerateMACKey(), content, mac) {
+		t.Fatal("MAC accepted under a different key")
+	}
+	tampered := []byte(mac)
+	tampered[0] ^= 1
+	if VerifyContentMAC(key, content, string(tampered)) {
+		t.Fatal("altered MAC accepted")
+	}
+	if VerifyContentMAC(key, content, "not base64!") {
+		t.Fatal("malformed MAC accepted")
+	}
+}
@@ -0,0 +1,89 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// A padded blob hides the plaintext length from anyone who can only see
+// the blob or its size:
+//
+//	magic(4) | length(4) | inner blob
+//
+// The inner blob is an original or profile blob over the plaintext padded
+// with zeros to its bucket. The header is bound into the inner blob's
+// context, so the recorded length is authenticated.
+var padMagic = []byte{'s', 's', 'l', 1}
+
+const (
+	padHeaderSize = 4 + 4
+	// MinPadBucket is the smallest padded plaintext size.
+	MinPadBucket = 32
+)
+
+// PaddedLength returns the bucket for a plaintext of n bytes: the next power
+// of two from MinPadBucket up to maxBucket, and past that the next multiple
+// of maxBucket.
+func PaddedLength(n, maxBucket int) int {
+	bucket := MinPadBucket
+	for bucket < n && bucket < maxBucket {
+		bucket *= 2
+	}
+	if n <= bucket {
+		return bucket
+	}
+	return (n + maxBucket - 1) / maxBucket * maxBucket
+}
+
+// EncryptPadded pads plaintext to its bucket (see PaddedLength) and
+// encrypts it with profile, or the original scheme when profile is nil.
+// DecryptWithContext strips the padding again.
+func EncryptPadded(plaintext []byte, passphrase string, encContext []byte, profile *Profile, maxBucket int) ([]byte, error) {
+	if maxBucket < MinPadBucket {
+		return nil, fmt.Errorf("pad bucket must be at least %d bytes", MinPadBucket)
+	}
+
+	header := make([]byte, 0, padHeaderSize)
+	header = append(header, padMagic...)
+	header = binary.BigEndian.AppendUint32(header, uint32(len(plaintext)))
+
+	padded := make([]byte, PaddedLength(len(plaintext), maxBucket))
+	copy(padded, plaintext)
+
+	innerContext := padContext(header, encContext)
+	var inner []byte
+	var err error
+	if profile == nil {
+		inner, err = encryptWithDeriver(padded, passphrase, innerContext, DefaultDeriver())
+	} else if err = profile.Validate(); err == nil {
+		inner, err = encryptWithProfile(padded, passphrase, innerContext, *profile)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return append(header, inner...), nil
+}
+
+// decryptPadded opens a padded blob. ok is false when the blob has no
+// padding header or doesn't open as one, in which case the caller tries
+// the unpadded formats.
+func decryptPadded(blob []byte, passphrase string, encContext []byte, d KeyDeriver) (plaintext []byte, ok bool) {
+	if len(blob) < padHeaderSize || !bytes.HasPrefix(blob, padMagic) {
+		return nil, false
+	}
+	header := blob[:padHeaderSize]
+	length := int(binary.BigEndian.Uint32(header[4:]))
+
+	padded, err := decryptUnpadded(blob[padHeaderSize:], passphrase, padContext(header, encContext), d)
+	if err != nil || length > len(padded) {
+		return nil, false
+	}
+	return padded[:length], true
+}
+
+func padContext(header, encContext []byte) []byte {
+	ctx := make([]byte, 0, len(header)+len(encContext))
+	ctx = append(ctx, header...)
+	return append(ctx, encContext...)
+}
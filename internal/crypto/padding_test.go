@@ -0,0 +1,71 @@
+package crypto
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPaddedLength(t *testing.T) {
+	tests := []struct{ n, max, want int }{
+		{0, 4096, 32},
+		{1, 4096, 32},
+		{32, 4096, 32},
+		{33, 4096, 64},
+		{4096, 4096, 4096},
+		{4097, 4096, 8192},
+		{10000, 4096, 12288},
+		{100, 100, 128},
+		{129, 100, 200},
+	}
+	for _, tt := range tests {
+		if got := PaddedLength(tt.n, tt.max); got != tt.want {
+			t.Errorf("PaddedLength(%d, %d) = %d, want %d", tt.n, tt.max, got, tt.want)
+		}
+	}
+}
+
+func TestEncryptPaddedHidesLength(t *testing.T) {
+	profile := &Profile{Cipher: CipherAES128GCM, KDFIterations: MinKDFIterations}
+	for _, p := range []*Profile{nil, profile} {
+		inputs := [][]byte{{}, []byte("a"), []byte("hunter2"), bytes.Repeat([]byte("x"), 32)}
+		size := -1
+		for _, in := range inputs {
+			blob, err := EncryptPadded(in, "pass", []byte("ctx"), p, 4096)
+			if err != nil {
+				t.Fatalf("encrypt failed: %v", err)
+			}
+			if size != -1 && len(blob) != size {
+				t.Fatalf("%d-byte input gave a %d-byte blob, want %d", len(in), len(blob), size)
+			}
+			size = len(blob)
+
+			got, err := DecryptWithContext(blob, "pass", []byte("ctx"))
+			if err != nil {
+				t.Fatalf("decrypt failed: %v", err)
+			}
+			if !bytes.Equal(got, in) {
+				t.Fatalf("round trip mismatch: got %q, want %q", got, in)
+			}
+		}
+
+		// One byte over the bucket moves to the next one.
+		blob, _ := EncryptPadded(bytes.Repeat([]byte("x"), 33), "pass", []byte("ctx"), p, 4096)
+		if len(blob) <= size {
+			t.Fatalf("33-byte input stayed in the 32-byte bucket")
+		}
+	}
+}
+
+func TestEncryptPaddedLengthIsAuthenticated(t *testing.T) {
+	blob, err := EncryptPadded([]byte("hunter2"), "pass", nil, nil, 4096)
+	if err != nil {
+		t.Fatalf("encrypt failed: %v", err)
+	}
+	blob[padHeaderSize-1] = 32 // claim the padding is content
+	if _, err := DecryptWithContext(blob, "pass", nil); err == nil {
+		t.Fatal("decrypt with a tampered length succeeded")
+	}
+	if _, err := DecryptWithContext(blob, "wrong", nil); err == nil {
+		t.Fatal("decrypt with wrong passphrase succeeded")
+	}
+}
@@ -2,11 +2,9 @@
 package crypto
 
 import (
-	"crypto/aes"
-	"crypto/cipher"
 	"crypto/rand"
-	"crypto/sha256"
 	"encoding/base64"
+	"errors"
 	"fmt"
 )
 
@@ -32,57 +30,100 @@ func GeneratePassphrase() string {
 	return base64.RawURLEncoding.EncodeToString(bytes)
 }
 
-func Encrypt(plaintext []byte, passphrase string) ([]byte, error) {
-	key := deriveKey(passphrase)
-
-	block, err := aes.NewCipher(key)
-	if err != nil {
-		return nil, fmt.Errorf("cipher creation failed: %w", err)
-	}
+var ErrMalformedPassphrase = errors.New("malformed passphrase")
 
-	gcm, err := cipher.NewGCM(block)
-	if err != nil {
-		return nil, fmt.Errorf("GCM creation failed: %w", err)
+// CheckPassphraseFormat reports whether passphrase has the shape of one made
+// by GeneratePassphrase, so a mangled link can be told apart from a wrong key.
+func CheckPassphraseFormat(passphrase string) error {
+	raw, err := base64.RawURLEncoding.DecodeString(passphrase)
+	if err != nil || len(raw) != passphraseLength {
+		return ErrMalformedPassphrase
 	}
+	return nil
+}
 
-	nonce := make([]byte, nonceSize)
-	if _, err := rand.Read(nonce); err != nil {
-		return nil, fmt.Errorf("nonce generation failed: %w", err)
-	}
+func Encrypt(plaintext []byte, passphrase string) ([]byte, error) {
+	return EncryptWithContext(plaintext, passphrase, nil)
+}
 
-	ciphertext := gcm.Seal(nonce, nonce, plaintext, nil)
-	return ciphertext, nil
+// EncryptWithContext binds a non-secret context label into both the key and
+// the GCM additional data, so a blob only decrypts under the same label.
+// The key is derived with Argon2id at the costs set by SetKDFParams. The
+// plaintext is compressed first if SetCompression turned that on.
+func EncryptWithContext(plaintext []byte, passphrase string, encContext []byte) ([]byte, error) {
+	return sealCompressed(plaintext, encContext, func(plaintext, encContext []byte) ([]byte, error) {
+		return encryptWithDeriver(plaintext, passphrase, encContext, DefaultDeriver())
+	})
 }
 
 func Decrypt(ciphertext []byte, passphrase string) ([]byte, error) {
-	if len(ciphertext) < nonceSize {
-		return nil, fmt.Errorf("ciphertext too short")
+	return DecryptWithContext(ciphertext, passphrase, nil)
+}
+
+// DecryptWithContext opens blobs from EncryptWithContext, EncryptWithProfile
+// and EncryptPadded, compressed or not, telling them apart by their headers,
+// as well as blobs in the original SHA-256 format.
+func DecryptWithContext(ciphertext []byte, passphrase string, encContext []byte) ([]byte, error) {
+	return decryptWithDeriver(ciphertext, passphrase, encContext, nil)
+}
+
+// decryptWithDeriver tries the formats in turn; d, if not nil, opens
+// blobs from EncryptWithDeriver under a deriver of the caller's own.
+func decryptWithDeriver(ciphertext []byte, passphrase string, encContext []byte, d KeyDeriver) ([]byte, error) {
+	if plaintext, ok := decryptPadded(ciphertext, passphrase, encContext, d); ok {
+		return plaintext, nil
 	}
+	if plaintext, ok, err := decryptCompressed(ciphertext, passphrase, encContext, d); ok {
+		return plaintext, err
+	}
+	return decryptUnpadded(ciphertext, passphrase, encContext, d)
+}
 
-	key := deriveKey(passphrase)
+func decryptUnpadded(ciphertext []byte, passphrase string, encContext []byte, d KeyDeriver) ([]byte, error) {
+	plaintext, isDerived, derivedErr := decryptDerived(ciphertext, passphrase, encContext, d)
+	if isDerived && derivedErr == nil {
+		return plaintext, nil
+	}
+	plaintext, isProfile, profileErr := decryptProfile(ciphertext, passphrase, encContext)
+	if isProfile && profileErr == nil {
+		return plaintext, nil
+	}
+	plaintext, isKDF, kdfErr := decryptKDF(ciphertext, passphrase, encContext)
+	if isKDF && kdfErr == nil {
+		return plaintext, nil
+	}
+	// An original-format blob whose random nonce happens to start with one
+	// of the magics still gets its chance below.
+	plaintext, err := decryptOriginal(ciphertext, passphrase, encContext)
+	if err != nil && isKDF {
+		return nil, kdfErr
+	}
+	if err != nil && isProfile {
+		return nil, profileErr
+	}
+	if err != nil && isDerived {
+		return nil, derivedErr
+	}
+	return plaintext, err
+}
 
-	block, err := aes.NewCipher(key)
-	if err != nil {
-		return nil, fmt.Errorf("cipher creation failed: %w", err)
+func decryptOriginal(ciphertext []byte, passphrase string, encContext []byte) ([]byte, error) {
+	if len(ciphertext) < nonceSize {
+		return nil, fmt.Errorf("ciphertext too short")
 	}
 
-	gcm, err := cipher.NewGCM(block)
+	gcm, err := deriveGCM(SHA256Deriver{}, passphrase, encContext)
 	if err != nil {
-		return nil, fmt.Errorf("GCM creation failed: %w", err)
+		return nil, err
 	}
 
 	nonce := ciphertext[:nonceSize]
 	ciphertext = ciphertext[nonceSize:]
 
-	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, encContext)
 	if err != nil {
 		return nil, fmt.Errorf("decryption failed: %w", err)
 	}
 
 	return plaintext, nil
 }
-
-func deriveKey(passphrase string) []byte {
-	hash := sha256.Sum256([]byte(passphrase))
-	return hash[:]
-}
@@ -0,0 +1,27 @@
+package crypto
+
+import "testing"
+
+func TestEncryptWithContext(t *testing.T) {
+	passphrase := GeneratePassphrase()
+
+	ciphertext, err := EncryptWithContext([]byte("data"), passphrase, []byte("billing"))
+	if err != nil {
+		t.Fatalf("encrypt failed: %v", err)
+	}
+
+	plaintext, err := DecryptWithContext(ciphertext, passphrase, []byte("billing"))
+	if err != nil {
+		t.Fatalf("decrypt with matching context failed: %v", err)
+	}
+	if string(plaintext) != "data" {
+		t.Fatalf("plaintext mismatch: got %q, want %q", plaintext, "data")
+	}
+
+	if _, err := DecryptWithContext(ciphertext, passphrase, []byte("payroll")); err == nil {
+		t.Fatalf("expected decrypt with a different context to fail")
+	}
+	if _, err := Decrypt(ciphertext, passphrase); err == nil {
+		t.Fatalf("expected decrypt without context to fail")
+	}
+}
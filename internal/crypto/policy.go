@@ -0,0 +1,117 @@
+package crypto
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// PassphrasePolicy decides whether a client-supplied passphrase is acceptable.
+// Generated passphrases never go through a policy.
+type PassphrasePolicy interface {
+	Validate(passphrase string) error
+}
+
+// PolicyFunc adapts a plain function to the PassphrasePolicy interface.
+type PolicyFunc func(passphrase string) error
+
+func (f PolicyFunc) Validate(passphrase string) error {
+	return f(passphrase)
+}
+
+type Violation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// PolicyError lists every rule a passphrase failed, so callers can report
+// all problems at once instead of one per attempt.
+type PolicyError struct {
+	Violations []Violation
+}
+
+func (e *PolicyError) Error() string {
+	msgs := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		msgs[i] = v.Message
+	}
+	return "passphrase rejected: " + strings.Join(msgs, "; ")
+}
+
+// DefaultPolicy checks length and character classes, and turns away
+// repetitive, sequential or low-entropy passphrases as PassphraseStrength
+// does, against its own entropy floor.
+type DefaultPolicy struct {
+	MinLength      int
+	MaxLength      int     // 0 means unlimited
+	MinClasses     int     // lower, upper, digit, other
+	MinEntropyBits float64 // 0 leaves only the repetition and sequence checks
+}
+
+func (p DefaultPolicy) Validate(passphrase string) error {
+	var violations []Violation
+
+	length := len([]rune(passphrase))
+	if length < p.MinLength {
+		violations = append(violations, Violation{
+			Rule:    "min_length",
+			Message: fmt.Sprintf("must be at least %d characters", p.MinLength),
+		})
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		violations = append(violations, Violation{
+			Rule:    "max_length",
+			Message: fmt.Sprintf("must be at most %d characters", p.MaxLength),
+		})
+	}
+
+	classes, _ := charClasses(passphrase)
+	if classes < p.MinClasses {
+		violations = append(violations, Violation{
+			Rule:    "min_classes",
+			Message: fmt.Sprintf("must mix at least %d of lowercase, uppercase, digits and symbols", p.MinClasses),
+		})
+	}
+
+	violations = append(violations, p.weaknesses(passphrase, entropyBits(passphrase))...)
+
+	if len(violations) > 0 {
+		return &PolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// charClasses returns how many character classes appear in s and the size of
+// the combined alphabet they imply.
+func charClasses(s string) (classes, poolSize int) {
+	var lower, upper, digit, other bool
+	for _, r := range s {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			other = true
+		}
+	}
+	if lower {
+		classes++
+		poolSize += 26
+	}
+	if upper {
+		classes++
+		poolSize += 26
+	}
+	if digit {
+		classes++
+		poolSize += 10
+	}
+	if other {
+		classes++
+		poolSize += 33
+	}
+	return classes, poolSize
+}
@@ -0,0 +1,57 @@
+package crypto
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDefaultPolicy(t *testing.T) {
+	policy := DefaultPolicy{MinLength: 12, MaxLength: 64, MinClasses: 3, MinEntropyBits: 60}
+
+	accepted := []string{"Correct-Horse-Battery-9", "tr0ub4dor&3-Staple"}
+	for _, p := range accepted {
+		if err := policy.Validate(p); err != nil {
+			t.Fatalf("expected %q to be accepted, got %v", p, err)
+		}
+	}
+
+	rejected := map[string]string{
+		"short1A":                 "min_length",
+		"alllowercaseletters":     "min_classes",
+		strings.Repeat("aB3", 30): "max_length",
+	}
+	for p, rule := range rejected {
+		err := policy.Validate(p)
+		var perr *PolicyError
+		if !errors.As(err, &perr) {
+			t.Fatalf("expected PolicyError for %q, got %v", p, err)
+		}
+		found := false
+		for _, v := range perr.Violations {
+			if v.Rule == rule {
+				found = true
+			}
+		}
+		if !found {
+			t.Fatalf("expected %q to violate %s, got %+v", p, rule, perr.Violations)
+		}
+	}
+}
+
+func TestCustomPolicy(t *testing.T) {
+	noSpaces := PolicyFunc(func(p string) error {
+		if strings.ContainsRune(p, ' ') {
+			return &PolicyError{Violations: []Violation{{Rule: "no_spaces", Message: "must not contain spaces"}}}
+		}
+		return nil
+	})
+
+	var policy PassphrasePolicy = noSpaces
+	if err := policy.Validate("no-spaces-here"); err != nil {
+		t.Fatalf("expected passphrase to be accepted, got %v", err)
+	}
+	if err := policy.Validate("has spaces"); err == nil {
+		t.Fatalf("expected passphrase with spaces to be rejected")
+	}
+}
@@ -0,0 +1,135 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Cipher names accepted in a Profile.
+const (
+	CipherAES128GCM = "aes-128-gcm"
+	CipherAES256GCM = "aes-256-gcm"
+)
+
+// Bounds on a profile's PBKDF2 cost. They are also checked when reading a
+// header, so a stored blob can't make a reveal arbitrarily slow.
+const (
+	MinKDFIterations = 1000
+	MaxKDFIterations = 10_000_000
+)
+
+// Profile is a server-defined cipher and key-derivation cost a client can
+// pick per secret.
+type Profile struct {
+	Cipher        string
+	KDFIterations int
+}
+
+var ErrInvalidProfile = errors.New("invalid crypto profile")
+
+func (p Profile) Validate() error {
+	if _, ok := cipherIDs[p.Cipher]; !ok {
+		return fmt.Errorf("%w: unknown cipher %q", ErrInvalidProfile, p.Cipher)
+	}
+	if p.KDFIterations < MinKDFIterations || p.KDFIterations > MaxKDFIterations {
+		return fmt.Errorf("%w: kdf iterations must be between %d and %d", ErrInvalidProfile, MinKDFIterations, MaxKDFIterations)
+	}
+	return nil
+}
+
+// A profile blob is self-describing:
+//
+//	magic(4) | cipher(1) | iterations(4) | salt(16) | nonce(12) | ciphertext
+//
+// The header is authenticated as part of the GCM additional data. Blobs
+// without the magic are Argon2id or original SHA-256 blobs.
+var profileMagic = []byte{'s', 's', 'p', 1}
+
+const (
+	profileSaltSize   = 16
+	profileHeaderSize = 4 + 1 + 4 + profileSaltSize
+)
+
+var cipherIDs = map[string]byte{
+	CipherAES128GCM: 1,
+	CipherAES256GCM: 2,
+}
+
+var cipherKeySizes = map[byte]int{
+	1: 16,
+	2: 32,
+}
+
+// EncryptWithProfile encrypts like EncryptWithContext but with p's cipher
+// and a PBKDF2 key, recording both in the blob so decryption needs no
+// out-of-band choice.
+func EncryptWithProfile(plaintext []byte, passphrase string, encContext []byte, p Profile) ([]byte, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return sealCompressed(plaintext, encContext, func(plaintext, encContext []byte) ([]byte, error) {
+		return encryptWithProfile(plaintext, passphrase, encContext, p)
+	})
+}
+
+func encryptWithProfile(plaintext []byte, passphrase string, encContext []byte, p Profile) ([]byte, error) {
+	id := cipherIDs[p.Cipher]
+
+	header := make([]byte, 0, profileHeaderSize)
+	header = append(header, profileMagic...)
+	header = append(header, id)
+	header = binary.BigEndian.AppendUint32(header, uint32(p.KDFIterations))
+	salt := make([]byte, profileSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("salt generation failed: %w", err)
+	}
+	header = append(header, salt...)
+
+	gcm, err := deriveGCM(PBKDF2Deriver{Iterations: p.KDFIterations, KeyLen: cipherKeySizes[id]}, contextPassword(passphrase, encContext), salt)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, nonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("nonce generation failed: %w", err)
+	}
+
+	out := append(header, nonce...)
+	return gcm.Seal(out, nonce, plaintext, profileAAD(header, encContext)), nil
+}
+
+// decryptProfile opens a profile blob. ok is false when the blob has no
+// valid profile header, in which case the caller falls back to the
+// original format.
+func decryptProfile(blob []byte, passphrase string, encContext []byte) (plaintext []byte, ok bool, err error) {
+	if len(blob) < profileHeaderSize+nonceSize || !bytes.HasPrefix(blob, profileMagic) {
+		return nil, false, nil
+	}
+	header := blob[:profileHeaderSize]
+	id := header[4]
+	iterations := int(binary.BigEndian.Uint32(header[5:9]))
+	if _, known := cipherKeySizes[id]; !known || iterations < MinKDFIterations || iterations > MaxKDFIterations {
+		return nil, false, nil
+	}
+
+	gcm, err := deriveGCM(PBKDF2Deriver{Iterations: iterations, KeyLen: cipherKeySizes[id]}, contextPassword(passphrase, encContext), header[9:])
+	if err != nil {
+		return nil, true, err
+	}
+	nonce := blob[profileHeaderSize : profileHeaderSize+nonceSize]
+	plaintext, err = gcm.Open(nil, nonce, blob[profileHeaderSize+nonceSize:], profileAAD(header, encContext))
+	if err != nil {
+		return nil, true, fmt.Errorf("decryption failed: %w", err)
+	}
+	return plaintext, true, nil
+}
+
+func profileAAD(header, encContext []byte) []byte {
+	aad := make([]byte, 0, len(header)+len(encContext))
+	aad = append(aad, header...)
+	return append(aad, encContext...)
+}
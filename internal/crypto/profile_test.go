@@ -0,0 +1,58 @@
+package crypto
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestEncryptWithProfileRoundTrip(t *testing.T) {
+	profiles := []Profile{
+		{Cipher: CipherAES128GCM, KDFIterations: MinKDFIterations},
+		{Cipher: CipherAES256GCM, KDFIterations: 2 * MinKDFIterations},
+	}
+	for _, p := range profiles {
+		blob, err := EncryptWithProfile([]byte("hunter2"), "pass", []byte("ctx"), p)
+		if err != nil {
+			t.Fatalf("%s: encrypt failed: %v", p.Cipher, err)
+		}
+		got, err := DecryptWithContext(blob, "pass", []byte("ctx"))
+		if err != nil {
+			t.Fatalf("%s: decrypt failed: %v", p.Cipher, err)
+		}
+		if !bytes.Equal(got, []byte("hunter2")) {
+			t.Fatalf("%s: plaintext mismatch: %q", p.Cipher, got)
+		}
+		if _, err := DecryptWithContext(blob, "wrong", []byte("ctx")); err == nil {
+			t.Fatalf("%s: decrypt with wrong passphrase succeeded", p.Cipher)
+		}
+		if _, err := DecryptWithContext(blob, "pass", []byte("other")); err == nil {
+			t.Fatalf("%s: decrypt with wrong context succeeded", p.Cipher)
+		}
+	}
+}
+
+func TestEncryptWithProfileHeaderIsAuthenticated(t *testing.T) {
+	blob, err := EncryptWithProfile([]byte("hunter2"), "pass", nil, Profile{Cipher: CipherAES256GCM, KDFIterations: MinKDFIterations})
+	if err != nil {
+		t.Fatalf("encrypt failed: %v", err)
+	}
+	// Downgrading the recorded cipher must not yield a usable blob.
+	blob[4] = cipherIDs[CipherAES128GCM]
+	if _, err := DecryptWithContext(blob, "pass", nil); err == nil {
+		t.Fatal("decrypt of tampered header succeeded")
+	}
+}
+
+func TestEncryptWithProfileRejectsInvalid(t *testing.T) {
+	invalid := []Profile{
+		{Cipher: "chacha20", KDFIterations: MinKDFIterations},
+		{Cipher: CipherAES256GCM, KDFIterations: 1},
+		{Cipher: CipherAES256GCM, KDFIterations: MaxKDFIterations + 1},
+	}
+	for _, p := range invalid {
+		if _, err := EncryptWithProfile([]byte("x"), "pass", nil, p); !errors.Is(err, ErrInvalidProfile) {
+			t.Fatalf("%+v: expected ErrInvalidProfile, got %v", p, err)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package crypto
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+// Shamir secret sharing over GF(2^8) using the AES reduction polynomial.
+// Each share is one x-coordinate byte followed by one y byte per key byte.
+
+var (
+	gfExp [510]byte
+	gfLog [256]byte
+)
+
+func init() {
+	x := byte(1)
+	for i := 0; i < 255; i++ {
+		gfExp[i] = x
+		gfLog[x] = byte(i)
+		// multiply by the generator 0x03
+		x ^= gfDouble(x)
+	}
+	for i := 255; i < len(gfExp); i++ {
+		gfExp[i] = gfExp[i-255]
+	}
+}
+
+func gfDouble(a byte) byte {
+	if a&0x80 != 0 {
+		return a<<1 ^ 0x1b
+	}
+	return a << 1
+}
+
+func gfMul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+int(gfLog[b])]
+}
+
+func gfDiv(a, b byte) byte {
+	if a == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+255-int(gfLog[b])]
+}
+
+// SplitKey splits key into total shares, any threshold of which recover it
+// with CombineKey. Fewer than threshold shares reveal nothing about key.
+func SplitKey(key []byte, threshold, total int) ([][]byte, error) {
+	if len(key) == 0 {
+		return nil, errors.New("key must not be empty")
+	}
+	if threshold < 2 || threshold > total || total > 255 {
+		return nil, fmt.Errorf("invalid threshold %d of %d shares", threshold, total)
+	}
+
+	shares := make([][]byte, total)
+	for i := range shares {
+		shares[i] = make([]byte, len(key)+1)
+		shares[i][0] = byte(i + 1)
+	}
+
+	coeffs := make([]byte, threshold)
+	for b, secretByte := range key {
+		if _, err := rand.Read(coeffs[1:]); err != nil {
+			return nil, fmt.Errorf("coefficient generation failed: %w", err)
+		}
+		coeffs[0] = secretByte
+
+		for _, share := range shares {
+			// Horner evaluation of the polynomial at x = share[0].
+			x, y := share[0], byte(0)
+			for c := threshold - 1; c >= 0; c-- {
+				y = gfMul(y, x) ^ coeffs[c]
+			}
+			share[b+1] = y
+		}
+	}
+
+	return shares, nil
+}
+
+// CombineKey reconstructs a key from shares produced by SplitKey. It cannot
+// tell whether enough shares were supplied; with too few it returns a wrong
+// key, which callers detect when decryption fails.
+func CombineKey(shares [][]byte) ([]byte, error) {
+	if len(shares) < 2 {
+		return nil, errors.New("at least 2 shares are required")
+	}
+
+	size := len(shares[0])
+	if size < 2 {
+		return nil, errors.New("share too short")
+	}
+	seen := make(map[byte]bool, len(shares))
+	for _, share := range shares {
+		if len(share) != size {
+			return nil, errors.New("shares have different lengths")
+		}
+		if share[0] == 0 || seen[share[0]] {
+			return nil, errors.New("invalid or duplicate share")
+		}
+		seen[share[0]] = true
+	}
+
+	key := make([]byte, size-1)
+	for b := range key {
+		// Lagrange interpolation at x = 0; addition and subtraction are XOR.
+		var value byte
+		for i, si := range shares {
+			basis := byte(1)
+			for j, sj := range shares {
+				if i == j {
+					continue
+				}
+				basis = gfMul(basis, gfDiv(sj[0], sj[0]^si[0]))
+			}
+			value ^= gfMul(si[b+1], basis)
+		}
+		key[b] = value
+	}
+
+	return key, nil
+}
@@ -0,0 +1,59 @@
+package crypto
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSplitCombineKey(t *testing.T) {
+	key := []byte(GeneratePassphrase())
+
+	shares, err := SplitKey(key, 2, 3)
+	if err != nil {
+		t.Fatalf("failed to split key: %v", err)
+	}
+	if len(shares) != 3 {
+		t.Fatalf("share count mismatch: got %d, want %d", len(shares), 3)
+	}
+
+	pairs := [][2]int{{0, 1}, {0, 2}, {1, 2}}
+	for _, p := range pairs {
+		got, err := CombineKey([][]byte{shares[p[0]], shares[p[1]]})
+		if err != nil {
+			t.Fatalf("failed to combine shares %v: %v", p, err)
+		}
+		if !bytes.Equal(got, key) {
+			t.Fatalf("shares %v reconstructed the wrong key", p)
+		}
+	}
+
+	all, err := CombineKey(shares)
+	if err != nil || !bytes.Equal(all, key) {
+		t.Fatalf("all shares failed to reconstruct the key: %v", err)
+	}
+}
+
+func TestCombineKeyInsufficientShares(t *testing.T) {
+	key := []byte(GeneratePassphrase())
+
+	shares, err := SplitKey(key, 3, 5)
+	if err != nil {
+		t.Fatalf("failed to split key: %v", err)
+	}
+
+	if _, err := CombineKey(shares[:1]); err == nil {
+		t.Fatalf("expected a single share to be rejected")
+	}
+
+	got, err := CombineKey(shares[:2])
+	if err != nil {
+		t.Fatalf("unexpected error combining two shares: %v", err)
+	}
+	if bytes.Equal(got, key) {
+		t.Fatalf("two shares of a 3-of-5 split must not recover the key")
+	}
+
+	if _, err := CombineKey([][]byte{shares[0], shares[0]}); err == nil {
+		t.Fatalf("expected duplicate shares to be rejected")
+	}
+}
@@ -0,0 +1,107 @@
+package crypto
+
+import (
+	"fmt"
+	"math"
+)
+
+// DefaultMinEntropyBits is the entropy PassphraseStrength asks for.
+const DefaultMinEntropyBits = 40
+
+// minDistinctChars is how many different characters a passphrase needs, to
+// turn away the likes of "aaaaaaaaaaaa" and "abababababab".
+const minDistinctChars = 5
+
+// PassphraseStrength estimates a chosen passphrase's entropy in bits and
+// reports whether it is strong enough: not repetitive, not a sequence, and
+// at least DefaultMinEntropyBits. Generated passphrases are strong by
+// construction and needn't be checked.
+func PassphraseStrength(p string) (bits float64, ok bool) {
+	return DefaultPolicy{MinEntropyBits: DefaultMinEntropyBits}.Strength(p)
+}
+
+// Strength is PassphraseStrength against p's own entropy floor, ignoring
+// its length and class rules.
+func (p DefaultPolicy) Strength(passphrase string) (bits float64, ok bool) {
+	bits = entropyBits(passphrase)
+	return bits, len(p.weaknesses(passphrase, bits)) == 0
+}
+
+func (p DefaultPolicy) weaknesses(passphrase string, bits float64) []Violation {
+	var violations []Violation
+	if distinctChars(passphrase) < minDistinctChars {
+		violations = append(violations, Violation{
+			Rule:    "repetitive",
+			Message: "too repetitive: use more different characters",
+		})
+	}
+	if isSequence(passphrase) {
+		violations = append(violations, Violation{
+			Rule:    "sequence",
+			Message: "a predictable sequence like abcd or 9876",
+		})
+	}
+	if bits < p.MinEntropyBits {
+		violations = append(violations, Violation{
+			Rule:    "min_entropy",
+			Message: fmt.Sprintf("too predictable (%.0f bits, need %.0f)", bits, p.MinEntropyBits),
+		})
+	}
+	return violations
+}
+
+// entropyBits is the lower of two estimates: the passphrase's length times
+// the bits per character of the alphabet its character classes imply, and
+// its length times the Shannon entropy of its own character frequencies,
+// which stays low for passphrases that reuse a few characters.
+func entropyBits(p string) float64 {
+	counts := make(map[rune]int)
+	length := 0
+	for _, c := range p {
+		counts[c]++
+		length++
+	}
+	if length == 0 {
+		return 0
+	}
+
+	var shannon float64
+	for _, n := range counts {
+		f := float64(n) / float64(length)
+		shannon -= f * math.Log2(f)
+	}
+	_, poolSize := charClasses(p)
+	return float64(length) * min(shannon, math.Log2(float64(max(poolSize, 1))))
+}
+
+func distinctChars(p string) int {
+	seen := make(map[rune]bool)
+	for _, c := range p {
+		seen[c] = true
+	}
+	return len(seen)
+}
+
+// isSequence reports a passphrase that is a run like "abcdefghijkl" or
+// "987654321098".
+func isSequence(p string) bool {
+	var prev, step rune
+	for i, c := range []rune(p) {
+		switch i {
+		case 0:
+		case 1:
+			step = c - prev
+		default:
+			// Digit runs wrap, so 8901 counts as a run.
+			if d := c - prev; d != step && !(isDigit(c) && isDigit(prev) && (d+10)%10 == (step+10)%10) {
+				return false
+			}
+		}
+		prev = c
+	}
+	return true
+}
+
+func isDigit(c rune) bool {
+	return c >= '0' && c <= '9'
+}
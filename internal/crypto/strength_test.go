@@ -0,0 +1,73 @@
+package crypto
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPassphraseStrength(t *testing.T) {
+	for p, want := range map[string]bool{
+		"":                      false,
+		"aaaaaaaaaaaa":          false,
+		"abcdefghijklmnop":      false,
+		"987654321098":          false,
+		"abcabcabcabc":          false,
+		"abababababababab":      false,
+		"hello world!":          false,
+		"correct horse battery": true,
+		"q8#Lm2!zR7wp":          true,
+		"Tr0ub4dor&3 plus more": true,
+	} {
+		bits, ok := PassphraseStrength(p)
+		if ok != want {
+			t.Errorf("PassphraseStrength(%q) = %.1f bits, %v, want %v", p, bits, ok, want)
+		}
+		policy := DefaultPolicy{MinEntropyBits: DefaultMinEntropyBits}
+		if ok != (policy.Validate(p) == nil) {
+			t.Errorf("DefaultPolicy.Validate(%q) disagrees with PassphraseStrength", p)
+		}
+	}
+}
+
+func TestPassphraseStrengthBits(t *testing.T) {
+	// Repeating characters lowers the estimate even with the same alphabet.
+	varied, _ := PassphraseStrength("plum kite grove")
+	repeated, _ := PassphraseStrength("plum plum plum!")
+	if repeated >= varied {
+		t.Errorf("repeated words estimated at %.1f bits, varied at %.1f", repeated, varied)
+	}
+	if bits, _ := PassphraseStrength("aaaaaaaaaaaa"); bits != 0 {
+		t.Errorf("a single repeated character estimated at %.1f bits", bits)
+	}
+}
+
+func TestPassphraseWeaknesses(t *testing.T) {
+	rules := func(p string) map[string]bool {
+		got := make(map[string]bool)
+		var perr *PolicyError
+		if errors.As(DefaultPolicy{MinEntropyBits: DefaultMinEntropyBits}.Validate(p), &perr) {
+			for _, v := range perr.Violations {
+				got[v.Rule] = true
+			}
+		}
+		return got
+	}
+	if got := rules("abababababab"); !got["repetitive"] || !got["min_entropy"] || got["sequence"] {
+		t.Errorf("abababababab: got %v", got)
+	}
+	if got := rules("123456789012"); !got["sequence"] || got["repetitive"] {
+		t.Errorf("123456789012: got %v", got)
+	}
+}
+
+func TestPolicyMinEntropyBits(t *testing.T) {
+	if _, ok := (DefaultPolicy{}).Strength("hello world!"); !ok {
+		t.Error("hello world! rejected without an entropy minimum")
+	}
+	if _, ok := (DefaultPolicy{}).Strength("aaaaaaaaaaaa"); ok {
+		t.Error("a repeated character passed without an entropy minimum")
+	}
+	if _, ok := (DefaultPolicy{MinEntropyBits: 200}).Strength("correct horse battery"); ok {
+		t.Error("correct horse battery passed a 200 bit minimum")
+	}
+}
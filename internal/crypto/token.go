@@ -0,0 +1,26 @@
+package crypto
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+)
+
+// GenerateOwnerToken returns a random token that lets the creator manage a
+// secret. It is unrelated to the passphrase, so it can never decrypt.
+func GenerateOwnerToken() string {
+	return GeneratePassphrase()
+}
+
+// HashOwnerToken is what gets stored; the token itself is only ever handed
+// to the creator.
+func HashOwnerToken(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
+
+func VerifyOwnerToken(token string, hash []byte) bool {
+	if len(hash) == 0 {
+		return false
+	}
+	return subtle.ConstantTimeCompare(HashOwnerToken(token), hash) == 1
+}
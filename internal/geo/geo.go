@@ -0,0 +1,9 @@
+package geo
+
+import "net/netip"
+
+// Resolver maps a client address to the ISO 3166-1 alpha-2 code of its
+// country, or "" when it doesn't know.
+type Resolver interface {
+	Country(ip netip.Addr) (string, error)
+}
@@ -0,0 +1,345 @@
+package geo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net/netip"
+	"os"
+)
+
+var _ Resolver = (*MaxMindDB)(nil)
+
+// metadataMarker precedes the metadata map at the end of every MaxMind DB
+// file, within its last 128KiB.
+var metadataMarker = []byte("\xab\xcd\xefMaxMind.com")
+
+const maxMetadataSize = 128 * 1024
+
+var errInvalidDB = errors.New("invalid maxmind database")
+
+// MaxMindDB reads country codes from a MaxMind DB file (GeoLite2-Country,
+// GeoIP2-City and the like). The whole file is held in memory; it is only
+// read after Open, so lookups are safe for concurrent use.
+type MaxMindDB struct {
+	buf        []byte
+	nodeCount  uint
+	recordSize uint
+	ipVersion  uint
+	// data and dataEnd bound the data section in buf.
+	data, dataEnd uint
+}
+
+func Open(path string) (*MaxMindDB, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parse(buf)
+}
+
+func parse(buf []byte) (*MaxMindDB, error) {
+	tail := buf[max(0, len(buf)-maxMetadataSize):]
+	i := bytes.LastIndex(tail, metadataMarker)
+	if i < 0 {
+		return nil, fmt.Errorf("%w: no metadata", errInvalidDB)
+	}
+	metaStart := len(buf) - len(tail) + i + len(metadataMarker)
+
+	d := decoder{buf: buf[metaStart:]}
+	v, _, err := d.decode(0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("%w: metadata: %v", errInvalidDB, err)
+	}
+	meta, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: metadata is not a map", errInvalidDB)
+	}
+
+	db := &MaxMindDB{buf: buf}
+	for key, dst := range map[string]*uint{
+		"node_count":  &db.nodeCount,
+		"record_size": &db.recordSize,
+		"ip_version":  &db.ipVersion,
+	} {
+		n, ok := meta[key].(uint64)
+		if !ok {
+			return nil, fmt.Errorf("%w: metadata has no %s", errInvalidDB, key)
+		}
+		*dst = uint(n)
+	}
+
+	switch db.recordSize {
+	case 24, 28, 32:
+	default:
+		return nil, fmt.Errorf("%w: unsupported record size %d", errInvalidDB, db.recordSize)
+	}
+	if db.ipVersion != 4 && db.ipVersion != 6 {
+		return nil, fmt.Errorf("%w: unsupported ip version %d", errInvalidDB, db.ipVersion)
+	}
+
+	// The search tree is followed by 16 zero bytes, then the data section.
+	treeSize := db.nodeCount * db.recordSize / 4
+	db.data = treeSize + 16
+	db.dataEnd = uint(metaStart - len(metadataMarker))
+	if db.data > db.dataEnd {
+		return nil, fmt.Errorf("%w: search tree overruns the file", errInvalidDB)
+	}
+	return db, nil
+}
+
+// Country returns the ISO 3166-1 alpha-2 code of the country ip is located
+// in, falling back to the country its network is registered in. It returns
+// "" for addresses the database has no country for.
+func (db *MaxMindDB) Country(ip netip.Addr) (string, error) {
+	offset, ok, err := db.lookup(ip.Unmap())
+	if err != nil || !ok {
+		return "", err
+	}
+
+	d := decoder{buf: db.buf[db.data:db.dataEnd]}
+	v, _, err := d.decode(offset, 0)
+	if err != nil {
+		return "", fmt.Errorf("%w: record: %v", errInvalidDB, err)
+	}
+	record, _ := v.(map[string]any)
+	for _, key := range []string{"country", "registered_country"} {
+		if c, ok := record[key].(map[string]any); ok {
+			if code, ok := c["iso_code"].(string); ok && code != "" {
+				return code, nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// lookup walks the search tree bit by bit and returns the data section
+// offset of ip's record, if it has one.
+func (db *MaxMindDB) lookup(ip netip.Addr) (uint, bool, error) {
+	raw := ip.AsSlice()
+	node := uint(0)
+	if ip.Is4() && db.ipVersion == 6 {
+		// IPv4 addresses live under ::/96 of an IPv6 tree.
+		for i := 0; i < 96 && node < db.nodeCount; i++ {
+			node = db.record(node, 0)
+		}
+	} else if ip.Is6() && db.ipVersion == 4 {
+		return 0, false, nil
+	}
+
+	for i := 0; i < len(raw)*8 && node < db.nodeCount; i++ {
+		bit := uint(raw[i/8]>>(7-i%8)) & 1
+		node = db.record(node, bit)
+	}
+
+	switch {
+	case node == db.nodeCount:
+		return 0, false, nil
+	case node > db.nodeCount:
+		offset := node - db.nodeCount - 16
+		if db.data+offset >= db.dataEnd {
+			return 0, false, fmt.Errorf("%w: record pointer out of range", errInvalidDB)
+		}
+		return offset, true, nil
+	default:
+		return 0, false, fmt.Errorf("%w: search tree is too shallow", errInvalidDB)
+	}
+}
+
+// record returns node's left (bit 0) or right (bit 1) record.
+func (db *MaxMindDB) record(node, bit uint) uint {
+	b := db.buf
+	switch db.recordSize {
+	case 24:
+		off := node*6 + bit*3
+		return uint(b[off])<<16 | uint(b[off+1])<<8 | uint(b[off+2])
+	case 28:
+		off := node * 7
+		// The middle byte holds the high nibble of each record.
+		if bit == 0 {
+			return uint(b[off+3]&0xf0)<<20 | uint(b[off])<<16 | uint(b[off+1])<<8 | uint(b[off+2])
+		}
+		return uint(b[off+3]&0x0f)<<24 | uint(b[off+4])<<16 | uint(b[off+5])<<8 | uint(b[off+6])
+	default:
+		off := node*8 + bit*4
+		return uint(binary.BigEndian.Uint32(b[off:]))
+	}
+}
+
+// MaxMind DB data section types.
+const (
+	typeExtended = iota
+	typePointer
+	typeString
+	typeDouble
+	typeBytes
+	typeUint16
+	typeUint32
+	typeMap
+	typeInt32
+	typeUint64
+	typeUint128
+	typeArray
+	typeContainer
+	typeEndMarker
+	typeBool
+	typeFloat
+)
+
+// maxDecodeDepth bounds nesting, so a corrupt file can't recurse forever
+// through pointers.
+const maxDecodeDepth = 32
+
+// decoder reads values from a data section (or the metadata, which uses
+// the same encoding). Unsigned integers decode as uint64 whatever their
+// width.
+type decoder struct {
+	buf []byte
+}
+
+func (d decoder) decode(offset uint, depth int) (any, uint, error) {
+	if depth > maxDecodeDepth {
+		return nil, 0, errors.New("values nested too deeply")
+	}
+	typ, size, offset, err := d.control(offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if typ == typePointer {
+		v, _, err := d.decode(size, depth+1)
+		return v, offset, err
+	}
+
+	switch typ {
+	case typeMap:
+		m := make(map[string]any, min(size, 64))
+		for range size {
+			var k, v any
+			if k, offset, err = d.decode(offset, depth+1); err != nil {
+				return nil, 0, err
+			}
+			key, ok := k.(string)
+			if !ok {
+				return nil, 0, errors.New("map key is not a string")
+			}
+			if v, offset, err = d.decode(offset, depth+1); err != nil {
+				return nil, 0, err
+			}
+			m[key] = v
+		}
+		return m, offset, nil
+	case typeArray:
+		a := make([]any, 0, min(size, 64))
+		for range size {
+			var v any
+			if v, offset, err = d.decode(offset, depth+1); err != nil {
+				return nil, 0, err
+			}
+			a = append(a, v)
+		}
+		return a, offset, nil
+	case typeBool:
+		return size != 0, offset, nil
+	}
+
+	end := offset + size
+	if end > uint(len(d.buf)) {
+		return nil, 0, errors.New("value overruns the buffer")
+	}
+	data := d.buf[offset:end]
+
+	switch typ {
+	case typeString:
+		return string(data), end, nil
+	case typeBytes, typeUint128:
+		return data, end, nil
+	case typeDouble:
+		if size != 8 {
+			return nil, 0, errors.New("double is not 8 bytes")
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(data)), end, nil
+	case typeFloat:
+		if size != 4 {
+			return nil, 0, errors.New("float is not 4 bytes")
+		}
+		return math.Float32frombits(binary.BigEndian.Uint32(data)), end, nil
+	case typeUint16, typeUint32, typeUint64, typeInt32:
+		if size > 8 {
+			return nil, 0, errors.New("integer is too wide")
+		}
+		var n uint64
+		for _, b := range data {
+			n = n<<8 | uint64(b)
+		}
+		if typ == typeInt32 {
+			return int32(n), end, nil
+		}
+		return n, end, nil
+	default:
+		return nil, 0, fmt.Errorf("unsupported data type %d", typ)
+	}
+}
+
+// control parses the control byte(s) at offset. For pointers size is the
+// pointed-to offset; for everything else it is the payload size, or the
+// element count of maps and arrays.
+func (d decoder) control(offset uint) (typ, size, next uint, err error) {
+	read := func(n uint) ([]byte, error) {
+		if offset+n > uint(len(d.buf)) {
+			return nil, errors.New("control bytes overrun the buffer")
+		}
+		b := d.buf[offset : offset+n]
+		offset += n
+		return b, nil
+	}
+
+	b, err := read(1)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	ctrl := uint(b[0])
+	typ = ctrl >> 5
+
+	if typ == typePointer {
+		n := (ctrl>>3)&0x3 + 1
+		b, err := read(n)
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		var p uint
+		if n < 4 {
+			p = ctrl & 0x7
+		}
+		for _, c := range b {
+			p = p<<8 | uint(c)
+		}
+		p += [...]uint{0, 2048, 526336, 0}[n-1]
+		return typ, p, offset, nil
+	}
+
+	if typ == typeExtended {
+		b, err := read(1)
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		typ = 7 + uint(b[0])
+	}
+
+	size = ctrl & 0x1f
+	if size >= 29 {
+		n := size - 28
+		b, err := read(n)
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		size = 0
+		for _, c := range b {
+			size = size<<8 | uint(c)
+		}
+		size += [...]uint{29, 285, 65821}[n-1]
+	}
+	return typ, size, offset, nil
+}
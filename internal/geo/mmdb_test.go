@@ -0,0 +1,171 @@
+package geo
+
+import (
+	"bytes"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Minimal MaxMind DB encoders, enough to build test databases.
+
+func mmdbString(s string) []byte {
+	return append([]byte{byte(typeString<<5 | len(s))}, s...)
+}
+
+func mmdbUint(typ int, n uint64) []byte {
+	var b []byte
+	for ; n > 0; n >>= 8 {
+		b = append([]byte{byte(n)}, b...)
+	}
+	return append([]byte{byte(typ<<5 | len(b))}, b...)
+}
+
+func mmdbMap(pairs ...[]byte) []byte {
+	out := []byte{byte(typeMap<<5 | len(pairs)/2)}
+	for _, p := range pairs {
+		out = append(out, p...)
+	}
+	return out
+}
+
+func mmdbPointer(offset int) []byte {
+	return []byte{byte(typePointer<<5 | offset>>8), byte(offset)}
+}
+
+type trieNode struct {
+	children [2]*trieNode
+	data     [2]int // data section offset+1 of a leaf record, 0 for none
+}
+
+// buildMMDB writes a database with 24-bit records holding the given
+// networks, whose data section records are at the given offsets.
+func buildMMDB(t *testing.T, ipVersion int, networks map[netip.Prefix]int, data []byte) []byte {
+	t.Helper()
+	root := &trieNode{}
+	nodes := []*trieNode{root}
+	for prefix, offset := range networks {
+		raw := prefix.Addr().AsSlice()
+		if ipVersion == 6 && prefix.Addr().Is4() {
+			raw = append(make([]byte, 12), raw...)
+		}
+		bits := prefix.Bits()
+		if ipVersion == 6 && prefix.Addr().Is4() {
+			bits += 96
+		}
+		n := root
+		for i := 0; i < bits; i++ {
+			bit := raw[i/8] >> (7 - i%8) & 1
+			if i == bits-1 {
+				n.data[bit] = offset + 1
+				break
+			}
+			if n.children[bit] == nil {
+				n.children[bit] = &trieNode{}
+				nodes = append(nodes, n.children[bit])
+			}
+			n = n.children[bit]
+		}
+	}
+
+	index := make(map[*trieNode]int, len(nodes))
+	for i, n := range nodes {
+		index[n] = i
+	}
+	var tree []byte
+	for _, n := range nodes {
+		for bit := range 2 {
+			record := len(nodes)
+			if c := n.children[bit]; c != nil {
+				record = index[c]
+			} else if n.data[bit] > 0 {
+				record = len(nodes) + 16 + n.data[bit] - 1
+			}
+			tree = append(tree, byte(record>>16), byte(record>>8), byte(record))
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.Write(tree)
+	buf.Write(make([]byte, 16))
+	buf.Write(data)
+	buf.Write(metadataMarker)
+	buf.Write(mmdbMap(
+		mmdbString("node_count"), mmdbUint(typeUint32, uint64(len(nodes))),
+		mmdbString("record_size"), mmdbUint(typeUint16, 24),
+		mmdbString("ip_version"), mmdbUint(typeUint16, uint64(ipVersion)),
+		mmdbString("binary_format_major_version"), mmdbUint(typeUint16, 2),
+	))
+	return buf.Bytes()
+}
+
+func TestMaxMindDBCountry(t *testing.T) {
+	var data []byte
+	us := len(data)
+	data = append(data, mmdbMap(mmdbString("country"), mmdbMap(mmdbString("iso_code"), mmdbString("US")))...)
+	// Real databases share repeated keys through pointers.
+	de := len(data)
+	data = append(data, mmdbMap(mmdbPointer(us+1), mmdbMap(mmdbString("iso_code"), mmdbString("DE")))...)
+	registered := len(data)
+	data = append(data, mmdbMap(mmdbString("registered_country"), mmdbMap(mmdbString("iso_code"), mmdbString("FR")))...)
+
+	for _, ipVersion := range []int{4, 6} {
+		networks := map[netip.Prefix]int{
+			netip.MustParsePrefix("198.51.100.0/24"): us,
+			netip.MustParsePrefix("203.0.113.0/24"):  de,
+			netip.MustParsePrefix("192.0.2.128/25"):  registered,
+		}
+		if ipVersion == 6 {
+			networks[netip.MustParsePrefix("2001:db8::/32")] = de
+		}
+
+		path := filepath.Join(t.TempDir(), "test.mmdb")
+		if err := os.WriteFile(path, buildMMDB(t, ipVersion, networks, data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		db, err := Open(path)
+		if err != nil {
+			t.Fatalf("ipv%d: open: %v", ipVersion, err)
+		}
+
+		cases := map[string]string{
+			"198.51.100.7":   "US",
+			"203.0.113.200":  "DE",
+			"192.0.2.200":    "FR",
+			"192.0.2.1":      "",
+			"10.0.0.1":       "",
+			"::ffff:1.2.3.4": "",
+		}
+		if ipVersion == 6 {
+			cases["2001:db8::1"] = "DE"
+			cases["::ffff:198.51.100.7"] = "US"
+		} else {
+			cases["2001:db8::1"] = ""
+		}
+		for ip, want := range cases {
+			got, err := db.Country(netip.MustParseAddr(ip))
+			if err != nil || got != want {
+				t.Fatalf("ipv%d: Country(%s) = %q, %v, want %q", ipVersion, ip, got, err, want)
+			}
+		}
+	}
+}
+
+func TestOpenInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bad.mmdb")
+	meta := append([]byte{}, metadataMarker...)
+	meta = append(meta, mmdbMap(mmdbString("node_count"), mmdbUint(typeUint32, 1000))...)
+	for _, content := range [][]byte{[]byte("not a database"), meta} {
+		if err := os.WriteFile(path, content, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Open(path); err == nil {
+			t.Fatalf("Open(%q) succeeded", content)
+		}
+	}
+
+	if _, err := Open(filepath.Join(t.TempDir(), "missing.mmdb")); err == nil {
+		t.Fatal("Open of a missing file succeeded")
+	}
+}
@@ -0,0 +1,45 @@
+package hooks
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// CodeSender delivers a one-time reveal code to the contact the sender of
+// a secret named, e.g. by email or SMS.
+type CodeSender interface {
+	SendCode(ctx context.Context, contact, code string) error
+}
+
+// ExecCodeSender runs a command with the contact and code in
+// REVEAL_CODE_CONTACT and REVEAL_CODE. Unlike ExecHook it runs in the
+// request, so the recipient learns whether the code went out.
+type ExecCodeSender struct {
+	command string
+	timeout time.Duration
+}
+
+func NewExecCodeSender(command string, timeout time.Duration) *ExecCodeSender {
+	return &ExecCodeSender{command: command, timeout: timeout}
+}
+
+func (s *ExecCodeSender) SendCode(ctx context.Context, contact, code string) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, s.command)
+	cmd.WaitDelay = time.Second
+	cmd.Env = []string{
+		"PATH=" + os.Getenv("PATH"),
+		"REVEAL_CODE_CONTACT=" + contact,
+		"REVEAL_CODE=" + code,
+	}
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("code command failed: %w: %s", err, out)
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package hooks
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExecCodeSender(t *testing.T) {
+	script, out := writeScript(t, `echo "$REVEAL_CODE_CONTACT $REVEAL_CODE" >> $OUT`)
+
+	sender := NewExecCodeSender(script, 5*time.Second)
+	if err := sender.SendCode(context.Background(), "bob@example.com", "123456"); err != nil {
+		t.Fatalf("send failed: %v", err)
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("code command did not run: %v", err)
+	}
+	if got := strings.TrimSpace(string(data)); got != "bob@example.com 123456" {
+		t.Fatalf("code command env mismatch: got %q", got)
+	}
+}
+
+func TestExecCodeSenderFailure(t *testing.T) {
+	script, _ := writeScript(t, `exit 1`)
+
+	if err := NewExecCodeSender(script, 5*time.Second).SendCode(context.Background(), "bob@example.com", "123456"); err == nil {
+		t.Fatal("failing code command reported success")
+	}
+}
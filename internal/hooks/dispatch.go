@@ -0,0 +1,95 @@
+package hooks
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Overflow policies for a Dispatcher whose workers and queue are all taken.
+const (
+	OverflowDrop  = "drop"
+	OverflowBlock = "block"
+)
+
+type QueueOptions struct {
+	Workers int
+	// Depth is how many events may wait for a free worker. With zero, an
+	// event is only accepted when a worker is idle.
+	Depth    int
+	Overflow string
+}
+
+type QueueStats struct {
+	Depth      int   `json:"depth"` // events waiting for a worker
+	Dropped    int64 `json:"dropped"`
+	Dispatched int64 `json:"dispatched"`
+}
+
+// Dispatcher hands events to a fixed pool of workers through a bounded
+// queue. When full it drops the event or makes the caller wait, per its
+// overflow policy.
+type Dispatcher struct {
+	handle   func(Event)
+	overflow string
+	// slots counts accepted events not yet handled, bounding queue so a
+	// send on it never blocks.
+	slots      chan struct{}
+	queue      chan Event
+	pending    sync.WaitGroup
+	dropped    atomic.Int64
+	dispatched atomic.Int64
+}
+
+func NewDispatcher(opts QueueOptions, handle func(Event)) *Dispatcher {
+	size := opts.Workers + opts.Depth
+	d := &Dispatcher{
+		handle:   handle,
+		overflow: opts.Overflow,
+		slots:    make(chan struct{}, size),
+		queue:    make(chan Event, size),
+	}
+	for range opts.Workers {
+		go d.work()
+	}
+	return d
+}
+
+// Dispatch queues e and reports whether it was accepted. Under
+// OverflowBlock it waits for room and always accepts.
+func (d *Dispatcher) Dispatch(e Event) bool {
+	if d.overflow == OverflowBlock {
+		d.slots <- struct{}{}
+	} else {
+		select {
+		case d.slots <- struct{}{}:
+		default:
+			d.dropped.Add(1)
+			return false
+		}
+	}
+	d.pending.Add(1)
+	d.queue <- e
+	return true
+}
+
+func (d *Dispatcher) work() {
+	for e := range d.queue {
+		d.handle(e)
+		d.dispatched.Add(1)
+		<-d.slots
+		d.pending.Done()
+	}
+}
+
+// Wait blocks until every accepted event has been handled.
+func (d *Dispatcher) Wait() {
+	d.pending.Wait()
+}
+
+func (d *Dispatcher) Stats() QueueStats {
+	return QueueStats{
+		Depth:      len(d.queue),
+		Dropped:    d.dropped.Load(),
+		Dispatched: d.dispatched.Load(),
+	}
+}
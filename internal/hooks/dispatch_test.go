@@ -0,0 +1,69 @@
+package hooks
+
+import (
+	"testing"
+	"time"
+)
+
+// blockingHandler holds every event until release is closed and signals
+// started as each one begins.
+func blockingHandler() (handle func(Event), started chan string, release chan struct{}) {
+	started = make(chan string, 16)
+	release = make(chan struct{})
+	return func(e Event) {
+		started <- e.SecretID
+		<-release
+	}, started, release
+}
+
+func TestDispatcherDropsWhenFull(t *testing.T) {
+	handle, started, release := blockingHandler()
+	d := NewDispatcher(QueueOptions{Workers: 1, Depth: 1, Overflow: OverflowDrop}, handle)
+
+	if !d.Dispatch(Event{SecretID: "a"}) {
+		t.Fatal("first event dropped")
+	}
+	<-started
+	if !d.Dispatch(Event{SecretID: "b"}) {
+		t.Fatal("queued event dropped")
+	}
+	if d.Dispatch(Event{SecretID: "c"}) {
+		t.Fatal("event accepted past the queue depth")
+	}
+
+	if stats := d.Stats(); stats.Depth != 1 || stats.Dropped != 1 || stats.Dispatched != 0 {
+		t.Fatalf("stats mismatch while full: %+v", stats)
+	}
+
+	close(release)
+	d.Wait()
+	if stats := d.Stats(); stats.Depth != 0 || stats.Dropped != 1 || stats.Dispatched != 2 {
+		t.Fatalf("stats mismatch after draining: %+v", stats)
+	}
+}
+
+func TestDispatcherBlocksWhenFull(t *testing.T) {
+	handle, started, release := blockingHandler()
+	d := NewDispatcher(QueueOptions{Workers: 1, Overflow: OverflowBlock}, handle)
+
+	d.Dispatch(Event{SecretID: "a"})
+	<-started
+
+	done := make(chan bool)
+	go func() { done <- d.Dispatch(Event{SecretID: "b"}) }()
+
+	select {
+	case <-done:
+		t.Fatal("dispatch to a full queue did not block")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	if !<-done {
+		t.Fatal("blocked event was dropped")
+	}
+	d.Wait()
+	if stats := d.Stats(); stats.Dropped != 0 || stats.Dispatched != 2 {
+		t.Fatalf("stats mismatch: %+v", stats)
+	}
+}
@@ -0,0 +1,98 @@
+package hooks
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"os"
+	"os/exec"
+	"time"
+)
+
+const (
+	EventCreate = "create"
+	EventReveal = "reveal"
+	EventExpire = "expire"
+	// EventDecoy is a reveal of a honeypot ID.
+	EventDecoy = "decoy"
+)
+
+// Event describes something that happened to a secret. It deliberately has
+// no content, passphrase or even the raw ID: hooks run arbitrary code.
+type Event struct {
+	Name     string
+	SecretID string
+	Time     time.Time
+}
+
+// ExecHook runs a command for each event with the details in environment
+// variables. Commands run in the background under a timeout, on a bounded
+// queue that drops or blocks when full.
+type ExecHook struct {
+	command    string
+	timeout    time.Duration
+	dispatcher *Dispatcher
+}
+
+// NewExecHook runs up to maxConcurrent commands at once and drops events
+// that arrive while all of them are busy.
+func NewExecHook(command string, timeout time.Duration, maxConcurrent int) *ExecHook {
+	return NewExecHookWithQueue(command, timeout, QueueOptions{Workers: maxConcurrent, Overflow: OverflowDrop})
+}
+
+func NewExecHookWithQueue(command string, timeout time.Duration, opts QueueOptions) *ExecHook {
+	h := &ExecHook{command: command, timeout: timeout}
+	h.dispatcher = NewDispatcher(opts, h.run)
+	return h
+}
+
+// Fire queues the command for e. It returns immediately unless the queue
+// is full and its overflow policy is OverflowBlock.
+func (h *ExecHook) Fire(e Event) {
+	if !h.dispatcher.Dispatch(e) {
+		slog.Warn("exec hook queue full, event dropped", "event", e.Name)
+	}
+}
+
+// Wait blocks until every queued command has finished.
+func (h *ExecHook) Wait() {
+	h.dispatcher.Wait()
+}
+
+func (h *ExecHook) Stats() QueueStats {
+	return h.dispatcher.Stats()
+}
+
+func (h *ExecHook) run(e Event) {
+	ctx, cancel := context.WithTimeout(context.Background(), h.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, h.command)
+	// Children of a killed script can hold the output pipe open; stop
+	// waiting for them shortly after the timeout.
+	cmd.WaitDelay = time.Second
+	// Only PATH is passed through so server secrets in the environment
+	// (admin token, store passwords) never reach the hook.
+	cmd.Env = []string{
+		"PATH=" + os.Getenv("PATH"),
+		"SECRET_EVENT=" + e.Name,
+		"SECRET_ID_HASH=" + hashID(e.SecretID),
+		"SECRET_EVENT_TIME=" + e.Time.UTC().Format(time.RFC3339),
+	}
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		slog.Warn("exec hook failed",
+			"event", e.Name,
+			"error", err,
+			"output", string(out),
+		)
+	}
+}
+
+// hashID lets hook scripts correlate events for the same secret without
+// learning an ID that could be used to look it up.
+func hashID(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return hex.EncodeToString(sum[:])
+}
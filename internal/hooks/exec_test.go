@@ -0,0 +1,78 @@
+package hooks
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeScript(t *testing.T, body string) (script, out string) {
+	t.Helper()
+	dir := t.TempDir()
+	out = filepath.Join(dir, "events.log")
+	script = filepath.Join(dir, "hook.sh")
+	content := "#!/bin/sh\n" + strings.ReplaceAll(body, "$OUT", out) + "\n"
+	if err := os.WriteFile(script, []byte(content), 0o755); err != nil {
+		t.Fatalf("failed to write hook script: %v", err)
+	}
+	return script, out
+}
+
+func TestExecHookRecordsEvent(t *testing.T) {
+	script, out := writeScript(t, `echo "$SECRET_EVENT $SECRET_ID_HASH $SECRET_EVENT_TIME $ADMIN_TOKEN" >> $OUT`)
+	t.Setenv("ADMIN_TOKEN", "must-not-leak")
+
+	hook := NewExecHook(script, 5*time.Second, 2)
+	hook.Fire(Event{Name: EventCreate, SecretID: "abc123", Time: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)})
+	hook.Wait()
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("hook did not run: %v", err)
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) != 3 {
+		t.Fatalf("unexpected hook output: %q", data)
+	}
+	if fields[0] != EventCreate || fields[1] != hashID("abc123") || fields[2] != "2025-01-02T03:04:05Z" {
+		t.Fatalf("hook env mismatch: got %q", data)
+	}
+	if strings.Contains(string(data), "abc123") {
+		t.Fatalf("raw secret id reached the hook: %q", data)
+	}
+}
+
+func TestExecHookConcurrencyCap(t *testing.T) {
+	script, out := writeScript(t, `sleep 0.2; echo run >> $OUT`)
+
+	hook := NewExecHook(script, 5*time.Second, 1)
+	hook.Fire(Event{Name: EventReveal, SecretID: "a", Time: time.Now()})
+	hook.Fire(Event{Name: EventReveal, SecretID: "b", Time: time.Now()})
+	hook.Wait()
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("hook did not run: %v", err)
+	}
+	if runs := strings.Count(string(data), "run"); runs != 1 {
+		t.Fatalf("expected 1 run with a cap of 1, got %d", runs)
+	}
+}
+
+func TestExecHookTimeout(t *testing.T) {
+	script, out := writeScript(t, `sleep 5; echo done >> $OUT`)
+
+	hook := NewExecHook(script, 50*time.Millisecond, 1)
+	start := time.Now()
+	hook.Fire(Event{Name: EventExpire, SecretID: "a", Time: time.Now()})
+	hook.Wait()
+
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("hook was not killed at its timeout: ran %s", elapsed)
+	}
+	if _, err := os.Stat(out); err == nil {
+		t.Fatalf("timed-out hook still completed")
+	}
+}
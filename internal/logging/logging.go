@@ -0,0 +1,69 @@
+// Package logging builds the server's slog logger and carries attributes
+// in request contexts, so every line logged for a request shares them.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+)
+
+// New returns a logger writing to w as "text" (key=value) or "json" lines,
+// dropping records below level ("debug", "info", "warn" or "error"). Records
+// logged with a context carry the attributes added to it by With.
+func New(w io.Writer, format, level string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("log level: %w", err)
+	}
+	opts := &slog.HandlerOptions{Level: lvl}
+
+	var h slog.Handler
+	switch format {
+	case "text", "":
+		h = slog.NewTextHandler(w, opts)
+	case "json":
+		h = slog.NewJSONHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("unknown log format %q", format)
+	}
+	return slog.New(contextHandler{h}), nil
+}
+
+type attrsKey struct{}
+
+// With returns a copy of ctx whose log records also carry attrs.
+func With(ctx context.Context, attrs ...slog.Attr) context.Context {
+	all := append(append([]slog.Attr(nil), attrsFrom(ctx)...), attrs...)
+	return context.WithValue(ctx, attrsKey{}, all)
+}
+
+func attrsFrom(ctx context.Context) []slog.Attr {
+	if ctx == nil {
+		return nil
+	}
+	attrs, _ := ctx.Value(attrsKey{}).([]slog.Attr)
+	return attrs
+}
+
+// contextHandler adds the attributes stored in a record's context.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if attrs := attrsFrom(ctx); len(attrs) > 0 {
+		r = r.Clone()
+		r.AddAttrs(attrs...)
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
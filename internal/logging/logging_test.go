@@ -0,0 +1,67 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestNewJSON(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, "json", "info")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := With(context.Background(), slog.String("request_id", "abc123"))
+	ctx = With(ctx, slog.String("secret", "s1"))
+	logger.InfoContext(ctx, "request completed", "status", 200)
+	logger.Info("no context")
+	logger.DebugContext(ctx, "below the level")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2:\n%s", len(lines), buf.String())
+	}
+	var line map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &line); err != nil {
+		t.Fatalf("not JSON: %s", lines[0])
+	}
+	if line["msg"] != "request completed" || line["request_id"] != "abc123" || line["secret"] != "s1" || line["status"] != float64(200) {
+		t.Errorf("unexpected line: %v", line)
+	}
+	if strings.Contains(lines[1], "request_id") {
+		t.Errorf("line without a context carries request_id: %s", lines[1])
+	}
+}
+
+func TestNewText(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, "text", "WARN")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := With(context.Background(), slog.String("request_id", "abc123"))
+	logger.With("component", "test").WarnContext(ctx, "careful")
+	logger.InfoContext(ctx, "dropped")
+
+	out := buf.String()
+	if !strings.Contains(out, "msg=careful") || !strings.Contains(out, "component=test") || !strings.Contains(out, "request_id=abc123") {
+		t.Errorf("unexpected output: %s", out)
+	}
+	if strings.Contains(out, "dropped") {
+		t.Errorf("info line logged at warn level: %s", out)
+	}
+}
+
+func TestNewRejectsUnknown(t *testing.T) {
+	if _, err := New(&bytes.Buffer{}, "xml", "info"); err == nil {
+		t.Error("accepted format xml")
+	}
+	if _, err := New(&bytes.Buffer{}, "json", "loud"); err == nil {
+		t.Error("accepted level loud")
+	}
+}
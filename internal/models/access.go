@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// AccessEntry records one reveal of a secret for its owner. IP is only the
+// network prefix the request came from, not the full address.
+type AccessEntry struct {
+	Time      time.Time `json:"time"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+}
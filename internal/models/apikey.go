@@ -0,0 +1,20 @@
+package models
+
+import (
+	"slices"
+	"time"
+)
+
+// APIKey authenticates a server-to-server client for the scopes it was
+// issued with. Only a hash of the key's secret part is stored.
+type APIKey struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Scopes    []string  `json:"scopes"`
+	Hash      []byte    `json:"hash"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (k *APIKey) HasScope(scope string) bool {
+	return slices.Contains(k.Scopes, scope)
+}
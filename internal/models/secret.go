@@ -5,9 +5,49 @@ import "time"
 type Secret struct {
 	ID            string    `json:"id"`
 	EncryptedData []byte    `json:"-"`         // PGP encrypted
+	EncryptedNote []byte    `json:"-"`         // Optional note to the recipient, same key as the data
 	MaxViews      int       `json:"max_views"` // e.g., 3
 	CurrentViews  int       `json:"current_views"`
 	ExpiresAt     time.Time `json:"expires_at"`
 	CreatedAt     time.Time `json:"created_at"`
-	Passphrase    string    `json:"-"` // For symmetric PGP (optional)
+	Passphrase    string    `json:"-"`                   // Only set by versions that stored it; see store.DropPassphrases
+	Threshold     int       `json:"threshold,omitempty"` // >0: key split into Shamir shares, Passphrase not stored
+	Label         string    `json:"label,omitempty"`     // Non-secret, operator-visible tag
+	Context       string    `json:"context,omitempty"`   // Non-secret label bound into the key and AEAD data
+	OwnerHash     []byte    `json:"-"`                   // SHA-256 of the creator's owner token, if issued
+	HasPIN        bool      `json:"has_pin,omitempty"`   // EncryptedData opens to a second layer keyed by a PIN
+	PINFailures   int       `json:"pin_failures,omitempty"`
+	RequireAck    bool      `json:"require_ack,omitempty"`   // Recipient must POST /ack before reveal
+	Archive       bool      `json:"archive,omitempty"`       // Content is a bundle of named files, revealed as a zip
+	RequireNonce  bool      `json:"require_nonce,omitempty"` // Reveal URLs carry a one-time nonce tied to the view count
+	HideViews     bool      `json:"hide_views,omitempty"`    // Responses don't say how many views are left
+	// EncryptedContact is where reveal codes go, same key as the data. When
+	// set, a reveal needs a code from /request-code.
+	EncryptedContact []byte `json:"-"`
+	// AllowedCountries or BlockedCountries restrict reveal by the GeoIP
+	// country of the requester.
+	AllowedCountries []string `json:"allowed_countries,omitempty"`
+	BlockedCountries []string `json:"blocked_countries,omitempty"`
+	// ContentType is the media type of the content; it picks how /render
+	// formats it.
+	ContentType string `json:"content_type,omitempty"`
+	// Filename is set for secrets uploaded as a file, which reveal as a
+	// download under this name.
+	Filename string `json:"filename,omitempty"`
+	// WrappedKey is the passphrase wrapped under the server's signed link
+	// key, for secrets created with signed_links.
+	WrappedKey []byte `json:"-"`
+	// ClientEncrypted secrets were encrypted by the creator's browser.
+	// EncryptedData is their ciphertext as sent and there is no passphrase
+	// on the server.
+	ClientEncrypted bool `json:"client_encrypted,omitempty"`
+	// AuthHash is the SHA-256 of the auth value the creator sent with a
+	// client-encrypted secret, if any; a reveal must present that value.
+	AuthHash []byte `json:"-"`
+	// PassphraseHash is the SHA-256 of a generated passphrase, so it can be
+	// checked by comparison without being able to open the secret.
+	PassphraseHash []byte `json:"-"`
+	// UserPassphrase secrets are keyed by a passphrase the creator chose.
+	// It isn't stored or put in the URL, and needn't look generated.
+	UserPassphrase bool `json:"user_passphrase,omitempty"`
 }
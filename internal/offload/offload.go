@@ -0,0 +1,17 @@
+// Package offload hands revealed content to object storage, so a large
+// download goes from the storage service to the recipient instead of
+// through this server.
+package offload
+
+import (
+	"context"
+	"time"
+)
+
+// Storage holds objects that recipients fetch through pre-signed URLs.
+type Storage interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	// PresignGet returns a URL anyone can GET key with until ttl passes.
+	PresignGet(ctx context.Context, key string, ttl time.Duration) (string, error)
+	Delete(ctx context.Context, key string) error
+}
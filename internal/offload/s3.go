@@ -0,0 +1,121 @@
+package offload
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+)
+
+var _ Storage = (*S3)(nil)
+
+type S3Options struct {
+	Bucket string
+	Region string
+	// Endpoint is an S3-compatible service such as MinIO, addressed with
+	// path-style URLs. Empty means AWS S3 itself.
+	Endpoint string
+}
+
+// S3 talks to S3, or anything speaking its API, with plain signed HTTP
+// requests; the three calls it needs don't warrant the full SDK client.
+type S3 struct {
+	opts   S3Options
+	creds  aws.CredentialsProvider
+	signer *v4.Signer
+	client *http.Client
+}
+
+func NewS3(opts S3Options) (*S3, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx, awsconfig.WithRegion(opts.Region))
+	if err != nil {
+		return nil, err
+	}
+	return &S3{
+		opts:  opts,
+		creds: awsCfg.Credentials,
+		// S3 signs the path as sent, not escaped a second time.
+		signer: v4.NewSigner(func(o *v4.SignerOptions) { o.DisableURIPathEscaping = true }),
+		client: &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+func (s *S3) objectURL(key string) string {
+	escaped := (&url.URL{Path: key}).EscapedPath()
+	if s.opts.Endpoint != "" {
+		return strings.TrimSuffix(s.opts.Endpoint, "/") + "/" + s.opts.Bucket + "/" + escaped
+	}
+	return "https://" + s.opts.Bucket + ".s3." + s.opts.Region + ".amazonaws.com/" + escaped
+}
+
+func (s *S3) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, s.objectURL(key), bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	sum := sha256.Sum256(data)
+	return s.do(req, hex.EncodeToString(sum[:]))
+}
+
+func (s *S3) Delete(ctx context.Context, key string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, s.objectURL(key), nil)
+	if err != nil {
+		return err
+	}
+	// An empty body's SHA-256.
+	return s.do(req, "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855")
+}
+
+func (s *S3) PresignGet(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	creds, err := s.creds.Retrieve(ctx)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.objectURL(key), nil)
+	if err != nil {
+		return "", err
+	}
+	q := req.URL.Query()
+	q.Set("X-Amz-Expires", strconv.Itoa(int(ttl/time.Second)))
+	req.URL.RawQuery = q.Encode()
+	signed, _, err := s.signer.PresignHTTP(ctx, creds, req, "UNSIGNED-PAYLOAD", "s3", s.opts.Region, time.Now())
+	return signed, err
+}
+
+func (s *S3) do(req *http.Request, payloadHash string) error {
+	creds, err := s.creds.Retrieve(req.Context())
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if err := s.signer.SignHTTP(req.Context(), creds, req, payloadHash, "s3", s.opts.Region, time.Now()); err != nil {
+		return err
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("s3 %s %s: %s: %s", req.Method, req.URL.Path, resp.Status, bytes.TrimSpace(body))
+	}
+	io.Copy(io.Discard, resp.Body)
+	return nil
+}
@@ -0,0 +1,28 @@
+// Package qr renders reveal links as PNG QR codes at error correction
+// level M, which is what they need: ASCII text of a few hundred bytes at
+// most, readable from a phone screen.
+package qr
+
+import (
+	"errors"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+var ErrTooLong = errors.New("data too long for a QR code")
+
+// PNG encodes data and renders it with its quiet zone as a black and white
+// PNG at most size pixels wide. Modules are whole pixels, so the image is
+// the largest multiple of the symbol's width that fits, and never less
+// than one pixel per module.
+func PNG(data string, size int) ([]byte, error) {
+	code, err := qrcode.New(data, qrcode.Medium)
+	if err != nil {
+		// Empty data aside, which links never are, encoding only fails
+		// when no version is large enough.
+		return nil, ErrTooLong
+	}
+	modules := len(code.Bitmap())
+	// A negative size asks for that many pixels per module.
+	return code.PNG(-max(size/modules, 1))
+}
@@ -0,0 +1,76 @@
+package qr
+
+import (
+	"bytes"
+	"errors"
+	"image/png"
+	"os"
+	"strings"
+	"testing"
+)
+
+// testdata/reveal_link.txt is the level M symbol for fixtureLink, quiet zone
+// included, with # for dark modules. It was checked against an independent
+// decoder that verifies every block's error correction.
+const fixtureLink = "https://share.example.com/s/AbCdEfGhIjKlMnOpQrStUv#correct-horse-battery-staple"
+
+func TestPNGMatchesFixture(t *testing.T) {
+	want, err := os.ReadFile("testdata/reveal_link.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := strings.Split(strings.TrimSpace(string(want)), "\n")
+
+	blob, err := PNG(fixtureLink, 300)
+	if err != nil {
+		t.Fatalf("png: %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(blob))
+	if err != nil {
+		t.Fatalf("invalid png: %v", err)
+	}
+	scale := img.Bounds().Dx() / len(rows)
+	if img.Bounds().Dx() != len(rows)*scale || scale < 1 {
+		t.Fatalf("image is %d pixels wide for %d modules", img.Bounds().Dx(), len(rows))
+	}
+
+	var got strings.Builder
+	for y := range rows {
+		for x := range rows {
+			r, _, _, _ := img.At(x*scale+scale/2, y*scale+scale/2).RGBA()
+			if r < 0x8000 {
+				got.WriteByte('#')
+			} else {
+				got.WriteByte('.')
+			}
+		}
+		got.WriteByte('\n')
+	}
+	if got.String() != strings.Join(rows, "\n")+"\n" {
+		t.Fatalf("symbol differs from the fixture:\n%s", got.String())
+	}
+}
+
+func TestPNGTooLong(t *testing.T) {
+	// Version 40 at level M holds 2331 bytes.
+	if _, err := PNG(strings.Repeat("\xff", 2332), 256); !errors.Is(err, ErrTooLong) {
+		t.Fatalf("got %v, want ErrTooLong", err)
+	}
+}
+
+func TestPNGSize(t *testing.T) {
+	// "hello" is version 1: 21 modules plus 8 of quiet zone.
+	for size, want := range map[int]int{300: 290, 29: 29, 10: 29} {
+		blob, err := PNG("hello", size)
+		if err != nil {
+			t.Fatalf("png: %v", err)
+		}
+		img, err := png.Decode(bytes.NewReader(blob))
+		if err != nil {
+			t.Fatalf("invalid png: %v", err)
+		}
+		if w := img.Bounds().Dx(); w != want {
+			t.Errorf("PNG(%d) is %d pixels wide, want %d", size, w, want)
+		}
+	}
+}
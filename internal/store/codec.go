@@ -0,0 +1,155 @@
+package store
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+
+	"secure.share/internal/models"
+)
+
+// Compression selects how the Redis and DynamoDB stores compress encoded
+// secrets before writing them. It only saves space on what gob adds around
+// the ciphertext (IDs, notes, file names, ...); ciphertext itself doesn't
+// compress.
+type Compression string
+
+const (
+	CompressionNone  Compression = "none"
+	CompressionFlate Compression = "flate"
+	CompressionGzip  Compression = "gzip"
+)
+
+// Header bytes of compressed blobs. A gob stream opens with the uvarint
+// length of its first message, whose first byte is below 0x80 or at least
+// 0xf8, so bytes in between can't be mistaken for uncompressed data. That
+// keeps blobs written before compression was enabled, or by an instance
+// with it disabled, readable.
+const (
+	blobFlate byte = 0x80
+	blobGzip  byte = 0x81
+)
+
+// DefaultMaxInflated bounds how large a compressed blob may inflate when
+// the store options don't say.
+const DefaultMaxInflated = 64 << 20
+
+// ErrBlobTooLarge is returned for a compressed blob that inflates past the
+// store's limit, e.g. a crafted one planted in a shared Redis.
+var ErrBlobTooLarge = errors.New("stored secret inflates past the size limit")
+
+// ParseCompression accepts the config names, with "" meaning none.
+func ParseCompression(name string) (Compression, error) {
+	switch c := Compression(name); c {
+	case "", CompressionNone:
+		return CompressionNone, nil
+	case CompressionFlate, CompressionGzip:
+		return c, nil
+	default:
+		return "", fmt.Errorf("unknown compression %q", name)
+	}
+}
+
+// blobCodec serializes secrets for the remote stores. Only writes depend on
+// its compression; decode reads every form, but stops inflating a blob at
+// maxInflated bytes.
+type blobCodec struct {
+	compression Compression
+	maxInflated int64
+}
+
+func newBlobCodec(compression Compression, maxInflated int64) blobCodec {
+	if maxInflated <= 0 {
+		maxInflated = DefaultMaxInflated
+	}
+	return blobCodec{compression: compression, maxInflated: maxInflated}
+}
+
+func (c blobCodec) encode(secret *models.Secret) ([]byte, error) {
+	var buf bytes.Buffer
+	var w io.Writer = &buf
+	var zw io.WriteCloser
+
+	switch c.compression {
+	case CompressionFlate:
+		buf.WriteByte(blobFlate)
+		zw, _ = flate.NewWriter(&buf, flate.DefaultCompression)
+	case CompressionGzip:
+		buf.WriteByte(blobGzip)
+		zw = gzip.NewWriter(&buf)
+	}
+	if zw != nil {
+		w = zw
+	}
+
+	if err := gob.NewEncoder(w).Encode(secret); err != nil {
+		return nil, err
+	}
+	if zw != nil {
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func (c blobCodec) decode(data []byte) (*models.Secret, error) {
+	var r io.Reader = bytes.NewReader(data)
+	var limited *inflateLimit
+	if len(data) > 0 {
+		var zr io.ReadCloser
+		switch data[0] {
+		case blobFlate:
+			zr = flate.NewReader(bytes.NewReader(data[1:]))
+		case blobGzip:
+			var err error
+			if zr, err = gzip.NewReader(bytes.NewReader(data[1:])); err != nil {
+				return nil, err
+			}
+		}
+		if zr != nil {
+			defer zr.Close()
+			limited = &inflateLimit{r: zr, remaining: c.maxInflated}
+			r = limited
+		}
+	}
+
+	var secret models.Secret
+	if err := gob.NewDecoder(r).Decode(&secret); err != nil {
+		if limited != nil && limited.exceeded {
+			return nil, ErrBlobTooLarge
+		}
+		return nil, err
+	}
+	return &secret, nil
+}
+
+// inflateLimit fails reads once more than remaining bytes have come out of
+// a decompressor, so a small blob can't balloon in memory.
+type inflateLimit struct {
+	r         io.Reader
+	remaining int64
+	exceeded  bool
+}
+
+func (l *inflateLimit) Read(p []byte) (int, error) {
+	if l.remaining <= 0 {
+		// Only an error if there is more to come.
+		var probe [1]byte
+		if n, _ := l.r.Read(probe[:]); n > 0 {
+			l.exceeded = true
+			return 0, ErrBlobTooLarge
+		}
+		return 0, io.EOF
+	}
+	if int64(len(p)) > l.remaining {
+		p = p[:l.remaining]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	return n, err
+}
@@ -0,0 +1,97 @@
+package store
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"secure.share/internal/models"
+)
+
+func TestBlobCodecRoundTrip(t *testing.T) {
+	secret := &models.Secret{
+		ID:            "codec",
+		EncryptedData: []byte(strings.Repeat("ciphertext", 50)),
+		EncryptedNote: []byte(strings.Repeat("note", 50)),
+		MaxViews:      3,
+		CurrentViews:  1,
+		ExpiresAt:     time.Now().Add(time.Hour).Round(0),
+		CreatedAt:     time.Now().Round(0),
+	}
+
+	// Blobs written under every setting must decode side by side, as they
+	// do in a store whose compression setting changed over time.
+	blobs := make(map[Compression][]byte)
+	for _, c := range []Compression{CompressionNone, CompressionFlate, CompressionGzip} {
+		data, err := newBlobCodec(c, 0).encode(secret)
+		if err != nil {
+			t.Fatalf("%s: encode: %v", c, err)
+		}
+		blobs[c] = data
+	}
+
+	if first := blobs[CompressionNone][0]; first >= blobFlate && first < 0xf8 {
+		t.Fatalf("uncompressed blob starts with header range byte %#x", first)
+	}
+	if blobs[CompressionFlate][0] != blobFlate || blobs[CompressionGzip][0] != blobGzip {
+		t.Fatalf("compressed blobs lack their header byte")
+	}
+	if len(blobs[CompressionGzip]) >= len(blobs[CompressionNone]) {
+		t.Fatalf("gzip blob is %d bytes, uncompressed %d", len(blobs[CompressionGzip]), len(blobs[CompressionNone]))
+	}
+
+	for c, data := range blobs {
+		got, err := newBlobCodec(CompressionNone, 0).decode(data)
+		if err != nil {
+			t.Fatalf("%s: decode: %v", c, err)
+		}
+		if got.ID != secret.ID || !bytes.Equal(got.EncryptedData, secret.EncryptedData) ||
+			!bytes.Equal(got.EncryptedNote, secret.EncryptedNote) || got.CurrentViews != 1 ||
+			!got.ExpiresAt.Equal(secret.ExpiresAt) {
+			t.Fatalf("%s: round trip mismatch: %+v", c, got)
+		}
+	}
+}
+
+func TestBlobCodecCorrupt(t *testing.T) {
+	for _, data := range [][]byte{{blobFlate, 1, 2, 3}, {blobGzip, 1, 2, 3}} {
+		if _, err := newBlobCodec(CompressionNone, 0).decode(data); err == nil {
+			t.Fatalf("decode(%v) succeeded", data)
+		}
+	}
+}
+
+func TestBlobCodecInflateLimit(t *testing.T) {
+	// A few KiB of ciphertext that inflates to 4MiB.
+	bomb := &models.Secret{ID: "bomb", EncryptedData: make([]byte, 4<<20)}
+	for _, c := range []Compression{CompressionFlate, CompressionGzip} {
+		data, err := newBlobCodec(c, 0).encode(bomb)
+		if err != nil {
+			t.Fatalf("%s: encode: %v", c, err)
+		}
+		if len(data) > 64<<10 {
+			t.Fatalf("%s: blob is %d bytes, not much of a bomb", c, len(data))
+		}
+
+		if _, err := newBlobCodec(CompressionNone, 1<<20).decode(data); !errors.Is(err, ErrBlobTooLarge) {
+			t.Fatalf("%s: decode past the limit: got %v, want ErrBlobTooLarge", c, err)
+		}
+		if _, err := newBlobCodec(CompressionNone, 8<<20).decode(data); err != nil {
+			t.Fatalf("%s: decode within the limit: %v", c, err)
+		}
+	}
+}
+
+func TestParseCompression(t *testing.T) {
+	if c, err := ParseCompression(""); err != nil || c != CompressionNone {
+		t.Fatalf("ParseCompression(\"\") = %q, %v", c, err)
+	}
+	if c, err := ParseCompression("gzip"); err != nil || c != CompressionGzip {
+		t.Fatalf("ParseCompression(gzip) = %q, %v", c, err)
+	}
+	if _, err := ParseCompression("zstd"); err == nil {
+		t.Fatal("ParseCompression(zstd) succeeded")
+	}
+}
@@ -0,0 +1,905 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"secure.share/internal/models"
+)
+
+var _ Store = (*DynamoStore)(nil)
+
+// Item attributes. The secret itself is stored gob-encoded in "data"; the
+// view counter and expiry are kept as separate numbers so condition
+// expressions can check them without decoding.
+const (
+	dynamoKeyAttr      = "id"
+	dynamoDataAttr     = "data"
+	dynamoViewsAttr    = "views"
+	dynamoMaxViewsAttr = "max_views"
+	// dynamoExpiresAttr must be configured as the table's TTL attribute.
+	// DynamoDB deletes expired items lazily (up to days later), so reads
+	// still check expiry themselves.
+	dynamoExpiresAttr = "expires_at"
+	dynamoCountAttr   = "count"
+	dynamoPINAttr     = "pin_failures"
+	dynamoTokensAttr  = "tokens"
+	dynamoUpdatedAttr = "updated_ms"
+	dynamoVersionAttr = "version"
+	// dynamoAPIKeyAttr holds an API key as JSON, on items keyed
+	// "apikey:<id>". They have no data attribute, so secret scans skip them.
+	dynamoAPIKeyAttr = "api_key"
+	// dynamoAccessAttr is a list of access entries as JSON on the secret's
+	// own item, so it is deleted and expired with the secret.
+	dynamoAccessAttr = "access_log"
+
+	// quotaKey is the item SaveWithinQuota versions to serialize saves.
+	quotaKey            = "stats:quota"
+	pingKey             = "health:ping"
+	dynamoQuotaAttempts = 10
+)
+
+type DynamoOptions struct {
+	Region string
+	Table  string
+	// Endpoint overrides the AWS endpoint, e.g. for DynamoDB Local.
+	Endpoint    string
+	Compression Compression
+	// MaxInflated bounds how large a compressed blob may inflate on read;
+	// zero means DefaultMaxInflated.
+	MaxInflated int64
+}
+
+type DynamoStore struct {
+	client *dynamodb.Client
+	table  string
+	codec  blobCodec
+}
+
+func NewDynamoStore(opts DynamoOptions) (*DynamoStore, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx, awsconfig.WithRegion(opts.Region))
+	if err != nil {
+		return nil, err
+	}
+
+	client := dynamodb.NewFromConfig(awsCfg, func(o *dynamodb.Options) {
+		if opts.Endpoint != "" {
+			o.BaseEndpoint = aws.String(opts.Endpoint)
+		}
+	})
+
+	// Verify the table exists and is reachable
+	if _, err := client.DescribeTable(ctx, &dynamodb.DescribeTableInput{
+		TableName: aws.String(opts.Table),
+	}); err != nil {
+		return nil, err
+	}
+
+	return &DynamoStore{client: client, table: opts.Table, codec: newBlobCodec(opts.Compression, opts.MaxInflated)}, nil
+}
+
+func (d *DynamoStore) Save(ctx context.Context, secret *models.Secret) error {
+	_, err := d.SaveReturningTTL(ctx, secret)
+	return err
+}
+
+// SaveReturningTTL writes the secret only if no item with the same ID
+// exists, so an ID collision can never overwrite another secret.
+func (d *DynamoStore) SaveReturningTTL(ctx context.Context, secret *models.Secret) (time.Duration, error) {
+	data, err := d.codec.encode(secret)
+	if err != nil {
+		return 0, err
+	}
+
+	ttl := time.Until(secret.ExpiresAt)
+	if ttl <= 0 {
+		return 0, ErrExpired
+	}
+
+	_, err = d.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:                aws.String(d.table),
+		Item:                     secretItem(secret, data),
+		ConditionExpression:      aws.String("attribute_not_exists(#id)"),
+		ExpressionAttributeNames: map[string]string{"#id": dynamoKeyAttr},
+	})
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return 0, ErrExists
+		}
+		return 0, err
+	}
+
+	return ttl, nil
+}
+
+// SaveWithinQuota counts live secrets with a scan, then writes the secret
+// in a transaction that also bumps a version on the quota item, conditioned
+// on the version it read before counting. Concurrent quota saves therefore
+// commit one at a time and a loser recounts; deletes and expiry only lower
+// the count, so they need no coordination. The scan makes this expensive on
+// large tables.
+func (d *DynamoStore) SaveWithinQuota(ctx context.Context, secret *models.Secret, max int) error {
+	data, err := d.codec.encode(secret)
+	if err != nil {
+		return err
+	}
+	if time.Until(secret.ExpiresAt) <= 0 {
+		return ErrExpired
+	}
+
+	for attempt := 0; attempt < dynamoQuotaAttempts; attempt++ {
+		version, err := d.quotaVersion(ctx)
+		if err != nil {
+			return err
+		}
+		live, err := d.countLive(ctx)
+		if err != nil {
+			return err
+		}
+		if live >= max {
+			return ErrQuotaExceeded
+		}
+
+		_, err = d.client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+			TransactItems: []types.TransactWriteItem{
+				{Update: &types.Update{
+					TableName:                aws.String(d.table),
+					Key:                      d.key(quotaKey),
+					UpdateExpression:         aws.String("SET #version = :next"),
+					ConditionExpression:      aws.String("attribute_not_exists(#version) OR #version = :read"),
+					ExpressionAttributeNames: map[string]string{"#version": dynamoVersionAttr},
+					ExpressionAttributeValues: map[string]types.AttributeValue{
+						":read": numberAttr(version),
+						":next": numberAttr(version + 1),
+					},
+				}},
+				{Put: &types.Put{
+					TableName:                aws.String(d.table),
+					Item:                     secretItem(secret, data),
+					ConditionExpression:      aws.String("attribute_not_exists(#id)"),
+					ExpressionAttributeNames: map[string]string{"#id": dynamoKeyAttr},
+				}},
+			},
+		})
+		if err == nil {
+			return nil
+		}
+
+		var tce *types.TransactionCanceledException
+		if !errors.As(err, &tce) {
+			return err
+		}
+		if len(tce.CancellationReasons) == 2 && aws.ToString(tce.CancellationReasons[1].Code) == "ConditionalCheckFailed" {
+			return ErrExists
+		}
+		// Another save won the race for this version; recount.
+	}
+	return errors.New("quota save kept conflicting with concurrent saves")
+}
+
+func (d *DynamoStore) quotaVersion(ctx context.Context) (int64, error) {
+	out, err := d.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(d.table),
+		Key:            d.key(quotaKey),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return 0, err
+	}
+	if out.Item == nil {
+		return 0, nil
+	}
+	return numberValue(out.Item[dynamoVersionAttr])
+}
+
+// countLive counts secrets that are neither expired nor used up.
+func (d *DynamoStore) countLive(ctx context.Context) (int, error) {
+	count := 0
+	paginator := dynamodb.NewScanPaginator(d.client, &dynamodb.ScanInput{
+		TableName:        aws.String(d.table),
+		FilterExpression: aws.String("attribute_exists(#data) AND #exp > :now AND #views < #max"),
+		ExpressionAttributeNames: map[string]string{
+			"#data":  dynamoDataAttr,
+			"#exp":   dynamoExpiresAttr,
+			"#views": dynamoViewsAttr,
+			"#max":   dynamoMaxViewsAttr,
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":now": numberAttr(time.Now().Unix()),
+		},
+		Select:         types.SelectCount,
+		ConsistentRead: aws.Bool(true),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return count, err
+		}
+		count += int(page.Count)
+	}
+	return count, nil
+}
+
+func (d *DynamoStore) Get(ctx context.Context, id string) (*models.Secret, error) {
+	secret, err := d.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if time.Now().After(secret.ExpiresAt) {
+		_ = d.Delete(ctx, id)
+		return nil, ErrExpired
+	}
+
+	// Check max views
+	if secret.CurrentViews >= secret.MaxViews {
+		_ = d.Delete(ctx, id)
+		return nil, ErrMaxViews
+	}
+
+	return secret, nil
+}
+
+// get fetches and decodes a secret with the live view count applied,
+// without any expiry or view checks.
+func (d *DynamoStore) get(ctx context.Context, id string) (*models.Secret, error) {
+	out, err := d.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(d.table),
+		Key:            d.key(id),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if out.Item == nil {
+		return nil, ErrNotFound
+	}
+	return d.decodeItem(out.Item)
+}
+
+func (d *DynamoStore) Delete(ctx context.Context, id string) error {
+	_, err := d.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(d.table),
+		Key:       d.key(id),
+	})
+	return err
+}
+
+func (d *DynamoStore) DeleteWhere(ctx context.Context, match func(*models.Secret) bool) (int, error) {
+	deleted := 0
+	paginator := dynamodb.NewScanPaginator(d.client, &dynamodb.ScanInput{
+		TableName:                aws.String(d.table),
+		FilterExpression:         aws.String("attribute_exists(#data)"),
+		ExpressionAttributeNames: map[string]string{"#data": dynamoDataAttr},
+		ConsistentRead:           aws.Bool(true),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return deleted, err
+		}
+		for _, item := range page.Items {
+			secret, err := d.decodeItem(item)
+			if err != nil {
+				return deleted, err
+			}
+			if !match(secret) {
+				continue
+			}
+			if err := d.Delete(ctx, secret.ID); err != nil {
+				return deleted, err
+			}
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// UpdateWhere only writes a secret whose blob is still the one it read, so a
+// concurrent extend isn't undone; such secrets are skipped.
+func (d *DynamoStore) UpdateWhere(ctx context.Context, update func(*models.Secret) bool) (int, error) {
+	updated := 0
+	paginator := dynamodb.NewScanPaginator(d.client, &dynamodb.ScanInput{
+		TableName:                aws.String(d.table),
+		FilterExpression:         aws.String("attribute_exists(#data)"),
+		ExpressionAttributeNames: map[string]string{"#data": dynamoDataAttr},
+		ConsistentRead:           aws.Bool(true),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return updated, err
+		}
+		for _, item := range page.Items {
+			secret, err := d.decodeItem(item)
+			if err != nil {
+				return updated, err
+			}
+			if !update(secret) {
+				continue
+			}
+			data, err := d.codec.encode(secret)
+			if err != nil {
+				return updated, err
+			}
+
+			_, err = d.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+				TableName:                aws.String(d.table),
+				Key:                      d.key(secret.ID),
+				UpdateExpression:         aws.String("SET #data = :data"),
+				ConditionExpression:      aws.String("#data = :old"),
+				ExpressionAttributeNames: map[string]string{"#data": dynamoDataAttr},
+				ExpressionAttributeValues: map[string]types.AttributeValue{
+					":data": &types.AttributeValueMemberB{Value: data},
+					":old":  item[dynamoDataAttr],
+				},
+			})
+			var ccf *types.ConditionalCheckFailedException
+			if errors.As(err, &ccf) {
+				continue
+			}
+			if err != nil {
+				return updated, err
+			}
+			updated++
+		}
+	}
+	return updated, nil
+}
+
+func (d *DynamoStore) ExpiringWithin(ctx context.Context, within time.Duration) (int, error) {
+	now := time.Now()
+	count := 0
+	paginator := dynamodb.NewScanPaginator(d.client, &dynamodb.ScanInput{
+		TableName:        aws.String(d.table),
+		FilterExpression: aws.String("attribute_exists(#data) AND #exp BETWEEN :now AND :until"),
+		ExpressionAttributeNames: map[string]string{
+			"#data": dynamoDataAttr,
+			"#exp":  dynamoExpiresAttr,
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":now":   numberAttr(now.Unix()),
+			":until": numberAttr(now.Add(within).Unix()),
+		},
+		Select: types.SelectCount,
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return count, err
+		}
+		count += int(page.Count)
+	}
+	return count, nil
+}
+
+// Usage scans like ExpiringWithin; items past their expiry linger until
+// DynamoDB's TTL sweep removes them, so they are filtered out.
+func (d *DynamoStore) Usage(ctx context.Context, since time.Time) (Usage, error) {
+	var usage Usage
+	paginator := dynamodb.NewScanPaginator(d.client, &dynamodb.ScanInput{
+		TableName:        aws.String(d.table),
+		FilterExpression: aws.String("attribute_exists(#data) AND #exp > :now"),
+		ExpressionAttributeNames: map[string]string{
+			"#data": dynamoDataAttr,
+			"#exp":  dynamoExpiresAttr,
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":now": numberAttr(time.Now().Unix()),
+		},
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return usage, err
+		}
+		for _, item := range page.Items {
+			secret, err := d.decodeItem(item)
+			if err != nil {
+				return usage, err
+			}
+			usage.add(secret, since)
+		}
+	}
+	return usage, nil
+}
+
+// Extend rewrites the item with the new expiry. The write is conditioned on
+// the view count being unchanged, so a concurrent reveal is never undone.
+func (d *DynamoStore) Extend(ctx context.Context, id string, expiresAt time.Time) error {
+	secret, err := d.get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if time.Now().After(secret.ExpiresAt) {
+		_ = d.Delete(ctx, id)
+		return ErrExpired
+	}
+
+	secret.ExpiresAt = expiresAt
+	data, err := d.codec.encode(secret)
+	if err != nil {
+		return err
+	}
+
+	_, err = d.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:           aws.String(d.table),
+		Key:                 d.key(id),
+		UpdateExpression:    aws.String("SET #data = :data, #exp = :exp"),
+		ConditionExpression: aws.String("attribute_exists(#id) AND #views = :views"),
+		ExpressionAttributeNames: map[string]string{
+			"#id":    dynamoKeyAttr,
+			"#data":  dynamoDataAttr,
+			"#exp":   dynamoExpiresAttr,
+			"#views": dynamoViewsAttr,
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":data":  &types.AttributeValueMemberB{Value: data},
+			":exp":   numberAttr(expiresAt.Unix()),
+			":views": numberAttr(int64(secret.CurrentViews)),
+		},
+	})
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return ErrNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// IncrementViews bumps the view counter in a single conditional UpdateItem,
+// so concurrent reveals can never push it past max_views.
+func (d *DynamoStore) IncrementViews(ctx context.Context, id string) (int, int, error) {
+	out, err := d.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:        aws.String(d.table),
+		Key:              d.key(id),
+		UpdateExpression: aws.String("SET #views = #views + :one"),
+		ConditionExpression: aws.String(
+			"attribute_exists(#id) AND #views < #max AND #exp > :now",
+		),
+		ExpressionAttributeNames: map[string]string{
+			"#id":    dynamoKeyAttr,
+			"#views": dynamoViewsAttr,
+			"#max":   dynamoMaxViewsAttr,
+			"#exp":   dynamoExpiresAttr,
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":one": numberAttr(1),
+			":now": numberAttr(time.Now().Unix()),
+		},
+		ReturnValues: types.ReturnValueAllNew,
+	})
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return 0, 0, d.incrementFailure(ctx, id)
+		}
+		return 0, 0, err
+	}
+
+	views, err := numberValue(out.Attributes[dynamoViewsAttr])
+	if err != nil {
+		return 0, 0, err
+	}
+	maxViews, err := numberValue(out.Attributes[dynamoMaxViewsAttr])
+	if err != nil {
+		return 0, 0, err
+	}
+	if views >= maxViews {
+		_ = d.Delete(ctx, id)
+	}
+
+	return int(views), int(maxViews), nil
+}
+
+func (d *DynamoStore) GetAndDelete(ctx context.Context, id string) (*models.Secret, error) {
+	out, err := d.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName:    aws.String(d.table),
+		Key:          d.key(id),
+		ReturnValues: types.ReturnValueAllOld,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if out.Attributes == nil {
+		return nil, ErrNotFound
+	}
+
+	secret, err := d.decodeItem(out.Attributes)
+	if err != nil {
+		return nil, err
+	}
+	if time.Now().After(secret.ExpiresAt) {
+		return nil, ErrExpired
+	}
+	if secret.CurrentViews >= secret.MaxViews {
+		return nil, ErrMaxViews
+	}
+	return secret, nil
+}
+
+// incrementFailure works out which condition of IncrementViews failed.
+func (d *DynamoStore) incrementFailure(ctx context.Context, id string) error {
+	secret, err := d.get(ctx, id)
+	if err != nil {
+		return err
+	}
+	_ = d.Delete(ctx, id)
+	if secret.CurrentViews >= secret.MaxViews {
+		return ErrMaxViews
+	}
+	return ErrExpired
+}
+
+func (d *DynamoStore) IncrementPINFailures(ctx context.Context, id string) (int, error) {
+	out, err := d.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:           aws.String(d.table),
+		Key:                 d.key(id),
+		UpdateExpression:    aws.String("ADD #pin :one"),
+		ConditionExpression: aws.String("attribute_exists(#id)"),
+		ExpressionAttributeNames: map[string]string{
+			"#id":  dynamoKeyAttr,
+			"#pin": dynamoPINAttr,
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{":one": numberAttr(1)},
+		ReturnValues:              types.ReturnValueUpdatedNew,
+	})
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return 0, ErrNotFound
+		}
+		return 0, err
+	}
+	n, err := numberValue(out.Attributes[dynamoPINAttr])
+	return int(n), err
+}
+
+// AppendAccess appends in one conditional UpdateItem, then trims the oldest
+// entries if the log grew past max. The trim is conditional on the size it
+// saw, so a racing append is left for the next one to trim.
+func (d *DynamoStore) AppendAccess(ctx context.Context, id string, entry models.AccessEntry, max int) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	out, err := d.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:           aws.String(d.table),
+		Key:                 d.key(id),
+		UpdateExpression:    aws.String("SET #log = list_append(if_not_exists(#log, :empty), :entry)"),
+		ConditionExpression: aws.String("attribute_exists(#data) AND #exp > :now"),
+		ExpressionAttributeNames: map[string]string{
+			"#log":  dynamoAccessAttr,
+			"#data": dynamoDataAttr,
+			"#exp":  dynamoExpiresAttr,
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":empty": &types.AttributeValueMemberL{Value: []types.AttributeValue{}},
+			":entry": &types.AttributeValueMemberL{Value: []types.AttributeValue{&types.AttributeValueMemberB{Value: data}}},
+			":now":   numberAttr(time.Now().Unix()),
+		},
+		ReturnValues: types.ReturnValueUpdatedNew,
+	})
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return ErrNotFound
+		}
+		return err
+	}
+
+	log, _ := out.Attributes[dynamoAccessAttr].(*types.AttributeValueMemberL)
+	if log == nil || len(log.Value) <= max {
+		return nil
+	}
+	remove := make([]string, len(log.Value)-max)
+	for i := range remove {
+		remove[i] = "#log[" + strconv.Itoa(i) + "]"
+	}
+	_, err = d.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:                 aws.String(d.table),
+		Key:                       d.key(id),
+		UpdateExpression:          aws.String("REMOVE " + strings.Join(remove, ", ")),
+		ConditionExpression:       aws.String("size(#log) = :size"),
+		ExpressionAttributeNames:  map[string]string{"#log": dynamoAccessAttr},
+		ExpressionAttributeValues: map[string]types.AttributeValue{":size": numberAttr(int64(len(log.Value)))},
+	})
+	var ccf *types.ConditionalCheckFailedException
+	if errors.As(err, &ccf) {
+		return nil
+	}
+	return err
+}
+
+func (d *DynamoStore) AccessLog(ctx context.Context, id string) ([]models.AccessEntry, error) {
+	out, err := d.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(d.table),
+		Key:            d.key(id),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := out.Item[dynamoDataAttr]; !ok {
+		return nil, ErrNotFound
+	}
+
+	entries := []models.AccessEntry{}
+	if log, ok := out.Item[dynamoAccessAttr].(*types.AttributeValueMemberL); ok {
+		for _, av := range log.Value {
+			data, ok := av.(*types.AttributeValueMemberB)
+			if !ok {
+				return nil, errors.New("dynamodb access entry is not binary")
+			}
+			var entry models.AccessEntry
+			if err := json.Unmarshal(data.Value, &entry); err != nil {
+				return nil, err
+			}
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// SaveTombstone writes a data-less item, so DeleteWhere's scan skips it and
+// the table's TTL eventually removes it.
+func (d *DynamoStore) SaveTombstone(ctx context.Context, id string, ttl time.Duration) error {
+	_, err := d.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(d.table),
+		Item: map[string]types.AttributeValue{
+			dynamoKeyAttr:     &types.AttributeValueMemberS{Value: tombstoneKey(id)},
+			dynamoExpiresAttr: numberAttr(time.Now().Add(ttl).Unix()),
+		},
+	})
+	return err
+}
+
+func (d *DynamoStore) HasTombstone(ctx context.Context, id string) (bool, error) {
+	out, err := d.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(d.table),
+		Key:       d.key(tombstoneKey(id)),
+	})
+	if err != nil {
+		return false, err
+	}
+	if out.Item == nil {
+		return false, nil
+	}
+	expires, err := numberValue(out.Item[dynamoExpiresAttr])
+	if err != nil {
+		return false, err
+	}
+	return time.Now().Unix() < expires, nil
+}
+
+// AllowReveal keeps the bucket in its own item and writes it back only if
+// nobody else updated it in between; losing that race counts as throttled,
+// since the winner just spent a token.
+func (d *DynamoStore) AllowReveal(ctx context.Context, id string, rate float64, burst int) (bool, time.Duration, error) {
+	out, err := d.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(d.table),
+		Key:            d.key(throttleKey(id)),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return false, 0, err
+	}
+
+	var b bucket
+	condition := "attribute_not_exists(#id)"
+	names := map[string]string{"#id": dynamoKeyAttr}
+	var values map[string]types.AttributeValue
+	if out.Item != nil {
+		tokens, ok := out.Item[dynamoTokensAttr].(*types.AttributeValueMemberN)
+		if !ok {
+			return false, 0, errors.New("dynamodb throttle item has no tokens")
+		}
+		if b.tokens, err = strconv.ParseFloat(tokens.Value, 64); err != nil {
+			return false, 0, err
+		}
+		updated, err := numberValue(out.Item[dynamoUpdatedAttr])
+		if err != nil {
+			return false, 0, err
+		}
+		b.updated = time.UnixMilli(updated)
+
+		condition = "#updated = :prev"
+		names = map[string]string{"#updated": dynamoUpdatedAttr}
+		values = map[string]types.AttributeValue{":prev": numberAttr(updated)}
+	}
+
+	b, allowed, wait := b.take(time.Now(), rate, burst)
+
+	item := d.key(throttleKey(id))
+	item[dynamoTokensAttr] = &types.AttributeValueMemberN{Value: strconv.FormatFloat(b.tokens, 'f', -1, 64)}
+	item[dynamoUpdatedAttr] = numberAttr(b.updated.UnixMilli())
+	item[dynamoExpiresAttr] = numberAttr(b.full.Unix() + 1)
+
+	input := &dynamodb.PutItemInput{
+		TableName:                 aws.String(d.table),
+		Item:                      item,
+		ConditionExpression:       aws.String(condition),
+		ExpressionAttributeNames:  names,
+		ExpressionAttributeValues: values,
+	}
+	if _, err := d.client.PutItem(ctx, input); err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return false, time.Duration(float64(time.Second) / rate), nil
+		}
+		return false, 0, err
+	}
+	return allowed, wait, nil
+}
+
+func (d *DynamoStore) IncrementRevealCount(ctx context.Context) (int64, error) {
+	out, err := d.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:                 aws.String(d.table),
+		Key:                       d.key(revealCountKey),
+		UpdateExpression:          aws.String("ADD #count :one"),
+		ExpressionAttributeNames:  map[string]string{"#count": dynamoCountAttr},
+		ExpressionAttributeValues: map[string]types.AttributeValue{":one": numberAttr(1)},
+		ReturnValues:              types.ReturnValueUpdatedNew,
+	})
+	if err != nil {
+		return 0, err
+	}
+	return numberValue(out.Attributes[dynamoCountAttr])
+}
+
+func (d *DynamoStore) RevealCount(ctx context.Context) (int64, error) {
+	out, err := d.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(d.table),
+		Key:       d.key(revealCountKey),
+	})
+	if err != nil {
+		return 0, err
+	}
+	if out.Item == nil {
+		return 0, nil
+	}
+	return numberValue(out.Item[dynamoCountAttr])
+}
+
+// SweptExpired is unsupported: DynamoDB's own TTL sweep removes secrets.
+func (d *DynamoStore) SweptExpired(ctx context.Context) (int64, error) {
+	return 0, errors.ErrUnsupported
+}
+
+func (d *DynamoStore) SaveAPIKey(ctx context.Context, key *models.APIKey) error {
+	data, err := json.Marshal(key)
+	if err != nil {
+		return err
+	}
+	_, err = d.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(d.table),
+		Item: map[string]types.AttributeValue{
+			dynamoKeyAttr:    &types.AttributeValueMemberS{Value: apiKeyKey(key.ID)},
+			dynamoAPIKeyAttr: &types.AttributeValueMemberB{Value: data},
+		},
+	})
+	return err
+}
+
+func (d *DynamoStore) GetAPIKey(ctx context.Context, id string) (*models.APIKey, error) {
+	out, err := d.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(d.table),
+		Key:            d.key(apiKeyKey(id)),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+	data, ok := out.Item[dynamoAPIKeyAttr].(*types.AttributeValueMemberB)
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	var key models.APIKey
+	if err := json.Unmarshal(data.Value, &key); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (d *DynamoStore) DeleteAPIKey(ctx context.Context, id string) error {
+	out, err := d.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName:    aws.String(d.table),
+		Key:          d.key(apiKeyKey(id)),
+		ReturnValues: types.ReturnValueAllOld,
+	})
+	if err != nil {
+		return err
+	}
+	if len(out.Attributes) == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Ping reads a key that never exists, a data-plane call that needs no more
+// permissions than the store already uses.
+func (d *DynamoStore) Ping(ctx context.Context) error {
+	_, err := d.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(d.table),
+		Key:       d.key(pingKey),
+	})
+	return err
+}
+
+func (d *DynamoStore) Close() error {
+	return nil
+}
+
+// Helpers
+
+func secretItem(secret *models.Secret, data []byte) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		dynamoKeyAttr:      &types.AttributeValueMemberS{Value: secret.ID},
+		dynamoDataAttr:     &types.AttributeValueMemberB{Value: data},
+		dynamoViewsAttr:    numberAttr(int64(secret.CurrentViews)),
+		dynamoMaxViewsAttr: numberAttr(int64(secret.MaxViews)),
+		dynamoExpiresAttr:  numberAttr(secret.ExpiresAt.Unix()),
+	}
+}
+
+func (d *DynamoStore) key(id string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		dynamoKeyAttr: &types.AttributeValueMemberS{Value: id},
+	}
+}
+
+func (d *DynamoStore) decodeItem(item map[string]types.AttributeValue) (*models.Secret, error) {
+	data, ok := item[dynamoDataAttr].(*types.AttributeValueMemberB)
+	if !ok {
+		return nil, errors.New("dynamodb item has no secret data")
+	}
+	secret, err := d.codec.decode(data.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	// The encoded blob holds the counters from Save; the attributes are
+	// the ones the Increment methods keep current.
+	views, err := numberValue(item[dynamoViewsAttr])
+	if err != nil {
+		return nil, err
+	}
+	secret.CurrentViews = int(views)
+
+	if av, ok := item[dynamoPINAttr]; ok {
+		failures, err := numberValue(av)
+		if err != nil {
+			return nil, err
+		}
+		secret.PINFailures = int(failures)
+	}
+	return secret, nil
+}
+
+func numberAttr(n int64) types.AttributeValue {
+	return &types.AttributeValueMemberN{Value: strconv.FormatInt(n, 10)}
+}
+
+func numberValue(av types.AttributeValue) (int64, error) {
+	n, ok := av.(*types.AttributeValueMemberN)
+	if !ok {
+		return 0, errors.New("dynamodb attribute is not a number")
+	}
+	return strconv.ParseInt(n.Value, 10, 64)
+}
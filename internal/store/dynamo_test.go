@@ -0,0 +1,178 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"secure.share/internal/models"
+)
+
+// newDynamoLocalStore creates a fresh table on DynamoDB Local, e.g.
+//
+//	docker run -p 8000:8000 amazon/dynamodb-local
+//	DYNAMODB_LOCAL_ENDPOINT=http://localhost:8000 go test ./internal/store/
+func newDynamoLocalStore(t *testing.T) *DynamoStore {
+	t.Helper()
+	endpoint := os.Getenv("DYNAMODB_LOCAL_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("DYNAMODB_LOCAL_ENDPOINT not set")
+	}
+	// DynamoDB Local accepts any credentials.
+	t.Setenv("AWS_ACCESS_KEY_ID", "local")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "local")
+
+	ctx := context.Background()
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx, awsconfig.WithRegion("us-east-1"))
+	if err != nil {
+		t.Fatalf("failed to load aws config: %v", err)
+	}
+	client := dynamodb.NewFromConfig(awsCfg, func(o *dynamodb.Options) {
+		o.BaseEndpoint = aws.String(endpoint)
+	})
+
+	table := "secrets_" + t.Name()
+	_, err = client.CreateTable(ctx, &dynamodb.CreateTableInput{
+		TableName: aws.String(table),
+		AttributeDefinitions: []types.AttributeDefinition{
+			{AttributeName: aws.String(dynamoKeyAttr), AttributeType: types.ScalarAttributeTypeS},
+		},
+		KeySchema: []types.KeySchemaElement{
+			{AttributeName: aws.String(dynamoKeyAttr), KeyType: types.KeyTypeHash},
+		},
+		BillingMode: types.BillingModePayPerRequest,
+	})
+	if err != nil {
+		t.Fatalf("failed to create table: %v", err)
+	}
+	t.Cleanup(func() {
+		_, _ = client.DeleteTable(ctx, &dynamodb.DeleteTableInput{TableName: aws.String(table)})
+	})
+
+	store, err := NewDynamoStore(DynamoOptions{Region: "us-east-1", Table: table, Endpoint: endpoint})
+	if err != nil {
+		t.Fatalf("failed to create dynamodb store: %v", err)
+	}
+	return store
+}
+
+func TestDynamoStore(t *testing.T) {
+	store := newDynamoLocalStore(t)
+	ctx := context.Background()
+
+	secret := &models.Secret{
+		ID:            "123",
+		EncryptedData: []byte("test"),
+		MaxViews:      2,
+		ExpiresAt:     time.Now().Add(time.Hour),
+		CreatedAt:     time.Now(),
+		Passphrase:    "test",
+	}
+	if err := store.Save(ctx, secret); err != nil {
+		t.Fatalf("failed to save secret: %v", err)
+	}
+	if err := store.Save(ctx, secret); !errors.Is(err, ErrExists) {
+		t.Fatalf("expected ErrExists for duplicate save, got %v", err)
+	}
+
+	got, err := store.Get(ctx, secret.ID)
+	if err != nil {
+		t.Fatalf("failed to get secret: %v", err)
+	}
+	if string(got.EncryptedData) != "test" {
+		t.Fatalf("secret data mismatch: got %s, want %s", string(got.EncryptedData), "test")
+	}
+
+	for want := 1; want <= 2; want++ {
+		views, _, err := store.IncrementViews(ctx, secret.ID)
+		if err != nil {
+			t.Fatalf("failed to increment views: %v", err)
+		}
+		if views != want {
+			t.Fatalf("views mismatch: got %d, want %d", views, want)
+		}
+	}
+	if _, err := store.Get(ctx, secret.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound after last view, got %v", err)
+	}
+
+	expired := &models.Secret{
+		ID:        "expired",
+		MaxViews:  1,
+		ExpiresAt: time.Now().Add(time.Second),
+		CreatedAt: time.Now(),
+	}
+	if err := store.Save(ctx, expired); err != nil {
+		t.Fatalf("failed to save secret: %v", err)
+	}
+	time.Sleep(2 * time.Second)
+	if _, err := store.Get(ctx, expired.ID); !errors.Is(err, ErrExpired) {
+		t.Fatalf("expected ErrExpired, got %v", err)
+	}
+}
+
+func TestDynamoStoreConcurrentIncrement(t *testing.T) {
+	store := newDynamoLocalStore(t)
+	ctx := context.Background()
+
+	secret := &models.Secret{
+		ID:        "race",
+		MaxViews:  3,
+		ExpiresAt: time.Now().Add(time.Hour),
+		CreatedAt: time.Now(),
+	}
+	if err := store.Save(ctx, secret); err != nil {
+		t.Fatalf("failed to save secret: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded := 0
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, _, err := store.IncrementViews(ctx, secret.ID); err == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if succeeded != secret.MaxViews {
+		t.Fatalf("successful increments mismatch: got %d, want %d", succeeded, secret.MaxViews)
+	}
+}
+
+func TestDynamoStoreSaveWithinQuota(t *testing.T) {
+	checkQuotaRace(t, newDynamoLocalStore(t), "dynamo", 5)
+}
+
+func TestDynamoStoreGetAndDelete(t *testing.T) {
+	checkGetAndDelete(t, newDynamoLocalStore(t), "dynamo")
+}
+
+func TestDynamoStoreAPIKeys(t *testing.T) {
+	checkAPIKeys(t, newDynamoLocalStore(t), "dynamo")
+}
+
+func TestDynamoStoreDropPassphrases(t *testing.T) {
+	checkDropPassphrases(t, newDynamoLocalStore(t), "dynamo")
+}
+
+func TestDynamoStoreUsage(t *testing.T) {
+	checkUsage(t, newDynamoLocalStore(t), "dynamo")
+}
+
+func TestDynamoStoreAccessLog(t *testing.T) {
+	checkAccessLog(t, newDynamoLocalStore(t), "dynamo")
+}
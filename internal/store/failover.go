@@ -0,0 +1,75 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrUnavailable is returned when the backing store stays unreachable
+// through every retry, e.g. while a failover is still in progress.
+var ErrUnavailable = errors.New("store temporarily unavailable")
+
+// FailoverPolicy bounds how a store retries operations that failed because
+// its topology was changing.
+type FailoverPolicy struct {
+	Retries int
+	// Backoff is the wait before the first retry; it doubles each time.
+	Backoff time.Duration
+}
+
+// failoverReplies are Redis error replies sent instead of running a command
+// while a node is moving, loading or demoted.
+var failoverReplies = []string{"MOVED ", "ASK ", "LOADING ", "READONLY ", "TRYAGAIN ", "CLUSTERDOWN ", "MASTERDOWN "}
+
+// isFailoverError reports whether err means the command never ran because
+// the server was unreachable or mid-failover. Only such errors are retried:
+// a connection lost after sending could have applied a write already, and
+// replaying IncrementViews then would use an extra view.
+func isFailoverError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+	msg := err.Error()
+	for _, prefix := range failoverReplies {
+		if strings.HasPrefix(msg, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// retryFailover runs op, retrying failover errors with exponential backoff.
+// When retries run out it returns ErrUnavailable wrapping the last error.
+func retryFailover(ctx context.Context, policy FailoverPolicy, op func() error) error {
+	backoff := policy.Backoff
+	for attempt := 0; ; attempt++ {
+		err := op()
+		if !isFailoverError(err) {
+			return err
+		}
+		if attempt >= policy.Retries {
+			return fmt.Errorf("%w: %v", ErrUnavailable, err)
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%w: %v", ErrUnavailable, err)
+		}
+		backoff *= 2
+	}
+}
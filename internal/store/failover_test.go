@@ -0,0 +1,67 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// flakyOp fails with err for the first failures calls, then succeeds.
+func flakyOp(failures int, err error) (op func() error, calls *int) {
+	calls = new(int)
+	return func() error {
+		*calls++
+		if *calls <= failures {
+			return err
+		}
+		return nil
+	}, calls
+}
+
+func TestRetryFailoverRecovers(t *testing.T) {
+	policy := FailoverPolicy{Retries: 3, Backoff: time.Millisecond}
+	transient := []error{
+		errors.New("MOVED 3999 10.0.0.2:6379"),
+		errors.New("ASK 3999 10.0.0.2:6379"),
+		errors.New("READONLY You can't write against a read only replica."),
+		&net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED},
+		fmt.Errorf("redis: %w", syscall.ECONNREFUSED),
+	}
+	for _, err := range transient {
+		op, calls := flakyOp(2, err)
+		if got := retryFailover(context.Background(), policy, op); got != nil {
+			t.Fatalf("%v: expected eventual success, got %v", err, got)
+		}
+		if *calls != 3 {
+			t.Fatalf("%v: calls mismatch: got %d, want 3", err, *calls)
+		}
+	}
+}
+
+func TestRetryFailoverGivesUp(t *testing.T) {
+	policy := FailoverPolicy{Retries: 2, Backoff: time.Millisecond}
+	op, calls := flakyOp(10, errors.New("CLUSTERDOWN The cluster is down"))
+	if err := retryFailover(context.Background(), policy, op); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("expected ErrUnavailable, got %v", err)
+	}
+	if *calls != 3 {
+		t.Fatalf("calls mismatch: got %d, want 3", *calls)
+	}
+}
+
+func TestRetryFailoverPermanentError(t *testing.T) {
+	policy := FailoverPolicy{Retries: 3, Backoff: time.Millisecond}
+	for _, err := range []error{ErrNotFound, errors.New("WRONGTYPE Operation against a key holding the wrong kind of value")} {
+		op, calls := flakyOp(10, err)
+		if got := retryFailover(context.Background(), policy, op); !errors.Is(got, err) {
+			t.Fatalf("expected %v unchanged, got %v", err, got)
+		}
+		if *calls != 1 {
+			t.Fatalf("%v: permanent error retried %d times", err, *calls-1)
+		}
+	}
+}
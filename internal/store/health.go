@@ -0,0 +1,245 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"secure.share/internal/models"
+)
+
+// maxHealthSamples bounds how many operations a busy instance remembers
+// within the window; older ones are dropped first.
+const maxHealthSamples = 4096
+
+// HealthPolicy sets when a monitored store reports itself degraded: once it
+// has seen MinSamples operations within Window, and either their error rate
+// exceeds MaxErrorRate or their mean latency exceeds MaxLatency.
+type HealthPolicy struct {
+	Window       time.Duration
+	MaxErrorRate float64
+	// MaxLatency of zero ignores latency.
+	MaxLatency time.Duration
+	MinSamples int
+}
+
+// Health summarizes recent store operations. Score runs from 1 (no errors,
+// latency within bounds) down to 0.
+type Health struct {
+	Score     float64
+	ErrorRate float64
+	Latency   time.Duration // mean
+	Samples   int
+	Degraded  bool
+}
+
+type healthSample struct {
+	at      time.Time
+	latency time.Duration
+	failed  bool
+}
+
+// MonitoredStore wraps a Store and tracks the latency and error rate of its
+// operations over a rolling window, so the service can report degraded
+// before the store fails outright.
+type MonitoredStore struct {
+	Store
+	policy HealthPolicy
+
+	mu      sync.Mutex
+	samples []healthSample
+}
+
+var _ Store = (*MonitoredStore)(nil)
+
+func NewMonitoredStore(s Store, policy HealthPolicy) *MonitoredStore {
+	return &MonitoredStore{Store: s, policy: policy}
+}
+
+// healthFailure reports whether err says something about the store itself
+// rather than the secret asked for or the caller giving up.
+func healthFailure(err error) bool {
+	switch {
+	case err == nil,
+		errors.Is(err, ErrNotFound),
+		errors.Is(err, ErrExpired),
+		errors.Is(err, ErrMaxViews),
+		errors.Is(err, ErrExists),
+		errors.Is(err, ErrQuotaExceeded),
+		errors.Is(err, context.Canceled):
+		return false
+	}
+	return true
+}
+
+func (m *MonitoredStore) record(start time.Time, err error) {
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.prune(now)
+	if len(m.samples) >= maxHealthSamples {
+		m.samples = m.samples[1:]
+	}
+	m.samples = append(m.samples, healthSample{at: now, latency: now.Sub(start), failed: healthFailure(err)})
+}
+
+func (m *MonitoredStore) prune(now time.Time) {
+	cutoff := now.Add(-m.policy.Window)
+	i := 0
+	for i < len(m.samples) && m.samples[i].at.Before(cutoff) {
+		i++
+	}
+	m.samples = m.samples[i:]
+}
+
+// Health reports on the operations seen within the policy's window.
+func (m *MonitoredStore) Health() Health {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.prune(time.Now())
+
+	h := Health{Score: 1, Samples: len(m.samples)}
+	if h.Samples == 0 {
+		return h
+	}
+
+	var failed int
+	var total time.Duration
+	for _, s := range m.samples {
+		if s.failed {
+			failed++
+		}
+		total += s.latency
+	}
+	h.ErrorRate = float64(failed) / float64(h.Samples)
+	h.Latency = total / time.Duration(h.Samples)
+
+	h.Score = 1 - h.ErrorRate
+	if m.policy.MaxLatency > 0 && h.Latency > m.policy.MaxLatency {
+		h.Score *= float64(m.policy.MaxLatency) / float64(h.Latency)
+	}
+
+	slow := m.policy.MaxLatency > 0 && h.Latency > m.policy.MaxLatency
+	h.Degraded = h.Samples >= m.policy.MinSamples && (h.ErrorRate > m.policy.MaxErrorRate || slow)
+	return h
+}
+
+func observe[T any](m *MonitoredStore, op func() (T, error)) (T, error) {
+	start := time.Now()
+	v, err := op()
+	m.record(start, err)
+	return v, err
+}
+
+func (m *MonitoredStore) observeErr(op func() error) error {
+	start := time.Now()
+	err := op()
+	m.record(start, err)
+	return err
+}
+
+func (m *MonitoredStore) Save(ctx context.Context, secret *models.Secret) error {
+	return m.observeErr(func() error { return m.Store.Save(ctx, secret) })
+}
+
+func (m *MonitoredStore) SaveReturningTTL(ctx context.Context, secret *models.Secret) (time.Duration, error) {
+	return observe(m, func() (time.Duration, error) { return m.Store.SaveReturningTTL(ctx, secret) })
+}
+
+func (m *MonitoredStore) SaveWithinQuota(ctx context.Context, secret *models.Secret, max int) error {
+	return m.observeErr(func() error { return m.Store.SaveWithinQuota(ctx, secret, max) })
+}
+
+func (m *MonitoredStore) Get(ctx context.Context, id string) (*models.Secret, error) {
+	return observe(m, func() (*models.Secret, error) { return m.Store.Get(ctx, id) })
+}
+
+func (m *MonitoredStore) Delete(ctx context.Context, id string) error {
+	return m.observeErr(func() error { return m.Store.Delete(ctx, id) })
+}
+
+func (m *MonitoredStore) DeleteWhere(ctx context.Context, match func(*models.Secret) bool) (int, error) {
+	return observe(m, func() (int, error) { return m.Store.DeleteWhere(ctx, match) })
+}
+
+func (m *MonitoredStore) UpdateWhere(ctx context.Context, update func(*models.Secret) bool) (int, error) {
+	return observe(m, func() (int, error) { return m.Store.UpdateWhere(ctx, update) })
+}
+
+func (m *MonitoredStore) ExpiringWithin(ctx context.Context, d time.Duration) (int, error) {
+	return observe(m, func() (int, error) { return m.Store.ExpiringWithin(ctx, d) })
+}
+
+func (m *MonitoredStore) Usage(ctx context.Context, since time.Time) (Usage, error) {
+	return observe(m, func() (Usage, error) { return m.Store.Usage(ctx, since) })
+}
+
+func (m *MonitoredStore) Extend(ctx context.Context, id string, expiresAt time.Time) error {
+	return m.observeErr(func() error { return m.Store.Extend(ctx, id, expiresAt) })
+}
+
+func (m *MonitoredStore) IncrementViews(ctx context.Context, id string) (currentViews, maxViews int, err error) {
+	err = m.observeErr(func() error {
+		currentViews, maxViews, err = m.Store.IncrementViews(ctx, id)
+		return err
+	})
+	return currentViews, maxViews, err
+}
+
+func (m *MonitoredStore) GetAndDelete(ctx context.Context, id string) (*models.Secret, error) {
+	return observe(m, func() (*models.Secret, error) { return m.Store.GetAndDelete(ctx, id) })
+}
+
+func (m *MonitoredStore) IncrementPINFailures(ctx context.Context, id string) (int, error) {
+	return observe(m, func() (int, error) { return m.Store.IncrementPINFailures(ctx, id) })
+}
+
+func (m *MonitoredStore) AppendAccess(ctx context.Context, id string, entry models.AccessEntry, max int) error {
+	return m.observeErr(func() error { return m.Store.AppendAccess(ctx, id, entry, max) })
+}
+
+func (m *MonitoredStore) AccessLog(ctx context.Context, id string) ([]models.AccessEntry, error) {
+	return observe(m, func() ([]models.AccessEntry, error) { return m.Store.AccessLog(ctx, id) })
+}
+
+// Ping isn't observed: health probes arrive steadily and would swamp the
+// samples of real operations.
+func (m *MonitoredStore) Ping(ctx context.Context) error {
+	return m.Store.Ping(ctx)
+}
+
+func (m *MonitoredStore) SaveTombstone(ctx context.Context, id string, ttl time.Duration) error {
+	return m.observeErr(func() error { return m.Store.SaveTombstone(ctx, id, ttl) })
+}
+
+func (m *MonitoredStore) HasTombstone(ctx context.Context, id string) (bool, error) {
+	return observe(m, func() (bool, error) { return m.Store.HasTombstone(ctx, id) })
+}
+
+func (m *MonitoredStore) AllowReveal(ctx context.Context, id string, rate float64, burst int) (bool, time.Duration, error) {
+	start := time.Now()
+	ok, wait, err := m.Store.AllowReveal(ctx, id, rate, burst)
+	m.record(start, err)
+	return ok, wait, err
+}
+
+func (m *MonitoredStore) IncrementRevealCount(ctx context.Context) (int64, error) {
+	return observe(m, func() (int64, error) { return m.Store.IncrementRevealCount(ctx) })
+}
+
+func (m *MonitoredStore) RevealCount(ctx context.Context) (int64, error) {
+	return observe(m, func() (int64, error) { return m.Store.RevealCount(ctx) })
+}
+
+func (m *MonitoredStore) SaveAPIKey(ctx context.Context, key *models.APIKey) error {
+	return m.observeErr(func() error { return m.Store.SaveAPIKey(ctx, key) })
+}
+
+func (m *MonitoredStore) GetAPIKey(ctx context.Context, id string) (*models.APIKey, error) {
+	return observe(m, func() (*models.APIKey, error) { return m.Store.GetAPIKey(ctx, id) })
+}
+
+func (m *MonitoredStore) DeleteAPIKey(ctx context.Context, id string) error {
+	return m.observeErr(func() error { return m.Store.DeleteAPIKey(ctx, id) })
+}
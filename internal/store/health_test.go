@@ -0,0 +1,83 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"secure.share/internal/models"
+)
+
+// stubStore makes Get slow or failing on demand.
+type stubStore struct {
+	*MemoryStore
+	delay time.Duration
+	err   error
+}
+
+func (s *stubStore) Get(ctx context.Context, id string) (*models.Secret, error) {
+	time.Sleep(s.delay)
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.MemoryStore.Get(ctx, id)
+}
+
+func TestMonitoredStoreErrorRate(t *testing.T) {
+	stub := &stubStore{MemoryStore: NewMemoryStore(time.Minute)}
+	defer stub.Close()
+	m := NewMonitoredStore(stub, HealthPolicy{Window: time.Minute, MaxErrorRate: 0.5, MinSamples: 4})
+	ctx := context.Background()
+
+	// Lookups of missing secrets are not store failures.
+	for i := 0; i < 4; i++ {
+		m.Get(ctx, "missing")
+	}
+	if h := m.Health(); h.Degraded || h.ErrorRate != 0 || h.Score != 1 {
+		t.Fatalf("healthy store reported %+v", h)
+	}
+
+	stub.err = errors.New("connection reset by peer")
+	for i := 0; i < 4; i++ {
+		m.Get(ctx, "missing")
+	}
+	if h := m.Health(); h.Degraded || h.ErrorRate != 0.5 {
+		t.Fatalf("at the threshold: got %+v, want error rate 0.5 and not degraded", h)
+	}
+
+	m.Get(ctx, "missing")
+	if h := m.Health(); !h.Degraded || h.Score >= 0.5 {
+		t.Fatalf("over the threshold: got %+v, want degraded", h)
+	}
+}
+
+func TestMonitoredStoreLatency(t *testing.T) {
+	stub := &stubStore{MemoryStore: NewMemoryStore(time.Minute), delay: 10 * time.Millisecond}
+	defer stub.Close()
+	m := NewMonitoredStore(stub, HealthPolicy{Window: time.Minute, MaxErrorRate: 1, MaxLatency: time.Millisecond, MinSamples: 2})
+
+	m.Get(context.Background(), "missing")
+	if h := m.Health(); h.Degraded {
+		t.Fatalf("degraded before min samples: %+v", h)
+	}
+	m.Get(context.Background(), "missing")
+	if h := m.Health(); !h.Degraded || h.Score >= 1 {
+		t.Fatalf("slow store not degraded: %+v", h)
+	}
+}
+
+func TestMonitoredStoreWindow(t *testing.T) {
+	stub := &stubStore{MemoryStore: NewMemoryStore(time.Minute), err: errors.New("i/o timeout")}
+	defer stub.Close()
+	m := NewMonitoredStore(stub, HealthPolicy{Window: 20 * time.Millisecond, MinSamples: 1})
+
+	m.Get(context.Background(), "missing")
+	if !m.Health().Degraded {
+		t.Fatal("failing store not degraded")
+	}
+	time.Sleep(40 * time.Millisecond)
+	if h := m.Health(); h.Degraded || h.Samples != 0 {
+		t.Fatalf("old failures still counted: %+v", h)
+	}
+}
@@ -3,6 +3,7 @@ package store
 import (
 	"context"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"secure.share/internal/models"
@@ -11,16 +12,48 @@ import (
 // Compile-time interface check
 var _ Store = (*MemoryStore)(nil)
 
+// MemoryStore keeps everything in process. Its operations don't block on
+// I/O, but each still returns ctx's error without doing any work once ctx
+// is done, as a networked store would.
 type MemoryStore struct {
 	secrets       map[string]*models.Secret
+	tombstones    map[string]time.Time // id -> tombstone expiry
+	pending       map[string]pendingDelete
+	buckets       map[string]bucket // per-secret reveal throttle
+	apiKeys       map[string]*models.APIKey
+	accessLogs    map[string][]models.AccessEntry
+	gracePeriod   time.Duration
 	mu            sync.RWMutex
 	cleanupCancel context.CancelFunc
+	reveals       atomic.Int64
+	swept         atomic.Int64
+}
+
+// pendingDelete holds a secret whose last view was consumed. Until deadline
+// it stays readable, but only to the request that consumed it.
+type pendingDelete struct {
+	secret   *models.Secret
+	token    string
+	deadline time.Time
 }
 
 func NewMemoryStore(cleanupInterval time.Duration) *MemoryStore {
+	return NewMemoryStoreWithGrace(cleanupInterval, 0)
+}
+
+// NewMemoryStoreWithGrace keeps a secret for gracePeriod after its last view
+// so the request that consumed it (identified by WithRetryToken) can retry
+// if the response was lost. Zero deletes immediately.
+func NewMemoryStoreWithGrace(cleanupInterval, gracePeriod time.Duration) *MemoryStore {
 	ctx, cancel := context.WithCancel(context.Background())
 	store := &MemoryStore{
 		secrets:       make(map[string]*models.Secret),
+		tombstones:    make(map[string]time.Time),
+		pending:       make(map[string]pendingDelete),
+		buckets:       make(map[string]bucket),
+		apiKeys:       make(map[string]*models.APIKey),
+		accessLogs:    make(map[string][]models.AccessEntry),
+		gracePeriod:   gracePeriod,
 		cleanupCancel: cancel,
 	}
 	go store.cleanupLoop(ctx, cleanupInterval)
@@ -28,6 +61,9 @@ func NewMemoryStore(cleanupInterval time.Duration) *MemoryStore {
 }
 
 func (s *MemoryStore) Save(ctx context.Context, secret *models.Secret) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -35,12 +71,48 @@ func (s *MemoryStore) Save(ctx context.Context, secret *models.Secret) error {
 	return nil
 }
 
+func (s *MemoryStore) SaveReturningTTL(ctx context.Context, secret *models.Secret) (time.Duration, error) {
+	if err := s.Save(ctx, secret); err != nil {
+		return 0, err
+	}
+	// Memory has no native TTL; expiry is enforced against ExpiresAt.
+	return time.Until(secret.ExpiresAt), nil
+}
+
+func (s *MemoryStore) SaveWithinQuota(ctx context.Context, secret *models.Secret, max int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	live := 0
+	for _, existing := range s.secrets {
+		if now.Before(existing.ExpiresAt) {
+			live++
+		}
+	}
+	if live >= max {
+		return ErrQuotaExceeded
+	}
+
+	s.secrets[secret.ID] = secret
+	return nil
+}
+
 func (s *MemoryStore) Get(ctx context.Context, id string) (*models.Secret, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	secret, ok := s.secrets[id]
 	if !ok {
+		if secret, ok := s.retryable(ctx, id); ok {
+			return secret, nil
+		}
 		return nil, ErrNotFound
 	}
 
@@ -58,40 +130,348 @@ func (s *MemoryStore) Get(ctx context.Context, id string) (*models.Secret, error
 }
 
 func (s *MemoryStore) Delete(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	delete(s.secrets, id)
+	s.remove(id)
+	delete(s.pending, id)
 	return nil
 }
 
-func (s *MemoryStore) IncrementViews(ctx context.Context, id string) (int, error) {
+func (s *MemoryStore) DeleteWhere(ctx context.Context, match func(*models.Secret) bool) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deleted := 0
+	for id, secret := range s.secrets {
+		if match(secret) {
+			s.remove(id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (s *MemoryStore) UpdateWhere(ctx context.Context, update func(*models.Secret) bool) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	updated := 0
+	for id, secret := range s.secrets {
+		changed := *secret
+		if update(&changed) {
+			changed.ExpiresAt = secret.ExpiresAt
+			changed.CurrentViews = secret.CurrentViews
+			s.secrets[id] = &changed
+			updated++
+		}
+	}
+	return updated, nil
+}
+
+func (s *MemoryStore) ExpiringWithin(ctx context.Context, d time.Duration) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	until := now.Add(d)
+	count := 0
+	for _, secret := range s.secrets {
+		if secret.ExpiresAt.After(now) && !secret.ExpiresAt.After(until) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s *MemoryStore) Usage(ctx context.Context, since time.Time) (Usage, error) {
+	if err := ctx.Err(); err != nil {
+		return Usage{}, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	var usage Usage
+	for _, secret := range s.secrets {
+		if !secret.ExpiresAt.After(now) || secret.CurrentViews >= secret.MaxViews {
+			continue
+		}
+		usage.add(secret, since)
+	}
+	return usage, nil
+}
+
+func (s *MemoryStore) Extend(ctx context.Context, id string, expiresAt time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	secret, ok := s.secrets[id]
 	if !ok {
-		return 0, ErrNotFound
+		return ErrNotFound
 	}
 
 	if time.Now().After(secret.ExpiresAt) {
-		delete(s.secrets, id)
-		return 0, ErrExpired
+		s.remove(id)
+		return ErrExpired
+	}
+
+	secret.ExpiresAt = expiresAt
+	return nil
+}
+
+func (s *MemoryStore) IncrementViews(ctx context.Context, id string) (int, int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	secret, ok := s.secrets[id]
+	if !ok {
+		// A retry of the consuming request gets the same answer again
+		// without using another view.
+		if secret, ok := s.retryable(ctx, id); ok {
+			return secret.CurrentViews, secret.MaxViews, nil
+		}
+		return 0, 0, ErrNotFound
+	}
+
+	if time.Now().After(secret.ExpiresAt) {
+		s.remove(id)
+		return 0, 0, ErrExpired
 	}
 
 	if secret.CurrentViews >= secret.MaxViews {
-		delete(s.secrets, id)
-		return 0, ErrMaxViews
+		s.remove(id)
+		return 0, 0, ErrMaxViews
 	}
 
 	secret.CurrentViews++
 
 	// Auto-delete if max views reached
 	if secret.CurrentViews >= secret.MaxViews {
-		delete(s.secrets, id)
+		s.remove(id)
+		if token := retryToken(ctx); s.gracePeriod > 0 && token != "" {
+			s.pending[id] = pendingDelete{
+				secret:   secret,
+				token:    token,
+				deadline: time.Now().Add(s.gracePeriod),
+			}
+		}
+	}
+
+	return secret.CurrentViews, secret.MaxViews, nil
+}
+
+func (s *MemoryStore) GetAndDelete(ctx context.Context, id string) (*models.Secret, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	secret, ok := s.secrets[id]
+	if !ok {
+		if secret, ok := s.retryable(ctx, id); ok {
+			return secret, nil
+		}
+		return nil, ErrNotFound
+	}
+	s.remove(id)
+
+	if time.Now().After(secret.ExpiresAt) {
+		return nil, ErrExpired
+	}
+	if secret.CurrentViews >= secret.MaxViews {
+		return nil, ErrMaxViews
+	}
+
+	if token := retryToken(ctx); s.gracePeriod > 0 && token != "" {
+		s.pending[id] = pendingDelete{
+			secret:   secret,
+			token:    token,
+			deadline: time.Now().Add(s.gracePeriod),
+		}
+	}
+	return secret, nil
+}
+
+// retryable returns a consumed secret if ctx carries the token of the request
+// that consumed it and the grace period hasn't run out. Callers hold s.mu.
+func (s *MemoryStore) retryable(ctx context.Context, id string) (*models.Secret, bool) {
+	p, ok := s.pending[id]
+	if !ok || time.Now().After(p.deadline) {
+		return nil, false
+	}
+	token := retryToken(ctx)
+	if token == "" || token != p.token {
+		return nil, false
+	}
+	return p.secret, true
+}
+
+func (s *MemoryStore) IncrementPINFailures(ctx context.Context, id string) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	secret, ok := s.secrets[id]
+	if !ok {
+		return 0, ErrNotFound
+	}
+
+	secret.PINFailures++
+	return secret.PINFailures, nil
+}
+
+func (s *MemoryStore) AppendAccess(ctx context.Context, id string, entry models.AccessEntry, max int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	secret, ok := s.secrets[id]
+	if !ok || time.Now().After(secret.ExpiresAt) {
+		return ErrNotFound
 	}
 
-	return secret.CurrentViews, nil
+	log := append(s.accessLogs[id], entry)
+	if len(log) > max {
+		log = append([]models.AccessEntry(nil), log[len(log)-max:]...)
+	}
+	s.accessLogs[id] = log
+	return nil
+}
+
+func (s *MemoryStore) AccessLog(ctx context.Context, id string) ([]models.AccessEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.secrets[id]; !ok {
+		return nil, ErrNotFound
+	}
+	return append([]models.AccessEntry{}, s.accessLogs[id]...), nil
+}
+
+func (s *MemoryStore) SaveTombstone(ctx context.Context, id string, ttl time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tombstones[id] = time.Now().Add(ttl)
+	return nil
+}
+
+func (s *MemoryStore) HasTombstone(ctx context.Context, id string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	expiresAt, ok := s.tombstones[id]
+	return ok && time.Now().Before(expiresAt), nil
+}
+
+func (s *MemoryStore) AllowReveal(ctx context.Context, id string, rate float64, burst int) (bool, time.Duration, error) {
+	if err := ctx.Err(); err != nil {
+		return false, 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, ok, wait := s.buckets[id].take(time.Now(), rate, burst)
+	s.buckets[id] = b
+	return ok, wait, nil
+}
+
+func (s *MemoryStore) IncrementRevealCount(ctx context.Context) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return s.reveals.Add(1), nil
+}
+
+func (s *MemoryStore) RevealCount(ctx context.Context) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return s.reveals.Load(), nil
+}
+
+func (s *MemoryStore) SweptExpired(ctx context.Context) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return s.swept.Load(), nil
+}
+
+func (s *MemoryStore) SaveAPIKey(ctx context.Context, key *models.APIKey) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.apiKeys[key.ID] = key
+	return nil
+}
+
+func (s *MemoryStore) GetAPIKey(ctx context.Context, id string) (*models.APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	key, ok := s.apiKeys[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return key, nil
+}
+
+func (s *MemoryStore) DeleteAPIKey(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.apiKeys[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.apiKeys, id)
+	return nil
+}
+
+// Ping succeeds unless ctx is done; there is nothing to reach.
+func (s *MemoryStore) Ping(ctx context.Context) error {
+	return ctx.Err()
 }
 
 func (s *MemoryStore) Close() error {
@@ -102,9 +482,19 @@ func (s *MemoryStore) Close() error {
 	defer s.mu.Unlock()
 
 	s.secrets = nil
+	s.tombstones = nil
+	s.pending = nil
+	s.buckets = nil
+	s.accessLogs = nil
 	return nil
 }
 
+// remove deletes a secret and its access log. Callers hold s.mu.
+func (s *MemoryStore) remove(id string) {
+	delete(s.secrets, id)
+	delete(s.accessLogs, id)
+}
+
 func (s *MemoryStore) cleanupLoop(ctx context.Context, interval time.Duration) {
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
@@ -125,8 +515,27 @@ func (s *MemoryStore) cleanup() {
 
 	now := time.Now()
 	for id, secret := range s.secrets {
-		if now.After(secret.ExpiresAt) || secret.CurrentViews >= secret.MaxViews {
-			delete(s.secrets, id)
+		switch {
+		case now.After(secret.ExpiresAt):
+			s.remove(id)
+			s.swept.Add(1)
+		case secret.CurrentViews >= secret.MaxViews:
+			s.remove(id)
+		}
+	}
+	for id, p := range s.pending {
+		if now.After(p.deadline) {
+			delete(s.pending, id)
+		}
+	}
+	for id, b := range s.buckets {
+		if now.After(b.full) {
+			delete(s.buckets, id)
+		}
+	}
+	for id, expiresAt := range s.tombstones {
+		if now.After(expiresAt) {
+			delete(s.tombstones, id)
 		}
 	}
 }
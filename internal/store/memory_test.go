@@ -2,6 +2,9 @@ package store
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sync"
 	"testing"
 	"time"
 
@@ -51,3 +54,709 @@ func TestMemoryStore(t *testing.T) {
 		t.Fatalf("secret should be nil")
 	}
 }
+
+func TestMemoryStoreCancelledContext(t *testing.T) {
+	store := NewMemoryStore(time.Minute)
+	defer store.Close()
+
+	secret := &models.Secret{ID: "cancelled", MaxViews: 2, ExpiresAt: time.Now().Add(time.Hour)}
+	if err := store.Save(context.Background(), secret); err != nil {
+		t.Fatalf("failed to save secret: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := store.Save(ctx, &models.Secret{ID: "unsaved", MaxViews: 1, ExpiresAt: time.Now().Add(time.Hour)}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("save: got %v, want context.Canceled", err)
+	}
+	if _, err := store.Get(ctx, secret.ID); !errors.Is(err, context.Canceled) {
+		t.Fatalf("get: got %v, want context.Canceled", err)
+	}
+	if _, _, err := store.IncrementViews(ctx, secret.ID); !errors.Is(err, context.Canceled) {
+		t.Fatalf("increment: got %v, want context.Canceled", err)
+	}
+	if err := store.Delete(ctx, secret.ID); !errors.Is(err, context.Canceled) {
+		t.Fatalf("delete: got %v, want context.Canceled", err)
+	}
+	if err := store.Ping(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("ping: got %v, want context.Canceled", err)
+	}
+
+	// None of it happened.
+	if _, err := store.Get(context.Background(), "unsaved"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("cancelled save stored the secret: %v", err)
+	}
+	got, err := store.Get(context.Background(), secret.ID)
+	if err != nil || got.CurrentViews != 0 {
+		t.Fatalf("cancelled calls changed the secret: %+v, %v", got, err)
+	}
+}
+
+func TestMemoryStoreSaveReturningTTL(t *testing.T) {
+	store := NewMemoryStore(1 * time.Minute)
+	defer store.Close()
+
+	requested := 1 * time.Hour
+	secret := &models.Secret{
+		ID:            "ttl",
+		EncryptedData: []byte("test"),
+		MaxViews:      1,
+		ExpiresAt:     time.Now().Add(requested),
+		CreatedAt:     time.Now(),
+	}
+	applied, err := store.SaveReturningTTL(context.Background(), secret)
+	if err != nil {
+		t.Fatalf("failed to save secret: %v", err)
+	}
+	if applied > requested || requested-applied > time.Second {
+		t.Fatalf("applied ttl mismatch: got %s, want ~%s", applied, requested)
+	}
+}
+
+func TestMemoryStoreRevealCount(t *testing.T) {
+	store := NewMemoryStore(1 * time.Minute)
+	defer store.Close()
+
+	for i := int64(1); i <= 3; i++ {
+		n, err := store.IncrementRevealCount(context.Background())
+		if err != nil {
+			t.Fatalf("failed to increment reveal count: %v", err)
+		}
+		if n != i {
+			t.Fatalf("reveal count mismatch: got %d, want %d", n, i)
+		}
+	}
+
+	n, err := store.RevealCount(context.Background())
+	if err != nil {
+		t.Fatalf("failed to read reveal count: %v", err)
+	}
+	if n != 3 {
+		t.Fatalf("reveal count mismatch: got %d, want %d", n, 3)
+	}
+}
+
+func TestMemoryStoreSweptExpired(t *testing.T) {
+	store := NewMemoryStore(1 * time.Minute)
+	defer store.Close()
+	ctx := context.Background()
+
+	for _, secret := range []*models.Secret{
+		{ID: "expiring", MaxViews: 1, ExpiresAt: time.Now().Add(10 * time.Millisecond)},
+		{ID: "used-up", MaxViews: 1, CurrentViews: 1, ExpiresAt: time.Now().Add(time.Hour)},
+	} {
+		if err := store.Save(ctx, secret); err != nil {
+			t.Fatalf("failed to save secret: %v", err)
+		}
+	}
+	time.Sleep(20 * time.Millisecond)
+	store.cleanup()
+
+	// Only the expiry counts; the used-up secret was a burn.
+	if n, err := store.SweptExpired(ctx); err != nil || n != 1 {
+		t.Fatalf("swept expired = %d, %v; want 1", n, err)
+	}
+}
+
+func TestMemoryStoreDeleteWhere(t *testing.T) {
+	store := NewMemoryStore(1 * time.Minute)
+	defer store.Close()
+
+	for _, s := range []*models.Secret{
+		{ID: "a", Label: "incident", MaxViews: 1, ExpiresAt: time.Now().Add(time.Hour)},
+		{ID: "b", Label: "incident", MaxViews: 1, ExpiresAt: time.Now().Add(time.Hour)},
+		{ID: "c", Label: "other", MaxViews: 1, ExpiresAt: time.Now().Add(time.Hour)},
+	} {
+		store.Save(context.Background(), s)
+	}
+
+	deleted, err := store.DeleteWhere(context.Background(), func(s *models.Secret) bool {
+		return s.Label == "incident"
+	})
+	if err != nil {
+		t.Fatalf("failed to delete secrets: %v", err)
+	}
+	if deleted != 2 {
+		t.Fatalf("deleted count mismatch: got %d, want %d", deleted, 2)
+	}
+	if _, err := store.Get(context.Background(), "a"); err != ErrNotFound {
+		t.Fatalf("expected matching secret to be deleted, got %v", err)
+	}
+	if _, err := store.Get(context.Background(), "c"); err != nil {
+		t.Fatalf("expected non-matching secret to remain, got %v", err)
+	}
+}
+
+func TestMemoryStoreExtend(t *testing.T) {
+	store := NewMemoryStore(1 * time.Minute)
+	defer store.Close()
+
+	secret := &models.Secret{
+		ID:        "extend",
+		MaxViews:  1,
+		ExpiresAt: time.Now().Add(time.Minute),
+		CreatedAt: time.Now(),
+	}
+	if err := store.Save(context.Background(), secret); err != nil {
+		t.Fatalf("failed to save secret: %v", err)
+	}
+
+	expiresAt := time.Now().Add(time.Hour)
+	if err := store.Extend(context.Background(), secret.ID, expiresAt); err != nil {
+		t.Fatalf("failed to extend secret: %v", err)
+	}
+	got, err := store.Get(context.Background(), secret.ID)
+	if err != nil {
+		t.Fatalf("failed to get secret: %v", err)
+	}
+	if !got.ExpiresAt.Equal(expiresAt) {
+		t.Fatalf("expiry mismatch: got %s, want %s", got.ExpiresAt, expiresAt)
+	}
+
+	if err := store.Extend(context.Background(), "missing", expiresAt); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestMemoryStoreTombstone(t *testing.T) {
+	store := NewMemoryStore(1 * time.Minute)
+	defer store.Close()
+
+	if err := store.SaveTombstone(context.Background(), "burned", 50*time.Millisecond); err != nil {
+		t.Fatalf("failed to save tombstone: %v", err)
+	}
+	if ok, _ := store.HasTombstone(context.Background(), "burned"); !ok {
+		t.Fatalf("expected tombstone within window")
+	}
+	if ok, _ := store.HasTombstone(context.Background(), "other"); ok {
+		t.Fatalf("unexpected tombstone for unknown id")
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if ok, _ := store.HasTombstone(context.Background(), "burned"); ok {
+		t.Fatalf("expected tombstone to lapse after its window")
+	}
+}
+
+func TestMemoryStoreGracePeriod(t *testing.T) {
+	store := NewMemoryStoreWithGrace(1*time.Minute, 50*time.Millisecond)
+	defer store.Close()
+
+	secret := &models.Secret{
+		ID:        "grace",
+		MaxViews:  1,
+		ExpiresAt: time.Now().Add(time.Minute),
+		CreatedAt: time.Now(),
+	}
+	if err := store.Save(context.Background(), secret); err != nil {
+		t.Fatalf("failed to save secret: %v", err)
+	}
+
+	ctx := WithRetryToken(context.Background(), "req-1")
+	if views, _, err := store.IncrementViews(ctx, secret.ID); err != nil || views != 1 {
+		t.Fatalf("last view: got %d, %v", views, err)
+	}
+
+	// Retry by the same request within the window
+	if _, err := store.Get(ctx, secret.ID); err != nil {
+		t.Fatalf("retry within grace window failed: %v", err)
+	}
+	if views, _, err := store.IncrementViews(ctx, secret.ID); err != nil || views != 1 {
+		t.Fatalf("retried view: got %d, %v", views, err)
+	}
+
+	// Anyone else sees it gone
+	other := WithRetryToken(context.Background(), "req-2")
+	if _, err := store.Get(other, secret.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for another request, got %v", err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if _, err := store.Get(ctx, secret.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound after grace window, got %v", err)
+	}
+}
+
+func TestMemoryStoreExpiringWithin(t *testing.T) {
+	store := NewMemoryStore(1 * time.Minute)
+	defer store.Close()
+
+	for i, ttl := range []time.Duration{5 * time.Minute, 30 * time.Minute, 2 * time.Hour} {
+		secret := &models.Secret{
+			ID:        string(rune('a' + i)),
+			MaxViews:  1,
+			ExpiresAt: time.Now().Add(ttl),
+			CreatedAt: time.Now(),
+		}
+		if err := store.Save(context.Background(), secret); err != nil {
+			t.Fatalf("failed to save secret: %v", err)
+		}
+	}
+
+	n, err := store.ExpiringWithin(context.Background(), time.Hour)
+	if err != nil {
+		t.Fatalf("failed to count expiring secrets: %v", err)
+	}
+	if n != 2 {
+		t.Fatalf("expiring count mismatch: got %d, want %d", n, 2)
+	}
+}
+
+func TestMemoryStoreAllowReveal(t *testing.T) {
+	store := NewMemoryStore(1 * time.Minute)
+	defer store.Close()
+
+	for i := 0; i < 3; i++ {
+		ok, _, err := store.AllowReveal(context.Background(), "hot", 10, 3)
+		if err != nil || !ok {
+			t.Fatalf("reveal %d within burst: ok=%v err=%v", i, ok, err)
+		}
+	}
+	ok, wait, _ := store.AllowReveal(context.Background(), "hot", 10, 3)
+	if ok || wait <= 0 || wait > 100*time.Millisecond {
+		t.Fatalf("expected throttle with wait up to 100ms, got ok=%v wait=%s", ok, wait)
+	}
+	if ok, _, _ := store.AllowReveal(context.Background(), "cold", 10, 3); !ok {
+		t.Fatalf("unrelated id was throttled")
+	}
+
+	time.Sleep(wait)
+	if ok, _, _ := store.AllowReveal(context.Background(), "hot", 10, 3); !ok {
+		t.Fatalf("expected a token after waiting %s", wait)
+	}
+}
+
+func TestMemoryStoreSaveWithinQuota(t *testing.T) {
+	store := NewMemoryStore(time.Minute)
+	defer store.Close()
+
+	expired := &models.Secret{ID: "old", MaxViews: 1, ExpiresAt: time.Now().Add(-time.Minute)}
+	if err := store.Save(context.Background(), expired); err != nil {
+		t.Fatalf("failed to save secret: %v", err)
+	}
+	// Expired secrets don't count against the quota.
+	checkQuotaRace(t, store, "memory", 5)
+}
+
+// checkQuotaRace races many SaveWithinQuota calls against a quota of max
+// secrets on top of the live secrets already in s, and fails unless
+// exactly max of them succeed.
+func checkQuotaRace(t *testing.T, s Store, prefix string, max int) {
+	t.Helper()
+	ctx := context.Background()
+	live, err := s.ExpiringWithin(ctx, 100*365*24*time.Hour)
+	if err != nil {
+		t.Fatalf("failed to count live secrets: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var saved []string
+	full := 0
+	defer func() {
+		for _, id := range saved {
+			_ = s.Delete(ctx, id)
+		}
+	}()
+	for i := 0; i < 4*max; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			secret := &models.Secret{
+				ID:        fmt.Sprintf("%s-quota-%d", prefix, i),
+				MaxViews:  1,
+				ExpiresAt: time.Now().Add(time.Hour),
+			}
+			err := s.SaveWithinQuota(ctx, secret, live+max)
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				saved = append(saved, secret.ID)
+			case errors.Is(err, ErrQuotaExceeded):
+				full++
+			default:
+				t.Errorf("unexpected save error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(saved) != max {
+		t.Fatalf("saved %d secrets within quota, want %d (%d rejected)", len(saved), max, full)
+	}
+}
+
+func TestMemoryStoreGetAndDelete(t *testing.T) {
+	store := NewMemoryStore(time.Minute)
+	defer store.Close()
+	checkGetAndDelete(t, store, "memory")
+}
+
+// checkGetAndDelete races GetAndDelete callers over one secret and fails
+// unless exactly one gets it and it is gone straight after.
+func checkGetAndDelete(t *testing.T, s Store, prefix string) {
+	t.Helper()
+	ctx := context.Background()
+	secret := &models.Secret{
+		ID:            prefix + "-getdel",
+		EncryptedData: []byte("blob"),
+		MaxViews:      1,
+		ExpiresAt:     time.Now().Add(time.Hour),
+	}
+	if err := s.Save(ctx, secret); err != nil {
+		t.Fatalf("failed to save secret: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded := 0
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			got, err := s.GetAndDelete(ctx, secret.ID)
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				succeeded++
+				if string(got.EncryptedData) != "blob" {
+					t.Errorf("content mismatch: got %q", got.EncryptedData)
+				}
+			case !errors.Is(err, ErrNotFound):
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if succeeded != 1 {
+		t.Fatalf("successful fetches mismatch: got %d, want 1", succeeded)
+	}
+	if _, err := s.Get(ctx, secret.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("secret still readable after GetAndDelete: %v", err)
+	}
+}
+
+func TestMemoryStoreAPIKeys(t *testing.T) {
+	store := NewMemoryStore(time.Minute)
+	defer store.Close()
+	checkAPIKeys(t, store, "memory")
+}
+
+// checkAPIKeys saves, reads back and revokes an API key, and checks that
+// keys don't show up among secrets.
+func checkAPIKeys(t *testing.T, s Store, prefix string) {
+	t.Helper()
+	ctx := context.Background()
+	key := &models.APIKey{
+		ID:        prefix + "-key",
+		Name:      "billing",
+		Scopes:    []string{"create"},
+		Hash:      []byte("hash"),
+		CreatedAt: time.Now().Round(0),
+	}
+	if err := s.SaveAPIKey(ctx, key); err != nil {
+		t.Fatalf("failed to save api key: %v", err)
+	}
+
+	got, err := s.GetAPIKey(ctx, key.ID)
+	if err != nil {
+		t.Fatalf("failed to get api key: %v", err)
+	}
+	if got.Name != "billing" || !got.HasScope("create") || string(got.Hash) != "hash" || !got.CreatedAt.Equal(key.CreatedAt) {
+		t.Fatalf("api key mismatch: got %+v", got)
+	}
+
+	purged, err := s.DeleteWhere(ctx, func(*models.Secret) bool { return true })
+	if err != nil {
+		t.Fatalf("DeleteWhere failed: %v", err)
+	}
+	if _, err := s.GetAPIKey(ctx, key.ID); err != nil {
+		t.Fatalf("api key gone after purging %d secrets: %v", purged, err)
+	}
+
+	if err := s.DeleteAPIKey(ctx, key.ID); err != nil {
+		t.Fatalf("failed to revoke api key: %v", err)
+	}
+	if _, err := s.GetAPIKey(ctx, key.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("revoked api key still readable: %v", err)
+	}
+	if err := s.DeleteAPIKey(ctx, key.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("revoking a revoked key: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestMemoryStoreDropPassphrases(t *testing.T) {
+	store := NewMemoryStore(time.Minute)
+	defer store.Close()
+	checkDropPassphrases(t, store, "memory")
+}
+
+// checkDropPassphrases migrates a secret with a stored passphrase and one
+// without, and checks the first keeps everything but the passphrase.
+func checkDropPassphrases(t *testing.T, s Store, prefix string) {
+	t.Helper()
+	ctx := context.Background()
+	old := &models.Secret{
+		ID:            prefix + "-old",
+		EncryptedData: []byte("ciphertext"),
+		EncryptedNote: []byte("note"),
+		Passphrase:    "correct-horse-battery",
+		MaxViews:      3,
+		ExpiresAt:     time.Now().Add(time.Hour).Round(0),
+		CreatedAt:     time.Now().Round(0),
+		Label:         "legacy",
+	}
+	split := &models.Secret{
+		ID:            prefix + "-split",
+		EncryptedData: []byte("ciphertext"),
+		Threshold:     2,
+		MaxViews:      1,
+		ExpiresAt:     time.Now().Add(time.Hour).Round(0),
+		CreatedAt:     time.Now().Round(0),
+	}
+	for _, secret := range []*models.Secret{old, split} {
+		if err := s.Save(ctx, secret); err != nil {
+			t.Fatalf("failed to save %s: %v", secret.ID, err)
+		}
+	}
+	if _, _, err := s.IncrementViews(ctx, old.ID); err != nil {
+		t.Fatalf("failed to increment views: %v", err)
+	}
+
+	n, err := DropPassphrases(ctx, s)
+	if err != nil {
+		t.Fatalf("DropPassphrases failed: %v", err)
+	}
+	if n != 1 {
+		t.Fatalf("migrated %d secrets, want 1", n)
+	}
+
+	got, err := s.Get(ctx, old.ID)
+	if err != nil {
+		t.Fatalf("migrated secret unreadable: %v", err)
+	}
+	if got.Passphrase != "" {
+		t.Fatal("passphrase survived the migration")
+	}
+	if string(got.EncryptedData) != "ciphertext" || string(got.EncryptedNote) != "note" || got.Label != "legacy" ||
+		got.MaxViews != 3 || got.CurrentViews != 1 || !got.ExpiresAt.Equal(old.ExpiresAt) {
+		t.Fatalf("migration changed more than the passphrase: %+v", got)
+	}
+
+	if n, err := DropPassphrases(ctx, s); err != nil || n != 0 {
+		t.Fatalf("second run migrated %d secrets (%v), want 0", n, err)
+	}
+}
+
+func TestMemoryStoreBurnOnLastView(t *testing.T) {
+	store := NewMemoryStore(time.Minute)
+	defer store.Close()
+	checkBurnOnLastView(t, store, "memory")
+}
+
+// checkBurnOnLastView checks that the view reaching MaxViews deletes the
+// secret, which reveal reports as burned.
+func checkBurnOnLastView(t *testing.T, s Store, prefix string) {
+	t.Helper()
+	ctx := context.Background()
+	secret := &models.Secret{
+		ID:            prefix + "-burn",
+		EncryptedData: []byte("ciphertext"),
+		MaxViews:      2,
+		ExpiresAt:     time.Now().Add(time.Hour),
+		CreatedAt:     time.Now(),
+	}
+	if err := s.Save(ctx, secret); err != nil {
+		t.Fatalf("failed to save secret: %v", err)
+	}
+
+	if views, _, err := s.IncrementViews(ctx, secret.ID); err != nil || views != 1 {
+		t.Fatalf("first view: got %d, %v", views, err)
+	}
+	if _, err := s.Get(ctx, secret.ID); err != nil {
+		t.Fatalf("secret gone before its last view: %v", err)
+	}
+	if views, _, err := s.IncrementViews(ctx, secret.ID); err != nil || views != 2 {
+		t.Fatalf("last view: got %d, %v", views, err)
+	}
+	if _, err := s.Get(ctx, secret.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("secret readable after its last view: %v", err)
+	}
+}
+
+func TestMemoryStoreIncrementRace(t *testing.T) {
+	store := NewMemoryStore(time.Minute)
+	defer store.Close()
+	checkIncrementRace(t, store, "memory")
+}
+
+// checkIncrementRace checks that concurrent views of a secret succeed
+// exactly MaxViews times, each with its own view count, and that the
+// views after that fail rather than error out on contention.
+func checkIncrementRace(t *testing.T, s Store, prefix string) {
+	t.Helper()
+	ctx := context.Background()
+	secret := &models.Secret{
+		ID:            prefix + "-increment-race",
+		EncryptedData: []byte("ciphertext"),
+		MaxViews:      5,
+		ExpiresAt:     time.Now().Add(time.Hour),
+		CreatedAt:     time.Now(),
+	}
+	if err := s.Save(ctx, secret); err != nil {
+		t.Fatalf("failed to save secret: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	seen := make(map[int]bool)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			views, maxViews, err := s.IncrementViews(ctx, secret.ID)
+			if err != nil && !errors.Is(err, ErrNotFound) && !errors.Is(err, ErrMaxViews) {
+				t.Errorf("unexpected increment error: %v", err)
+				return
+			}
+			if err == nil && maxViews != secret.MaxViews {
+				t.Errorf("increment returned max views %d, want %d", maxViews, secret.MaxViews)
+			}
+			if err == nil {
+				mu.Lock()
+				seen[views] = true
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(seen) != secret.MaxViews {
+		t.Fatalf("got %d distinct successful views, want %d", len(seen), secret.MaxViews)
+	}
+	for v := 1; v <= secret.MaxViews; v++ {
+		if !seen[v] {
+			t.Fatalf("no view returned count %d", v)
+		}
+	}
+}
+
+func TestMemoryStoreUsage(t *testing.T) {
+	store := NewMemoryStore(time.Minute)
+	defer store.Close()
+	checkUsage(t, store, "memory")
+}
+
+// checkUsage checks that Usage counts only live secrets and splits out the
+// ones created since the given time.
+func checkUsage(t *testing.T, s Store, prefix string) {
+	t.Helper()
+	ctx := context.Background()
+	now := time.Now()
+	since := now.Add(-time.Hour)
+	for _, secret := range []*models.Secret{
+		{ID: prefix + "-usage-new", CreatedAt: now, ExpiresAt: now.Add(time.Hour)},
+		{ID: prefix + "-usage-old", CreatedAt: now.Add(-2 * time.Hour), ExpiresAt: now.Add(time.Hour)},
+	} {
+		secret.EncryptedData = []byte("ciphertext")
+		secret.MaxViews = 1
+		if err := s.Save(ctx, secret); err != nil {
+			t.Fatalf("failed to save secret: %v", err)
+		}
+	}
+
+	usage, err := s.Usage(ctx, since)
+	if err != nil {
+		t.Fatalf("usage failed: %v", err)
+	}
+	if usage.Active < 2 || usage.CreatedSince < 1 || usage.CreatedSince >= usage.Active {
+		t.Fatalf("unexpected usage: %+v", usage)
+	}
+	if usage.TotalTTL < 4*time.Hour-time.Second {
+		t.Fatalf("total ttl %v is less than the saved secrets' lifetimes", usage.TotalTTL)
+	}
+
+	before := usage
+	if err := s.Delete(ctx, prefix+"-usage-new"); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+	usage, err = s.Usage(ctx, since)
+	if err != nil {
+		t.Fatalf("usage failed: %v", err)
+	}
+	if usage.Active != before.Active-1 || usage.CreatedSince != before.CreatedSince-1 {
+		t.Fatalf("deleted secret still counted: before %+v, after %+v", before, usage)
+	}
+}
+
+func TestMemoryStoreAccessLog(t *testing.T) {
+	store := NewMemoryStore(time.Minute)
+	defer store.Close()
+	checkAccessLog(t, store, "memory")
+}
+
+// checkAccessLog appends past the cap, then checks the log goes with its
+// secret whether it is deleted or its last view is used.
+func checkAccessLog(t *testing.T, s Store, prefix string) {
+	t.Helper()
+	ctx := context.Background()
+	secret := &models.Secret{
+		ID:            prefix + "-access",
+		EncryptedData: []byte("ciphertext"),
+		MaxViews:      2,
+		ExpiresAt:     time.Now().Add(time.Hour).Round(0),
+		CreatedAt:     time.Now().Round(0),
+	}
+	if err := s.Save(ctx, secret); err != nil {
+		t.Fatalf("failed to save secret: %v", err)
+	}
+
+	for i := 0; i < 4; i++ {
+		entry := models.AccessEntry{Time: time.Unix(int64(i), 0).UTC(), IP: "203.0.113.0/24", UserAgent: fmt.Sprint(i)}
+		if err := s.AppendAccess(ctx, secret.ID, entry, 3); err != nil {
+			t.Fatalf("failed to append access: %v", err)
+		}
+	}
+	log, err := s.AccessLog(ctx, secret.ID)
+	if err != nil {
+		t.Fatalf("failed to read access log: %v", err)
+	}
+	if len(log) != 3 || log[0].UserAgent != "1" || log[2].UserAgent != "3" || !log[2].Time.Equal(time.Unix(3, 0)) {
+		t.Fatalf("access log = %+v, want the last 3 entries oldest first", log)
+	}
+
+	if err := s.AppendAccess(ctx, prefix+"-missing", models.AccessEntry{}, 3); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("append for a missing secret: got %v, want ErrNotFound", err)
+	}
+
+	if err := s.Delete(ctx, secret.ID); err != nil {
+		t.Fatalf("failed to delete secret: %v", err)
+	}
+	if _, err := s.AccessLog(ctx, secret.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("access log of a deleted secret: got %v, want ErrNotFound", err)
+	}
+	if err := s.Save(ctx, secret); err != nil {
+		t.Fatalf("failed to save secret again: %v", err)
+	}
+	if log, err := s.AccessLog(ctx, secret.ID); err != nil || len(log) != 0 {
+		t.Fatalf("log survived delete: %+v, %v", log, err)
+	}
+
+	if err := s.AppendAccess(ctx, secret.ID, models.AccessEntry{UserAgent: "first"}, 3); err != nil {
+		t.Fatalf("failed to append access: %v", err)
+	}
+	for i := 0; i < secret.MaxViews; i++ {
+		if _, _, err := s.IncrementViews(ctx, secret.ID); err != nil {
+			t.Fatalf("failed to increment views: %v", err)
+		}
+	}
+	if err := s.Save(ctx, secret); err != nil {
+		t.Fatalf("failed to save secret again: %v", err)
+	}
+	if log, err := s.AccessLog(ctx, secret.ID); err != nil || len(log) != 0 {
+		t.Fatalf("log survived the last view: %+v, %v", log, err)
+	}
+}
@@ -0,0 +1,23 @@
+package store
+
+import (
+	"context"
+
+	"secure.share/internal/models"
+)
+
+// DropPassphrases blanks the passphrase that versions before the switch to
+// storing only its hash kept with each secret, so the stored data alone no
+// longer opens them. Everything else, including expiry and views, is kept.
+// Reveals of these secrets check the passphrase by decrypting, as for split
+// keys; with nothing to compare it against, their recipients can't burn
+// them.
+func DropPassphrases(ctx context.Context, s Store) (int, error) {
+	return s.UpdateWhere(ctx, func(secret *models.Secret) bool {
+		if secret.Passphrase == "" {
+			return false
+		}
+		secret.Passphrase = ""
+		return true
+	})
+}
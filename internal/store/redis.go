@@ -2,10 +2,12 @@
 package store
 
 import (
-	"bytes"
 	"context"
-	"encoding/gob"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -15,25 +17,169 @@ import (
 var _ Store = (*RedisStore)(nil)
 
 type RedisStore struct {
-	client *redis.Client
+	client   redis.UniversalClient
+	failover FailoverPolicy
+	codec    blobCodec
+	// prefix starts every key; in cluster mode it is a hash tag, see
+	// clusterKeyPrefix.
+	prefix string
 }
 
+// RedisStoreOptions are the settings NewRedisStoreWithOptions takes on top
+// of the client's.
+type RedisStoreOptions struct {
+	Failover    FailoverPolicy
+	Compression Compression
+	// MaxInflated bounds how large a compressed blob may inflate on read;
+	// zero means DefaultMaxInflated.
+	MaxInflated int64
+}
+
+// DefaultFailoverPolicy rides out a typical Sentinel or Cluster failover of
+// a second or two.
+var DefaultFailoverPolicy = FailoverPolicy{Retries: 3, Backoff: 200 * time.Millisecond}
+
 func NewRedisStore(options *redis.Options) (*RedisStore, error) {
-	client := redis.NewClient(options)
+	return NewRedisStoreWithFailover(options, DefaultFailoverPolicy)
+}
+
+// NewRedisStoreWithFailover retries operations that fail because the server
+// is unreachable or mid-failover according to policy, then reports
+// ErrUnavailable.
+func NewRedisStoreWithFailover(options *redis.Options, policy FailoverPolicy) (*RedisStore, error) {
+	return NewRedisStoreWithOptions(options, RedisStoreOptions{Failover: policy})
+}
+
+// NewRedisStoreWithOptions connects with options, except that commands
+// always give up at their context's deadline: a request that has timed out
+// shouldn't hold a connection waiting on a slow server.
+func NewRedisStoreWithOptions(options *redis.Options, opts RedisStoreOptions) (*RedisStore, error) {
+	withDeadlines := *options
+	withDeadlines.ContextTimeoutEnabled = true
+	return newRedisStore(redis.NewClient(&withDeadlines), "", opts)
+}
+
+// Redis deployment modes taken by NewRedisStoreForMode.
+const (
+	RedisSingle   = "single"
+	RedisSentinel = "sentinel"
+	RedisCluster  = "cluster"
+)
 
+// clusterKeyPrefix is a hash tag putting every key in one cluster slot, so
+// the scripts and transactions spanning a secret, its access log and the
+// expiry index keep working. The cluster still fails over, but this app's
+// keys live on a single shard.
+const clusterKeyPrefix = "{secure-share}:"
+
+// NewRedisStoreForMode connects to a single server at options.Addrs[0], to
+// the master Sentinels at options.Addrs know as options.MasterName, or to
+// the cluster with options.Addrs as seed nodes. Cluster keys are prefixed
+// with clusterKeyPrefix, so moving an existing single server's data into a
+// cluster needs its keys renamed. As with NewRedisStoreWithOptions, commands
+// give up at their context's deadline.
+func NewRedisStoreForMode(mode string, options *redis.UniversalOptions, opts RedisStoreOptions) (*RedisStore, error) {
+	withDeadlines := *options
+	withDeadlines.ContextTimeoutEnabled = true
+	options = &withDeadlines
+	switch mode {
+	case RedisSingle, "":
+		return newRedisStore(redis.NewClient(options.Simple()), "", opts)
+	case RedisSentinel:
+		return newRedisStore(redis.NewFailoverClient(options.Failover()), "", opts)
+	case RedisCluster:
+		return newRedisStore(redis.NewClusterClient(options.Cluster()), clusterKeyPrefix, opts)
+	default:
+		return nil, fmt.Errorf("unknown redis mode %q", mode)
+	}
+}
+
+func newRedisStore(client redis.UniversalClient, prefix string, opts RedisStoreOptions) (*RedisStore, error) {
 	// Verify connection
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
 		return nil, err
 	}
 
-	return &RedisStore{client: client}, nil
+	return &RedisStore{
+		client:   client,
+		failover: opts.Failover,
+		codec:    newBlobCodec(opts.Compression, opts.MaxInflated),
+		prefix:   prefix,
+	}, nil
 }
 
 func (r *RedisStore) Save(ctx context.Context, secret *models.Secret) error {
-	data, err := encode(secret)
+	_, err := r.SaveReturningTTL(ctx, secret)
+	return err
+}
+
+func (r *RedisStore) SaveReturningTTL(ctx context.Context, secret *models.Secret) (time.Duration, error) {
+	var result time.Duration
+	err := retryFailover(ctx, r.failover, func() error {
+		var err error
+		result, err = r.saveReturningTTL(ctx, secret)
+		return err
+	})
+	return result, err
+}
+
+func (r *RedisStore) saveReturningTTL(ctx context.Context, secret *models.Secret) (time.Duration, error) {
+	data, err := r.codec.encode(secret)
+	if err != nil {
+		return 0, err
+	}
+
+	ttl := time.Until(secret.ExpiresAt)
+	if ttl <= 0 {
+		return 0, ErrExpired
+	}
+
+	key := r.secretKey(secret.ID)
+	var applied *redis.DurationCmd
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, key)
+		pipe.HSet(ctx, key, secretFields(secret, data)...)
+		pipe.PExpire(ctx, key, ttl)
+		pipe.ZAdd(ctx, r.key(expiryIndexKey), expiryMember(secret))
+		applied = pipe.PTTL(ctx, key)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return applied.Val(), nil
+}
+
+// saveWithinQuotaScript prunes expired entries from the expiry index, whose
+// size is then the live secret count, and saves only if it is below max.
+var saveWithinQuotaScript = redis.NewScript(`
+	local key, index = KEYS[1], KEYS[2]
+	local ttl, max, now, expires, id = ARGV[1], tonumber(ARGV[2]), ARGV[3], ARGV[4], ARGV[5]
+
+	redis.call('ZREMRANGEBYSCORE', index, '-inf', now)
+	if redis.call('ZCARD', index) >= max then
+		return 0
+	end
+	redis.call('DEL', key)
+	redis.call('HSET', key, unpack(ARGV, 6))
+	redis.call('PEXPIRE', key, ttl)
+	redis.call('ZADD', index, expires, id)
+	return 1
+`)
+
+func (r *RedisStore) SaveWithinQuota(ctx context.Context, secret *models.Secret, max int) error {
+	return retryFailover(ctx, r.failover, func() error {
+		return r.saveWithinQuota(ctx, secret, max)
+	})
+}
+
+func (r *RedisStore) saveWithinQuota(ctx context.Context, secret *models.Secret, max int) error {
+	data, err := r.codec.encode(secret)
 	if err != nil {
 		return err
 	}
@@ -43,19 +189,32 @@ func (r *RedisStore) Save(ctx context.Context, secret *models.Secret) error {
 		return ErrExpired
 	}
 
-	return r.client.Set(ctx, secretKey(secret.ID), data, ttl).Err()
+	args := []interface{}{ttl.Milliseconds(), max, time.Now().UnixMilli(), secret.ExpiresAt.UnixMilli(), secret.ID}
+	saved, err := saveWithinQuotaScript.Run(ctx, r.client,
+		[]string{r.secretKey(secret.ID), r.key(expiryIndexKey)},
+		append(args, secretFields(secret, data)...)...,
+	).Int()
+	if err != nil {
+		return err
+	}
+	if saved == 0 {
+		return ErrQuotaExceeded
+	}
+	return nil
 }
 
 func (r *RedisStore) Get(ctx context.Context, id string) (*models.Secret, error) {
-	data, err := r.client.Get(ctx, secretKey(id)).Bytes()
-	if err != nil {
-		if errors.Is(err, redis.Nil) {
-			return nil, ErrNotFound
-		}
-		return nil, err
-	}
+	var result *models.Secret
+	err := retryFailover(ctx, r.failover, func() error {
+		var err error
+		result, err = r.get(ctx, id)
+		return err
+	})
+	return result, err
+}
 
-	secret, err := decode(data)
+func (r *RedisStore) get(ctx context.Context, id string) (*models.Secret, error) {
+	secret, err := r.load(ctx, r.client, r.secretKey(id))
 	if err != nil {
 		return nil, err
 	}
@@ -70,78 +229,216 @@ func (r *RedisStore) Get(ctx context.Context, id string) (*models.Secret, error)
 }
 
 func (r *RedisStore) Delete(ctx context.Context, id string) error {
-	return r.client.Del(ctx, secretKey(id)).Err()
+	return retryFailover(ctx, r.failover, func() error {
+		return r.delete(ctx, id)
+	})
 }
 
-var incrementViewsScript = redis.NewScript(`
-	local key = KEYS[1]
-	local data = redis.call('GET', key)
-	if not data then
+func (r *RedisStore) delete(ctx context.Context, id string) error {
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, r.secretKey(id), r.accessLogKey(id))
+		pipe.ZRem(ctx, r.key(expiryIndexKey), id)
 		return nil
-	end
-	return data
-`)
-
-func (r *RedisStore) IncrementViews(ctx context.Context, id string) (int, error) {
-	key := secretKey(id)
-	var resultViews int
+	})
+	return err
+}
 
-	txf := func(tx *redis.Tx) error {
-		result := incrementViewsScript.Run(ctx, tx, []string{key})
-		if result.Err() != nil {
-			if errors.Is(result.Err(), redis.Nil) {
-				return ErrNotFound
-			}
-			return result.Err()
+func (r *RedisStore) DeleteWhere(ctx context.Context, match func(*models.Secret) bool) (int, error) {
+	deleted := 0
+	iter, err := r.scanSecrets(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for iter.Next(ctx) {
+		key := iter.Val()
+		secret, err := r.load(ctx, r.client, key)
+		if errors.Is(err, ErrNotFound) {
+			continue // expired or deleted since SCAN returned it
+		}
+		if err != nil {
+			return deleted, err
+		}
+		if !match(secret) {
+			continue
 		}
 
-		val, err := result.Result()
+		var del *redis.IntCmd
+		_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			del = pipe.Del(ctx, key)
+			pipe.Del(ctx, r.accessLogKey(secret.ID))
+			pipe.ZRem(ctx, r.key(expiryIndexKey), secret.ID)
+			return nil
+		})
 		if err != nil {
-			if errors.Is(err, redis.Nil) {
-				return ErrNotFound
+			return deleted, err
+		}
+		deleted += int(del.Val())
+	}
+	return deleted, iter.Err()
+}
+
+// UpdateWhere rewrites each secret under WATCH, so a view or extend racing
+// the rewrite is retried on the fresh value rather than lost.
+func (r *RedisStore) UpdateWhere(ctx context.Context, update func(*models.Secret) bool) (int, error) {
+	updated := 0
+	iter, err := r.scanSecrets(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for iter.Next(ctx) {
+		key := iter.Val()
+		changed := false
+		txf := func(tx *redis.Tx) error {
+			changed = false
+			secret, err := r.load(ctx, tx, key)
+			if errors.Is(err, ErrNotFound) {
+				return nil // expired or deleted since SCAN returned it
+			}
+			if err != nil {
+				return err
+			}
+			if !update(secret) {
+				return nil
+			}
+			newData, err := r.codec.encode(secret)
+			if err != nil {
+				return err
 			}
+
+			_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				pipe.HSet(ctx, key, secretFields(secret, newData)...)
+				return nil
+			})
+			changed = err == nil
 			return err
 		}
 
-		var data []byte
-		switch v := val.(type) {
-		case string:
-			data = []byte(v)
-		case []byte:
-			data = v
-		default:
-			return errors.New("unexpected data type from script")
+		var err error
+		for i := 0; i < 3; i++ {
+			if err = r.client.Watch(ctx, txf, key); !errors.Is(err, redis.TxFailedErr) {
+				break
+			}
+		}
+		if err != nil {
+			return updated, err
+		}
+		if changed {
+			updated++
+		}
+	}
+	return updated, iter.Err()
+}
+
+// ExpiringWithin counts from the expiry index. Keys that Redis expired on
+// its own are still in the index, so entries already in the past are pruned
+// first.
+func (r *RedisStore) ExpiringWithin(ctx context.Context, d time.Duration) (int, error) {
+	now := time.Now()
+	var count *redis.IntCmd
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRemRangeByScore(ctx, r.key(expiryIndexKey), "-inf", strconv.FormatInt(now.UnixMilli(), 10))
+		count = pipe.ZCount(ctx, r.key(expiryIndexKey),
+			"("+strconv.FormatInt(now.UnixMilli(), 10),
+			strconv.FormatInt(now.Add(d).UnixMilli(), 10),
+		)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return int(count.Val()), nil
+}
+
+func (r *RedisStore) Usage(ctx context.Context, since time.Time) (Usage, error) {
+	var usage Usage
+	iter, err := r.scanSecrets(ctx)
+	if err != nil {
+		return usage, err
+	}
+	for iter.Next(ctx) {
+		secret, err := r.load(ctx, r.client, iter.Val())
+		if errors.Is(err, ErrNotFound) {
+			continue // expired or deleted since SCAN returned it
+		}
+		if err != nil {
+			return usage, err
 		}
+		usage.add(secret, since)
+	}
+	return usage, iter.Err()
+}
+
+func (r *RedisStore) Extend(ctx context.Context, id string, expiresAt time.Time) error {
+	return retryFailover(ctx, r.failover, func() error {
+		return r.extend(ctx, id, expiresAt)
+	})
+}
 
-		secret, err := decode(data)
+func (r *RedisStore) extend(ctx context.Context, id string, expiresAt time.Time) error {
+	key := r.secretKey(id)
+
+	txf := func(tx *redis.Tx) error {
+		secret, err := r.load(ctx, tx, key)
 		if err != nil {
 			return err
 		}
+		secret.ExpiresAt = expiresAt
 
-		now := time.Now()
-		if now.After(secret.ExpiresAt) {
-			return ErrExpired
+		newData, err := r.codec.encode(secret)
+		if err != nil {
+			return err
 		}
 
-		if secret.CurrentViews >= secret.MaxViews {
-			return ErrMaxViews
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.HSet(ctx, key, secretFields(secret, newData)...)
+			pipe.PExpire(ctx, key, time.Until(expiresAt))
+			pipe.PExpire(ctx, r.accessLogKey(id), time.Until(expiresAt))
+			pipe.ZAdd(ctx, r.key(expiryIndexKey), expiryMember(secret))
+			return nil
+		})
+		return err
+	}
+
+	for i := 0; i < 3; i++ {
+		err := r.client.Watch(ctx, txf, key)
+		if errors.Is(err, redis.TxFailedErr) {
+			continue
 		}
+		return err
+	}
+
+	return redis.TxFailedErr
+}
+
+func (r *RedisStore) IncrementPINFailures(ctx context.Context, id string) (int, error) {
+	var result int
+	err := retryFailover(ctx, r.failover, func() error {
+		var err error
+		result, err = r.incrementPINFailures(ctx, id)
+		return err
+	})
+	return result, err
+}
 
-		secret.CurrentViews++
-		resultViews = secret.CurrentViews
+func (r *RedisStore) incrementPINFailures(ctx context.Context, id string) (int, error) {
+	key := r.secretKey(id)
+	var failures int
 
-		newData, err := encode(secret)
+	txf := func(tx *redis.Tx) error {
+		secret, err := r.load(ctx, tx, key)
+		if err != nil {
+			return err
+		}
+		secret.PINFailures++
+		failures = secret.PINFailures
+
+		newData, err := r.codec.encode(secret)
 		if err != nil {
 			return err
 		}
 
-		ttl := tx.TTL(ctx, key).Val()
 		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
-			if secret.CurrentViews >= secret.MaxViews {
-				pipe.Del(ctx, key)
-			} else if ttl > 0 {
-				pipe.Set(ctx, key, newData, ttl)
-			}
+			pipe.HSet(ctx, key, fieldData, newData)
 			return nil
 		})
 		return err
@@ -149,23 +446,323 @@ func (r *RedisStore) IncrementViews(ctx context.Context, id string) (int, error)
 
 	for i := 0; i < 3; i++ {
 		err := r.client.Watch(ctx, txf, key)
-		if err == nil {
-			return resultViews, nil
-		}
 		if errors.Is(err, redis.TxFailedErr) {
 			continue
 		}
-		if errors.Is(err, ErrExpired) || errors.Is(err, ErrMaxViews) {
-			_ = r.Delete(ctx, id)
-			return 0, err
+		return failures, err
+	}
+
+	return 0, redis.TxFailedErr
+}
+
+// incrementViewsScript counts a view from the hash's current_views and max_views
+// fields, deleting the secret on its last view or if it is found expired or
+// used up. It returns the new view count, or one of the negative sentinels
+// below, followed by max_views; "now" is the caller's clock, as for
+// saveWithinQuotaScript. A secret still in the legacy string layout is left
+// for the caller to migrate.
+var incrementViewsScript = redis.NewScript(`
+	local key, index, log = KEYS[1], KEYS[2], KEYS[3]
+	local now, id = tonumber(ARGV[1]), ARGV[2]
+
+	if redis.call('TYPE', key).ok == 'string' then
+		return {-4, 0}
+	end
+	local state = redis.call('HMGET', key, 'current_views', 'max_views', 'expires_at')
+	if not state[1] then
+		return {-1, 0}
+	end
+	local views, max, expires = tonumber(state[1]), tonumber(state[2]), tonumber(state[3])
+
+	local result
+	if now > expires then
+		result = -2
+	elseif views >= max then
+		result = -3
+	else
+		result = redis.call('HINCRBY', key, 'current_views', 1)
+		if result < max then
+			return {result, max}
+		end
+	end
+	redis.call('DEL', key, log)
+	redis.call('ZREM', index, id)
+	return {result, max}
+`)
+
+// Sentinel replies of incrementViewsScript.
+const (
+	incrementNotFound = -1
+	incrementExpired  = -2
+	incrementMaxViews = -3
+	incrementLegacy   = -4
+)
+
+func (r *RedisStore) IncrementViews(ctx context.Context, id string) (currentViews, maxViews int, err error) {
+	err = retryFailover(ctx, r.failover, func() error {
+		var err error
+		currentViews, maxViews, err = r.incrementViews(ctx, id)
+		return err
+	})
+	return currentViews, maxViews, err
+}
+
+func (r *RedisStore) incrementViews(ctx context.Context, id string) (int, int, error) {
+	key := r.secretKey(id)
+	reply, err := r.runIncrementViews(ctx, key, id)
+	if err == nil && len(reply) == 2 && reply[0] == incrementLegacy {
+		if err := r.migrateLegacy(ctx, key); err != nil {
+			return 0, 0, err
 		}
-		if errors.Is(err, ErrNotFound) {
-			return 0, err
+		reply, err = r.runIncrementViews(ctx, key, id)
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(reply) != 2 {
+		return 0, 0, fmt.Errorf("unexpected increment reply %v", reply)
+	}
+
+	switch views := int(reply[0]); views {
+	case incrementNotFound:
+		return 0, 0, ErrNotFound
+	case incrementExpired:
+		return 0, 0, ErrExpired
+	case incrementMaxViews:
+		return 0, 0, ErrMaxViews
+	case incrementLegacy:
+		return 0, 0, errors.New("secret still in the legacy redis layout after migrating it")
+	default:
+		return views, int(reply[1]), nil
+	}
+}
+
+func (r *RedisStore) runIncrementViews(ctx context.Context, key, id string) ([]int64, error) {
+	return incrementViewsScript.Run(ctx, r.client,
+		[]string{key, r.key(expiryIndexKey), r.accessLogKey(id)},
+		time.Now().UnixMilli(), id,
+	).Int64Slice()
+}
+
+func (r *RedisStore) GetAndDelete(ctx context.Context, id string) (*models.Secret, error) {
+	var result *models.Secret
+	err := retryFailover(ctx, r.failover, func() error {
+		var err error
+		result, err = r.getAndDelete(ctx, id)
+		return err
+	})
+	return result, err
+}
+
+func (r *RedisStore) getAndDelete(ctx context.Context, id string) (*models.Secret, error) {
+	key := r.secretKey(id)
+	// Only one of the reads succeeds: HMGET on a hash, GET on a secret an
+	// earlier version saved as a plain string.
+	var fields *redis.SliceCmd
+	var legacy *redis.StringCmd
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		fields = pipe.HMGet(ctx, key, fieldData, fieldViews)
+		legacy = pipe.Get(ctx, key)
+		pipe.Del(ctx, key, r.accessLogKey(id))
+		pipe.ZRem(ctx, r.key(expiryIndexKey), id)
+		return nil
+	})
+	if err != nil && !errors.Is(err, redis.Nil) && !isWrongType(err) {
+		return nil, err
+	}
+
+	var secret *models.Secret
+	if data, err := legacy.Bytes(); err == nil {
+		secret, err = r.codec.decode(data)
+		if err != nil {
+			return nil, err
 		}
-		return 0, err
+	} else if secret, err = r.decodeFields(fields.Val()); err != nil {
+		return nil, err
+	}
+	if time.Now().After(secret.ExpiresAt) {
+		return nil, ErrExpired
+	}
+	if secret.CurrentViews >= secret.MaxViews {
+		return nil, ErrMaxViews
+	}
+	return secret, nil
+}
+
+// appendAccessScript appends to a secret's access log only while the
+// secret exists, trimming it to the last ARGV[2] entries and giving it the
+// secret's remaining TTL so the two expire together.
+var appendAccessScript = redis.NewScript(`
+	local key, log = KEYS[1], KEYS[2]
+	local ttl = redis.call('PTTL', key)
+	if ttl < 0 then
+		return 0
+	end
+	redis.call('RPUSH', log, ARGV[1])
+	redis.call('LTRIM', log, -tonumber(ARGV[2]), -1)
+	redis.call('PEXPIRE', log, ttl)
+	return 1
+`)
+
+func (r *RedisStore) AppendAccess(ctx context.Context, id string, entry models.AccessEntry, max int) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
 	}
+	appended, err := appendAccessScript.Run(ctx, r.client,
+		[]string{r.secretKey(id), r.accessLogKey(id)},
+		data, max,
+	).Int()
+	if err != nil {
+		return err
+	}
+	if appended == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
 
-	return 0, redis.TxFailedErr
+func (r *RedisStore) AccessLog(ctx context.Context, id string) ([]models.AccessEntry, error) {
+	var exists *redis.IntCmd
+	var entries *redis.StringSliceCmd
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		exists = pipe.Exists(ctx, r.secretKey(id))
+		entries = pipe.LRange(ctx, r.accessLogKey(id), 0, -1)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if exists.Val() == 0 {
+		return nil, ErrNotFound
+	}
+
+	log := make([]models.AccessEntry, 0, len(entries.Val()))
+	for _, data := range entries.Val() {
+		var entry models.AccessEntry
+		if err := json.Unmarshal([]byte(data), &entry); err != nil {
+			return nil, err
+		}
+		log = append(log, entry)
+	}
+	return log, nil
+}
+
+func (r *RedisStore) SaveTombstone(ctx context.Context, id string, ttl time.Duration) error {
+	return r.client.Set(ctx, r.key(tombstoneKey(id)), 1, ttl).Err()
+}
+
+func (r *RedisStore) HasTombstone(ctx context.Context, id string) (bool, error) {
+	n, err := r.client.Exists(ctx, r.key(tombstoneKey(id))).Result()
+	return n > 0, err
+}
+
+// allowRevealScript is the token bucket from throttle.go run atomically in
+// Redis, on the server's clock so every instance agrees. It returns
+// {allowed, wait_ms}.
+var allowRevealScript = redis.NewScript(`
+	local key = KEYS[1]
+	local rate = tonumber(ARGV[1])
+	local burst = tonumber(ARGV[2])
+	local t = redis.call('TIME')
+	local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+
+	local state = redis.call('HMGET', key, 'tokens', 'ts')
+	local tokens = tonumber(state[1])
+	if tokens == nil then
+		tokens = burst
+	else
+		tokens = math.min(burst, tokens + (now - tonumber(state[2])) / 1000 * rate)
+	end
+
+	local allowed, wait = 0, 0
+	if tokens >= 1 then
+		tokens = tokens - 1
+		allowed = 1
+	else
+		wait = math.ceil((1 - tokens) / rate * 1000)
+	end
+
+	redis.call('HSET', key, 'tokens', tostring(tokens), 'ts', now)
+	redis.call('PEXPIRE', key, math.ceil((burst - tokens) / rate * 1000) + 1)
+	return {allowed, wait}
+`)
+
+func (r *RedisStore) AllowReveal(ctx context.Context, id string, rate float64, burst int) (bool, time.Duration, error) {
+	res, err := allowRevealScript.Run(ctx, r.client, []string{r.key(throttleKey(id))}, rate, burst).Int64Slice()
+	if err != nil {
+		return false, 0, err
+	}
+	if len(res) != 2 {
+		return false, 0, errors.New("unexpected reply from throttle script")
+	}
+	return res[0] == 1, time.Duration(res[1]) * time.Millisecond, nil
+}
+
+func (r *RedisStore) IncrementRevealCount(ctx context.Context) (int64, error) {
+	return r.client.Incr(ctx, r.key(revealCountKey)).Result()
+}
+
+func (r *RedisStore) RevealCount(ctx context.Context) (int64, error) {
+	n, err := r.client.Get(ctx, r.key(revealCountKey)).Int64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	return n, err
+}
+
+// API keys are stored as JSON without a TTL; they live until revoked.
+// SweptExpired is unsupported: Redis expires secrets with key TTLs.
+func (r *RedisStore) SweptExpired(ctx context.Context) (int64, error) {
+	return 0, errors.ErrUnsupported
+}
+
+func (r *RedisStore) SaveAPIKey(ctx context.Context, key *models.APIKey) error {
+	data, err := json.Marshal(key)
+	if err != nil {
+		return err
+	}
+	return retryFailover(ctx, r.failover, func() error {
+		return r.client.Set(ctx, r.key(apiKeyKey(key.ID)), data, 0).Err()
+	})
+}
+
+func (r *RedisStore) GetAPIKey(ctx context.Context, id string) (*models.APIKey, error) {
+	var data []byte
+	err := retryFailover(ctx, r.failover, func() error {
+		var err error
+		data, err = r.client.Get(ctx, r.key(apiKeyKey(id))).Bytes()
+		return err
+	})
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var key models.APIKey
+	if err := json.Unmarshal(data, &key); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *RedisStore) DeleteAPIKey(ctx context.Context, id string) error {
+	var n int64
+	err := retryFailover(ctx, r.failover, func() error {
+		var err error
+		n, err = r.client.Del(ctx, r.key(apiKeyKey(id))).Result()
+		return err
+	})
+	if err == nil && n == 0 {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (r *RedisStore) Ping(ctx context.Context) error {
+	return r.client.Ping(ctx).Err()
 }
 
 func (r *RedisStore) Close() error {
@@ -174,22 +771,170 @@ func (r *RedisStore) Close() error {
 
 // Helpers
 
-func secretKey(id string) string {
-	return "secret:" + id
+// key prefixes a fixed key name.
+func (r *RedisStore) key(name string) string {
+	return r.prefix + name
+}
+
+// scanSecrets iterates over secret keys. A cluster client would scan one
+// arbitrary node, so there the scan goes to the master holding the tagged
+// slot.
+func (r *RedisStore) scanSecrets(ctx context.Context) (*redis.ScanIterator, error) {
+	var node redis.Cmdable = r.client
+	if cluster, ok := r.client.(*redis.ClusterClient); ok {
+		master, err := cluster.MasterForKey(ctx, r.secretKey(""))
+		if err != nil {
+			return nil, err
+		}
+		node = master
+	}
+	return node.Scan(ctx, 0, r.secretKey("*"), 100).Iterator(), nil
+}
+
+const (
+	revealCountKey = "stats:reveals"
+	// expiryIndexKey is a sorted set of secret IDs scored by expiry in unix
+	// milliseconds.
+	expiryIndexKey = "secrets:expiry"
+)
+
+func expiryMember(secret *models.Secret) redis.Z {
+	return redis.Z{Score: float64(secret.ExpiresAt.UnixMilli()), Member: secret.ID}
+}
+
+// A secret is a hash of its encoded blob plus the fields below, which
+// incrementViewsScript reads without decoding the blob. current_views is
+// the view count; the blob's own CurrentViews goes stale after a view.
+// Secrets saved as plain strings by earlier versions are rewritten as
+// hashes by migrateLegacy the first time they are read.
+const (
+	fieldData     = "data"
+	fieldViews    = "current_views"
+	fieldMaxViews = "max_views"
+	fieldExpires  = "expires_at"
+)
+
+// secretFields are the HSET arguments storing secret, encoded as data.
+func secretFields(secret *models.Secret, data []byte) []interface{} {
+	return []interface{}{
+		fieldData, data,
+		fieldViews, secret.CurrentViews,
+		fieldMaxViews, secret.MaxViews,
+		fieldExpires, secret.ExpiresAt.UnixMilli(),
+	}
 }
 
-func encode(secret *models.Secret) ([]byte, error) {
-	var buf bytes.Buffer
-	if err := gob.NewEncoder(&buf).Encode(secret); err != nil {
+// load reads the secret stored at key, with c either the client or a
+// transaction watching key. Access logs share the secret: prefix, so scans
+// over secrets see them too; they load as not found.
+func (r *RedisStore) load(ctx context.Context, c redis.Cmdable, key string) (*models.Secret, error) {
+	if strings.HasSuffix(key, accessLogSuffix) {
+		return nil, ErrNotFound
+	}
+	fields, err := c.HMGet(ctx, key, fieldData, fieldViews).Result()
+	if isWrongType(err) {
+		// Migrating a key c watches fails c's transaction, which the
+		// callers retry, and then find a hash.
+		if err := r.migrateLegacy(ctx, key); err != nil {
+			return nil, err
+		}
+		fields, err = c.HMGet(ctx, key, fieldData, fieldViews).Result()
+	}
+	if err != nil {
 		return nil, err
 	}
-	return buf.Bytes(), nil
+	return r.decodeFields(fields)
+}
+
+// migrateLegacy rewrites a secret an earlier version saved at key as a
+// plain string of its encoded blob in the hash layout, keeping its TTL.
+// A key that is gone or already a hash is left alone.
+func (r *RedisStore) migrateLegacy(ctx context.Context, key string) error {
+	txf := func(tx *redis.Tx) error {
+		data, err := tx.Get(ctx, key).Bytes()
+		if errors.Is(err, redis.Nil) || isWrongType(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		secret, err := r.codec.decode(data)
+		if err != nil {
+			return err
+		}
+		ttl, err := tx.PTTL(ctx, key).Result()
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Del(ctx, key)
+			pipe.HSet(ctx, key, secretFields(secret, data)...)
+			if ttl > 0 {
+				pipe.PExpire(ctx, key, ttl)
+			}
+			pipe.ZAdd(ctx, r.key(expiryIndexKey), expiryMember(secret))
+			return nil
+		})
+		return err
+	}
+
+	for i := 0; i < 3; i++ {
+		err := r.client.Watch(ctx, txf, key)
+		if errors.Is(err, redis.TxFailedErr) {
+			continue
+		}
+		return err
+	}
+	return redis.TxFailedErr
+}
+
+// isWrongType reports a command run against a key of another type, as
+// when a secret in one layout is read as the other.
+func isWrongType(err error) bool {
+	return redis.HasErrorPrefix(err, "WRONGTYPE")
 }
 
-func decode(data []byte) (*models.Secret, error) {
-	var secret models.Secret
-	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&secret); err != nil {
+// decodeFields decodes an HMGET of the data and current_views fields.
+func (r *RedisStore) decodeFields(fields []interface{}) (*models.Secret, error) {
+	if len(fields) != 2 || fields[0] == nil {
+		return nil, ErrNotFound
+	}
+	data, ok1 := fields[0].(string)
+	views, ok2 := fields[1].(string)
+	if !ok1 || !ok2 {
+		return nil, errors.New("unexpected secret fields in redis")
+	}
+
+	secret, err := r.codec.decode([]byte(data))
+	if err != nil {
 		return nil, err
 	}
-	return &secret, nil
+	if secret.CurrentViews, err = strconv.Atoi(views); err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+func (r *RedisStore) secretKey(id string) string {
+	return r.prefix + "secret:" + id
+}
+
+const accessLogSuffix = ":log"
+
+// accessLogKey is a list of the secret's access entries as JSON.
+func (r *RedisStore) accessLogKey(id string) string {
+	return r.secretKey(id) + accessLogSuffix
+}
+
+func tombstoneKey(id string) string {
+	return "tombstone:" + id
+}
+
+func apiKeyKey(id string) string {
+	return "apikey:" + id
+}
+
+func throttleKey(id string) string {
+	return "throttle:" + id
 }
@@ -2,6 +2,8 @@ package store
 
 import (
 	"context"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -59,3 +61,302 @@ func TestRedisStore(t *testing.T) {
 		t.Fatalf("secret should be nil")
 	}
 }
+
+func TestRedisStoreSaveReturningTTL(t *testing.T) {
+	store, err := NewRedisStore(&redis.Options{
+		Addr:     "localhost:6379",
+		Password: "",
+		DB:       0,
+	})
+	if err != nil {
+		t.Fatalf("failed to create redis store: %v", err)
+	}
+	defer store.Close()
+
+	requested := 1 * time.Hour
+	secret := &models.Secret{
+		ID:            "ttl",
+		EncryptedData: []byte("test"),
+		MaxViews:      1,
+		ExpiresAt:     time.Now().Add(requested),
+		CreatedAt:     time.Now(),
+	}
+	applied, err := store.SaveReturningTTL(context.Background(), secret)
+	if err != nil {
+		t.Fatalf("failed to save secret: %v", err)
+	}
+	if applied > requested || requested-applied > time.Second {
+		t.Fatalf("applied ttl mismatch: got %s, want ~%s", applied, requested)
+	}
+}
+
+func TestRedisStoreExpiringWithin(t *testing.T) {
+	store, err := NewRedisStore(&redis.Options{
+		Addr:     "localhost:6379",
+		Password: "",
+		DB:       0,
+	})
+	if err != nil {
+		t.Fatalf("failed to create redis store: %v", err)
+	}
+	defer store.Close()
+
+	before, err := store.ExpiringWithin(context.Background(), time.Hour)
+	if err != nil {
+		t.Fatalf("failed to count expiring secrets: %v", err)
+	}
+
+	soon := &models.Secret{ID: "expiring-soon", MaxViews: 1, ExpiresAt: time.Now().Add(10 * time.Minute)}
+	later := &models.Secret{ID: "expiring-later", MaxViews: 1, ExpiresAt: time.Now().Add(2 * time.Hour)}
+	for _, secret := range []*models.Secret{soon, later} {
+		if err := store.Save(context.Background(), secret); err != nil {
+			t.Fatalf("failed to save secret: %v", err)
+		}
+		defer store.Delete(context.Background(), secret.ID)
+	}
+
+	after, err := store.ExpiringWithin(context.Background(), time.Hour)
+	if err != nil {
+		t.Fatalf("failed to count expiring secrets: %v", err)
+	}
+	if after-before != 1 {
+		t.Fatalf("expiring count mismatch: got %d more, want 1", after-before)
+	}
+}
+
+func TestRedisStoreSaveWithinQuota(t *testing.T) {
+	store, err := NewRedisStore(&redis.Options{
+		Addr:     "localhost:6379",
+		Password: "",
+		DB:       0,
+	})
+	if err != nil {
+		t.Fatalf("failed to create redis store: %v", err)
+	}
+	defer store.Close()
+
+	checkQuotaRace(t, store, "redis", 5)
+}
+
+func TestRedisStoreGetAndDelete(t *testing.T) {
+	store, err := NewRedisStore(&redis.Options{
+		Addr:     "localhost:6379",
+		Password: "",
+		DB:       0,
+	})
+	if err != nil {
+		t.Fatalf("failed to create redis store: %v", err)
+	}
+	defer store.Close()
+
+	checkGetAndDelete(t, store, "redis")
+}
+
+func TestRedisStoreCompression(t *testing.T) {
+	options := &redis.Options{Addr: "localhost:6379"}
+	compressed, err := NewRedisStoreWithOptions(options, RedisStoreOptions{
+		Failover:    DefaultFailoverPolicy,
+		Compression: CompressionGzip,
+	})
+	if err != nil {
+		t.Fatalf("failed to create redis store: %v", err)
+	}
+	defer compressed.Close()
+	plain, err := NewRedisStore(options)
+	if err != nil {
+		t.Fatalf("failed to create redis store: %v", err)
+	}
+	defer plain.Close()
+
+	ctx := context.Background()
+	for _, s := range []*RedisStore{compressed, plain} {
+		id := "compression-" + strconv.FormatBool(s == compressed)
+		if err := s.Save(ctx, &models.Secret{
+			ID:            id,
+			EncryptedData: []byte(id),
+			MaxViews:      2,
+			ExpiresAt:     time.Now().Add(time.Hour),
+			CreatedAt:     time.Now(),
+		}); err != nil {
+			t.Fatalf("save %s: %v", id, err)
+		}
+		defer s.Delete(ctx, id)
+
+		// Both stores read, and rewrite on increment, what either wrote.
+		for _, r := range []*RedisStore{compressed, plain} {
+			secret, err := r.Get(ctx, id)
+			if err != nil || string(secret.EncryptedData) != id {
+				t.Fatalf("get %s: %v, %v", id, secret, err)
+			}
+		}
+		if views, _, err := plain.IncrementViews(ctx, id); err != nil || views != 1 {
+			t.Fatalf("increment %s: %d, %v", id, views, err)
+		}
+	}
+}
+
+func TestRedisStoreAPIKeys(t *testing.T) {
+	store, err := NewRedisStore(&redis.Options{
+		Addr:     "localhost:6379",
+		Password: "",
+		DB:       0,
+	})
+	if err != nil {
+		t.Fatalf("failed to create redis store: %v", err)
+	}
+	defer store.Close()
+
+	checkAPIKeys(t, store, "redis")
+}
+
+func TestRedisStoreUsage(t *testing.T) {
+	store, err := NewRedisStore(&redis.Options{
+		Addr:     "localhost:6379",
+		Password: "",
+		DB:       0,
+	})
+	if err != nil {
+		t.Fatalf("failed to create redis store: %v", err)
+	}
+	defer store.Close()
+
+	checkUsage(t, store, "redis")
+}
+
+func TestRedisStoreBurnOnLastView(t *testing.T) {
+	store, err := NewRedisStore(&redis.Options{
+		Addr:     "localhost:6379",
+		Password: "",
+		DB:       0,
+	})
+	if err != nil {
+		t.Fatalf("failed to create redis store: %v", err)
+	}
+	defer store.Close()
+
+	checkBurnOnLastView(t, store, "redis")
+}
+
+func TestRedisStoreIncrementRace(t *testing.T) {
+	store, err := NewRedisStore(&redis.Options{
+		Addr:     "localhost:6379",
+		Password: "",
+		DB:       0,
+	})
+	if err != nil {
+		t.Fatalf("failed to create redis store: %v", err)
+	}
+	defer store.Close()
+
+	checkIncrementRace(t, store, "redis")
+}
+
+func TestRedisStoreDropPassphrases(t *testing.T) {
+	store, err := NewRedisStore(&redis.Options{
+		Addr:     "localhost:6379",
+		Password: "",
+		DB:       0,
+	})
+	if err != nil {
+		t.Fatalf("failed to create redis store: %v", err)
+	}
+	defer store.Close()
+
+	checkDropPassphrases(t, store, "redis")
+}
+
+func TestRedisStoreLegacyStrings(t *testing.T) {
+	store, err := NewRedisStore(&redis.Options{
+		Addr:     "localhost:6379",
+		Password: "",
+		DB:       0,
+	})
+	if err != nil {
+		t.Fatalf("failed to create redis store: %v", err)
+	}
+	defer store.Close()
+	ctx := context.Background()
+
+	// saveLegacy stores secret the way versions before the hash layout did.
+	saveLegacy := func(id string, maxViews int) {
+		t.Helper()
+		data, err := store.codec.encode(&models.Secret{
+			ID:            id,
+			EncryptedData: []byte("legacy"),
+			MaxViews:      maxViews,
+			ExpiresAt:     time.Now().Add(time.Hour),
+			CreatedAt:     time.Now(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := store.client.Set(ctx, store.secretKey(id), data, time.Hour).Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	saveLegacy("legacy-get", 2)
+	if secret, err := store.Get(ctx, "legacy-get"); err != nil || string(secret.EncryptedData) != "legacy" {
+		t.Fatalf("get of a legacy secret: %v, %v", secret, err)
+	}
+	if ttl := store.client.PTTL(ctx, store.secretKey("legacy-get")).Val(); ttl <= 0 {
+		t.Fatalf("migration lost the TTL: %v", ttl)
+	}
+
+	saveLegacy("legacy-view", 2)
+	if views, max, err := store.IncrementViews(ctx, "legacy-view"); err != nil || views != 1 || max != 2 {
+		t.Fatalf("view of a legacy secret: %d/%d, %v", views, max, err)
+	}
+
+	saveLegacy("legacy-burn", 1)
+	if secret, err := store.GetAndDelete(ctx, "legacy-burn"); err != nil || string(secret.EncryptedData) != "legacy" {
+		t.Fatalf("get and delete of a legacy secret: %v, %v", secret, err)
+	}
+	if _, err := store.Get(ctx, "legacy-burn"); err == nil {
+		t.Fatal("legacy secret still there after get and delete")
+	}
+
+	saveLegacy("legacy-scan", 1)
+	if _, err := store.Usage(ctx, time.Now().Add(-time.Minute)); err != nil {
+		t.Fatalf("usage over a legacy secret: %v", err)
+	}
+	saveLegacy("legacy-scan", 1)
+	deleted, err := store.DeleteWhere(ctx, func(s *models.Secret) bool { return strings.HasPrefix(s.ID, "legacy-") })
+	if err != nil || deleted < 1 {
+		t.Fatalf("delete over legacy secrets: %d, %v", deleted, err)
+	}
+}
+
+func TestRedisStoreAccessLog(t *testing.T) {
+	store, err := NewRedisStore(&redis.Options{
+		Addr:     "localhost:6379",
+		Password: "",
+		DB:       0,
+	})
+	if err != nil {
+		t.Fatalf("failed to create redis store: %v", err)
+	}
+	defer store.Close()
+
+	checkAccessLog(t, store, "redis")
+}
+
+func TestRedisStoreUnknownMode(t *testing.T) {
+	if _, err := NewRedisStoreForMode("ring", &redis.UniversalOptions{Addrs: []string{"localhost:6379"}}, RedisStoreOptions{}); err == nil {
+		t.Fatal("accepted an unknown mode")
+	}
+}
+
+// TestRedisClusterKeys checks every key a secret's scripts and transactions
+// touch carries the same hash tag, so a cluster keeps them in one slot.
+func TestRedisClusterKeys(t *testing.T) {
+	r := &RedisStore{prefix: clusterKeyPrefix}
+	for _, key := range []string{r.secretKey("abc"), r.accessLogKey("abc"), r.key(expiryIndexKey), r.key(tombstoneKey("abc"))} {
+		if !strings.HasPrefix(key, "{secure-share}:") {
+			t.Errorf("key %q lacks the cluster hash tag", key)
+		}
+	}
+	if r.accessLogKey("abc") != r.secretKey("abc")+accessLogSuffix {
+		t.Error("access log key isn't the secret key plus the log suffix")
+	}
+}
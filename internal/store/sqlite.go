@@ -0,0 +1,696 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"secure.share/internal/models"
+
+	_ "modernc.org/sqlite"
+)
+
+var _ Store = (*SQLiteStore)(nil)
+
+// sqliteSchema is created on open. As with DynamoDB the secret is stored
+// encoded in data, and the columns queries filter on are kept beside it;
+// views and pin_failures are the live counters. Times are unix
+// milliseconds. access_log rows cascade with their secret, so every path
+// that deletes a secret, the expiry sweep included, deletes its log too.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS secrets (
+	id           TEXT PRIMARY KEY,
+	data         BLOB NOT NULL,
+	views        INTEGER NOT NULL,
+	max_views    INTEGER NOT NULL,
+	pin_failures INTEGER NOT NULL DEFAULT 0,
+	expires_at   INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS secrets_expires_at ON secrets (expires_at);
+CREATE TABLE IF NOT EXISTS access_log (
+	secret_id  TEXT NOT NULL REFERENCES secrets (id) ON DELETE CASCADE,
+	at         INTEGER NOT NULL,
+	ip         TEXT NOT NULL,
+	user_agent TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS access_log_secret_id ON access_log (secret_id);
+CREATE TABLE IF NOT EXISTS tombstones (
+	id         TEXT PRIMARY KEY,
+	expires_at INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS throttle (
+	id         TEXT PRIMARY KEY,
+	tokens     REAL NOT NULL,
+	updated_at INTEGER NOT NULL,
+	expires_at INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS counters (
+	name  TEXT PRIMARY KEY,
+	value INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS api_keys (
+	id   TEXT PRIMARY KEY,
+	data BLOB NOT NULL
+);
+`
+
+type SQLiteOptions struct {
+	// Path is the database file, created if missing.
+	Path string
+	// CleanupInterval is how often expired rows are deleted; zero means
+	// one minute. Reads check expiry themselves in between.
+	CleanupInterval time.Duration
+	Compression     Compression
+	// MaxInflated bounds how large a compressed blob may inflate on read;
+	// zero means DefaultMaxInflated.
+	MaxInflated int64
+}
+
+// SQLiteStore keeps secrets in a local SQLite database, for single-binary
+// deployments that need them to survive a restart.
+type SQLiteStore struct {
+	db            *sql.DB
+	codec         blobCodec
+	cleanupCancel context.CancelFunc
+	cleanupDone   chan struct{}
+	swept         atomic.Int64
+}
+
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	return NewSQLiteStoreWithOptions(SQLiteOptions{Path: path})
+}
+
+func NewSQLiteStoreWithOptions(opts SQLiteOptions) (*SQLiteStore, error) {
+	db, err := sql.Open("sqlite", opts.Path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=foreign_keys(1)")
+	if err != nil {
+		return nil, err
+	}
+	// SQLite allows one writer at a time. A single connection queues
+	// writers in process instead of failing them with SQLITE_BUSY, and
+	// makes every transaction below serializable.
+	db.SetMaxOpenConns(1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := db.ExecContext(ctx, sqliteSchema); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	interval := opts.CleanupInterval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	cleanupCtx, cleanupCancel := context.WithCancel(context.Background())
+	s := &SQLiteStore{
+		db:            db,
+		codec:         newBlobCodec(opts.Compression, opts.MaxInflated),
+		cleanupCancel: cleanupCancel,
+		cleanupDone:   make(chan struct{}),
+	}
+	go s.cleanupLoop(cleanupCtx, interval)
+	return s, nil
+}
+
+func (s *SQLiteStore) Save(ctx context.Context, secret *models.Secret) error {
+	_, err := s.SaveReturningTTL(ctx, secret)
+	return err
+}
+
+// SaveReturningTTL inserts the secret only if its ID is free, so a
+// collision can never overwrite another secret.
+func (s *SQLiteStore) SaveReturningTTL(ctx context.Context, secret *models.Secret) (time.Duration, error) {
+	ttl := time.Until(secret.ExpiresAt)
+	if ttl <= 0 {
+		return 0, ErrExpired
+	}
+	if err := s.insert(ctx, s.db, secret); err != nil {
+		return 0, err
+	}
+	return ttl, nil
+}
+
+// SaveWithinQuota counts and inserts in one transaction, which the single
+// connection serializes with every other write.
+func (s *SQLiteStore) SaveWithinQuota(ctx context.Context, secret *models.Secret, max int) error {
+	if time.Until(secret.ExpiresAt) <= 0 {
+		return ErrExpired
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var live int
+	err = tx.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM secrets WHERE expires_at > ? AND views < max_views`,
+		time.Now().UnixMilli(),
+	).Scan(&live)
+	if err != nil {
+		return err
+	}
+	if live >= max {
+		return ErrQuotaExceeded
+	}
+
+	if err := s.insert(ctx, tx, secret); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// execer is what insert needs from either the database or a transaction.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+func (s *SQLiteStore) insert(ctx context.Context, db execer, secret *models.Secret) error {
+	data, err := s.codec.encode(secret)
+	if err != nil {
+		return err
+	}
+
+	res, err := db.ExecContext(ctx,
+		`INSERT INTO secrets (id, data, views, max_views, pin_failures, expires_at)
+		 VALUES (?, ?, ?, ?, ?, ?) ON CONFLICT (id) DO NOTHING`,
+		secret.ID, data, secret.CurrentViews, secret.MaxViews, secret.PINFailures, secret.ExpiresAt.UnixMilli(),
+	)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrExists
+	}
+	return nil
+}
+
+func (s *SQLiteStore) Get(ctx context.Context, id string) (*models.Secret, error) {
+	secret, err := s.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if time.Now().After(secret.ExpiresAt) {
+		_ = s.Delete(ctx, id)
+		return nil, ErrExpired
+	}
+
+	if secret.CurrentViews >= secret.MaxViews {
+		_ = s.Delete(ctx, id)
+		return nil, ErrMaxViews
+	}
+
+	return secret, nil
+}
+
+// get fetches and decodes a secret with the live counters applied, without
+// any expiry or view checks.
+func (s *SQLiteStore) get(ctx context.Context, id string) (*models.Secret, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT data, views, pin_failures FROM secrets WHERE id = ?`, id)
+	return s.scanSecret(row)
+}
+
+func (s *SQLiteStore) Delete(ctx context.Context, id string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM secrets WHERE id = ?`, id)
+	return err
+}
+
+// storedSecret is a decoded row along with the blob it was decoded from.
+type storedSecret struct {
+	secret *models.Secret
+	data   []byte
+}
+
+// all decodes the stored secrets matching where. The rows are read to the end before
+// returning, since the single connection can't run another statement while
+// they are open.
+func (s *SQLiteStore) all(ctx context.Context, where string, args ...any) ([]storedSecret, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT data, views, pin_failures FROM secrets `+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stored []storedSecret
+	for rows.Next() {
+		var data []byte
+		var views, failures int
+		if err := rows.Scan(&data, &views, &failures); err != nil {
+			return nil, err
+		}
+		secret, err := s.decode(data, views, failures)
+		if err != nil {
+			return nil, err
+		}
+		stored = append(stored, storedSecret{secret: secret, data: data})
+	}
+	return stored, rows.Err()
+}
+
+func (s *SQLiteStore) DeleteWhere(ctx context.Context, match func(*models.Secret) bool) (int, error) {
+	stored, err := s.all(ctx, "")
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for _, st := range stored {
+		if !match(st.secret) {
+			continue
+		}
+		if err := s.Delete(ctx, st.secret.ID); err != nil {
+			return deleted, err
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+// UpdateWhere only writes a secret whose blob is still the one it read, so a
+// concurrent extend isn't undone; such secrets are skipped.
+func (s *SQLiteStore) UpdateWhere(ctx context.Context, update func(*models.Secret) bool) (int, error) {
+	stored, err := s.all(ctx, "")
+	if err != nil {
+		return 0, err
+	}
+
+	updated := 0
+	for _, st := range stored {
+		if !update(st.secret) {
+			continue
+		}
+		data, err := s.codec.encode(st.secret)
+		if err != nil {
+			return updated, err
+		}
+
+		res, err := s.db.ExecContext(ctx,
+			`UPDATE secrets SET data = ? WHERE id = ? AND data = ?`,
+			data, st.secret.ID, st.data,
+		)
+		if err != nil {
+			return updated, err
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return updated, err
+		} else if n > 0 {
+			updated++
+		}
+	}
+	return updated, nil
+}
+
+func (s *SQLiteStore) ExpiringWithin(ctx context.Context, d time.Duration) (int, error) {
+	now := time.Now()
+	var count int
+	err := s.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM secrets WHERE expires_at > ? AND expires_at <= ?`,
+		now.UnixMilli(), now.Add(d).UnixMilli(),
+	).Scan(&count)
+	return count, err
+}
+
+func (s *SQLiteStore) Usage(ctx context.Context, since time.Time) (Usage, error) {
+	var usage Usage
+	stored, err := s.all(ctx, `WHERE expires_at > ? AND views < max_views`, time.Now().UnixMilli())
+	if err != nil {
+		return usage, err
+	}
+	for _, st := range stored {
+		usage.add(st.secret, since)
+	}
+	return usage, nil
+}
+
+// Extend rewrites the row with the new expiry. The write is conditioned on
+// the view count being unchanged, so a concurrent reveal is never undone.
+func (s *SQLiteStore) Extend(ctx context.Context, id string, expiresAt time.Time) error {
+	secret, err := s.get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if time.Now().After(secret.ExpiresAt) {
+		_ = s.Delete(ctx, id)
+		return ErrExpired
+	}
+
+	secret.ExpiresAt = expiresAt
+	data, err := s.codec.encode(secret)
+	if err != nil {
+		return err
+	}
+
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE secrets SET data = ?, expires_at = ? WHERE id = ? AND views = ?`,
+		data, expiresAt.UnixMilli(), id, secret.CurrentViews,
+	)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// IncrementViews bumps the counter with a conditional UPDATE ... RETURNING,
+// so concurrent reveals can never push it past max_views, and deletes the
+// row in the same transaction when that was the last view.
+func (s *SQLiteStore) IncrementViews(ctx context.Context, id string) (int, int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+
+	var views, maxViews int
+	err = tx.QueryRowContext(ctx,
+		`UPDATE secrets SET views = views + 1
+		 WHERE id = ? AND views < max_views AND expires_at > ?
+		 RETURNING views, max_views`,
+		id, time.Now().UnixMilli(),
+	).Scan(&views, &maxViews)
+	if errors.Is(err, sql.ErrNoRows) {
+		tx.Rollback()
+		return 0, 0, s.incrementFailure(ctx, id)
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if views >= maxViews {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM secrets WHERE id = ?`, id); err != nil {
+			return 0, 0, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, 0, err
+	}
+	return views, maxViews, nil
+}
+
+// incrementFailure works out which condition of IncrementViews failed.
+func (s *SQLiteStore) incrementFailure(ctx context.Context, id string) error {
+	secret, err := s.get(ctx, id)
+	if err != nil {
+		return err
+	}
+	_ = s.Delete(ctx, id)
+	if secret.CurrentViews >= secret.MaxViews {
+		return ErrMaxViews
+	}
+	return ErrExpired
+}
+
+func (s *SQLiteStore) GetAndDelete(ctx context.Context, id string) (*models.Secret, error) {
+	row := s.db.QueryRowContext(ctx, `DELETE FROM secrets WHERE id = ? RETURNING data, views, pin_failures`, id)
+	secret, err := s.scanSecret(row)
+	if err != nil {
+		return nil, err
+	}
+	if time.Now().After(secret.ExpiresAt) {
+		return nil, ErrExpired
+	}
+	if secret.CurrentViews >= secret.MaxViews {
+		return nil, ErrMaxViews
+	}
+	return secret, nil
+}
+
+func (s *SQLiteStore) IncrementPINFailures(ctx context.Context, id string) (int, error) {
+	var failures int
+	err := s.db.QueryRowContext(ctx,
+		`UPDATE secrets SET pin_failures = pin_failures + 1 WHERE id = ? RETURNING pin_failures`,
+		id,
+	).Scan(&failures)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrNotFound
+	}
+	return failures, err
+}
+
+func (s *SQLiteStore) SaveTombstone(ctx context.Context, id string, ttl time.Duration) error {
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO tombstones (id, expires_at) VALUES (?, ?)
+		 ON CONFLICT (id) DO UPDATE SET expires_at = excluded.expires_at`,
+		id, time.Now().Add(ttl).UnixMilli(),
+	)
+	return err
+}
+
+func (s *SQLiteStore) HasTombstone(ctx context.Context, id string) (bool, error) {
+	var expiresAt int64
+	err := s.db.QueryRowContext(ctx, `SELECT expires_at FROM tombstones WHERE id = ?`, id).Scan(&expiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return time.Now().UnixMilli() < expiresAt, nil
+}
+
+// AllowReveal reads and writes the bucket in one transaction, which the
+// single connection serializes with every other reveal.
+func (s *SQLiteStore) AllowReveal(ctx context.Context, id string, rate float64, burst int) (bool, time.Duration, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, 0, err
+	}
+	defer tx.Rollback()
+
+	var b bucket
+	var updated int64
+	err = tx.QueryRowContext(ctx, `SELECT tokens, updated_at FROM throttle WHERE id = ?`, id).Scan(&b.tokens, &updated)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+	case err != nil:
+		return false, 0, err
+	default:
+		b.updated = time.UnixMilli(updated)
+	}
+
+	b, allowed, wait := b.take(time.Now(), rate, burst)
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO throttle (id, tokens, updated_at, expires_at) VALUES (?, ?, ?, ?)
+		 ON CONFLICT (id) DO UPDATE SET tokens = excluded.tokens, updated_at = excluded.updated_at, expires_at = excluded.expires_at`,
+		id, b.tokens, b.updated.UnixMilli(), b.full.UnixMilli(),
+	)
+	if err != nil {
+		return false, 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return false, 0, err
+	}
+	return allowed, wait, nil
+}
+
+func (s *SQLiteStore) IncrementRevealCount(ctx context.Context) (int64, error) {
+	var count int64
+	err := s.db.QueryRowContext(ctx,
+		`INSERT INTO counters (name, value) VALUES (?, 1)
+		 ON CONFLICT (name) DO UPDATE SET value = value + 1
+		 RETURNING value`,
+		revealCountKey,
+	).Scan(&count)
+	return count, err
+}
+
+func (s *SQLiteStore) RevealCount(ctx context.Context) (int64, error) {
+	var count int64
+	err := s.db.QueryRowContext(ctx, `SELECT value FROM counters WHERE name = ?`, revealCountKey).Scan(&count)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	return count, err
+}
+
+func (s *SQLiteStore) SweptExpired(ctx context.Context) (int64, error) {
+	return s.swept.Load(), nil
+}
+
+func (s *SQLiteStore) SaveAPIKey(ctx context.Context, key *models.APIKey) error {
+	data, err := json.Marshal(key)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx,
+		`INSERT INTO api_keys (id, data) VALUES (?, ?)
+		 ON CONFLICT (id) DO UPDATE SET data = excluded.data`,
+		key.ID, data,
+	)
+	return err
+}
+
+func (s *SQLiteStore) GetAPIKey(ctx context.Context, id string) (*models.APIKey, error) {
+	var data []byte
+	err := s.db.QueryRowContext(ctx, `SELECT data FROM api_keys WHERE id = ?`, id).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var key models.APIKey
+	if err := json.Unmarshal(data, &key); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (s *SQLiteStore) DeleteAPIKey(ctx context.Context, id string) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM api_keys WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *SQLiteStore) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+// Close stops the sweeper before closing the database, so a sweep is never
+// cut off halfway.
+func (s *SQLiteStore) Close() error {
+	s.cleanupCancel()
+	<-s.cleanupDone
+	return s.db.Close()
+}
+
+func (s *SQLiteStore) cleanupLoop(ctx context.Context, interval time.Duration) {
+	defer close(s.cleanupDone)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.cleanup(ctx)
+		}
+	}
+}
+
+// cleanup deletes expired rows. Secrets are deleted as soon as their last
+// view is used, so only expiry needs sweeping.
+func (s *SQLiteStore) cleanup(ctx context.Context) {
+	now := time.Now().UnixMilli()
+	for _, table := range []string{"secrets", "tombstones", "throttle"} {
+		// A failed sweep is retried on the next tick; reads check expiry
+		// themselves meanwhile.
+		res, err := s.db.ExecContext(ctx, `DELETE FROM `+table+` WHERE expires_at < ?`, now)
+		if err != nil || table != "secrets" {
+			continue
+		}
+		if n, err := res.RowsAffected(); err == nil {
+			s.swept.Add(n)
+		}
+	}
+}
+
+// AppendAccess inserts only if the secret is live and then trims the log,
+// in one transaction.
+func (s *SQLiteStore) AppendAccess(ctx context.Context, id string, entry models.AccessEntry, max int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx,
+		`INSERT INTO access_log (secret_id, at, ip, user_agent)
+		 SELECT id, ?, ?, ? FROM secrets WHERE id = ? AND expires_at > ?`,
+		entry.Time.UnixMilli(), entry.IP, entry.UserAgent, id, time.Now().UnixMilli(),
+	)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrNotFound
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM access_log WHERE secret_id = ? AND rowid NOT IN (
+			SELECT rowid FROM access_log WHERE secret_id = ? ORDER BY rowid DESC LIMIT ?)`,
+		id, id, max,
+	); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *SQLiteStore) AccessLog(ctx context.Context, id string) ([]models.AccessEntry, error) {
+	var exists bool
+	err := s.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM secrets WHERE id = ?)`, id).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrNotFound
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT at, ip, user_agent FROM access_log WHERE secret_id = ? ORDER BY rowid`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	log := []models.AccessEntry{}
+	for rows.Next() {
+		var at int64
+		var entry models.AccessEntry
+		if err := rows.Scan(&at, &entry.IP, &entry.UserAgent); err != nil {
+			return nil, err
+		}
+		entry.Time = time.UnixMilli(at)
+		log = append(log, entry)
+	}
+	return log, rows.Err()
+}
+
+// Helpers
+
+func (s *SQLiteStore) scanSecret(row *sql.Row) (*models.Secret, error) {
+	var data []byte
+	var views, failures int
+	err := row.Scan(&data, &views, &failures)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.decode(data, views, failures)
+}
+
+// decode applies the live counters to the blob written by Save.
+func (s *SQLiteStore) decode(data []byte, views, failures int) (*models.Secret, error) {
+	secret, err := s.codec.decode(data)
+	if err != nil {
+		return nil, err
+	}
+	secret.CurrentViews = views
+	secret.PINFailures = failures
+	return secret, nil
+}
@@ -0,0 +1,200 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"secure.share/internal/models"
+)
+
+func newSQLiteTestStore(t *testing.T, path string) *SQLiteStore {
+	t.Helper()
+	store, err := NewSQLiteStoreWithOptions(SQLiteOptions{Path: path, CleanupInterval: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("failed to open sqlite store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func newSQLiteStore(t *testing.T) *SQLiteStore {
+	return newSQLiteTestStore(t, filepath.Join(t.TempDir(), "secrets.db"))
+}
+
+func TestSQLiteStore(t *testing.T) {
+	store := newSQLiteStore(t)
+	ctx := context.Background()
+
+	secret := &models.Secret{
+		ID:            "123",
+		EncryptedData: []byte("test"),
+		MaxViews:      2,
+		ExpiresAt:     time.Now().Add(time.Hour),
+		CreatedAt:     time.Now(),
+		Passphrase:    "test",
+	}
+	if err := store.Save(ctx, secret); err != nil {
+		t.Fatalf("failed to save secret: %v", err)
+	}
+	if err := store.Save(ctx, secret); !errors.Is(err, ErrExists) {
+		t.Fatalf("expected ErrExists for duplicate save, got %v", err)
+	}
+
+	got, err := store.Get(ctx, secret.ID)
+	if err != nil {
+		t.Fatalf("failed to get secret: %v", err)
+	}
+	if string(got.EncryptedData) != "test" {
+		t.Fatalf("secret data mismatch: got %s, want %s", string(got.EncryptedData), "test")
+	}
+
+	for want := 1; want <= 2; want++ {
+		views, _, err := store.IncrementViews(ctx, secret.ID)
+		if err != nil {
+			t.Fatalf("failed to increment views: %v", err)
+		}
+		if views != want {
+			t.Fatalf("views mismatch: got %d, want %d", views, want)
+		}
+	}
+	if _, err := store.Get(ctx, secret.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound after last view, got %v", err)
+	}
+
+	expired := &models.Secret{
+		ID:        "expired",
+		MaxViews:  1,
+		ExpiresAt: time.Now().Add(20 * time.Millisecond),
+		CreatedAt: time.Now(),
+	}
+	if err := store.Save(ctx, expired); err != nil {
+		t.Fatalf("failed to save secret: %v", err)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if _, _, err := store.IncrementViews(ctx, expired.ID); !errors.Is(err, ErrExpired) {
+		t.Fatalf("expected ErrExpired, got %v", err)
+	}
+}
+
+func TestSQLiteStoreSurvivesReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.db")
+	ctx := context.Background()
+
+	first, err := NewSQLiteStore(path)
+	if err != nil {
+		t.Fatalf("failed to open sqlite store: %v", err)
+	}
+	secret := &models.Secret{ID: "kept", EncryptedData: []byte("data"), MaxViews: 3, ExpiresAt: time.Now().Add(time.Hour)}
+	if err := first.Save(ctx, secret); err != nil {
+		t.Fatalf("failed to save secret: %v", err)
+	}
+	if _, _, err := first.IncrementViews(ctx, secret.ID); err != nil {
+		t.Fatalf("failed to increment views: %v", err)
+	}
+	if err := first.Close(); err != nil {
+		t.Fatalf("failed to close: %v", err)
+	}
+
+	got, err := newSQLiteTestStore(t, path).Get(ctx, secret.ID)
+	if err != nil {
+		t.Fatalf("secret lost on reopen: %v", err)
+	}
+	if got.CurrentViews != 1 || string(got.EncryptedData) != "data" {
+		t.Fatalf("reopened secret = %+v", got)
+	}
+}
+
+func TestSQLiteStoreSweepsExpired(t *testing.T) {
+	store := newSQLiteStore(t)
+	ctx := context.Background()
+
+	secret := &models.Secret{ID: "short", MaxViews: 1, ExpiresAt: time.Now().Add(20 * time.Millisecond)}
+	if err := store.Save(ctx, secret); err != nil {
+		t.Fatalf("failed to save secret: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		var n int
+		if err := store.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM secrets`).Scan(&n); err != nil {
+			t.Fatalf("failed to count rows: %v", err)
+		}
+		if n == 0 {
+			if swept, err := store.SweptExpired(ctx); err != nil || swept != 1 {
+				t.Fatalf("swept expired = %d, %v; want 1", swept, err)
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expired secret was never swept")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestSQLiteStoreConcurrentIncrement(t *testing.T) {
+	store := newSQLiteStore(t)
+	ctx := context.Background()
+
+	secret := &models.Secret{
+		ID:        "race",
+		MaxViews:  3,
+		ExpiresAt: time.Now().Add(time.Hour),
+		CreatedAt: time.Now(),
+	}
+	if err := store.Save(ctx, secret); err != nil {
+		t.Fatalf("failed to save secret: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded := 0
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, _, err := store.IncrementViews(ctx, secret.ID); err == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if succeeded != secret.MaxViews {
+		t.Fatalf("successful increments mismatch: got %d, want %d", succeeded, secret.MaxViews)
+	}
+}
+
+func TestSQLiteStoreSaveWithinQuota(t *testing.T) {
+	checkQuotaRace(t, newSQLiteStore(t), "sqlite", 5)
+}
+
+func TestSQLiteStoreGetAndDelete(t *testing.T) {
+	checkGetAndDelete(t, newSQLiteStore(t), "sqlite")
+}
+
+func TestSQLiteStoreAPIKeys(t *testing.T) {
+	checkAPIKeys(t, newSQLiteStore(t), "sqlite")
+}
+
+func TestSQLiteStoreDropPassphrases(t *testing.T) {
+	checkDropPassphrases(t, newSQLiteStore(t), "sqlite")
+}
+
+func TestSQLiteStoreBurnOnLastView(t *testing.T) {
+	checkBurnOnLastView(t, newSQLiteStore(t), "sqlite")
+}
+
+func TestSQLiteStoreUsage(t *testing.T) {
+	checkUsage(t, newSQLiteStore(t), "sqlite")
+}
+
+func TestSQLiteStoreAccessLog(t *testing.T) {
+	checkAccessLog(t, newSQLiteStore(t), "sqlite")
+}
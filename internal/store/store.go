@@ -3,6 +3,7 @@ package store
 import (
 	"context"
 	"errors"
+	"time"
 
 	"secure.share/internal/models"
 )
@@ -11,12 +12,112 @@ var (
 	ErrNotFound = errors.New("secret not found")
 	ErrExpired  = errors.New("secret has expired")
 	ErrMaxViews = errors.New("secret has reached maximum views")
+	// ErrExists is returned by stores that refuse to overwrite an existing
+	// secret on Save.
+	ErrExists = errors.New("secret already exists")
+	// ErrQuotaExceeded is returned by SaveWithinQuota when the store
+	// already holds the maximum number of live secrets.
+	ErrQuotaExceeded = errors.New("secret quota exceeded")
 )
 
+type retryTokenKey struct{}
+
+// WithRetryToken tags ctx with a token identifying the caller's request, so
+// stores that support a grace window can let the same request retry a reveal
+// whose last view it already consumed.
+func WithRetryToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, retryTokenKey{}, token)
+}
+
+func retryToken(ctx context.Context) string {
+	token, _ := ctx.Value(retryTokenKey{}).(string)
+	return token
+}
+
+// Usage aggregates the metadata of live secrets. It holds counts only, never
+// IDs or content.
+type Usage struct {
+	Active int
+	// CreatedSince counts the live secrets created at or after the time
+	// Usage was asked for.
+	CreatedSince int
+	// TotalTTL sums each live secret's lifetime, from creation to expiry.
+	TotalTTL time.Duration
+}
+
+func (u *Usage) add(secret *models.Secret, since time.Time) {
+	u.Active++
+	if !secret.CreatedAt.Before(since) {
+		u.CreatedSince++
+	}
+	u.TotalTTL += secret.ExpiresAt.Sub(secret.CreatedAt)
+}
+
 type Store interface {
 	Save(ctx context.Context, secret *models.Secret) error
+	// SaveReturningTTL saves like Save and reports the TTL the store actually
+	// applied, which may differ slightly from secret.ExpiresAt (e.g. Redis
+	// millisecond rounding).
+	SaveReturningTTL(ctx context.Context, secret *models.Secret) (time.Duration, error)
+	// SaveWithinQuota saves only if fewer than max live secrets are stored,
+	// checking and inserting as one atomic step; otherwise it returns
+	// ErrQuotaExceeded.
+	SaveWithinQuota(ctx context.Context, secret *models.Secret, max int) error
 	Get(ctx context.Context, id string) (*models.Secret, error)
 	Delete(ctx context.Context, id string) error
-	IncrementViews(ctx context.Context, id string) (currentViews int, err error)
+	// DeleteWhere removes every stored secret for which match returns true
+	// and reports how many were deleted.
+	DeleteWhere(ctx context.Context, match func(*models.Secret) bool) (int, error)
+	// UpdateWhere rewrites every stored secret that update changes, keeping
+	// its expiry and view count, and reports how many were rewritten.
+	// update returns false to leave a secret as it is.
+	UpdateWhere(ctx context.Context, update func(*models.Secret) bool) (int, error)
+	// ExpiringWithin counts live secrets whose expiry falls within d from now.
+	ExpiringWithin(ctx context.Context, d time.Duration) (int, error)
+	// Usage aggregates live secrets' metadata without decrypting any.
+	Usage(ctx context.Context, since time.Time) (Usage, error)
+	// Extend moves a live secret's expiry to expiresAt.
+	Extend(ctx context.Context, id string, expiresAt time.Time) error
+	// IncrementViews uses one view of id and deletes the secret in the same
+	// step when that was its last, so currentViews reaching maxViews means
+	// the secret is gone. Both counts are read in that step, so they agree
+	// with each other whatever else is revealing the secret.
+	IncrementViews(ctx context.Context, id string) (currentViews, maxViews int, err error)
+	// GetAndDelete removes a live secret and returns it as stored, in one
+	// atomic step, so at most one caller ever gets it. Single-view reveals
+	// use it instead of IncrementViews.
+	GetAndDelete(ctx context.Context, id string) (*models.Secret, error)
+	// IncrementPINFailures records a wrong PIN for id and returns the new
+	// failure count.
+	IncrementPINFailures(ctx context.Context, id string) (int, error)
+	// AppendAccess records a reveal of a live secret, keeping the last max
+	// entries. The log is deleted with the secret, however that happens.
+	AppendAccess(ctx context.Context, id string, entry models.AccessEntry, max int) error
+	// AccessLog returns id's entries, oldest first.
+	AccessLog(ctx context.Context, id string) ([]models.AccessEntry, error)
+	// SaveTombstone records that id existed and was consumed, for ttl. It
+	// holds no content.
+	SaveTombstone(ctx context.Context, id string, ttl time.Duration) error
+	HasTombstone(ctx context.Context, id string) (bool, error)
+	// AllowReveal spends a token from id's reveal bucket (rate per second,
+	// up to burst). When the bucket is empty it returns false and how long
+	// until the next token.
+	AllowReveal(ctx context.Context, id string, rate float64, burst int) (bool, time.Duration, error)
+	// IncrementRevealCount bumps the instance-wide reveal counter, which is
+	// independent of any secret's own view count.
+	IncrementRevealCount(ctx context.Context) (int64, error)
+	RevealCount(ctx context.Context) (int64, error)
+	// SweptExpired reports how many expired secrets this instance's sweeper
+	// has deleted. Stores whose backend expires secrets itself (Redis,
+	// DynamoDB) never see it happen and return errors.ErrUnsupported.
+	SweptExpired(ctx context.Context) (int64, error)
+	// SaveAPIKey stores key, replacing any key with the same ID.
+	SaveAPIKey(ctx context.Context, key *models.APIKey) error
+	// GetAPIKey returns ErrNotFound for unknown and revoked keys.
+	GetAPIKey(ctx context.Context, id string) (*models.APIKey, error)
+	// DeleteAPIKey revokes a key, returning ErrNotFound if there is none.
+	DeleteAPIKey(ctx context.Context, id string) error
+	// Ping checks the store can be reached, for health checks.
+	Ping(ctx context.Context) error
 	Close() error
 }
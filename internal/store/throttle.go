@@ -0,0 +1,38 @@
+package store
+
+import (
+	"math"
+	"time"
+)
+
+// bucket is a token bucket refilled at rate tokens per second up to burst.
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	// full is when the bucket will have refilled to burst; after that it
+	// can be forgotten.
+	full time.Time
+}
+
+// take refills b up to now and tries to spend one token. If none is left it
+// reports how long until one will be.
+func (b bucket) take(now time.Time, rate float64, burst int) (bucket, bool, time.Duration) {
+	if b.updated.IsZero() {
+		b.tokens = float64(burst)
+	} else {
+		elapsed := now.Sub(b.updated).Seconds()
+		b.tokens = math.Min(float64(burst), b.tokens+elapsed*rate)
+	}
+	b.updated = now
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	b.full = now.Add(time.Duration((float64(burst) - b.tokens) / rate * float64(time.Second)))
+	if allowed {
+		return b, true, 0
+	}
+	wait := time.Duration((1 - b.tokens) / rate * float64(time.Second))
+	return b, false, wait
+}
@@ -2,7 +2,9 @@
 package web
 
 import (
+	"crypto/sha512"
 	"embed"
+	"encoding/base64"
 	"fmt"
 	"io/fs"
 	"net/http"
@@ -38,3 +40,14 @@ func GetFile(name string) ([]byte, error) {
 	}
 	return content, err
 }
+
+// Integrity returns the Subresource Integrity value ("sha384-...") of an
+// embedded file, usable both in integrity attributes and CSP hash sources.
+func Integrity(name string) (string, error) {
+	content, err := GetFile(name)
+	if err != nil {
+		return "", err
+	}
+	sum := sha512.Sum384(content)
+	return "sha384-" + base64.StdEncoding.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,280 @@
+// State management
+const states = {
+    loading: document.getElementById('loadingState'),
+    confirm: document.getElementById('confirmState'),
+    secret: document.getElementById('secretState'),
+    error: document.getElementById('errorState')
+};
+
+let secretContent = '';
+let passphrase = '';
+let secretId = '';
+let nonce = '';
+let clientEncrypted = false;
+let clientAuth = '';
+let userPassphrase = false;
+
+function showState(state) {
+    Object.values(states).forEach(s => s.classList.remove('active'));
+    states[state].classList.add('active');
+}
+
+function showError(message) {
+    document.getElementById('errorMessage').textContent = message;
+    showState('error');
+}
+
+function goHome() {
+    window.location.href = '/';
+}
+
+async function copySecret() {
+    if (!secretContent) return;
+
+    try {
+        await navigator.clipboard.writeText(secretContent);
+        const btn = document.getElementById('copyBtn');
+        btn.textContent = '✓ Copied!';
+        setTimeout(() => btn.textContent = '📋 Skopiuj hasło', 2000);
+    } catch (err) {
+        alert('Failed to copy. Please select and copy manually.');
+    }
+}
+
+async function checkStatus() {
+    // Parse URL
+    const path = window.location.pathname;
+    const hash = window.location.hash;
+
+    // Extract secret ID from path
+    const match = path.match(/\/s\/([^/]+)/);
+
+    if (!match) {
+        showError('Invalid secret URL - could not extract ID from path');
+        return;
+    }
+
+    secretId = match[1];
+    passphrase = hash.slice(1); // Remove #
+    nonce = new URLSearchParams(window.location.search).get('n') || '';
+
+    // Build API URL
+    const apiUrl = `/api/secrets/${secretId}/status`;
+
+    try {
+        const response = await fetch(apiUrl);
+        const responseText = await response.text();
+
+        let data;
+        try {
+            data = JSON.parse(responseText);
+        } catch (parseErr) {
+            showError('Invalid response from server');
+            return;
+        }
+
+        if (!data.exists) {
+            if (data.expired) {
+                showError('To hasło wygasło');
+            } else {
+                showError('To hasło nie istnieje lub zostało usunięte');
+            }
+            return;
+        }
+
+        const statusInfo = document.getElementById('statusInfo');
+        const expiresAt = new Date(data.expires_at);
+        statusInfo.textContent = data.views_remaining !== undefined
+            ? `Pozostało wyświetleń: ${data.views_remaining} • Wygasa: ${expiresAt.toLocaleString()}`
+            : `Wygasa: ${expiresAt.toLocaleString()}`;
+
+        if (data.user_passphrase) {
+            // The creator chose the passphrase and sent it separately.
+            userPassphrase = true;
+            document.getElementById('passphraseInput').hidden = false;
+        } else if (!passphrase) {
+            showError('Missing decryption key in URL (no # fragment)');
+            return;
+        }
+        if (data.client_encrypted) {
+            // The fragment is our AES key; the server only ever sees a hash of it.
+            clientEncrypted = true;
+            clientAuth = await clientAuthValue();
+        }
+        if (data.pin_required) {
+            document.getElementById('pinInput').hidden = false;
+        }
+        if (data.ack_required) {
+            document.getElementById('ackLabel').hidden = false;
+        }
+        if (data.code_required) {
+            document.getElementById('codeBox').hidden = false;
+        }
+
+        showState('confirm');
+
+    } catch (err) {
+        if (err.name === 'TypeError' && err.message.includes('fetch')) {
+            showError('Błąd sieci - nie można połączyć z serwerem. Sprawdź, czy serwer działa.');
+        } else {
+            showError(`Nie udało się sprawdzić statusu hasła: ${err.message}`);
+        }
+    }
+}
+
+async function acknowledge() {
+    const response = await fetch(`/api/secrets/${secretId}/ack`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ accepted: true })
+    });
+    const data = await response.json();
+    if (!response.ok) {
+        throw new Error(data.error || 'acknowledgment failed');
+    }
+    return data.ack_token;
+}
+
+function keyQuery() {
+    if (clientEncrypted) {
+        return `auth=${encodeURIComponent(clientAuth)}`;
+    }
+    return `passphrase=${encodeURIComponent(passphrase)}`;
+}
+
+// Client-encrypted secrets carry a raw 256-bit AES-GCM key as base64url in
+// the fragment, and the content is a 12-byte IV followed by the ciphertext.
+function fragmentKeyBytes() {
+    const b64 = passphrase.replace(/-/g, '+').replace(/_/g, '/');
+    return Uint8Array.from(atob(b64), c => c.charCodeAt(0));
+}
+
+async function clientAuthValue() {
+    const digest = await crypto.subtle.digest('SHA-256', fragmentKeyBytes());
+    return btoa(String.fromCharCode(...new Uint8Array(digest)))
+        .replace(/\+/g, '-').replace(/\//g, '_').replace(/=+$/, '');
+}
+
+async function decryptClientContent(content) {
+    const blob = Uint8Array.from(atob(content), c => c.charCodeAt(0));
+    const key = await crypto.subtle.importKey('raw', fragmentKeyBytes(), 'AES-GCM', false, ['decrypt']);
+    const plain = await crypto.subtle.decrypt({ name: 'AES-GCM', iv: blob.slice(0, 12) }, key, blob.slice(12));
+    return new TextDecoder().decode(plain);
+}
+
+async function sendCode() {
+    const response = await fetch(`/api/secrets/${secretId}/request-code?${keyQuery()}`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: '{}'
+    });
+    if (!response.ok) {
+        const data = await response.json();
+        alert(data.error || 'Nie udało się wysłać kodu');
+        return;
+    }
+    document.getElementById('sendCodeBtn').textContent = 'Wyślij ponownie';
+}
+
+async function burnSecret() {
+    if (!confirm('Usunąć hasło na stałe? Nikt nie będzie mógł go już wyświetlić.')) {
+        return;
+    }
+    const response = await fetch(`/api/secrets/${secretId}/burn?${keyQuery()}`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: '{}'
+    });
+    if (!response.ok) {
+        const data = await response.json();
+        alert(data.error || 'Nie udało się usunąć hasła');
+        return;
+    }
+    document.getElementById('burnBtn').hidden = true;
+    document.getElementById('viewsRemaining').textContent = 'Hasło zostało usunięte';
+}
+
+async function revealSecret() {
+    const ackRequired = !document.getElementById('ackLabel').hidden;
+    if (ackRequired && !document.getElementById('ackInput').checked) {
+        alert('Zaakceptuj warunki, aby wyświetlić hasło.');
+        return;
+    }
+
+    if (userPassphrase) {
+        passphrase = document.getElementById('passphraseInput').value;
+        if (!passphrase) {
+            alert('Wpisz hasło otrzymane od nadawcy.');
+            return;
+        }
+    }
+
+    showState('loading');
+
+    let apiUrl = `/api/secrets/${secretId}?${keyQuery()}`;
+    const pinInput = document.getElementById('pinInput');
+    if (!pinInput.hidden) {
+        apiUrl += `&pin=${encodeURIComponent(pinInput.value)}`;
+    }
+    if (nonce) {
+        apiUrl += `&nonce=${encodeURIComponent(nonce)}`;
+    }
+    if (!document.getElementById('codeBox').hidden) {
+        apiUrl += `&code=${encodeURIComponent(document.getElementById('codeInput').value)}`;
+    }
+
+    try {
+        if (ackRequired) {
+            apiUrl += `&ack=${encodeURIComponent(await acknowledge())}`;
+        }
+
+        const response = await fetch(apiUrl);
+        const responseText = await response.text();
+
+        let data;
+        try {
+            data = JSON.parse(responseText);
+        } catch (parseErr) {
+            showError('Invalid response from server');
+            return;
+        }
+
+        if (!response.ok) {
+            showError(data.error || 'Failed to reveal secret');
+            return;
+        }
+
+        if (data.next_nonce) {
+            // The old nonce is spent; keep the address bar usable for the next view.
+            nonce = data.next_nonce;
+            history.replaceState(null, '', `?n=${encodeURIComponent(nonce)}${window.location.hash}`);
+        }
+
+        secretContent = data.client_encrypted
+            ? await decryptClientContent(data.content)
+            : data.content;
+        document.getElementById('secretContent').textContent = secretContent;
+
+        const viewsText = data.burned
+            ? 'This was the last view - secret has been deleted'
+            : data.views_remaining === undefined
+            ? ''
+            : `${data.views_remaining} view${data.views_remaining !== 1 ? 's' : ''} remaining`;
+
+        document.getElementById('viewsRemaining').textContent = viewsText;
+        document.getElementById('burnBtn').hidden = !data.can_burn;
+
+        showState('secret');
+
+    } catch (err) {
+        showError(`Failed to reveal secret: ${err.message}`);
+    }
+}
+
+document.getElementById('revealBtn').addEventListener('click', revealSecret);
+document.getElementById('copyBtn').addEventListener('click', copySecret);
+document.getElementById('sendCodeBtn').addEventListener('click', sendCode);
+document.getElementById('burnBtn').addEventListener('click', burnSecret);
+document.querySelectorAll('.home-btn').forEach(btn => btn.addEventListener('click', goHome));
+
+checkStatus();